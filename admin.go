@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// NewAdminHandler returns an http.Handler that dumps the metrics of r, or
+// DefaultRegistry if r is nil.  It is meant to be mounted under a debug
+// prefix:
+//
+//	http.Handle("/debug/metrics/", http.StripPrefix("/debug/metrics", metrics.NewAdminHandler(nil)))
+//
+// GET /debug/metrics/ lists every metric and GET /debug/metrics/<name> (or
+// ?name=<name>) looks up a single one, answering 404 if it doesn't exist.
+// The listing can be narrowed with ?prefix=<prefix> and any number of
+// ?tag=<key>:<value> parameters, where key is one of ns, grp, tgt, act or sub.
+// ?percentiles=0.5,0.99 replaces the percentiles reported for histograms and
+// timers.  Responses are JSON when ?format=json is given or the request
+// accepts application/json, in a format added by RegisterEncoder when
+// ?format=<name> names it or the request accepts its content type, and one
+// line per metric otherwise.
+//
+// ?history=<duration>, such as ?history=5m, answers instead with the JSON
+// samples of the selected metrics taken over that duration by the running
+// HistoryRecorder of r, or 404 if there is none.
+func NewAdminHandler(r Registry) http.Handler {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return &adminHandler{registry: r}
+}
+
+type adminHandler struct {
+	registry Registry
+}
+
+// adminQuery is the parsed form of an admin request.
+type adminQuery struct {
+	name        string
+	prefix      string
+	tags        map[string]string
+	percentiles []float64
+	json        bool
+	encoder     Encoder
+	history     time.Duration
+}
+
+func (h *adminHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q, err := parseAdminQuery(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if q.history > 0 {
+		hr := historyOf(h.registry)
+		if nil == hr {
+			http.Error(w, "no history recorded", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(q.collectHistory(hr))
+		return
+	}
+
+	data := q.collect(h.registry)
+	if q.name != "" && len(data) == 0 {
+		http.Error(w, fmt.Sprintf("metric %q not found", q.name), http.StatusNotFound)
+		return
+	}
+
+	if nil != q.encoder {
+		w.Header().Set("Content-Type", q.encoder.ContentType())
+		q.encoder.Encode(w, &filteredRegistry{Registry: h.registry, keep: func(name string) bool {
+			_, ok := data[name]
+			return ok
+		}})
+		return
+	}
+	if q.json {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writeAdminText(w, data, q.percentiles)
+}
+
+func parseAdminQuery(req *http.Request) (*adminQuery, error) {
+	v := req.URL.Query()
+	q := &adminQuery{
+		name:        strings.TrimPrefix(req.URL.Path, "/"),
+		prefix:      v.Get("prefix"),
+		percentiles: DefaultJSONPercentiles,
+	}
+	if name := v.Get("name"); name != "" {
+		q.name = name
+	}
+	for _, tag := range v["tag"] {
+		kv := strings.SplitN(tag, ":", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("tag %q is not of the form key:value", tag)
+		}
+		if q.tags == nil {
+			q.tags = make(map[string]string)
+		}
+		q.tags[kv[0]] = kv[1]
+	}
+	if s := v.Get("percentiles"); s != "" {
+		ps, err := parsePercentiles(s)
+		if err != nil {
+			return nil, err
+		}
+		q.percentiles = ps
+	}
+	if s := v.Get("history"); s != "" {
+		d, err := parsePositiveDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("history %q: %v", s, err)
+		}
+		q.history = d
+	}
+	switch format := v.Get("format"); format {
+	case "json":
+		q.json = true
+	case "text":
+	case "":
+		accept := req.Header.Get("Accept")
+		if q.json = strings.Contains(accept, "application/json"); !q.json {
+			q.encoder = negotiateEncoder(accept)
+		}
+	default:
+		if q.encoder = LookupEncoder(format); nil == q.encoder {
+			return nil, fmt.Errorf("unknown format %q", format)
+		}
+	}
+	return q, nil
+}
+
+// collect returns the values of the metrics in r selected by q, keyed by
+// name.
+func (q *adminQuery) collect(r Registry) map[string]map[string]interface{} {
+	data := make(map[string]map[string]interface{})
+	if q.name != "" {
+		if m := r.Get(q.name); m != nil {
+			data[q.name] = jsonValues(m, q.percentiles)
+		}
+		return data
+	}
+	r.EachUnsorted(func(name string, i interface{}) {
+		if strings.HasPrefix(name, q.prefix) && matchTags(name, q.tags) {
+			data[name] = jsonValues(i, q.percentiles)
+		}
+	})
+	return data
+}
+
+// collectHistory returns the samples hr took over the last q.history of the
+// metrics selected by q, keyed by name.
+func (q *adminQuery) collectHistory(hr *HistoryRecorder) map[string][]HistoryPoint {
+	since := hr.clock.Now().Add(-q.history)
+	data := make(map[string][]HistoryPoint)
+	for _, name := range hr.Names() {
+		if q.name != "" && name != q.name ||
+			!strings.HasPrefix(name, q.prefix) || !matchTags(name, q.tags) {
+			continue
+		}
+		if points := hr.History(name, since); len(points) > 0 {
+			data[name] = points
+		}
+	}
+	return data
+}
+
+// matchTags reports whether the tagged metric name carries every tag in
+// want.  Untagged names only match an empty want.
+func matchTags(name string, want map[string]string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	if !IsTagged(name) {
+		return false
+	}
+	_, tags := ParseTaggedMetric(name)
+	for k, v := range want {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// writeAdminText writes one "name: key: value, ..." line per metric in name
+// order, with the keys in a fixed order.
+func writeAdminText(w http.ResponseWriter, data map[string]map[string]interface{}, ps []float64) {
+	keys := []string{"count", "value", "error", "min", "max", "mean", "stddev"}
+	for _, p := range ps {
+		keys = append(keys, percentileKey(p))
+	}
+	keys = append(keys, "1m.rate", "5m.rate", "15m.rate", "mean.rate")
+
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := data[name]
+		fields := make([]string, 0, len(values))
+		for _, k := range keys {
+			if v, ok := values[k]; ok {
+				fields = append(fields, fmt.Sprintf("%s: %v", k, v))
+			}
+		}
+		fmt.Fprintf(w, "%s: %s\n", name, strings.Join(fields, ", "))
+	}
+}
+
+// NewAdminResetHandler returns an http.Handler that zeroes counters and
+// histograms of r, or DefaultRegistry if r is nil, on
+// POST ?name=<name>&name=<name>.  Each request must first be allowed by
+// authorize; a nil authorize refuses every request.  Names are reset in order
+// and the handler stops at the first one that is missing (404) or can't be
+// reset (400).
+func NewAdminResetHandler(r Registry, authorize func(*http.Request) bool) http.Handler {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if authorize == nil || !authorize(req) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		names := req.URL.Query()["name"]
+		if len(names) == 0 {
+			http.Error(w, "no metric names given", http.StatusBadRequest)
+			return
+		}
+		for _, name := range names {
+			if err := r.Reset(name); err != nil {
+				code := http.StatusBadRequest
+				if _, ok := err.(MissingMetric); ok {
+					code = http.StatusNotFound
+				}
+				http.Error(w, err.Error(), code)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
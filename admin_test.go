@@ -0,0 +1,147 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAdminTestRegistry() Registry {
+	r := NewRegistry()
+	NewRegisteredCounter("requests", r).Inc(3)
+	NewRegisteredCounter(TaggedMetricName("errors", NewTagBoard("lobby", "login")), r).Inc(1)
+	NewRegisteredCounter(TaggedMetricName("errors", NewTagBoard("lobby", "match")), r).Inc(2)
+	h := NewRegisteredHistogram("latency", r, NewUniformSample(100))
+	for i := int64(1); i <= 100; i++ {
+		h.Update(i)
+	}
+	return r
+}
+
+func adminGet(t *testing.T, h http.Handler, url string, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", url, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdminHandlerText(t *testing.T) {
+	h := NewAdminHandler(newAdminTestRegistry())
+	w := adminGet(t, h, "/", "")
+	if w.Code != 200 {
+		t.Fatal(w.Code)
+	}
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatal(lines)
+	}
+	if lines[3] != "requests: count: 3" {
+		t.Fatal(lines[3])
+	}
+	if !strings.HasPrefix(lines[0], "latency: count: 100, min: 1, max: 100, mean: 50.5, stddev: ") ||
+		!strings.Contains(lines[0], "median: 50.5, 75%: 75.75,") {
+		t.Fatal(lines[0])
+	}
+}
+
+func TestAdminHandlerJSON(t *testing.T) {
+	h := NewAdminHandler(newAdminTestRegistry())
+	for _, w := range []*httptest.ResponseRecorder{
+		adminGet(t, h, "/?format=json&percentiles=0.9,0.999", ""),
+		adminGet(t, h, "/?percentiles=0.9,0.999", "application/json"),
+	} {
+		var data map[string]map[string]float64
+		if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+			t.Fatal(err, w.Body.String())
+		}
+		if len(data) != 4 {
+			t.Fatal(data)
+		}
+		latency := data["latency"]
+		if _, ok := latency["median"]; ok {
+			t.Error(latency)
+		}
+		if latency["90%"] != 90.9 || latency["99.9%"] != 100 {
+			t.Error(latency)
+		}
+	}
+}
+
+func TestAdminHandlerLookup(t *testing.T) {
+	h := NewAdminHandler(newAdminTestRegistry())
+	if w := adminGet(t, h, "/requests", ""); w.Body.String() != "requests: count: 3\n" {
+		t.Fatal(w.Body.String())
+	}
+	if w := adminGet(t, h, "/?name=requests", ""); w.Body.String() != "requests: count: 3\n" {
+		t.Fatal(w.Body.String())
+	}
+	if w := adminGet(t, h, "/missing", ""); w.Code != http.StatusNotFound {
+		t.Fatal(w.Code)
+	}
+}
+
+func TestAdminHandlerFilters(t *testing.T) {
+	h := NewAdminHandler(newAdminTestRegistry())
+	w := adminGet(t, h, "/?tag=ns:lobby&tag=grp:match", "")
+	if s := w.Body.String(); s != "lobby|matchTAGerrors: count: 2\n" {
+		t.Fatal(s)
+	}
+	w = adminGet(t, h, "/?prefix=lobby|", "")
+	if n := strings.Count(w.Body.String(), "\n"); n != 2 {
+		t.Fatal(w.Body.String())
+	}
+	for _, url := range []string{"/?tag=ns", "/?percentiles=2", "/?format=xml"} {
+		if w := adminGet(t, h, url, ""); w.Code != http.StatusBadRequest {
+			t.Error(url, w.Code)
+		}
+	}
+}
+
+func TestAdminResetHandler(t *testing.T) {
+	r := newAdminTestRegistry()
+	NewRegisteredMeter("meter", r)
+	allow := func(req *http.Request) bool { return req.Header.Get("X-Token") == "secret" }
+	h := NewAdminResetHandler(r, allow)
+
+	post := func(url, token string) int {
+		req := httptest.NewRequest("POST", url, nil)
+		req.Header.Set("X-Token", token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+	if code := post("/?name=requests", "wrong"); code != http.StatusForbidden {
+		t.Fatal(code)
+	}
+	if c := r.Get("requests").(Counter).Count(); c != 3 {
+		t.Fatal(c)
+	}
+	if code := post("/?name=requests&name=latency", "secret"); code != http.StatusNoContent {
+		t.Fatal(code)
+	}
+	if c := r.Get("requests").(Counter).Count(); c != 0 {
+		t.Fatal(c)
+	}
+	if c := r.Get("latency").(Histogram).Count(); c != 0 {
+		t.Fatal(c)
+	}
+	if code := post("/?name=missing", "secret"); code != http.StatusNotFound {
+		t.Fatal(code)
+	}
+	if code := post("/?name=meter", "secret"); code != http.StatusBadRequest {
+		t.Fatal(code)
+	}
+	if w := adminGet(t, h, "/?name=requests", ""); w.Code != http.StatusMethodNotAllowed {
+		t.Fatal(w.Code)
+	}
+	w := httptest.NewRecorder()
+	NewAdminResetHandler(r, nil).ServeHTTP(w, httptest.NewRequest("POST", "/?name=requests", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatal(w.Code)
+	}
+}
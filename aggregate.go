@@ -0,0 +1,271 @@
+package metrics
+
+import (
+	"sort"
+	"strings"
+)
+
+// An AggregationRule maps a metric name to the name of the series it is
+// merged into before export, or returns "" to leave the metric alone.
+type AggregationRule func(name string) string
+
+// TruncateTags merges tagged metrics that share their first depth tags, so
+// TruncateTags(2) folds lobby|login|ios|fbTAGerrors and
+// lobby|login|androidTAGerrors into lobby|loginTAGerrors.
+func TruncateTags(depth int) AggregationRule {
+	return func(name string) string {
+		i := strings.Index(name, TAG_METRIC_DELIMITER)
+		if i < 0 {
+			return ""
+		}
+		tags := strings.Split(name[:i], TAG_DELIMITER)
+		if len(tags) <= depth {
+			return ""
+		}
+		return strings.Join(tags[:depth], TAG_DELIMITER) + name[i:]
+	}
+}
+
+// MergePrefix merges every metric whose name starts with prefix into target.
+func MergePrefix(prefix, target string) AggregationRule {
+	return func(name string) string {
+		if strings.HasPrefix(name, prefix) {
+			return target
+		}
+		return ""
+	}
+}
+
+// AggregatedRegistry is a read-only view of a Registry in which metrics are
+// merged according to rules whenever the view is read.  Counters, Instant
+// counters and gauges are summed, meters have their counts and rates summed,
+// and histograms and timers are combined with their snapshots' Merge.  The
+// first rule that returns a name wins.  A metric already registered under
+// the name a rule merges into is merged with the others.  Metrics whose type
+// differs from that one's, or else from the first, in name order, of those
+// merged into the same name, and healthchecks, are passed through unmerged.
+//
+// Pass an AggregatedRegistry to an exporter in place of the registry it
+// wraps to cut the number of series sent from high-cardinality services.
+// Writes go straight to the underlying registry, and clearing an aggregated
+// Instant counter clears every counter merged into it, so exporters that
+// reset Instant counters keep working.
+type AggregatedRegistry struct {
+	Registry
+	rules []AggregationRule
+}
+
+// NewAggregatedRegistry returns a view of r aggregated by rules.
+func NewAggregatedRegistry(r Registry, rules ...AggregationRule) *AggregatedRegistry {
+	return &AggregatedRegistry{Registry: r, rules: rules}
+}
+
+// Each calls f for each aggregated metric in name order.
+func (r *AggregatedRegistry) Each(f func(string, interface{})) {
+	metrics := r.aggregate()
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f(name, metrics[name])
+	}
+}
+
+// EachUnsorted calls f for each aggregated metric in no particular order.
+func (r *AggregatedRegistry) EachUnsorted(f func(string, interface{})) {
+	for name, m := range r.aggregate() {
+		f(name, m)
+	}
+}
+
+// Get returns the aggregated metric with the given name or nil.
+func (r *AggregatedRegistry) Get(name string) interface{} {
+	return r.aggregate()[name]
+}
+
+// GetCurrent renders the aggregated metrics like StandardRegistry.GetCurrent.
+func (r *AggregatedRegistry) GetCurrent() string {
+	return renderCurrent(r.Each)
+}
+
+// MarshalJSON renders the aggregated metrics like StandardRegistry.MarshalJSON.
+func (r *AggregatedRegistry) MarshalJSON() ([]byte, error) {
+	return marshalRegistry(r)
+}
+
+func (r *AggregatedRegistry) target(name string) string {
+	for _, rule := range r.rules {
+		if target := rule(name); target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
+// aggregate groups the underlying metrics by target and merges each group.
+func (r *AggregatedRegistry) aggregate() map[string]interface{} {
+	groups := make(map[string][]namedMetric)
+	out := make(map[string]interface{})
+	r.Registry.EachUnsorted(func(name string, i interface{}) {
+		if _, ok := i.(Healthcheck); ok {
+			out[name] = i
+			return
+		}
+		target := r.target(name)
+		if target == "" {
+			out[name] = i
+			return
+		}
+		groups[target] = append(groups[target], namedMetric{name, i})
+	})
+	for target, nms := range groups {
+		sort.Sort(namedMetricSlice(nms))
+		// A series already named target is folded into, and sets the kind
+		// of, the merged one.
+		if i, ok := out[target]; ok {
+			if _, ok := i.(Healthcheck); !ok {
+				nms = append([]namedMetric{{target, i}}, nms...)
+			}
+		}
+		merged, rest := mergeMetrics(nms)
+		out[target] = merged
+		for _, nm := range rest {
+			out[nm.name] = nm.m
+		}
+	}
+	return out
+}
+
+// mergeMetrics merges the metrics of the same kind as the first of nms and
+// returns the others.
+func mergeMetrics(nms []namedMetric) (interface{}, []namedMetric) {
+	var rest []namedMetric
+	switch nms[0].m.(type) {
+	case Counter:
+		var sum int64
+		for _, nm := range nms {
+			if c, ok := nm.m.(Counter); ok {
+				sum += c.Count()
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return CounterSnapshot(sum), rest
+	case CounterFloat64:
+		var sum float64
+		for _, nm := range nms {
+			if c, ok := nm.m.(CounterFloat64); ok {
+				sum += c.Count()
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return CounterFloat64Snapshot(sum), rest
+	case Instant:
+		agg := aggregateInstant{}
+		for _, nm := range nms {
+			if c, ok := nm.m.(Instant); ok {
+				agg = append(agg, c)
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return agg, rest
+	case Gauge:
+		var sum int64
+		for _, nm := range nms {
+			if g, ok := nm.m.(Gauge); ok {
+				sum += g.Value()
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return GaugeSnapshot(sum), rest
+	case GaugeFloat64:
+		var sum float64
+		for _, nm := range nms {
+			if g, ok := nm.m.(GaugeFloat64); ok {
+				sum += g.Value()
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return GaugeFloat64Snapshot(sum), rest
+	case Meter:
+		agg := &MeterSnapshot{}
+		for _, nm := range nms {
+			if m, ok := nm.m.(Meter); ok {
+				addMeterSnapshot(agg, m.Snapshot())
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return agg, rest
+	case Timer:
+		agg := &TimerSnapshot{histogram: &HistogramSnapshot{sample: &SampleSnapshot{}}, meter: &MeterSnapshot{}}
+		for _, nm := range nms {
+			if t, ok := nm.m.(Timer); ok {
+				agg = agg.Merge(t)
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return agg, rest
+	case Histogram:
+		agg := &HistogramSnapshot{sample: &SampleSnapshot{}}
+		for _, nm := range nms {
+			if h, ok := nm.m.(Histogram); ok {
+				agg = agg.Merge(h)
+			} else {
+				rest = append(rest, nm)
+			}
+		}
+		return agg, rest
+	}
+	return nms[0].m, nms[1:]
+}
+
+func addMeterSnapshot(dst *MeterSnapshot, m Meter) {
+	dst.count += m.Count()
+	dst.rate1 += m.Rate1()
+	dst.rate5 += m.Rate5()
+	dst.rate15 += m.Rate15()
+	dst.rateMean += m.RateMean()
+}
+
+// aggregateInstant is the sum of several Instant counters.  Clearing it
+// clears them all; incrementing it panics.
+type aggregateInstant []Instant
+
+// Clear clears every Instant counter in the aggregate.
+func (a aggregateInstant) Clear() {
+	for _, c := range a {
+		c.Clear()
+	}
+}
+
+// Count returns the sum of the counts.
+func (a aggregateInstant) Count() int64 {
+	var sum int64
+	for _, c := range a {
+		sum += c.Count()
+	}
+	return sum
+}
+
+// Dec panics.
+func (aggregateInstant) Dec(int64) {
+	panic("Dec called on an aggregated Instant")
+}
+
+// Inc panics.
+func (aggregateInstant) Inc(int64) {
+	panic("Inc called on an aggregated Instant")
+}
+
+// Update panics.
+func (aggregateInstant) Update(int64) {
+	panic("Update called on an aggregated Instant")
+}
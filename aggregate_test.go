@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTruncateTags(t *testing.T) {
+	rule := TruncateTags(2)
+	for name, want := range map[string]string{
+		"lobby|login|ios|fbTAGerrors":  "lobby|loginTAGerrors",
+		"lobby|login|androidTAGerrors": "lobby|loginTAGerrors",
+		"lobby|loginTAGerrors":         "",
+		"errors":                       "",
+	} {
+		if got := rule(name); got != want {
+			t.Errorf("%s: %q != %q", name, got, want)
+		}
+	}
+}
+
+func TestAggregatedRegistry(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("lobby|login|iosTAGerrors", r).Inc(1)
+	NewRegisteredCounter("lobby|login|androidTAGerrors", r).Inc(2)
+	NewRegisteredGauge("lobby|login|webTAGerrors", r).Update(7)
+	GetOrRegisterInstantCounter("lobby|match|iosTAGcalls", r).Inc(3)
+	GetOrRegisterInstantCounter("lobby|match|webTAGcalls", r).Inc(4)
+	h1 := NewRegisteredHistogram("lobby|match|iosTAGlatency", r, NewUniformSample(10))
+	h2 := NewRegisteredHistogram("lobby|match|webTAGlatency", r, NewUniformSample(10))
+	h1.Update(1)
+	h2.Update(3)
+	NewRegisteredTimer("api.get", r).Update(10)
+	NewRegisteredTimer("api.put", r).Update(30)
+	NewRegisteredCounter("untouched", r).Inc(5)
+
+	a := NewAggregatedRegistry(r, TruncateTags(2), MergePrefix("api.", "api"))
+	var names []string
+	a.Each(func(name string, i interface{}) { names = append(names, name) })
+	want := []string{"api", "lobby|loginTAGerrors", "lobby|login|webTAGerrors", "lobby|matchTAGcalls", "lobby|matchTAGlatency", "untouched"}
+	if len(names) != len(want) {
+		t.Fatal(names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatal(names)
+		}
+	}
+
+	if c := a.Get("lobby|loginTAGerrors").(Counter).Count(); c != 3 {
+		t.Fatal(c)
+	}
+	if g := a.Get("lobby|login|webTAGerrors").(Gauge).Value(); g != 7 {
+		t.Fatal(g)
+	}
+	if h := a.Get("lobby|matchTAGlatency").(Histogram); h.Count() != 2 || h.Mean() != 2 {
+		t.Fatal(h.Count(), h.Mean())
+	}
+	if tm := a.Get("api").(Timer); tm.Count() != 2 || tm.Max() != 30 {
+		t.Fatal(tm.Count(), tm.Max())
+	}
+
+	calls := a.Get("lobby|matchTAGcalls").(Instant)
+	if c := calls.Count(); c != 7 {
+		t.Fatal(c)
+	}
+	calls.Clear()
+	if c := r.Get("lobby|match|iosTAGcalls").(Instant).Count(); c != 0 {
+		t.Fatal(c)
+	}
+
+	var b bytes.Buffer
+	WriteJSONOnce(a, &b)
+	if !bytes.Contains(b.Bytes(), []byte(`"lobby|loginTAGerrors":{"count":3}`)) {
+		t.Fatal(b.String())
+	}
+}
+
+func TestAggregatedRegistryExistingTarget(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("lobby|loginTAGerrors", r).Inc(5)
+	NewRegisteredCounter("lobby|login|iosTAGerrors", r).Inc(1)
+	NewRegisteredGauge("api", r).Update(2)
+	NewRegisteredTimer("api.get", r).Update(10)
+
+	a := NewAggregatedRegistry(r, TruncateTags(2), MergePrefix("api.", "api"))
+	if c := a.Get("lobby|loginTAGerrors").(Counter).Count(); c != 6 {
+		t.Errorf("lobby|loginTAGerrors: %d", c)
+	}
+	// The existing series sets the kind; others pass through.
+	if g, ok := a.Get("api").(Gauge); !ok || g.Value() != 2 {
+		t.Errorf("api: %v", a.Get("api"))
+	}
+	if tm, ok := a.Get("api.get").(Timer); !ok || tm.Count() != 1 {
+		t.Errorf("api.get: %v", a.Get("api.get"))
+	}
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import "sync"
+
+// An AlertValue extracts the number an AlertRule compares from a metric, and
+// reports false for metrics it doesn't apply to.
+type AlertValue func(metric interface{}) (float64, bool)
+
+// AlertPercentile compares the given percentile of histograms and timers,
+// e.g. AlertPercentile(0.99) with a threshold of float64(500*time.Millisecond)
+// for "p99 over 500ms".
+func AlertPercentile(p float64) AlertValue {
+	return func(metric interface{}) (float64, bool) {
+		switch m := metric.(type) {
+		case Timer:
+			return m.Percentile(p), true
+		case Histogram:
+			return m.Percentile(p), true
+		}
+		return 0, false
+	}
+}
+
+// AlertRule describes a condition on one metric: its value is above
+// Threshold, or below it if Below is set, for For consecutive evaluations.
+type AlertRule struct {
+	Name      string
+	Metric    string
+	Value     AlertValue // nil compares the dashboard's headline value
+	Threshold float64
+	Below     bool
+	For       int // at least 1
+
+	// OnAlert is called when the rule starts firing and again, with
+	// Resolved set, when its condition first stops holding.
+	OnAlert func(AlertEvent)
+}
+
+// AlertEvent reports a rule starting or stopping firing.
+type AlertEvent struct {
+	Rule     *AlertRule
+	Value    float64
+	Resolved bool
+}
+
+// An Alerter evaluates AlertRules against a registry each time it is
+// collected, so it can run on a Scheduler alongside other collectors:
+//
+//	a := metrics.NewAlerter()
+//	a.Add(metrics.AlertRule{Name: "slow-login", Metric: "login", Value: metrics.AlertPercentile(0.99),
+//		Threshold: float64(500 * time.Millisecond), For: 3, OnAlert: page})
+//	metrics.AddCollector("alerts", a, 10*time.Second)
+type Alerter struct {
+	mutex sync.Mutex
+	rules []*alertState
+}
+
+type alertState struct {
+	rule     AlertRule
+	breaches int
+	firing   bool
+}
+
+// NewAlerter constructs an Alerter with no rules.
+func NewAlerter() *Alerter {
+	return &Alerter{}
+}
+
+// Add adds a rule to the alerter.
+func (a *Alerter) Add(rule AlertRule) {
+	if rule.For < 1 {
+		rule.For = 1
+	}
+	if rule.Value == nil {
+		rule.Value = headlineValue
+	}
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.rules = append(a.rules, &alertState{rule: rule})
+}
+
+// Collect evaluates every rule against r and calls OnAlert for those that
+// start or stop firing.  A missing metric counts as the condition not
+// holding.
+func (a *Alerter) Collect(r Registry) {
+	var events []AlertEvent
+	a.mutex.Lock()
+	for _, s := range a.rules {
+		v, ok := 0.0, false
+		if m := r.Get(s.rule.Metric); m != nil {
+			v, ok = s.rule.Value(m)
+		}
+		breached := ok && (v > s.rule.Threshold && !s.rule.Below || v < s.rule.Threshold && s.rule.Below)
+		if breached {
+			s.breaches++
+			if !s.firing && s.breaches >= s.rule.For {
+				s.firing = true
+				events = append(events, AlertEvent{Rule: &s.rule, Value: v})
+			}
+		} else {
+			s.breaches = 0
+			if s.firing {
+				s.firing = false
+				events = append(events, AlertEvent{Rule: &s.rule, Value: v, Resolved: true})
+			}
+		}
+	}
+	a.mutex.Unlock()
+
+	for _, e := range events {
+		if e.Rule.OnAlert != nil {
+			e.Rule.OnAlert(e)
+		}
+	}
+}
+
+// Firing returns the names of the rules currently firing.
+func (a *Alerter) Firing() []string {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	var names []string
+	for _, s := range a.rules {
+		if s.firing {
+			names = append(names, s.rule.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlerterGaugeFor(t *testing.T) {
+	r := NewRegistry()
+	g := NewRegisteredGauge("inflight", r)
+	var events []AlertEvent
+	a := NewAlerter()
+	a.Add(AlertRule{Name: "busy", Metric: "inflight", Threshold: 10, For: 2, OnAlert: func(e AlertEvent) {
+		events = append(events, e)
+	}})
+
+	g.Update(11)
+	a.Collect(r)
+	if len(events) != 0 {
+		t.Fatal(events)
+	}
+	a.Collect(r)
+	if len(events) != 1 || events[0].Resolved || events[0].Value != 11 {
+		t.Fatal(events)
+	}
+	a.Collect(r)
+	if len(events) != 1 {
+		t.Fatal(events)
+	}
+	if f := a.Firing(); len(f) != 1 || f[0] != "busy" {
+		t.Fatal(f)
+	}
+
+	g.Update(3)
+	a.Collect(r)
+	if len(events) != 2 || !events[1].Resolved {
+		t.Fatal(events)
+	}
+	if f := a.Firing(); len(f) != 0 {
+		t.Fatal(f)
+	}
+}
+
+func TestAlerterTimerPercentile(t *testing.T) {
+	r := NewRegistry()
+	tm := NewRegisteredTimer("login", r)
+	fired := 0
+	a := NewAlerter()
+	a.Add(AlertRule{Name: "slow", Metric: "login", Value: AlertPercentile(0.99),
+		Threshold: float64(500 * time.Millisecond), OnAlert: func(e AlertEvent) { fired++ }})
+	a.Add(AlertRule{Name: "missing", Metric: "nope", Threshold: -1, OnAlert: func(e AlertEvent) { t.Fatal(e) }})
+	a.Add(AlertRule{Name: "idle", Metric: "login", Value: func(m interface{}) (float64, bool) {
+		return m.(Timer).Rate1(), true
+	}, Below: true, Threshold: 1})
+
+	tm.UpdateTime(100 * time.Millisecond)
+	a.Collect(r)
+	if fired != 0 {
+		t.Fatal(fired)
+	}
+	tm.UpdateTime(time.Second)
+	a.Collect(r)
+	if fired != 1 {
+		t.Fatal(fired)
+	}
+	if f := a.Firing(); len(f) != 2 || f[0] != "slow" || f[1] != "idle" {
+		t.Fatal(f)
+	}
+}
@@ -0,0 +1,65 @@
+// Package awsmetrics records AWS SDK for Go v2 requests into a go-metrics
+// registry.
+//
+// Every operation updates metrics tagged ns=aws, grp=<service>,
+// tgt=<operation>:
+//
+//	latency     a Timer of operation durations, retries included
+//	calls       a Counter of operations
+//	errors      a Counter of operations that failed
+//	throttles   a Counter of operations that failed because they were throttled
+//	retries     a Counter of attempts beyond the first
+//
+// Install it on every client created from a config:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx, config.WithAPIOptions(
+//		[]func(*middleware.Stack) error{awsmetrics.Middleware(nil)}))
+package awsmetrics
+
+import (
+	"context"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/moonfrog/go-metrics"
+)
+
+const namespace = "aws"
+
+// Middleware returns an API option adding the metrics middleware, which
+// records into r, to an operation's stack.  It runs last of the initialize
+// step, after the middleware registering the service and operation names
+// it tags metrics with.
+func Middleware(r metrics.Registry) func(*middleware.Stack) error {
+	if nil == r {
+		r = metrics.DefaultRegistry
+	}
+	return func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("GoMetrics",
+			func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+				start := time.Now()
+				out, md, err := next.HandleInitialize(ctx, in)
+				record(r, ctx, md, start, err)
+				return out, md, err
+			}), middleware.After)
+	}
+}
+
+func record(r metrics.Registry, ctx context.Context, md middleware.Metadata, start time.Time, err error) {
+	tb := metrics.NewTagBoard(namespace, awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx))
+	metrics.GetOrRegisterTimer(metrics.TaggedMetricName("latency", tb), r).UpdateSince(start)
+	metrics.GetOrRegisterCounter(metrics.TaggedMetricName("calls", tb), r).Inc(1)
+
+	if results, ok := retry.GetAttemptResults(md); ok && len(results.Results) > 1 {
+		metrics.GetOrRegisterCounter(metrics.TaggedMetricName("retries", tb), r).Inc(int64(len(results.Results) - 1))
+	}
+	if err == nil {
+		return
+	}
+	metrics.GetOrRegisterCounter(metrics.TaggedMetricName("errors", tb), r).Inc(1)
+	if retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err).Bool() {
+		metrics.GetOrRegisterCounter(metrics.TaggedMetricName("throttles", tb), r).Inc(1)
+	}
+}
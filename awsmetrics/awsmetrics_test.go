@@ -0,0 +1,50 @@
+package awsmetrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/moonfrog/go-metrics"
+)
+
+// invoke runs an operation of S3 GetObject, answered with err, through a
+// stack set up as the SDK's clients set theirs up.
+func invoke(t *testing.T, r metrics.Registry, err error) {
+	stack := middleware.NewStack("GetObject", func() interface{} { return struct{}{} })
+	if e := stack.Initialize.Add(&awsmiddleware.RegisterServiceMetadata{
+		ServiceID:     "S3",
+		OperationName: "GetObject",
+	}, middleware.Before); e != nil {
+		t.Fatal(e)
+	}
+	if e := Middleware(r)(stack); e != nil {
+		t.Fatal(e)
+	}
+	h := middleware.DecorateHandler(middleware.HandlerFunc(
+		func(context.Context, interface{}) (interface{}, middleware.Metadata, error) {
+			return nil, middleware.Metadata{}, err
+		}), stack)
+	h.Handle(context.Background(), struct{}{})
+}
+
+func TestMiddleware(t *testing.T) {
+	r := metrics.NewRegistry()
+	invoke(t, r, nil)
+	invoke(t, r, errors.New("failed"))
+
+	tb := metrics.NewTagBoard(namespace, "S3", "GetObject")
+	if m, ok := r.Get(metrics.TaggedMetricName("latency", tb)).(metrics.Timer); !ok || 2 != m.Count() {
+		t.Errorf("latency: %v", m)
+	}
+	for name, want := range map[string]int64{"calls": 2, "errors": 1} {
+		if m, ok := r.Get(metrics.TaggedMetricName(name, tb)).(metrics.Counter); !ok || want != m.Count() {
+			t.Errorf("%s: %v, want %d", name, m, want)
+		}
+	}
+	if nil != r.Get(metrics.TaggedMetricName("latency", metrics.NewTagBoard(namespace))) {
+		t.Error("recorded without service and operation")
+	}
+}
@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+// The benchmarks in this file cover the hot paths applications hit
+// concurrently and the exporter flushes that walk the whole registry.  Run
+// them with
+//
+//	go test -run NONE -bench . -cpu 1,4,16
+//
+// and compare against the previous release with benchstat.
+
+func BenchmarkCounterParallel(b *testing.B) {
+	c := NewCounter()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Inc(1)
+		}
+	})
+}
+
+func BenchmarkMeterParallel(b *testing.B) {
+	m := NewMeter()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.Mark(1)
+		}
+	})
+}
+
+func BenchmarkTimerParallel(b *testing.B) {
+	tm := NewTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			tm.UpdateTime(time.Millisecond)
+		}
+	})
+}
+
+func BenchmarkTimerSnapshot(b *testing.B) {
+	tm := NewTimer()
+	for i := 0; i < 10000; i++ {
+		tm.Update(int64(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tm.Snapshot()
+	}
+}
+
+func BenchmarkRegistryGetOrRegisterNew(b *testing.B) {
+	r := NewRegistry()
+	names := make([]string, b.N)
+	for i := range names {
+		names[i] = fmt.Sprintf("counter%d", i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		GetOrRegisterCounter(names[i], r)
+	}
+}
+
+func BenchmarkRegistryUpdateParallel(b *testing.B) {
+	r := NewRegistry()
+	g := NewLoadGenerator(r, 1000)
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			r.Update(g.Names[i%len(g.Names)], 1)
+			i++
+		}
+	})
+}
+
+func BenchmarkLoadGenerator(b *testing.B) {
+	g := NewLoadGenerator(NewRegistry(), 1000)
+	b.ResetTimer()
+	g.Run(4, b.N)
+}
+
+func BenchmarkFlushWriteOnce100k(b *testing.B) {
+	r := newFilledRegistry(b, 100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		WriteOnce(r, ioutil.Discard)
+	}
+}
+
+func BenchmarkFlushWriteJSONOnce100k(b *testing.B) {
+	r := newFilledRegistry(b, 100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		WriteJSONOnce(r, ioutil.Discard)
+	}
+}
+
+func BenchmarkFlushEachUnsorted100k(b *testing.B) {
+	r := newFilledRegistry(b, 100000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.EachUnsorted(func(name string, i interface{}) {
+			if t, ok := i.(Timer); ok {
+				t.Snapshot()
+			}
+		})
+	}
+}
+
+var (
+	filledRegistry     Registry
+	filledRegistryOnce sync.Once
+)
+
+// newFilledRegistry returns a registry of n metrics with values, built once
+// and shared by the flush benchmarks since it takes a while to populate.
+func newFilledRegistry(b *testing.B, n int) Registry {
+	if testing.Short() {
+		b.Skip("skipping exporter flush in short mode")
+	}
+	filledRegistryOnce.Do(func() {
+		filledRegistry = NewRegistry()
+		NewLoadGenerator(filledRegistry, n).Fill()
+	})
+	return filledRegistry
+}
+
+func TestLoadGenerator(t *testing.T) {
+	r := NewRegistry()
+	g := NewLoadGenerator(r, 10)
+	if len(g.Names) != 10 {
+		t.Fatal(g.Names)
+	}
+	g.Fill()
+	g.Run(3, 100)
+
+	var count int64
+	r.Each(func(name string, i interface{}) {
+		switch m := i.(type) {
+		case Counter:
+			count += m.Count()
+		case Gauge:
+			count++
+		case Meter:
+			count += m.Count()
+		case Timer:
+			count += m.Count()
+		case Histogram:
+			count += m.Count()
+		}
+	})
+	if count < 110 {
+		t.Fatal(count)
+	}
+	if _, ok := r.Get("load.timer.4").(Timer); !ok {
+		t.Fatal(r.Get("load.timer.4"))
+	}
+}
+
+func TestLoadGeneratorEmpty(t *testing.T) {
+	defer func() {
+		if nil == recover() {
+			t.Error("no panic for an empty population")
+		}
+	}()
+	NewLoadGenerator(NewRegistry(), 0)
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TextBreakdown makes GetCurrent, WriteOnce and the Log functions group the
+// tagged metrics sharing a name under a line with that name and their total,
+// listing them below it largest first, by their tags and share of the total,
+// so the tag values that dominate a metric stand out:
+//
+//	logins: total 30 over 2 series
+//	  {ns=lobby,grp=google} 66.7%
+//	  {ns=lobby,grp=apple} 33.3%
+//
+// Totals add up the counts of counters, meters, histograms and timers and
+// the absolute values of gauges.  GetCurrent ignores CurrentGroupByNamespace
+// while TextBreakdown is set.
+var TextBreakdown = false
+
+// breakdownItem is a metric as the text writers print it when TextBreakdown
+// is set.
+type breakdownItem struct {
+	header  string // set on the first metric of a group, as "name: total ..."
+	display string // the name to print the metric under
+	name    string
+	m       interface{}
+}
+
+// breakdownItems returns the metrics each visits in name order, with those
+// of each group of tagged metrics sharing a name placed at the name and
+// ordered largest first.
+func breakdownItems(each func(func(string, interface{}))) []breakdownItem {
+	type member struct {
+		name, tags string
+		m          interface{}
+		magnitude  float64
+	}
+	groups := make(map[string][]member)
+	var bases []string
+	var items []breakdownItem
+	each(func(name string, m interface{}) {
+		base, _, err := ParseTagged(name)
+		if err != nil {
+			items = append(items, breakdownItem{display: name, name: name, m: m})
+			bases = append(bases, name)
+			return
+		}
+		if _, ok := groups[base]; !ok {
+			bases = append(bases, base)
+		}
+		display := currentName(name, false)
+		groups[base] = append(groups[base], member{name, display[len(base):], m, logMagnitude(m)})
+	})
+
+	plain := make(map[string]breakdownItem, len(items))
+	for _, item := range items {
+		plain[item.name] = item
+	}
+	sort.Strings(bases)
+	items = items[:0]
+	for i, base := range bases {
+		if i > 0 && base == bases[i-1] {
+			continue
+		}
+		if item, ok := plain[base]; ok {
+			items = append(items, item)
+		}
+		members := groups[base]
+		if len(members) == 0 {
+			continue
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			if members[i].magnitude != members[j].magnitude {
+				return members[i].magnitude > members[j].magnitude
+			}
+			return members[i].name < members[j].name
+		})
+		total := 0.0
+		for _, m := range members {
+			total += m.magnitude
+		}
+		for j, m := range members {
+			share := 0.0
+			if total > 0 {
+				share = 100 * m.magnitude / total
+			}
+			item := breakdownItem{display: fmt.Sprintf("  %s %.1f%%", m.tags, share), name: m.name, m: m.m}
+			if j == 0 {
+				item.header = fmt.Sprintf("%s: total %g over %d series", base, total, len(members))
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
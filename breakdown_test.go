@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTextBreakdown(t *testing.T) {
+	defer func(b bool) { TextBreakdown = b }(TextBreakdown)
+	TextBreakdown = true
+
+	r := NewRegistry()
+	NewRegisteredCounter("logins", r).Inc(5)
+	NewRegisteredCounter(taggedName("logins", []string{"lobby", "apple"}), r).Inc(10)
+	NewRegisteredCounter(taggedName("logins", []string{"lobby", "google"}), r).Inc(30)
+	NewRegisteredGauge("zzz", r).Update(7)
+
+	want := "<--------Metrics--------->\n" +
+		"Metrics: logins: 5\n" +
+		"Metrics: logins: total 40 over 2 series\n" +
+		"Metrics:   {ns=lobby,grp=google} 75.0%: 30\n" +
+		"Metrics:   {ns=lobby,grp=apple} 25.0%: 10\n" +
+		"Metrics: zzz: 7\n"
+	if got := r.GetCurrent(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	var b bytes.Buffer
+	WriteOnce(r, &b)
+	if !strings.Contains(b.String(), "logins: total 40 over 2 series\ncounter   {ns=lobby,grp=google} 75.0%\n") {
+		t.Error(b.String())
+	}
+}
@@ -0,0 +1,51 @@
+package metrics
+
+// Circuit breaker states as reported by the state gauge of BreakerMetrics.
+const (
+	BreakerClosed   = 0
+	BreakerHalfOpen = 1
+	BreakerOpen     = 2
+)
+
+// BreakerMetrics makes a circuit breaker observable.  For a breaker named
+// "payments" it registers:
+//
+//	payments.state            a Gauge of BreakerClosed, BreakerHalfOpen or BreakerOpen
+//	payments.trips            a Counter of transitions into BreakerOpen
+//	payments.shortCircuited   a Counter of calls rejected without being attempted
+//
+// The breakermetrics subpackage adapts it to github.com/sony/gobreaker.
+type BreakerMetrics struct {
+	state          Gauge
+	trips          Counter
+	shortCircuited Counter
+}
+
+// NewBreakerMetrics constructs a BreakerMetrics and registers its metrics
+// under the given name.
+func NewBreakerMetrics(name string, r Registry) *BreakerMetrics {
+	return &BreakerMetrics{
+		state:          GetOrRegisterGauge(name+".state", r),
+		trips:          GetOrRegisterCounter(name+".trips", r),
+		shortCircuited: GetOrRegisterCounter(name+".shortCircuited", r),
+	}
+}
+
+// SetState records a state change of the breaker, counting a trip when it
+// opens.
+func (b *BreakerMetrics) SetState(state int64) {
+	if state == BreakerOpen && b.state.Value() != BreakerOpen {
+		b.trips.Inc(1)
+	}
+	b.state.Update(state)
+}
+
+// State returns the last recorded state.
+func (b *BreakerMetrics) State() int64 {
+	return b.state.Value()
+}
+
+// ShortCircuited records a call rejected by the breaker.
+func (b *BreakerMetrics) ShortCircuited() {
+	b.shortCircuited.Inc(1)
+}
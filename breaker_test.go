@@ -0,0 +1,25 @@
+package metrics
+
+import "testing"
+
+func TestBreakerMetrics(t *testing.T) {
+	r := NewRegistry()
+	b := NewBreakerMetrics("payments", r)
+
+	b.SetState(BreakerOpen)
+	b.SetState(BreakerOpen)
+	b.ShortCircuited()
+	b.SetState(BreakerHalfOpen)
+	b.SetState(BreakerOpen)
+	b.SetState(BreakerClosed)
+
+	if v := r.Get("payments.state").(Gauge).Value(); v != BreakerClosed {
+		t.Fatal(v)
+	}
+	if c := r.Get("payments.trips").(Counter).Count(); c != 2 {
+		t.Fatal(c)
+	}
+	if c := r.Get("payments.shortCircuited").(Counter).Count(); c != 1 {
+		t.Fatal(c)
+	}
+}
@@ -0,0 +1,47 @@
+// Package breakermetrics reports the state of github.com/sony/gobreaker
+// circuit breakers through a metrics.BreakerMetrics.
+//
+//	bm := metrics.NewBreakerMetrics("payments", nil)
+//	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+//		Name:          "payments",
+//		OnStateChange: breakermetrics.OnStateChange(bm, nil),
+//	})
+//	result, err := breakermetrics.Execute(cb, bm, func() (interface{}, error) { ... })
+package breakermetrics
+
+import (
+	"github.com/moonfrog/go-metrics"
+	"github.com/sony/gobreaker"
+)
+
+// OnStateChange returns a gobreaker.Settings.OnStateChange callback recording
+// every transition on bm and then calling next, if not nil.
+func OnStateChange(bm *metrics.BreakerMetrics, next func(name string, from, to gobreaker.State)) func(name string, from, to gobreaker.State) {
+	return func(name string, from, to gobreaker.State) {
+		bm.SetState(State(to))
+		if next != nil {
+			next(name, from, to)
+		}
+	}
+}
+
+// Execute runs req through cb, recording calls the breaker rejects as
+// short-circuited on bm.
+func Execute(cb *gobreaker.CircuitBreaker, bm *metrics.BreakerMetrics, req func() (interface{}, error)) (interface{}, error) {
+	result, err := cb.Execute(req)
+	if err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
+		bm.ShortCircuited()
+	}
+	return result, err
+}
+
+// State maps a gobreaker.State to the metrics.Breaker* constants.
+func State(s gobreaker.State) int64 {
+	switch s {
+	case gobreaker.StateOpen:
+		return metrics.BreakerOpen
+	case gobreaker.StateHalfOpen:
+		return metrics.BreakerHalfOpen
+	}
+	return metrics.BreakerClosed
+}
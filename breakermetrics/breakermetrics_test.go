@@ -0,0 +1,85 @@
+package breakermetrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+	"github.com/sony/gobreaker"
+)
+
+func count(r metrics.Registry, name string) int64 {
+	if c, ok := r.Get(name).(metrics.Counter); ok {
+		return c.Count()
+	}
+	return 0
+}
+
+func TestState(t *testing.T) {
+	for s, want := range map[gobreaker.State]int64{
+		gobreaker.StateClosed:   metrics.BreakerClosed,
+		gobreaker.StateHalfOpen: metrics.BreakerHalfOpen,
+		gobreaker.StateOpen:     metrics.BreakerOpen,
+	} {
+		if got := State(s); want != got {
+			t.Errorf("State(%v): %v != %v", s, want, got)
+		}
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	r := metrics.NewRegistry()
+	bm := metrics.NewBreakerMetrics("payments", r)
+	var changes []gobreaker.State
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:    "payments",
+		Timeout: 10 * time.Millisecond,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 2
+		},
+		OnStateChange: OnStateChange(bm, func(name string, from, to gobreaker.State) {
+			changes = append(changes, to)
+		}),
+	})
+	fail := func() (interface{}, error) { return nil, errors.New("declined") }
+	ok := func() (interface{}, error) { return "paid", nil }
+
+	// Two failures trip the breaker and the calls after are rejected.
+	Execute(cb, bm, fail)
+	Execute(cb, bm, fail)
+	if _, err := Execute(cb, bm, ok); gobreaker.ErrOpenState != err {
+		t.Fatalf("open breaker: %v", err)
+	}
+	Execute(cb, bm, ok)
+	if s := bm.State(); metrics.BreakerOpen != s {
+		t.Errorf("state: %v", s)
+	}
+	if n := count(r, "payments.trips"); 1 != n {
+		t.Errorf("trips: 1 != %v", n)
+	}
+	if n := count(r, "payments.shortCircuited"); 2 != n {
+		t.Errorf("shortCircuited: 2 != %v", n)
+	}
+
+	// After the timeout a successful trial closes it again.
+	time.Sleep(20 * time.Millisecond)
+	if result, err := Execute(cb, bm, ok); "paid" != result || nil != err {
+		t.Fatal(result, err)
+	}
+	if s := bm.State(); metrics.BreakerClosed != s {
+		t.Errorf("state: %v", s)
+	}
+	want := []gobreaker.State{gobreaker.StateOpen, gobreaker.StateHalfOpen, gobreaker.StateClosed}
+	if len(want) != len(changes) {
+		t.Fatalf("changes: %v", changes)
+	}
+	for i := range want {
+		if want[i] != changes[i] {
+			t.Errorf("changes: %v != %v", want, changes)
+		}
+	}
+	if n := count(r, "payments.trips"); 1 != n {
+		t.Errorf("trips after closing: 1 != %v", n)
+	}
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import "time"
+
+// processStartTime approximates when the process started: the moment this
+// package was initialized.
+var processStartTime = time.Now()
+
+// BuildInfoName returns the name of the info gauge RegisterBuildInfo
+// registers: build.Info tagged with ns=build, grp=version, tgt=commit and
+// act=date.  As with NewTagBoard, tags after the first empty one are dropped.
+func BuildInfoName(version, commit, date string) string {
+	return TaggedMetricName("build.Info", NewTagBoard("build", version, commit, date))
+}
+
+// RegisterBuildInfo registers the standard identity metrics every service
+// exports:
+//
+//	build.Info          a gauge fixed at 1 tagged with version, commit and date
+//	process.StartTime   the unix time in seconds the process started
+//	process.Uptime      the seconds elapsed since the process started
+func RegisterBuildInfo(r Registry, version, commit, date string) {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	info := NewGauge()
+	info.Update(1)
+	r.Register(BuildInfoName(version, commit, date), info)
+
+	start := NewGauge()
+	start.Update(processStartTime.Unix())
+	r.Register("process.StartTime", start)
+
+	r.Register("process.Uptime", NewFunctionalGauge(func() int64 {
+		return int64(time.Since(processStartTime) / time.Second)
+	}))
+}
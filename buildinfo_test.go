@@ -0,0 +1,26 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegisterBuildInfo(t *testing.T) {
+	r := NewRegistry()
+	RegisterBuildInfo(r, "1.4.2", "abc123", "2026-10-01")
+
+	name := BuildInfoName("1.4.2", "abc123", "2026-10-01")
+	if name != "build|1.4.2|abc123|2026-10-01TAGbuild.Info" {
+		t.Fatal(name)
+	}
+	if v := r.Get(name).(Gauge).Value(); v != 1 {
+		t.Fatal(v)
+	}
+	if v := r.Get("process.StartTime").(Gauge).Value(); v != processStartTime.Unix() {
+		t.Fatal(v)
+	}
+	want := int64(time.Since(processStartTime) / time.Second)
+	if v := r.Get("process.Uptime").(Gauge).Value(); v < want {
+		t.Fatalf("got %d, want at least %d", v, want)
+	}
+}
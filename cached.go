@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A CachedRegistryView serves Get, Each and EachUnsorted from a copy of the
+// names and metrics of a Registry taken at most refresh ago, so readers
+// polling aggressively, such as dashboards and event streams during an
+// incident, don't contend for the registry's lock with the code updating it.
+// The metrics themselves are shared, so their values are current; only the
+// set of registered names may be up to refresh stale.  Writes, such as
+// Register and GetOrRegister, go to the underlying registry and show up in
+// the view on its next refresh.
+type CachedRegistryView struct {
+	Registry
+	clock   Clock
+	refresh time.Duration
+
+	mutex    sync.Mutex   // serializes refreshes
+	snapshot atomic.Value // *registryCopy
+}
+
+// registryCopy is the copy of a registry a CachedRegistryView serves.
+type registryCopy struct {
+	at      time.Time
+	names   []string // sorted
+	metrics map[string]interface{}
+}
+
+// NewCachedRegistryView returns a CachedRegistryView of r, or DefaultRegistry
+// if r is nil, copying its metrics again on reads at least refresh after the
+// last copy.
+func NewCachedRegistryView(r Registry, refresh time.Duration) *CachedRegistryView {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return &CachedRegistryView{Registry: r, clock: DefaultClock, refresh: refresh}
+}
+
+// Refresh copies the metrics of the underlying registry now.
+func (v *CachedRegistryView) Refresh() {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	v.copy()
+}
+
+func (v *CachedRegistryView) copy() *registryCopy {
+	c := &registryCopy{at: v.clock.Now(), metrics: make(map[string]interface{})}
+	v.Registry.EachUnsorted(func(name string, i interface{}) {
+		c.metrics[name] = i
+	})
+	c.names = make([]string, 0, len(c.metrics))
+	for name := range c.metrics {
+		c.names = append(c.names, name)
+	}
+	sort.Strings(c.names)
+	v.snapshot.Store(c)
+	return c
+}
+
+// current returns the copy to serve, copying the registry again if the last
+// copy is refresh old.  Only one reader copies; the others wait for it.
+func (v *CachedRegistryView) current() *registryCopy {
+	if c, ok := v.snapshot.Load().(*registryCopy); ok && v.fresh(c) {
+		return c
+	}
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if c, ok := v.snapshot.Load().(*registryCopy); ok && v.fresh(c) {
+		return c
+	}
+	return v.copy()
+}
+
+func (v *CachedRegistryView) fresh(c *registryCopy) bool {
+	return v.clock.Now().Sub(c.at) < v.refresh
+}
+
+// Each calls f for each metric of the copy, sorted by name.
+func (v *CachedRegistryView) Each(f func(string, interface{})) {
+	c := v.current()
+	for _, name := range c.names {
+		f(name, c.metrics[name])
+	}
+}
+
+// EachUnsorted calls f for each metric of the copy in no particular order.
+func (v *CachedRegistryView) EachUnsorted(f func(string, interface{})) {
+	c := v.current()
+	for name, i := range c.metrics {
+		f(name, i)
+	}
+}
+
+// Get returns the metric of the copy by the given name, or nil.
+func (v *CachedRegistryView) Get(name string) interface{} {
+	return v.current().metrics[name]
+}
+
+func (v *CachedRegistryView) GetCurrent() string {
+	return renderCurrent(v.Each)
+}
+
+func (v *CachedRegistryView) MarshalJSON() ([]byte, error) {
+	return marshalRegistry(v)
+}
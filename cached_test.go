@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCachedRegistryView(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	c := NewRegisteredCounter("b", r)
+	v := NewCachedRegistryView(r, 100*time.Millisecond)
+	if v.Get("b") != c {
+		t.Fatal("counter missing from the view")
+	}
+
+	NewRegisteredCounter("a", r)
+	if nil != v.Get("a") {
+		t.Error("view refreshed before its interval")
+	}
+	c.Inc(3)
+	if 3 != v.Get("b").(Counter).Count() {
+		t.Error("view doesn't share the registry's metrics")
+	}
+
+	clock.Add(100 * time.Millisecond)
+	var names []string
+	v.Each(func(name string, _ interface{}) { names = append(names, name) })
+	if 2 != len(names) || "a" != names[0] || "b" != names[1] {
+		t.Errorf("Each: %v", names)
+	}
+
+	r.Unregister("a")
+	v.Refresh()
+	if nil != v.Get("a") {
+		t.Error("unregistered counter still in the view after Refresh")
+	}
+}
@@ -0,0 +1,199 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CgroupRoot is where the cgroup filesystem is mounted.
+var CgroupRoot = "/sys/fs/cgroup"
+
+// cgroupStats holds the container statistics read by readCgroupStats.
+type cgroupStats struct {
+	memoryUsage       int64 // bytes
+	memoryLimit       int64 // bytes, 0 when unlimited
+	cpuPeriods        int64
+	cpuThrottled      int64
+	cpuThrottledNanos int64
+	oomKills          int64
+}
+
+var (
+	cgroupMetrics struct {
+		MemoryUsage       Gauge
+		MemoryLimit       Gauge
+		CPUPeriods        Gauge
+		CPUThrottled      Gauge
+		CPUThrottledNanos Gauge
+		OOMKills          Gauge
+		ReadErrors        Counter
+	}
+	cgStats cgroupStats
+)
+
+// Capture new values for the container statistics.  This is designed to be
+// called as a goroutine.
+func CaptureCgroupStats(r Registry, d time.Duration) {
+	for _ = range time.Tick(d) {
+		CaptureCgroupStatsOnce(r)
+	}
+}
+
+// Capture new values for the memory usage and limit, CPU throttling and OOM
+// kills of the cgroup the process runs in, from either the unified (v2) or
+// legacy (v1) hierarchy under CgroupRoot.  Failed reads increment
+// container.ReadErrors and leave the previous values in place.  Giving a
+// registry which has not been given to RegisterCgroupStats will panic.
+func CaptureCgroupStatsOnce(r Registry) {
+	if err := readCgroupStats(CgroupRoot, &cgStats); err != nil {
+		cgroupMetrics.ReadErrors.Inc(1)
+		return
+	}
+
+	cgroupMetrics.MemoryUsage.Update(cgStats.memoryUsage)
+	cgroupMetrics.MemoryLimit.Update(cgStats.memoryLimit)
+	cgroupMetrics.CPUPeriods.Update(cgStats.cpuPeriods)
+	cgroupMetrics.CPUThrottled.Update(cgStats.cpuThrottled)
+	cgroupMetrics.CPUThrottledNanos.Update(cgStats.cpuThrottledNanos)
+	cgroupMetrics.OOMKills.Update(cgStats.oomKills)
+}
+
+// Register metrics for the statistics of the container the process runs in.
+// Memory is in bytes and a MemoryLimit of 0 means unlimited.  CPUPeriods,
+// CPUThrottled, CPUThrottledNanos and OOMKills are totals since the cgroup
+// was created.
+func RegisterCgroupStats(r Registry) {
+	cgroupMetrics.MemoryUsage = NewGauge()
+	cgroupMetrics.MemoryLimit = NewGauge()
+	cgroupMetrics.CPUPeriods = NewGauge()
+	cgroupMetrics.CPUThrottled = NewGauge()
+	cgroupMetrics.CPUThrottledNanos = NewGauge()
+	cgroupMetrics.OOMKills = NewGauge()
+	cgroupMetrics.ReadErrors = NewCounter()
+
+	r.Register("container.MemoryUsage", cgroupMetrics.MemoryUsage)
+	r.Register("container.MemoryLimit", cgroupMetrics.MemoryLimit)
+	r.Register("container.CPUPeriods", cgroupMetrics.CPUPeriods)
+	r.Register("container.CPUThrottled", cgroupMetrics.CPUThrottled)
+	r.Register("container.CPUThrottledNanos", cgroupMetrics.CPUThrottledNanos)
+	r.Register("container.OOMKills", cgroupMetrics.OOMKills)
+	r.Register("container.ReadErrors", cgroupMetrics.ReadErrors)
+}
+
+func readCgroupStats(root string, s *cgroupStats) error {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err == nil {
+		return readCgroupV2Stats(cgroupV2Dir(root), s)
+	}
+	return readCgroupV1Stats(root, s)
+}
+
+// cgroupV2Dir returns the directory of the process's own cgroup in the
+// unified hierarchy, falling back to root when running in a cgroup
+// namespace.
+func cgroupV2Dir(root string) string {
+	data, err := ioutil.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return root
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "0::") {
+			dir := filepath.Join(root, strings.TrimPrefix(line, "0::"))
+			if _, err := os.Stat(filepath.Join(dir, "memory.current")); err == nil {
+				return dir
+			}
+		}
+	}
+	return root
+}
+
+func readCgroupV2Stats(dir string, s *cgroupStats) error {
+	var err error
+	if s.memoryUsage, err = readCgroupInt(filepath.Join(dir, "memory.current")); err != nil {
+		return err
+	}
+	if s.memoryLimit, err = readCgroupInt(filepath.Join(dir, "memory.max")); err != nil {
+		return err
+	}
+	cpu, err := readCgroupKeyValues(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	s.cpuPeriods = cpu["nr_periods"]
+	s.cpuThrottled = cpu["nr_throttled"]
+	s.cpuThrottledNanos = cpu["throttled_usec"] * int64(time.Microsecond)
+	events, err := readCgroupKeyValues(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return err
+	}
+	s.oomKills = events["oom_kill"]
+	return nil
+}
+
+func readCgroupV1Stats(root string, s *cgroupStats) error {
+	var err error
+	if s.memoryUsage, err = readCgroupInt(filepath.Join(root, "memory", "memory.usage_in_bytes")); err != nil {
+		return err
+	}
+	if s.memoryLimit, err = readCgroupInt(filepath.Join(root, "memory", "memory.limit_in_bytes")); err != nil {
+		return err
+	}
+	// v1 reports "no limit" as the largest page-aligned int64.
+	if s.memoryLimit >= 1<<62 {
+		s.memoryLimit = 0
+	}
+	cpu, err := readCgroupKeyValues(filepath.Join(root, "cpu", "cpu.stat"))
+	if err != nil {
+		return err
+	}
+	s.cpuPeriods = cpu["nr_periods"]
+	s.cpuThrottled = cpu["nr_throttled"]
+	s.cpuThrottledNanos = cpu["throttled_time"]
+	// oom_kill was added to memory.oom_control in Linux 4.13.
+	oom, err := readCgroupKeyValues(filepath.Join(root, "memory", "memory.oom_control"))
+	if err != nil {
+		return err
+	}
+	s.oomKills = oom["oom_kill"]
+	return nil
+}
+
+// readCgroupInt reads a file holding a single integer, treating "max" as 0.
+func readCgroupInt(path string) (int64, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	value := string(bytes.TrimSpace(data))
+	if value == "max" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// readCgroupKeyValues reads a file of "key value" lines such as cpu.stat.
+func readCgroupKeyValues(path string) (map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values := make(map[string]int64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, scanner.Err()
+}
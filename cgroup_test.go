@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeCgroupFiles(t *testing.T, root string, files map[string]string) {
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCgroupV2Stats(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeCgroupFiles(t, root, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"memory.current":     "1048576\n",
+		"memory.max":         "max\n",
+		"memory.events":      "low 0\nhigh 0\nmax 3\noom 2\noom_kill 1\n",
+		"cpu.stat":           "usage_usec 100\nnr_periods 50\nnr_throttled 5\nthrottled_usec 2000\n",
+	})
+
+	var s cgroupStats
+	if err := readCgroupV2Stats(root, &s); err != nil {
+		t.Fatal(err)
+	}
+	want := cgroupStats{1048576, 0, 50, 5, 2000000, 1}
+	if s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+}
+
+func TestCgroupV1Stats(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	writeCgroupFiles(t, root, map[string]string{
+		"memory/memory.usage_in_bytes": "2048\n",
+		"memory/memory.limit_in_bytes": "4096\n",
+		"memory/memory.oom_control":    "oom_kill_disable 0\nunder_oom 0\noom_kill 4\n",
+		"cpu/cpu.stat":                 "nr_periods 10\nnr_throttled 2\nthrottled_time 300\n",
+	})
+
+	var s cgroupStats
+	if err := readCgroupStats(root, &s); err != nil {
+		t.Fatal(err)
+	}
+	want := cgroupStats{2048, 4096, 10, 2, 300, 4}
+	if s != want {
+		t.Fatalf("got %+v, want %+v", s, want)
+	}
+}
+
+func TestCgroupStatsReadError(t *testing.T) {
+	defer func(root string) { CgroupRoot = root }(CgroupRoot)
+	CgroupRoot = filepath.Join(os.TempDir(), "no-such-cgroup")
+
+	r := NewRegistry()
+	RegisterCgroupStats(r)
+	CaptureCgroupStatsOnce(r)
+	if c := r.Get("container.ReadErrors").(Counter).Count(); c != 1 {
+		t.Fatal(c)
+	}
+}
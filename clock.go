@@ -0,0 +1,119 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// A Clock tells meters, timers, exponentially-decaying samples and periodic
+// exporters the time.  Tests swap DefaultClock for a MockClock to drive rate
+// decay and interval flushing without sleeping.
+type Clock interface {
+	Now() time.Time
+	NewTicker(d time.Duration) Ticker
+}
+
+// A Ticker delivers ticks like a time.Ticker.
+type Ticker interface {
+	Chan() <-chan time.Time
+	Stop()
+}
+
+// DefaultClock is the Clock given to metrics as they are constructed and to
+// RunPeriodic as it starts.  Metrics keep the clock they were constructed
+// with, so set it before constructing them.
+var DefaultClock Clock = SystemClock{}
+
+// SystemClock is the Clock backed by the time package.
+type SystemClock struct{}
+
+// Now returns time.Now().
+func (SystemClock) Now() time.Time { return time.Now() }
+
+// NewTicker returns a Ticker backed by a time.Ticker.
+func (SystemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (t systemTicker) Chan() <-chan time.Time { return t.C }
+
+// MockClock is a Clock that only moves when told to.  Its tickers tick as
+// Add and Set move it past their deadlines, dropping ticks for slow
+// receivers like a time.Ticker does.
+type MockClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	tickers []*mockTicker
+}
+
+// NewMockClock constructs a MockClock reading now.
+func NewMockClock(now time.Time) *MockClock {
+	return &MockClock{now: now}
+}
+
+// Add moves the clock forward by d.
+func (c *MockClock) Add(d time.Duration) {
+	c.mutex.Lock()
+	now := c.now.Add(d)
+	c.mutex.Unlock()
+	c.Set(now)
+}
+
+// Now returns the clock's time.
+func (c *MockClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTicker returns a Ticker ticking every d of the clock's time.
+func (c *MockClock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("non-positive interval for MockClock.NewTicker")
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &mockTicker{clock: c, c: make(chan time.Time, 1), d: d, next: c.now.Add(d)}
+	c.tickers = append(c.tickers, t)
+	return t
+}
+
+// Set sets the clock's time, ticking any tickers whose deadlines pass.
+func (c *MockClock) Set(now time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = now
+	for _, t := range c.tickers {
+		for !t.next.After(now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.d)
+		}
+	}
+}
+
+type mockTicker struct {
+	clock *MockClock
+	c     chan time.Time
+	d     time.Duration
+	next  time.Time
+}
+
+func (t *mockTicker) Chan() <-chan time.Time { return t.c }
+
+func (t *mockTicker) Stop() {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	for i, u := range t.clock.tickers {
+		if u == t {
+			t.clock.tickers = append(t.clock.tickers[:i], t.clock.tickers[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMockClockMeter(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	m := NewMeter()
+	m.Mark(60)
+	clock.Add(time.Minute)
+	if rate := m.RateMean(); 1 != rate {
+		t.Errorf("m.RateMean(): 1 != %v", rate)
+	}
+
+	ticked := NewEWMA1()
+	ticked.Update(60)
+	for i := 0; i < 12; i++ {
+		ticked.Tick()
+	}
+	if rate, want := m.Rate1(), ticked.Rate(); rate != want {
+		t.Errorf("m.Rate1(): %v != %v", rate, want)
+	}
+}
+
+func TestMockClockTimer(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	tm := NewTimer()
+	tm.Time(func() { clock.Add(50 * time.Millisecond) })
+	start := clock.Now()
+	clock.Add(20 * time.Millisecond)
+	tm.UpdateSince(start)
+	if max := tm.Max(); int64(50*time.Millisecond) != max {
+		t.Errorf("tm.Max(): 50ms != %v", time.Duration(max))
+	}
+	if min := tm.Min(); int64(20*time.Millisecond) != min {
+		t.Errorf("tm.Min(): 20ms != %v", time.Duration(min))
+	}
+}
+
+func TestMockClockExpDecaySampleRescale(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	s := NewExpDecaySample(2, 0.001).(*ExpDecaySample)
+	s.Update(1)
+	clock.Add(time.Hour)
+	s.Update(2)
+	s.Update(3)
+	if t0 := s.t0; !t0.Equal(time.Unix(0, 0).Add(time.Hour)) {
+		t.Errorf("s.t0: %v", t0)
+	}
+	for _, v := range s.Values() {
+		if 1 == v {
+			t.Errorf("s.Values(): %v kept the value from an hour ago", s.Values())
+		}
+	}
+}
+
+func TestMockClockTicker(t *testing.T) {
+	clock := NewMockClock(time.Unix(0, 0))
+	ticker := clock.NewTicker(time.Second)
+	clock.Add(999 * time.Millisecond)
+	select {
+	case <-ticker.Chan():
+		t.Fatal("ticked early")
+	default:
+	}
+	clock.Add(3 * time.Second)
+	if tick := <-ticker.Chan(); !tick.Equal(time.Unix(1, 0)) {
+		t.Errorf("tick: %v", tick)
+	}
+	select {
+	case <-ticker.Chan():
+		t.Fatal("missed ticks weren't dropped")
+	default:
+	}
+	ticker.Stop()
+	clock.Add(time.Minute)
+	select {
+	case <-ticker.Chan():
+		t.Fatal("ticked after Stop")
+	default:
+	}
+}
+
+func TestRunPeriodicMockClock(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	exports := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go RunPeriodicContext(ctx, time.Minute, func() {
+		select {
+		case exports <- struct{}{}:
+		default:
+		}
+	})
+
+	// Advance until the loop has started its ticker and exported.
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		clock.Add(time.Minute)
+		select {
+		case <-exports:
+			done = true
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			t.Fatal("RunPeriodic never exported")
+		}
+	}
+}
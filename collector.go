@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Collectors gather values from outside the application, such as the Go
+// runtime or the operating system, and publish them into a registry.
+type Collector interface {
+	Collect(Registry)
+}
+
+// CollectorFunc adapts an ordinary function to the Collector interface.  The
+// CaptureXOnce functions of this package are all CollectorFuncs, e.g.
+// CollectorFunc(CaptureRuntimeMemStatsOnce).
+type CollectorFunc func(Registry)
+
+// Collect calls f(r).
+func (f CollectorFunc) Collect(r Registry) { f(r) }
+
+// A Scheduler runs collectors at their configured intervals from a single
+// goroutine, instead of each collector spawning its own ticker.  A collector
+// that panics is recovered and counted so it can't take the process or the
+// other collectors down.
+//
+// For a collector added as "runtime" the scheduler registers the timer
+// collector.runtime.Duration and the counter collector.runtime.Panics.
+type Scheduler struct {
+	registry Registry
+	mutex    sync.Mutex
+	entries  []*scheduledCollector
+	started  bool
+	wake     chan struct{}
+	stop     chan struct{}
+}
+
+type scheduledCollector struct {
+	name      string
+	collector Collector
+	interval  time.Duration
+	next      time.Time
+	duration  Timer
+	panics    Counter
+}
+
+// NewScheduler constructs a Scheduler publishing into r, which stops it when
+// closed.
+func NewScheduler(r Registry) *Scheduler {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	s := &Scheduler{
+		registry: r,
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	if c, ok := r.(interface {
+		onClose(func())
+	}); ok {
+		c.onClose(s.Stop)
+	}
+	return s
+}
+
+// DefaultScheduler runs the collectors added with AddCollector.
+var DefaultScheduler = NewScheduler(DefaultRegistry)
+
+// AddCollector adds c to DefaultScheduler.
+func AddCollector(name string, c Collector, interval time.Duration) {
+	DefaultScheduler.Add(name, c, interval)
+}
+
+// Add schedules c to run every interval, starting now, and launches the
+// scheduler goroutine if it isn't running yet.  It panics if interval isn't
+// positive, like time.NewTicker.
+func (s *Scheduler) Add(name string, c Collector, interval time.Duration) {
+	if interval <= 0 {
+		panic("non-positive interval for Scheduler.Add")
+	}
+	entry := &scheduledCollector{
+		name:      name,
+		collector: c,
+		interval:  interval,
+		next:      time.Now(),
+		duration:  GetOrRegisterTimer("collector."+name+".Duration", s.registry),
+		panics:    GetOrRegisterCounter("collector."+name+".Panics", s.registry),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.entries = append(s.entries, entry)
+	if !s.started {
+		s.started = true
+		go s.run(s.stop)
+	}
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Stop stops the scheduler goroutine.  Collectors already running finish.
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		close(s.stop)
+		s.started = false
+		s.stop = make(chan struct{})
+	}
+}
+
+// run runs the scheduler goroutine until stop is closed.  stop is read by
+// Add, under the lock, so a goroutine outliving a Stop can't pick up the
+// channel of the goroutine started after it.
+func (s *Scheduler) run(stop chan struct{}) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-s.wake:
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		next := now.Add(time.Hour)
+		for _, entry := range s.due(now) {
+			entry.collect(s.registry)
+		}
+		s.mutex.Lock()
+		for _, entry := range s.entries {
+			if entry.next.Before(next) {
+				next = entry.next
+			}
+		}
+		s.mutex.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(time.Until(next))
+	}
+}
+
+// due returns the collectors due at now and advances their next run.
+func (s *Scheduler) due(now time.Time) []*scheduledCollector {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var due []*scheduledCollector
+	for _, entry := range s.entries {
+		if entry.next.After(now) {
+			continue
+		}
+		due = append(due, entry)
+		entry.next = entry.next.Add(entry.interval)
+		if entry.next.Before(now) {
+			// Skip the runs we missed instead of bursting to catch up.
+			entry.next = now.Add(entry.interval)
+		}
+	}
+	return due
+}
+
+func (e *scheduledCollector) collect(r Registry) {
+	defer func() {
+		if recover() != nil {
+			e.panics.Inc(1)
+		}
+	}()
+	defer e.duration.UpdateSince(time.Now())
+	e.collector.Collect(r)
+}
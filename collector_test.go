@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerRunsCollectors(t *testing.T) {
+	r := NewRegistry()
+	s := NewScheduler(r)
+	defer s.Stop()
+
+	var fast, slow int64
+	s.Add("fast", CollectorFunc(func(Registry) { atomic.AddInt64(&fast, 1) }), 10*time.Millisecond)
+	s.Add("slow", CollectorFunc(func(Registry) { atomic.AddInt64(&slow, 1) }), time.Hour)
+	time.Sleep(100 * time.Millisecond)
+
+	if n := atomic.LoadInt64(&fast); n < 3 {
+		t.Fatal(n)
+	}
+	if n := atomic.LoadInt64(&slow); n != 1 {
+		t.Fatal(n)
+	}
+	if c := r.Get("collector.fast.Duration").(Timer).Count(); c < 3 {
+		t.Fatal(c)
+	}
+}
+
+func TestSchedulerRejectsNonPositiveInterval(t *testing.T) {
+	r := NewRegistry()
+	s := NewScheduler(r)
+	defer s.Stop()
+	defer func() {
+		if nil == recover() {
+			t.Error("no panic for a zero interval")
+		}
+		if nil != r.Get("collector.spin.Duration") {
+			t.Error("rejected collector registered metrics")
+		}
+	}()
+	s.Add("spin", CollectorFunc(func(Registry) {}), 0)
+}
+
+func TestSchedulerIsolatesPanics(t *testing.T) {
+	r := NewRegistry()
+	s := NewScheduler(r)
+	defer s.Stop()
+
+	var runs int64
+	s.Add("bad", CollectorFunc(func(Registry) { panic("boom") }), 10*time.Millisecond)
+	s.Add("good", CollectorFunc(func(Registry) { atomic.AddInt64(&runs, 1) }), 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	if c := r.Get("collector.bad.Panics").(Counter).Count(); c < 1 {
+		t.Fatal(c)
+	}
+	if n := atomic.LoadInt64(&runs); n < 1 {
+		t.Fatal(n)
+	}
+}
+
+func TestSchedulerCollectsIntoRegistry(t *testing.T) {
+	r := NewRegistry()
+	RegisterGoroutineStats(r)
+	s := NewScheduler(r)
+	s.Add("goroutines", CollectorFunc(CaptureGoroutineStatsOnce), time.Hour)
+	time.Sleep(20 * time.Millisecond)
+	s.Stop()
+
+	if v := r.Get("runtime.Goroutines").(Gauge).Value(); v < 1 {
+		t.Fatal(v)
+	}
+}
+
+var (
+	_ Collector = &FilesystemStats{}
+	_ Collector = &DiskIOStats{}
+	_ Collector = &NetworkStats{}
+)
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by ConfigFromEnv.
+const (
+	// EnvNilMetrics sets UseNilMetrics, e.g. "true".
+	EnvNilMetrics = "METRICS_NIL"
+	// EnvTimerWindow sets TimerWindow, e.g. "10000".
+	EnvTimerWindow = "METRICS_TIMER_WINDOW"
+	// EnvMeterRescaleThreshold sets MeterRescaleThreshold, e.g. "1m".
+	EnvMeterRescaleThreshold = "METRICS_METER_RESCALE_THRESHOLD"
+	// EnvPercentiles sets DefaultJSONPercentiles, e.g. "0.5,0.9,0.99".
+	EnvPercentiles = "METRICS_PERCENTILES"
+	// EnvLogInterval sets LogInterval, e.g. "30s".
+	EnvLogInterval = "METRICS_LOG_INTERVAL"
+	// EnvGlobalTags sets GlobalTags, e.g. "dc=us-east,env=prod".
+	EnvGlobalTags = "METRICS_GLOBAL_TAGS"
+)
+
+// ConfigFromEnv sets the package's tunables from the environment variables
+// above, leaving those whose variable is unset or empty alone.  Call it early
+// in main, before any metrics are constructed, since UseNilMetrics and
+// TimerWindow are only read by constructors.  Malformed values are skipped and
+// reported in the returned error; the well-formed ones are still applied.
+func ConfigFromEnv() error {
+	var errs []string
+	fail := func(name, value string, err error) {
+		errs = append(errs, fmt.Sprintf("%s=%q: %v", name, value, err))
+	}
+
+	if v := os.Getenv(EnvNilMetrics); v != "" {
+		if b, err := strconv.ParseBool(v); err != nil {
+			fail(EnvNilMetrics, v, err)
+		} else {
+			UseNilMetrics = b
+		}
+	}
+	if v := os.Getenv(EnvTimerWindow); v != "" {
+		if n, err := strconv.Atoi(v); err != nil {
+			fail(EnvTimerWindow, v, err)
+		} else if n < 1 {
+			fail(EnvTimerWindow, v, fmt.Errorf("must be positive"))
+		} else {
+			TimerWindow = n
+		}
+	}
+	if v := os.Getenv(EnvMeterRescaleThreshold); v != "" {
+		if d, err := parsePositiveDuration(v); err != nil {
+			fail(EnvMeterRescaleThreshold, v, err)
+		} else {
+			MeterRescaleThreshold = d
+		}
+	}
+	if v := os.Getenv(EnvPercentiles); v != "" {
+		if ps, err := parsePercentiles(v); err != nil {
+			fail(EnvPercentiles, v, err)
+		} else {
+			DefaultJSONPercentiles = ps
+		}
+	}
+	if v := os.Getenv(EnvLogInterval); v != "" {
+		if d, err := parsePositiveDuration(v); err != nil {
+			fail(EnvLogInterval, v, err)
+		} else {
+			LogInterval = d
+		}
+	}
+	if v := os.Getenv(EnvGlobalTags); v != "" {
+		if tags, err := parseGlobalTags(v); err != nil {
+			fail(EnvGlobalTags, v, err)
+		} else {
+			GlobalTags = tags
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("metrics: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func parsePositiveDuration(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// parsePercentiles parses a comma-separated list of percentiles between 0
+// and 1.
+func parsePercentiles(s string) ([]float64, error) {
+	var ps []float64
+	for _, f := range strings.Split(s, ",") {
+		p, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+		if err != nil || p < 0 || p > 1 {
+			return nil, fmt.Errorf("percentile %q is not between 0 and 1", f)
+		}
+		ps = append(ps, p)
+	}
+	return ps, nil
+}
+
+// parseGlobalTags parses a comma-separated list of key=value pairs.
+func parseGlobalTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, f := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(f), "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("tag %q is not of the form key=value", f)
+		}
+		tags[kv[0]] = kv[1]
+	}
+	return tags, nil
+}
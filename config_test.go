@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func setenv(t *testing.T, env map[string]string) {
+	for k, v := range env {
+		if err := os.Setenv(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func unsetenv(env map[string]string) {
+	for k := range env {
+		os.Unsetenv(k)
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	defer func(n bool, w int, r, l time.Duration, ps []float64, tags map[string]string) {
+		UseNilMetrics, TimerWindow, MeterRescaleThreshold, LogInterval = n, w, r, l
+		DefaultJSONPercentiles, GlobalTags = ps, tags
+	}(UseNilMetrics, TimerWindow, MeterRescaleThreshold, LogInterval, DefaultJSONPercentiles, GlobalTags)
+
+	env := map[string]string{
+		EnvNilMetrics:            "true",
+		EnvTimerWindow:           "1000",
+		EnvMeterRescaleThreshold: "1m",
+		EnvPercentiles:           "0.5, 0.99",
+		EnvLogInterval:           "30s",
+		EnvGlobalTags:            "dc=us-east,env=prod",
+	}
+	setenv(t, env)
+	defer unsetenv(env)
+
+	if err := ConfigFromEnv(); err != nil {
+		t.Fatal(err)
+	}
+	if !UseNilMetrics {
+		t.Error("UseNilMetrics: false")
+	}
+	if 1000 != TimerWindow {
+		t.Errorf("TimerWindow: %v != 1000", TimerWindow)
+	}
+	if time.Minute != MeterRescaleThreshold {
+		t.Errorf("MeterRescaleThreshold: %v != 1m", MeterRescaleThreshold)
+	}
+	if !reflect.DeepEqual([]float64{0.5, 0.99}, DefaultJSONPercentiles) {
+		t.Errorf("DefaultJSONPercentiles: %v", DefaultJSONPercentiles)
+	}
+	if 30*time.Second != LogInterval {
+		t.Errorf("LogInterval: %v != 30s", LogInterval)
+	}
+	if tags := openTSDBTags("box", nil); "host=box dc=us-east env=prod" != tags {
+		t.Errorf("openTSDBTags: %q", tags)
+	}
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	defer func(w int, l time.Duration) {
+		TimerWindow, LogInterval = w, l
+	}(TimerWindow, LogInterval)
+
+	env := map[string]string{
+		EnvTimerWindow:           "0",
+		EnvLogInterval:           "30s",
+		EnvGlobalTags:            "dc",
+		EnvPercentiles:           "2",
+		EnvNilMetrics:            "",
+		EnvMeterRescaleThreshold: "",
+	}
+	setenv(t, env)
+	defer unsetenv(env)
+
+	window := TimerWindow
+	err := ConfigFromEnv()
+	if nil == err {
+		t.Fatal("no error")
+	}
+	t.Log(err)
+	if window != TimerWindow {
+		t.Errorf("TimerWindow changed to %v", TimerWindow)
+	}
+	if 30*time.Second != LogInterval {
+		t.Errorf("LogInterval: %v != 30s", LogInterval)
+	}
+}
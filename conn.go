@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// ConnStats instruments long-lived connections such as WebSockets.  For
+// connections named "ws" it registers:
+//
+//	ws.connections   a Gauge of connections currently open
+//	ws.duration      a Histogram of how long closed connections stayed open, in
+//	                 nanoseconds
+//	ws.messagesIn    a Meter of messages received
+//	ws.messagesOut   a Meter of messages sent
+type ConnStats struct {
+	open        int64
+	duration    Histogram
+	messagesIn  Meter
+	messagesOut Meter
+}
+
+// NewConnStats constructs a ConnStats and registers its metrics under the
+// given name, or returns the ConnStats already registered under it in r.
+func NewConnStats(name string, r Registry) *ConnStats {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	c := &ConnStats{
+		duration:    GetOrRegisterHistogram(name+".duration", r, NewExpDecaySample(1028, 0.015)),
+		messagesIn:  GetOrRegisterMeter(name+".messagesIn", r),
+		messagesOut: GetOrRegisterMeter(name+".messagesOut", r),
+	}
+	return registerOwnedGauge(r, name+".connections", c, c.Connections).(*ConnStats)
+}
+
+// Connections returns the number of connections currently open.
+func (c *ConnStats) Connections() int64 {
+	return atomic.LoadInt64(&c.open)
+}
+
+// Open records a new connection and returns its handle, which must be closed
+// when the connection ends.
+func (c *ConnStats) Open() *Conn {
+	atomic.AddInt64(&c.open, 1)
+	return &Conn{stats: c, opened: time.Now()}
+}
+
+// Conn is the handle of one connection tracked by a ConnStats.
+type Conn struct {
+	stats  *ConnStats
+	opened time.Time
+	closed int32
+}
+
+// Received records n messages received on the connection.
+func (c *Conn) Received(n int64) {
+	c.stats.messagesIn.Mark(n)
+}
+
+// Sent records n messages sent on the connection.
+func (c *Conn) Sent(n int64) {
+	c.stats.messagesOut.Mark(n)
+}
+
+// Close records the end of the connection.  Only the first call has an effect,
+// so it is safe to call from both the reader and the writer goroutine.
+func (c *Conn) Close() {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return
+	}
+	atomic.AddInt64(&c.stats.open, -1)
+	c.stats.duration.Update(int64(time.Since(c.opened)))
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import "testing"
+
+func TestConnStats(t *testing.T) {
+	r := NewRegistry()
+	c := NewConnStats("ws", r)
+
+	a := c.Open()
+	b := NewConnStats("ws", r).Open()
+	if v := r.Get("ws.connections").(Gauge).Value(); v != 2 {
+		t.Fatal(v)
+	}
+
+	a.Received(3)
+	b.Received(1)
+	a.Sent(2)
+	a.Close()
+	a.Close()
+
+	if v := r.Get("ws.connections").(Gauge).Value(); v != 1 {
+		t.Fatal(v)
+	}
+	if n := r.Get("ws.duration").(Histogram).Count(); n != 1 {
+		t.Fatal(n)
+	}
+	if n := r.Get("ws.messagesIn").(Meter).Count(); n != 4 {
+		t.Fatal(n)
+	}
+	if n := r.Get("ws.messagesOut").(Meter).Count(); n != 2 {
+		t.Fatal(n)
+	}
+}
@@ -18,6 +18,9 @@ func GetOrRegisterCounter(name string, r Registry) Counter {
 	if nil == r {
 		r = DefaultRegistry
 	}
+	if m, ok := r.Get(name).(Counter); ok {
+		return m
+	}
 	return r.GetOrRegister(name, NewCounter).(Counter)
 }
 
@@ -26,7 +29,7 @@ func NewCounter() Counter {
 	if UseNilMetrics {
 		return NilCounter{}
 	}
-	return &StandardCounter{0}
+	return &StandardCounter{}
 }
 
 // NewRegisteredCounter constructs and registers a new StandardCounter.
@@ -88,15 +91,28 @@ func (NilCounter) Update(i int64) {}
 // Snapshot is a no-op.
 func (NilCounter) Snapshot() Counter { return NilCounter{} }
 
+// CounterMisuse counts, process-wide, the updates that would leave a counter
+// negative: those that take a StandardCounter below zero, usually by Dec
+// misuse, or wrap it past math.MaxInt64, and the decrements a
+// MonotonicCounter rejects.  SelfMetrics reports it as counter.misuse.
+var CounterMisuse Counter = &StandardCounter{}
+
+// ClampCounters makes StandardCounters left negative by an update clamp at
+// zero, so one misused Dec or a wrapped total doesn't export as a huge
+// negative count.  Either way the update is counted in CounterMisuse.
+var ClampCounters = false
+
 // StandardCounter is the standard implementation of a Counter and uses the
 // sync/atomic package to manage a single int64 value.
 type StandardCounter struct {
-	count int64
+	count    int64
+	watchers watcherSet
 }
 
 // Clear sets the counter to zero.
 func (c *StandardCounter) Clear() {
-	atomic.StoreInt64(&c.count, 0)
+	old := atomic.SwapInt64(&c.count, 0)
+	c.watchers.notify(old, 0)
 }
 
 // Count returns the current count.
@@ -106,12 +122,36 @@ func (c *StandardCounter) Count() int64 {
 
 // Dec decrements the counter by the given amount.
 func (c *StandardCounter) Dec(i int64) {
-	atomic.AddInt64(&c.count, -i)
+	n := atomic.AddInt64(&c.count, -i)
+	old := n + i
+	if n < 0 {
+		n = c.negative(n)
+	}
+	c.watchers.notify(old, n)
 }
 
 // Inc increments the counter by the given amount.
 func (c *StandardCounter) Inc(i int64) {
-	atomic.AddInt64(&c.count, i)
+	n := atomic.AddInt64(&c.count, i)
+	old := n - i
+	if n < 0 {
+		n = c.negative(n)
+	}
+	c.watchers.notify(old, n)
+}
+
+// negative handles an update that left the counter at n, below zero: it
+// counts the misuse and, if ClampCounters is set, clamps the counter at zero
+// unless another update has already moved it.  It returns the count to
+// report to watchers.
+func (c *StandardCounter) negative(n int64) int64 {
+	if c != CounterMisuse {
+		CounterMisuse.Inc(1)
+	}
+	if ClampCounters && atomic.CompareAndSwapInt64(&c.count, n, 0) {
+		return 0
+	}
+	return n
 }
 
 func (c *StandardCounter) Update(i int64) {
@@ -122,3 +162,7 @@ func (c *StandardCounter) Update(i int64) {
 func (c *StandardCounter) Snapshot() Counter {
 	return CounterSnapshot(c.Count())
 }
+
+func (c *StandardCounter) watch(f func(old, new int64)) func() {
+	return c.watchers.add(f)
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// CounterFloat64s hold a float64 value that can be incremented and
+// decremented, for counts that aren't whole, such as money or seconds of
+// compute.
+type CounterFloat64 interface {
+	Clear()
+	Count() float64
+	Dec(float64)
+	Inc(float64)
+	Snapshot() CounterFloat64
+}
+
+// GetOrRegisterCounterFloat64 returns an existing CounterFloat64 or
+// constructs and registers a new StandardCounterFloat64.
+func GetOrRegisterCounterFloat64(name string, r Registry) CounterFloat64 {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	if m, ok := r.Get(name).(CounterFloat64); ok {
+		return m
+	}
+	return r.GetOrRegister(name, NewCounterFloat64).(CounterFloat64)
+}
+
+// NewCounterFloat64 constructs a new StandardCounterFloat64.
+func NewCounterFloat64() CounterFloat64 {
+	if UseNilMetrics {
+		return NilCounterFloat64{}
+	}
+	return &StandardCounterFloat64{}
+}
+
+// NewRegisteredCounterFloat64 constructs and registers a new
+// StandardCounterFloat64.
+func NewRegisteredCounterFloat64(name string, r Registry) CounterFloat64 {
+	c := NewCounterFloat64()
+	if nil == r {
+		r = DefaultRegistry
+	}
+	r.Register(name, c)
+	return c
+}
+
+// CounterFloat64Snapshot is a read-only copy of another CounterFloat64.
+type CounterFloat64Snapshot float64
+
+// Clear panics.
+func (CounterFloat64Snapshot) Clear() {
+	panic("Clear called on a CounterFloat64Snapshot")
+}
+
+// Count returns the count at the time the snapshot was taken.
+func (c CounterFloat64Snapshot) Count() float64 { return float64(c) }
+
+// Dec panics.
+func (CounterFloat64Snapshot) Dec(float64) {
+	panic("Dec called on a CounterFloat64Snapshot")
+}
+
+// Inc panics.
+func (CounterFloat64Snapshot) Inc(float64) {
+	panic("Inc called on a CounterFloat64Snapshot")
+}
+
+// Snapshot returns the snapshot.
+func (c CounterFloat64Snapshot) Snapshot() CounterFloat64 { return c }
+
+// NilCounterFloat64 is a no-op CounterFloat64.
+type NilCounterFloat64 struct{}
+
+// Clear is a no-op.
+func (NilCounterFloat64) Clear() {}
+
+// Count is a no-op.
+func (NilCounterFloat64) Count() float64 { return 0.0 }
+
+// Dec is a no-op.
+func (NilCounterFloat64) Dec(f float64) {}
+
+// Inc is a no-op.
+func (NilCounterFloat64) Inc(f float64) {}
+
+// Snapshot is a no-op.
+func (NilCounterFloat64) Snapshot() CounterFloat64 { return NilCounterFloat64{} }
+
+// StandardCounterFloat64 is the standard implementation of a CounterFloat64.
+// It keeps the bits of its float64 value in a uint64 and adds to it with a
+// compare-and-swap loop, so it takes no lock.
+type StandardCounterFloat64 struct {
+	bits uint64
+}
+
+// Clear sets the counter to zero.
+func (c *StandardCounterFloat64) Clear() {
+	atomic.StoreUint64(&c.bits, 0)
+}
+
+// Count returns the current count.
+func (c *StandardCounterFloat64) Count() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// Dec decrements the counter by the given amount.
+func (c *StandardCounterFloat64) Dec(f float64) {
+	c.Inc(-f)
+}
+
+// Inc increments the counter by the given amount.
+func (c *StandardCounterFloat64) Inc(f float64) {
+	for {
+		old := atomic.LoadUint64(&c.bits)
+		n := math.Float64bits(math.Float64frombits(old) + f)
+		if atomic.CompareAndSwapUint64(&c.bits, old, n) {
+			return
+		}
+	}
+}
+
+// Snapshot returns a read-only copy of the counter.
+func (c *StandardCounterFloat64) Snapshot() CounterFloat64 {
+	return CounterFloat64Snapshot(c.Count())
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func BenchmarkCounterFloat64(b *testing.B) {
+	c := NewCounterFloat64()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.Inc(1.5)
+	}
+}
+
+func TestCounterFloat64Clear(t *testing.T) {
+	c := NewCounterFloat64()
+	c.Inc(1.5)
+	c.Clear()
+	if count := c.Count(); 0 != count {
+		t.Errorf("c.Count(): 0 != %v\n", count)
+	}
+}
+
+func TestCounterFloat64Dec(t *testing.T) {
+	c := NewCounterFloat64()
+	c.Dec(0.25)
+	if count := c.Count(); -0.25 != count {
+		t.Errorf("c.Count(): -0.25 != %v\n", count)
+	}
+}
+
+func TestCounterFloat64Inc(t *testing.T) {
+	c := NewCounterFloat64()
+	c.Inc(0.5)
+	c.Inc(1.25)
+	if count := c.Count(); 1.75 != count {
+		t.Errorf("c.Count(): 1.75 != %v\n", count)
+	}
+}
+
+func TestCounterFloat64Concurrent(t *testing.T) {
+	c := NewCounterFloat64()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				c.Inc(0.5)
+			}
+		}()
+	}
+	wg.Wait()
+	if count := c.Count(); 4000 != count {
+		t.Errorf("c.Count(): 4000 != %v\n", count)
+	}
+}
+
+func TestCounterFloat64Snapshot(t *testing.T) {
+	c := NewCounterFloat64()
+	c.Inc(1.5)
+	snapshot := c.Snapshot()
+	c.Inc(1)
+	if count := snapshot.Count(); 1.5 != count {
+		t.Errorf("c.Count(): 1.5 != %v\n", count)
+	}
+}
+
+func TestGetOrRegisterCounterFloat64(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounterFloat64("foo", r).Inc(4.7)
+	if c := GetOrRegisterCounterFloat64("foo", r); 4.7 != c.Count() {
+		t.Fatal(c)
+	}
+}
+
+func TestCounterFloat64Registry(t *testing.T) {
+	r := NewRegistry()
+	c := NewRegisteredCounterFloat64("dollars", r)
+	c.Inc(2.5)
+	r.Update("dollars", 1)
+	if count := c.Count(); 3.5 != count {
+		t.Errorf("c.Count(): 3.5 != %v\n", count)
+	}
+	if s := r.GetCurrent(); !strings.Contains(s, "dollars: 3.500000") {
+		t.Errorf("GetCurrent: %q", s)
+	}
+	if err := r.Reset("dollars"); nil != err || 0 != c.Count() {
+		t.Errorf("Reset: %v, count %v", err, c.Count())
+	}
+}
@@ -0,0 +1,60 @@
+package metrics
+
+// A MonotonicCounter is a StandardCounter that only ever goes up, between
+// Clears, for totals that must never decrease, such as the counters rate
+// calculations are taken from.  It rejects decrements, and increments by
+// negative amounts, counting them in CounterMisuse instead.
+type MonotonicCounter struct {
+	StandardCounter
+}
+
+// GetOrRegisterMonotonicCounter returns an existing Counter or constructs
+// and registers a new MonotonicCounter.
+func GetOrRegisterMonotonicCounter(name string, r Registry) Counter {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	if m, ok := r.Get(name).(Counter); ok {
+		return m
+	}
+	return r.GetOrRegister(name, NewMonotonicCounter).(Counter)
+}
+
+// NewMonotonicCounter constructs a new MonotonicCounter.
+func NewMonotonicCounter() Counter {
+	if UseNilMetrics {
+		return NilCounter{}
+	}
+	return &MonotonicCounter{}
+}
+
+// NewRegisteredMonotonicCounter constructs and registers a new
+// MonotonicCounter.
+func NewRegisteredMonotonicCounter(name string, r Registry) Counter {
+	c := NewMonotonicCounter()
+	if nil == r {
+		r = DefaultRegistry
+	}
+	r.Register(name, c)
+	return c
+}
+
+// Dec rejects the decrement, unless i is negative, when it increments the
+// counter by -i.
+func (c *MonotonicCounter) Dec(i int64) {
+	c.Inc(-i)
+}
+
+// Inc increments the counter by the given amount, rejecting negative ones.
+func (c *MonotonicCounter) Inc(i int64) {
+	if i < 0 {
+		CounterMisuse.Inc(1)
+		return
+	}
+	c.StandardCounter.Inc(i)
+}
+
+// Update increments the counter like Inc.
+func (c *MonotonicCounter) Update(i int64) {
+	c.Inc(i)
+}
@@ -1,6 +1,9 @@
 package metrics
 
-import "testing"
+import (
+	"math"
+	"testing"
+)
 
 func BenchmarkCounter(b *testing.B) {
 	c := NewCounter()
@@ -75,3 +78,42 @@ func TestGetOrRegisterCounter(t *testing.T) {
 		t.Fatal(c)
 	}
 }
+
+func TestCounterNegative(t *testing.T) {
+	defer func(clamp bool) { ClampCounters = clamp }(ClampCounters)
+	misuse := CounterMisuse.Count()
+
+	c := NewCounter()
+	c.Inc(math.MaxInt64)
+	c.Inc(1)
+	if count := c.Count(); math.MinInt64 != count {
+		t.Errorf("wrapped c.Count(): %v", count)
+	}
+
+	ClampCounters = true
+	c = NewCounter()
+	c.Inc(1)
+	c.Dec(3)
+	if count := c.Count(); 0 != count {
+		t.Errorf("clamped c.Count(): 0 != %v", count)
+	}
+	if n := CounterMisuse.Count() - misuse; 2 != n {
+		t.Errorf("CounterMisuse: 2 != %v", n)
+	}
+}
+
+func TestMonotonicCounter(t *testing.T) {
+	misuse := CounterMisuse.Count()
+	r := NewRegistry()
+	c := GetOrRegisterMonotonicCounter("sent", r)
+	c.Inc(5)
+	c.Dec(2)
+	c.Update(-1)
+	c.Dec(-1)
+	if count := r.Get("sent").(Counter).Count(); 6 != count {
+		t.Errorf("c.Count(): 6 != %v", count)
+	}
+	if n := CounterMisuse.Count() - misuse; 2 != n {
+		t.Errorf("CounterMisuse: 2 != %v", n)
+	}
+}
@@ -0,0 +1,35 @@
+package metrics
+
+import "context"
+
+// TimerFromContext starts measuring an operation bounded by ctx and returns
+// a function that records the time elapsed since on the Timer called name in
+// the Scope ctx carries, and how the operation ended relative to ctx:
+//
+//	name                    a Timer of every operation
+//	name.deadline_exceeded  a Counter of operations that outlived the deadline
+//	name.canceled           a Counter of operations whose context was canceled
+//
+// so timeouts can be told from operations that are merely slow:
+//
+//	defer metrics.TimerFromContext(ctx, "db.query")()
+//
+// An operation outlived the deadline if ctx's error is DeadlineExceeded or
+// it ended after the deadline, whether or not it noticed.
+func TimerFromContext(ctx context.Context, name string) func() {
+	if UseNilMetrics {
+		return nopMeasure
+	}
+	s, start := ScopeFromContext(ctx), DefaultClock.Now()
+	return func() {
+		now := DefaultClock.Now()
+		s.Timer(name).UpdateTime(now.Sub(start))
+		deadline, ok := ctx.Deadline()
+		switch err := ctx.Err(); {
+		case context.Canceled == err:
+			s.Counter(name + ".canceled").Inc(1)
+		case context.DeadlineExceeded == err, ok && now.After(deadline):
+			s.Counter(name + ".deadline_exceeded").Inc(1)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimerFromContext(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	ctx := ContextWithScope(context.Background(), NewScope(r))
+
+	TimerFromContext(ctx, "op")()
+
+	deadline, cancel := context.WithDeadline(ctx, clock.Now().Add(time.Second))
+	defer cancel()
+	done := TimerFromContext(deadline, "op")
+	clock.Add(2 * time.Second)
+	done()
+
+	canceled, cancel := context.WithCancel(ctx)
+	done = TimerFromContext(canceled, "op")
+	cancel()
+	done()
+
+	if n := r.Get("op").(Timer).Count(); 3 != n {
+		t.Errorf("op: %d, want 3", n)
+	}
+	if n := r.Get("op.deadline_exceeded").(Counter).Count(); 1 != n {
+		t.Errorf("op.deadline_exceeded: %d, want 1", n)
+	}
+	if n := r.Get("op.canceled").(Counter).Count(); 1 != n {
+		t.Errorf("op.canceled: %d, want 1", n)
+	}
+	if max := r.Get("op").(Timer).Max(); int64(2*time.Second) != max {
+		t.Errorf("op max: %d", max)
+	}
+}
@@ -0,0 +1,272 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DashboardHandler serves a self-contained HTML page listing the metrics of
+// a registry with a sparkline of each one's recent history.  It samples the
+// registry every interval into a ring buffer per metric, so the page shows
+// trends from before it was opened.  Mount it with its prefix stripped:
+//
+//	ui := metrics.NewDashboardHandler(nil, 5*time.Second, 120)
+//	http.Handle("/metrics/ui/", http.StripPrefix("/metrics/ui", ui))
+//
+// The page polls data, relative to itself, for the series as JSON.
+type DashboardHandler struct {
+	registry Registry
+	interval time.Duration
+	points   int
+
+	mutex  sync.Mutex
+	series map[string]*historyRing
+	quit   chan struct{}
+	once   sync.Once
+}
+
+// NewDashboardHandler constructs a DashboardHandler keeping points samples of
+// every metric in r, or DefaultRegistry if r is nil, taken every interval.
+// It samples in the background until Stop is called.
+func NewDashboardHandler(r Registry, interval time.Duration, points int) *DashboardHandler {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	h := &DashboardHandler{
+		registry: r,
+		interval: interval,
+		points:   points,
+		series:   make(map[string]*historyRing),
+		quit:     make(chan struct{}),
+	}
+	h.sample()
+	go h.loop()
+	return h
+}
+
+// Stop stops sampling the registry.
+func (h *DashboardHandler) Stop() {
+	h.once.Do(func() { close(h.quit) })
+}
+
+func (h *DashboardHandler) loop() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			h.sample()
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// sample appends the headline value of every metric to its series and drops
+// the series of metrics that have been unregistered.
+func (h *DashboardHandler) sample() {
+	seen := make(map[string]bool)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.registry.EachUnsorted(func(name string, i interface{}) {
+		v, ok := headlineValue(i)
+		if !ok {
+			return
+		}
+		ring := h.series[name]
+		if ring == nil {
+			ring = newHistoryRing(h.points)
+			h.series[name] = ring
+		}
+		ring.add(v)
+		seen[name] = true
+	})
+	for name := range h.series {
+		if !seen[name] {
+			delete(h.series, name)
+		}
+	}
+}
+
+// headlineValue is the single number the dashboard plots for a metric: the
+// count of counters, the value of gauges, the one-minute rate of meters and
+// the 99th percentile of histograms and timers.
+func headlineValue(i interface{}) (float64, bool) {
+	switch m := i.(type) {
+	case Counter:
+		return float64(m.Count()), true
+	case CounterFloat64:
+		return m.Count(), true
+	case Instant:
+		return float64(m.Count()), true
+	case Gauge:
+		return float64(m.Value()), true
+	case GaugeFloat64:
+		return m.Value(), true
+	case Meter:
+		return m.Rate1(), true
+	case Timer:
+		return m.Percentile(0.99), true
+	case Histogram:
+		return m.Percentile(0.99), true
+	}
+	return 0, false
+}
+
+func (h *DashboardHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.URL.Path {
+	case "", "/":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, dashboardHTML)
+	case "/data":
+		h.mutex.Lock()
+		data := make(map[string][]float64, len(h.series))
+		for name, ring := range h.series {
+			data[name] = ring.values()
+		}
+		h.mutex.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Interval float64              `json:"interval"`
+			Series   map[string][]float64 `json:"series"`
+		}{h.interval.Seconds(), data})
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// historyRing is a fixed-size ring buffer of samples and, for those added
+// with addAt, the times they were taken.
+type historyRing struct {
+	buf   []float64
+	times []time.Time // nil until addAt is first called
+	start int
+	n     int
+}
+
+func newHistoryRing(size int) *historyRing {
+	if size < 1 {
+		size = 1
+	}
+	return &historyRing{buf: make([]float64, size)}
+}
+
+func (r *historyRing) add(v float64) {
+	r.addAt(time.Time{}, v)
+}
+
+func (r *historyRing) addAt(t time.Time, v float64) {
+	if !t.IsZero() && nil == r.times {
+		r.times = make([]time.Time, len(r.buf))
+	}
+	i := r.start
+	if r.n < len(r.buf) {
+		i = (r.start + r.n) % len(r.buf)
+		r.n++
+	} else {
+		r.start = (r.start + 1) % len(r.buf)
+	}
+	r.buf[i] = v
+	if nil != r.times {
+		r.times[i] = t
+	}
+}
+
+// values returns the samples oldest first.
+func (r *historyRing) values() []float64 {
+	values := make([]float64, r.n)
+	for i := range values {
+		values[i] = r.buf[(r.start+i)%len(r.buf)]
+	}
+	return values
+}
+
+// points returns the samples taken at or after since, oldest first.
+func (r *historyRing) points(since time.Time) []HistoryPoint {
+	var points []HistoryPoint
+	for i := 0; i < r.n; i++ {
+		j := (r.start + i) % len(r.buf)
+		var t time.Time
+		if nil != r.times {
+			t = r.times[j]
+		}
+		if !t.Before(since) {
+			points = append(points, HistoryPoint{Time: t, Value: r.buf[j]})
+		}
+	}
+	return points
+}
+
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>metrics</title>
+<style>
+body { font: 13px monospace; margin: 1em; }
+input { font: inherit; width: 30em; margin-bottom: 1em; }
+table { border-collapse: collapse; }
+td { padding: 2px 12px 2px 0; }
+td.v { text-align: right; }
+polyline { fill: none; stroke: #36c; stroke-width: 1.5; }
+</style>
+</head>
+<body>
+<input id="filter" placeholder="filter">
+<table id="metrics"></table>
+<script>
+var filter = document.getElementById("filter");
+var table = document.getElementById("metrics");
+var last = {series: {}, interval: 5};
+
+function sparkline(values) {
+  var w = 240, h = 24;
+  var svg = document.createElementNS("http://www.w3.org/2000/svg", "svg");
+  svg.setAttribute("width", w);
+  svg.setAttribute("height", h);
+  if (values.length < 2) return svg;
+  var min = Math.min.apply(null, values), max = Math.max.apply(null, values);
+  var span = max - min || 1;
+  var points = values.map(function (v, i) {
+    return (i * w / (values.length - 1)).toFixed(1) + "," + (h - 2 - (v - min) * (h - 4) / span).toFixed(1);
+  });
+  var line = document.createElementNS("http://www.w3.org/2000/svg", "polyline");
+  line.setAttribute("points", points.join(" "));
+  svg.appendChild(line);
+  return svg;
+}
+
+function render() {
+  var f = filter.value;
+  var names = Object.keys(last.series).sort();
+  table.textContent = "";
+  names.forEach(function (name) {
+    if (f && name.indexOf(f) < 0) return;
+    var values = last.series[name];
+    var row = table.insertRow();
+    row.insertCell().textContent = name;
+    var v = row.insertCell();
+    v.className = "v";
+    v.textContent = values.length ? +values[values.length - 1].toFixed(3) : "";
+    row.insertCell().appendChild(sparkline(values));
+  });
+}
+
+function poll() {
+  fetch("data").then(function (r) { return r.json(); }).then(function (data) {
+    last = data;
+    render();
+  }).finally(function () {
+    setTimeout(poll, Math.max(1, last.interval) * 1000);
+  });
+}
+
+filter.addEventListener("input", render);
+poll();
+</script>
+</body>
+</html>
+`
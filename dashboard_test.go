@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRing(t *testing.T) {
+	r := newHistoryRing(3)
+	for i := 1; i <= 5; i++ {
+		r.add(float64(i))
+	}
+	if v := r.values(); !reflect.DeepEqual(v, []float64{3, 4, 5}) {
+		t.Fatal(v)
+	}
+}
+
+func TestDashboardHandler(t *testing.T) {
+	r := NewRegistry()
+	c := NewRegisteredCounter("requests", r)
+	NewRegisteredGauge("gone", r)
+	h := NewDashboardHandler(r, time.Hour, 2)
+	defer h.Stop()
+	c.Inc(1)
+	r.Unregister("gone")
+	h.sample()
+	c.Inc(1)
+	h.sample()
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/data", nil))
+	var data struct {
+		Interval float64
+		Series   map[string][]float64
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Interval != 3600 {
+		t.Fatal(data.Interval)
+	}
+	if !reflect.DeepEqual(data.Series, map[string][]float64{"requests": {1, 2}}) {
+		t.Fatal(data.Series)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(w.Body.String(), "<table") {
+		t.Fatal(w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/nope", nil))
+	if w.Code != 404 {
+		t.Fatal(w.Code)
+	}
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// dialStats records the latency and failures of dials or lookups of a
+// destination host.  For stats named "backend" it registers, tagged with
+// the host and, for the counters, the ErrorClass of the failure:
+//
+//	hostTAGbackend.dial               a Timer of dials, resolution included
+//	host|classTAGbackend.dial.errors  a Counter of failed dials
+//	hostTAGbackend.dns                a Timer of name resolutions
+//	host|classTAGbackend.dns.errors   a Counter of failed resolutions
+//
+// Hosts are escaped and capped as the variables of a NameTemplate are, so a
+// service dialing many hosts can't create metrics without bound.
+type dialStats struct {
+	name  string
+	r     Registry
+	hosts *NameTemplate
+}
+
+func newDialStats(name string, r Registry) *dialStats {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return &dialStats{name: name, r: r, hosts: MustParseNameTemplate("{host}")}
+}
+
+// record records an operation, "dial" or "dns", on host that took since
+// start and failed with err if it isn't nil.
+func (s *dialStats) record(op, host string, start time.Time, err error) {
+	if UseNilMetrics {
+		return
+	}
+	host = s.hosts.Expand(map[string]string{"host": host})
+	GetOrRegisterTimer(taggedName(s.name+"."+op, []string{host}), s.r).UpdateTime(DefaultClock.Now().Sub(start))
+	if nil != err {
+		GetOrRegisterCounter(taggedName(s.name+"."+op+".errors", []string{host, ErrorClass(err)}), s.r).Inc(1)
+	}
+}
+
+// A Resolver is a net.Resolver recording the latency and failures of its
+// lookups per host, like Dialer.
+type Resolver struct {
+	resolver *net.Resolver
+	stats    *dialStats
+}
+
+// NewResolver returns a Resolver looking hosts up with res, or
+// net.DefaultResolver if res is nil, whose metrics are named name in r, or
+// DefaultRegistry if r is nil.
+func NewResolver(name string, r Registry, res *net.Resolver) *Resolver {
+	return newResolver(newDialStats(name, r), res)
+}
+
+func newResolver(stats *dialStats, res *net.Resolver) *Resolver {
+	if nil == res {
+		res = net.DefaultResolver
+	}
+	return &Resolver{resolver: res, stats: stats}
+}
+
+// LookupHost looks up host like net.Resolver.LookupHost.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	start := DefaultClock.Now()
+	addrs, err := r.resolver.LookupHost(ctx, host)
+	r.stats.record("dns", host, start, err)
+	return addrs, err
+}
+
+// LookupIPAddr looks up host like net.Resolver.LookupIPAddr.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	start := DefaultClock.Now()
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	r.stats.record("dns", host, start, err)
+	return addrs, err
+}
+
+// A Dialer is a net.Dialer recording the latency and failures of its dials
+// and of the resolution of the hosts it dials per destination host, to tell
+// slow or failing name servers from slow or failing backends:
+//
+//	d := metrics.NewDialer("backend", nil, &net.Dialer{Timeout: 5 * time.Second})
+//	transport := &http.Transport{DialContext: d.DialContext}
+//
+// It resolves host names itself, with the Resolver of the net.Dialer, and
+// tries the addresses in the order they're returned until one connects,
+// rather than racing IPv4 and IPv6 addresses as net.Dialer does.
+type Dialer struct {
+	dialer   *net.Dialer
+	resolver *Resolver
+	stats    *dialStats
+}
+
+// NewDialer returns a Dialer dialing with d, or a zero net.Dialer if d is
+// nil, whose metrics are named name in r, or DefaultRegistry if r is nil.
+func NewDialer(name string, r Registry, d *net.Dialer) *Dialer {
+	if nil == d {
+		d = &net.Dialer{}
+	}
+	stats := newDialStats(name, r)
+	return &Dialer{dialer: d, resolver: newResolver(stats, d.Resolver), stats: stats}
+}
+
+// Dial connects to address on network like net.Dialer.Dial.
+func (d *Dialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+// DialContext connects to address on network like net.Dialer.DialContext.
+func (d *Dialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || nil != net.ParseIP(host) || !dialResolves(network) {
+		start := DefaultClock.Now()
+		conn, err := d.dialer.DialContext(ctx, network, address)
+		d.stats.record("dial", host, start, err)
+		return conn, err
+	}
+
+	start := DefaultClock.Now()
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		d.stats.record("dial", host, start, err)
+		return nil, err
+	}
+	var conn net.Conn
+	err = &net.AddrError{Err: "no suitable address", Addr: host}
+	for _, addr := range addrs {
+		if !dialFamily(network, addr) {
+			continue
+		}
+		conn, err = d.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if nil == err || nil != ctx.Err() {
+			break
+		}
+	}
+	d.stats.record("dial", host, start, err)
+	return conn, err
+}
+
+// dialResolves returns whether the addresses of network have host names
+// Dialer resolves itself.
+func dialResolves(network string) bool {
+	switch network {
+	case "tcp", "tcp4", "tcp6", "udp", "udp4", "udp6":
+		return true
+	}
+	return false
+}
+
+// dialFamily returns whether the IP address addr can be dialed on network.
+func dialFamily(network, addr string) bool {
+	ip := net.ParseIP(addr)
+	switch {
+	case nil == ip:
+		return false
+	case strings.HasSuffix(network, "4"):
+		return nil != ip.To4()
+	case strings.HasSuffix(network, "6"):
+		return nil == ip.To4()
+	}
+	return true
+}
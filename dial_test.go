@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDialer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	r := NewRegistry()
+	d := NewDialer("backend", r, nil)
+	conn, err := d.Dial("tcp4", net.JoinHostPort("localhost", port))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	if n := r.Get("localhostTAGbackend.dial").(Timer).Count(); 1 != n {
+		t.Errorf("localhostTAGbackend.dial: 1 != %v", n)
+	}
+	if n := r.Get("localhostTAGbackend.dns").(Timer).Count(); 1 != n {
+		t.Errorf("localhostTAGbackend.dns: 1 != %v", n)
+	}
+
+	l.Close()
+	if _, err := d.Dial("tcp", l.Addr().String()); nil == err {
+		t.Fatal("dialed a closed listener")
+	}
+	if c, ok := r.Get("127_0_0_1|refusedTAGbackend.dial.errors").(Counter); !ok || 1 != c.Count() {
+		t.Errorf("127_0_0_1|refusedTAGbackend.dial.errors: %v", r.Get("127_0_0_1|refusedTAGbackend.dial.errors"))
+	}
+	if nil != r.Get("127_0_0_1TAGbackend.dns") {
+		t.Error("dialing an IP address resolved it")
+	}
+}
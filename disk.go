@@ -0,0 +1,175 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FilesystemStats publishes space usage for a set of mount points under a
+// common prefix.  For a prefix of "disk." and the mount /var/lib/replays it
+// registers the gauges disk.var.lib.replays.Total, .Used, .Free and
+// .Available, in bytes, where Used and Free add up to Total and Available
+// is the part of Free unprivileged users can use, without the blocks
+// reserved for root.  The mount / is named root.  Mounts that can't be read
+// are counted in disk.FilesystemReadErrors.
+type FilesystemStats struct {
+	registry Registry
+	prefix   string
+	mounts   []string
+	errors   Counter
+}
+
+// NewFilesystemStats constructs a FilesystemStats for the given mount points.
+func NewFilesystemStats(r Registry, prefix string, mounts ...string) *FilesystemStats {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return &FilesystemStats{
+		registry: r,
+		prefix:   prefix,
+		mounts:   mounts,
+		errors:   GetOrRegisterCounter(prefix+"FilesystemReadErrors", r),
+	}
+}
+
+// Capture new values for the filesystem usage.  This is designed to be called
+// as a goroutine.
+func (fs *FilesystemStats) Capture(d time.Duration) {
+	for _ = range time.Tick(d) {
+		fs.CaptureOnce()
+	}
+}
+
+// CaptureOnce captures new values for the filesystem usage.  A mount that
+// can't be read increments the FilesystemReadErrors counter and keeps its
+// previous values.
+func (fs *FilesystemStats) CaptureOnce() {
+	fs.Collect(fs.registry)
+}
+
+// Collect captures new values for the filesystem usage into r.
+func (fs *FilesystemStats) Collect(r Registry) {
+	for _, mount := range fs.mounts {
+		total, free, avail, err := filesystemUsage(mount)
+		if err != nil {
+			fs.errors.Inc(1)
+			continue
+		}
+		name := fs.prefix + mountMetricName(mount)
+		GetOrRegisterGauge(name+".Total", r).Update(int64(total))
+		GetOrRegisterGauge(name+".Used", r).Update(int64(total - free))
+		GetOrRegisterGauge(name+".Free", r).Update(int64(free))
+		GetOrRegisterGauge(name+".Available", r).Update(int64(avail))
+	}
+}
+
+func mountMetricName(mount string) string {
+	name := strings.Trim(mount, "/")
+	if name == "" {
+		return "root"
+	}
+	return strings.Replace(name, "/", ".", -1)
+}
+
+// ProcDiskstats is the file DiskIOStats reads device I/O counters from.
+var ProcDiskstats = "/proc/diskstats"
+
+// DiskIOStats publishes the I/O counters of block devices from
+// /proc/diskstats under a common prefix.  For a prefix of "disk." and the
+// device sda it registers the gauges disk.sda.Reads, .ReadBytes, .Writes,
+// .WriteBytes and .IOTimeMs, each a total since boot, and counts failed
+// reads in disk.IOReadErrors.  It is only available on Linux.
+type DiskIOStats struct {
+	registry Registry
+	prefix   string
+	devices  map[string]bool
+	errors   Counter
+}
+
+// NewDiskIOStats constructs a DiskIOStats for the given devices, or for all
+// devices except loop and ram disks if none are given.
+func NewDiskIOStats(r Registry, prefix string, devices ...string) *DiskIOStats {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	ds := &DiskIOStats{
+		registry: r,
+		prefix:   prefix,
+		errors:   GetOrRegisterCounter(prefix+"IOReadErrors", r),
+	}
+	if len(devices) > 0 {
+		ds.devices = make(map[string]bool, len(devices))
+		for _, device := range devices {
+			ds.devices[device] = true
+		}
+	}
+	return ds
+}
+
+// Capture new values for the device I/O counters.  This is designed to be
+// called as a goroutine.
+func (ds *DiskIOStats) Capture(d time.Duration) {
+	for _ = range time.Tick(d) {
+		ds.CaptureOnce()
+	}
+}
+
+// CaptureOnce captures new values for the device I/O counters.
+func (ds *DiskIOStats) CaptureOnce() {
+	ds.Collect(ds.registry)
+}
+
+// Collect captures new values for the device I/O counters into r.
+func (ds *DiskIOStats) Collect(r Registry) {
+	f, err := os.Open(ProcDiskstats)
+	if err != nil {
+		ds.errors.Inc(1)
+		return
+	}
+	defer f.Close()
+	if err := ds.update(r, f); err != nil {
+		ds.errors.Inc(1)
+	}
+}
+
+// diskSectorSize is the unit of the sector counts in /proc/diskstats,
+// regardless of the device's real sector size.
+const diskSectorSize = 512
+
+func (ds *DiskIOStats) update(r Registry, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		// major minor name reads merged sectors ms writes merged sectors ms
+		// in-flight io-ms ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 13 {
+			continue
+		}
+		device := fields[2]
+		if ds.devices != nil && !ds.devices[device] {
+			continue
+		}
+		if ds.devices == nil && (strings.HasPrefix(device, "loop") || strings.HasPrefix(device, "ram")) {
+			continue
+		}
+		var values [10]int64
+		for i := range values {
+			v, err := strconv.ParseInt(fields[i+3], 10, 64)
+			if err != nil {
+				return err
+			}
+			values[i] = v
+		}
+		name := ds.prefix + device
+		GetOrRegisterGauge(name+".Reads", r).Update(values[0])
+		GetOrRegisterGauge(name+".ReadBytes", r).Update(values[2] * diskSectorSize)
+		GetOrRegisterGauge(name+".Writes", r).Update(values[4])
+		GetOrRegisterGauge(name+".WriteBytes", r).Update(values[6] * diskSectorSize)
+		GetOrRegisterGauge(name+".IOTimeMs", r).Update(values[9])
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package metrics
+
+import "errors"
+
+func filesystemUsage(path string) (total, free, avail uint64, err error) {
+	return 0, 0, 0, errors.New("disk: filesystem usage not supported on this platform")
+}
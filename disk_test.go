@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFilesystemStats(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skipf("skipping TestFilesystemStats on %s", runtime.GOOS)
+	}
+	r := NewRegistry()
+	fs := NewFilesystemStats(r, "disk.", "/", os.TempDir())
+	fs.CaptureOnce()
+
+	if c := r.Get("disk.FilesystemReadErrors").(Counter).Count(); c != 0 {
+		t.Fatal(c)
+	}
+	total := r.Get("disk.root.Total").(Gauge).Value()
+	used := r.Get("disk.root.Used").(Gauge).Value()
+	if total <= 0 || used < 0 || used > total {
+		t.Fatalf("total: %d, used: %d", total, used)
+	}
+	free := r.Get("disk.root.Free").(Gauge).Value()
+	avail := r.Get("disk.root.Available").(Gauge).Value()
+	if used+free != total || avail > free {
+		t.Fatalf("total: %d, used: %d, free: %d, available: %d", total, used, free, avail)
+	}
+}
+
+func TestFilesystemStatsReadError(t *testing.T) {
+	r := NewRegistry()
+	NewFilesystemStats(r, "disk.", "/no/such/mount").CaptureOnce()
+	if c := r.Get("disk.FilesystemReadErrors").(Counter).Count(); c != 1 {
+		t.Fatal(c)
+	}
+}
+
+func TestMountMetricName(t *testing.T) {
+	for mount, want := range map[string]string{
+		"/":                "root",
+		"/var/lib/replays": "var.lib.replays",
+		"/data/":           "data",
+	} {
+		if name := mountMetricName(mount); name != want {
+			t.Fatalf("%s: got %s, want %s", mount, name, want)
+		}
+	}
+}
+
+const testDiskstats = `   7       0 loop0 10 0 20 0 0 0 0 0 0 0 0 0 0
+   8       0 sda 100 5 2000 40 50 3 800 30 0 70 70 0 0
+   8       1 sda1 90 5 1800 38 50 3 800 30 0 68 68 0 0
+`
+
+func TestDiskIOStats(t *testing.T) {
+	r := NewRegistry()
+	ds := NewDiskIOStats(r, "disk.")
+	if err := ds.update(r, strings.NewReader(testDiskstats)); err != nil {
+		t.Fatal(err)
+	}
+	if r.Get("disk.loop0.Reads") != nil {
+		t.Fatal("loop device exported")
+	}
+	for name, want := range map[string]int64{
+		"disk.sda.Reads":      100,
+		"disk.sda.ReadBytes":  2000 * 512,
+		"disk.sda.Writes":     50,
+		"disk.sda.WriteBytes": 800 * 512,
+		"disk.sda.IOTimeMs":   70,
+		"disk.sda1.Reads":     90,
+	} {
+		if v := r.Get(name).(Gauge).Value(); v != want {
+			t.Fatalf("%s: got %d, want %d", name, v, want)
+		}
+	}
+}
+
+func TestDiskIOStatsDevices(t *testing.T) {
+	r := NewRegistry()
+	ds := NewDiskIOStats(r, "disk.", "sda1")
+	if err := ds.update(r, strings.NewReader(testDiskstats)); err != nil {
+		t.Fatal(err)
+	}
+	if r.Get("disk.sda.Reads") != nil {
+		t.Fatal("unselected device exported")
+	}
+	if v := r.Get("disk.sda1.Reads").(Gauge).Value(); v != 90 {
+		t.Fatal(v)
+	}
+}
+
+func TestDiskStatsReadErrors(t *testing.T) {
+	defer func(path string) { ProcDiskstats = path }(ProcDiskstats)
+	ProcDiskstats = "/no/such/diskstats"
+	r := NewRegistry()
+	NewFilesystemStats(r, "disk.")
+	NewDiskIOStats(r, "disk.").CaptureOnce()
+	if c := r.Get("disk.IOReadErrors").(Counter).Count(); c != 1 {
+		t.Fatal(c)
+	}
+	if c := r.Get("disk.FilesystemReadErrors").(Counter).Count(); c != 0 {
+		t.Fatalf("filesystem errors: %d", c)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package metrics
+
+import "syscall"
+
+func filesystemUsage(path string) (total, free, avail uint64, err error) {
+	var st syscall.Statfs_t
+	if err = syscall.Statfs(path, &st); err != nil {
+		return 0, 0, 0, err
+	}
+	bsize := uint64(st.Bsize)
+	return st.Blocks * bsize, st.Bfree * bsize, st.Bavail * bsize, nil
+}
@@ -0,0 +1,115 @@
+package metrics
+
+import "sync/atomic"
+
+// A DuplicatePolicy decides what Register does when a metric is already
+// registered under the name it is given.
+type DuplicatePolicy int
+
+const (
+	// DuplicateError returns a DuplicateMetric and leaves the registered
+	// metric in place.  This is the default.
+	DuplicateError DuplicatePolicy = iota
+
+	// DuplicateReplace stops the registered metric and registers the new
+	// one in its place, for plugin systems that register their metrics
+	// again when they're reloaded.
+	DuplicateReplace
+
+	// DuplicateKeep leaves the registered metric in place and returns nil,
+	// for callers that then Get the metric registered under the name.
+	DuplicateKeep
+
+	// DuplicateMerge adds the count of the new metric to the registered one
+	// if both are counters of the same kind, and returns a DuplicateMetric
+	// otherwise.
+	DuplicateMerge
+)
+
+// DuplicatePolicy returns the policy Register follows for names that are
+// already registered.
+func (r *StandardRegistry) DuplicatePolicy() DuplicatePolicy {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.duplicates
+}
+
+// SetDuplicatePolicy sets the policy Register follows from now on for names
+// that are already registered.
+func (r *StandardRegistry) SetDuplicatePolicy(p DuplicatePolicy) {
+	r.lock()
+	defer r.mutex.Unlock()
+	r.duplicates = p
+}
+
+// DuplicatePolicy returns the underlying registry's DuplicatePolicy.
+func (r *PrefixedRegistry) DuplicatePolicy() DuplicatePolicy {
+	return registryDuplicatePolicy(r.underlying)
+}
+
+// SetDuplicatePolicy sets the underlying registry's DuplicatePolicy, if it
+// has one.
+func (r *PrefixedRegistry) SetDuplicatePolicy(p DuplicatePolicy) {
+	if u, ok := r.underlying.(interface {
+		SetDuplicatePolicy(DuplicatePolicy)
+	}); ok {
+		u.SetDuplicatePolicy(p)
+	}
+}
+
+// registryDuplicatePolicy returns the DuplicatePolicy of r, or DuplicateError
+// if r doesn't have one.
+func registryDuplicatePolicy(r Registry) DuplicatePolicy {
+	if r, ok := r.(interface {
+		DuplicatePolicy() DuplicatePolicy
+	}); ok {
+		return r.DuplicatePolicy()
+	}
+	return DuplicateError
+}
+
+// duplicate applies the registry's DuplicatePolicy to the registration of i
+// as name, under which existing is registered.
+// assumes lock is taken
+func (r *StandardRegistry) duplicate(name string, existing, i interface{}) error {
+	switch r.duplicates {
+	case DuplicateReplace:
+		if existing != i && registrable(i) {
+			stopMetric(existing)
+			r.metrics[name] = i
+			atomic.AddUint64(&r.generation, 1)
+			atomic.AddUint64(&r.stats.registrations, 1)
+		}
+		return nil
+	case DuplicateKeep:
+		return nil
+	case DuplicateMerge:
+		if existing == i || mergeCounts(existing, i) {
+			return nil
+		}
+	}
+	return DuplicateMetric(name)
+}
+
+// mergeCounts adds the count of the counter i to the counter existing and
+// returns true, or returns false if they aren't counters of the same kind.
+func mergeCounts(existing, i interface{}) bool {
+	switch c := existing.(type) {
+	case Counter:
+		if n, ok := i.(Counter); ok {
+			c.Inc(n.Count())
+			return true
+		}
+	case CounterFloat64:
+		if n, ok := i.(CounterFloat64); ok {
+			c.Inc(n.Count())
+			return true
+		}
+	case Instant:
+		if n, ok := i.(Instant); ok {
+			c.Inc(n.Count())
+			return true
+		}
+	}
+	return false
+}
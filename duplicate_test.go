@@ -0,0 +1,41 @@
+package metrics
+
+import "testing"
+
+func TestDuplicatePolicy(t *testing.T) {
+	r := NewRegistry().(*StandardRegistry)
+	c := NewRegisteredCounter("requests", r)
+	if _, ok := r.Register("requests", NewCounter()).(DuplicateMetric); !ok {
+		t.Error("default policy doesn't return a DuplicateMetric")
+	}
+
+	r.SetDuplicatePolicy(DuplicateKeep)
+	if err := r.Register("requests", NewCounter()); nil != err || r.Get("requests") != c {
+		t.Errorf("keep: %v, registered %v", err, r.Get("requests"))
+	}
+
+	r.SetDuplicatePolicy(DuplicateMerge)
+	other := NewCounter()
+	other.Inc(3)
+	c.Inc(2)
+	if err := r.Register("requests", other); nil != err || 5 != c.Count() {
+		t.Errorf("merge: %v, count %v", err, c.Count())
+	}
+	if _, ok := r.Register("requests", NewGauge()).(DuplicateMetric); !ok {
+		t.Error("merging a gauge into a counter doesn't return a DuplicateMetric")
+	}
+
+	p := NewPrefixedChildRegistry(r, "plugin.").(*PrefixedRegistry)
+	p.SetDuplicatePolicy(DuplicateReplace)
+	if DuplicateReplace != r.DuplicatePolicy() {
+		t.Errorf("prefixed registry didn't set the underlying policy: %v", r.DuplicatePolicy())
+	}
+	m := NewRegisteredMeter("calls", p)
+	if m != p.Get("calls") {
+		t.Fatal("meter missing")
+	}
+	replacement := NewMeter()
+	if err := p.Register("calls", replacement); nil != err || replacement != p.Get("calls") {
+		t.Errorf("replace: %v, registered %v", err, p.Get("calls"))
+	}
+}
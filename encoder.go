@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An Encoder writes the metrics of a registry in an export format.
+// Encoders registered with RegisterEncoder are served by the handler of
+// NewAdminHandler and pushed by the "push" exporter of StartPipeline.
+type Encoder interface {
+	// ContentType is the media type of the format, such as
+	// "application/json", which requests negotiate it by.
+	ContentType() string
+
+	// Encode writes the metrics of r to w.
+	Encode(w io.Writer, r Registry) error
+}
+
+// EncoderFunc adapts a function and a content type to an Encoder.
+type EncoderFunc struct {
+	Type string
+	F    func(w io.Writer, r Registry) error
+}
+
+// ContentType returns e.Type.
+func (e EncoderFunc) ContentType() string { return e.Type }
+
+// Encode calls e.F(w, r).
+func (e EncoderFunc) Encode(w io.Writer, r Registry) error { return e.F(w, r) }
+
+var (
+	encodersMutex sync.RWMutex
+	encoders      = map[string]Encoder{
+		"json": EncoderFunc{"application/json", encodeJSON},
+	}
+)
+
+// RegisterEncoder makes the format called name available to the exporters
+// and handlers of this package, replacing any registered before, so
+// packages can add formats without modifying this one:
+//
+//	metrics.RegisterEncoder("myformat", enc)
+func RegisterEncoder(name string, enc Encoder) {
+	encodersMutex.Lock()
+	defer encodersMutex.Unlock()
+	encoders[name] = enc
+}
+
+// LookupEncoder returns the Encoder registered under name, or nil if there
+// is none.
+func LookupEncoder(name string) Encoder {
+	encodersMutex.RLock()
+	defer encodersMutex.RUnlock()
+	return encoders[name]
+}
+
+// negotiateEncoder returns the Encoder of the first registered format, in
+// name order, whose media type accept lists, or nil if it lists none.
+func negotiateEncoder(accept string) Encoder {
+	if "" == accept {
+		return nil
+	}
+	encodersMutex.RLock()
+	defer encodersMutex.RUnlock()
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		typ := strings.TrimSpace(strings.SplitN(encoders[name].ContentType(), ";", 2)[0])
+		if strings.Contains(accept, typ) {
+			return encoders[name]
+		}
+	}
+	return nil
+}
+
+func encodeJSON(w io.Writer, r Registry) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// startPushExporter starts an exporter sending the metrics of r, encoded
+// in the format registered under the "format" option, to the TCP address
+// c.Addr every interval, on a connection it keeps open between flushes and
+// closes once ctx is done.
+func startPushExporter(ctx context.Context, c ExporterConfig, r Registry) error {
+	d, err := checkPipelineConfig(c, true, "tags", "prefix")
+	if err != nil {
+		return err
+	}
+	format := c.Options["format"]
+	enc := LookupEncoder(format)
+	if nil == enc {
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if c.SkipIdle {
+		r = newIdleFilter(r).view(r)
+	}
+	p := &pushExporter{addr: c.Addr, enc: enc}
+	go func() {
+		RunPeriodicContext(ctx, d, func() {
+			timeExport(format, func() { p.push(ctx, r) })
+		})
+		p.close()
+	}()
+	return nil
+}
+
+type pushExporter struct {
+	addr string
+	enc  Encoder
+	conn net.Conn
+}
+
+// push encodes r on the exporter's connection, dialing it if it isn't open
+// and closing it if writing fails, to be dialed again on the next push.
+func (p *pushExporter) push(ctx context.Context, r Registry) {
+	if nil == p.conn {
+		d := net.Dialer{Timeout: 10 * time.Second}
+		conn, err := d.DialContext(ctx, "tcp", p.addr)
+		if err != nil {
+			return
+		}
+		p.conn = conn
+	}
+	w := bufio.NewWriter(p.conn)
+	if err := p.enc.Encode(w, r); nil == err {
+		if err = w.Flush(); nil == err {
+			return
+		}
+	}
+	p.close()
+}
+
+func (p *pushExporter) close() {
+	if nil != p.conn {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// namesEncoder writes the name of every metric on a line of its own.
+var namesEncoder = EncoderFunc{"text/x-names", func(w io.Writer, r Registry) error {
+	var err error
+	r.Each(func(name string, _ interface{}) {
+		if nil == err {
+			_, err = fmt.Fprintln(w, name)
+		}
+	})
+	return err
+}}
+
+// swapEncoders replaces the registered encoders with m and returns a
+// function restoring them.
+func swapEncoders(m map[string]Encoder) func() {
+	encodersMutex.Lock()
+	defer encodersMutex.Unlock()
+	old := encoders
+	encoders = m
+	return func() {
+		encodersMutex.Lock()
+		defer encodersMutex.Unlock()
+		encoders = old
+	}
+}
+
+func TestRegisterEncoderAdmin(t *testing.T) {
+	defer swapEncoders(map[string]Encoder{})()
+	RegisterEncoder("names", namesEncoder)
+
+	h := NewAdminHandler(newAdminTestRegistry())
+	for _, c := range []struct{ url, accept string }{
+		{"/?format=names&prefix=lobby|", ""},
+		{"/?prefix=lobby|", "text/x-names, */*"},
+	} {
+		w := adminGet(t, h, c.url, c.accept)
+		if ct := w.Header().Get("Content-Type"); "text/x-names" != ct {
+			t.Errorf("%s: content type %q", c.url, ct)
+		}
+		if s := w.Body.String(); "lobby|loginTAGerrors\nlobby|matchTAGerrors\n" != s {
+			t.Errorf("%s: %q", c.url, s)
+		}
+	}
+	if w := adminGet(t, h, "/requests", "text/plain"); "requests: count: 3\n" != w.Body.String() {
+		t.Errorf("text: %q", w.Body.String())
+	}
+}
+
+func TestPushExporter(t *testing.T) {
+	defer swapEncoders(map[string]Encoder{})()
+	RegisterEncoder("names", namesEncoder)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	r := NewRegistry()
+	NewRegisteredCounter("requests", r)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = startPushExporter(ctx, ExporterConfig{
+		Type:     "push",
+		Addr:     ln.Addr().String(),
+		Interval: "50ms",
+		Options:  map[string]string{"format": "names"},
+	}, r)
+	if nil != err {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	br := bufio.NewReader(conn)
+	line, err := br.ReadString('\n')
+	if nil != err || "requests\n" != line {
+		t.Errorf("%q, %v", line, err)
+	}
+
+	// Once stopped, the exporter closes its connection.
+	cancel()
+	for err == nil {
+		_, err = br.ReadString('\n')
+	}
+	if io.EOF != err {
+		t.Errorf("connection not closed: %v", err)
+	}
+
+	err = startPushExporter(ctx, ExporterConfig{Type: "push", Addr: "127.0.0.1:0", Options: map[string]string{"format": "xml"}}, r)
+	if nil == err {
+		t.Error("unknown format accepted")
+	}
+}
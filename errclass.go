@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// The error classes ErrorClass knows without any registered classifier.
+const (
+	ErrorClassTimeout  = "timeout"
+	ErrorClassCanceled = "canceled"
+	ErrorClassRefused  = "refused"
+	ErrorClassReset    = "reset"
+	ErrorClassEOF      = "eof"
+	ErrorClassNotFound = "notfound"
+	ErrorClassOther    = "other"
+)
+
+var (
+	errorClassifierMutex sync.RWMutex
+	errorClassifiers     []func(error) string
+)
+
+// RegisterErrorClassifier adds a classifier ErrorClass consults, before its
+// own and after those registered earlier.  It returns the class of err, or ""
+// if it doesn't know err; it typically tests err with errors.As:
+//
+//	metrics.RegisterErrorClassifier(func(err error) string {
+//		var e *pq.Error
+//		if errors.As(err, &e) {
+//			return "pq_" + string(e.Code)
+//		}
+//		return ""
+//	})
+func RegisterErrorClassifier(f func(error) string) {
+	errorClassifierMutex.Lock()
+	defer errorClassifierMutex.Unlock()
+	errorClassifiers = append(errorClassifiers, f)
+}
+
+// ErrorClass returns the class of err: the first class a registered
+// classifier gives it, else ErrorClassTimeout, ErrorClassCanceled,
+// ErrorClassRefused, ErrorClassReset, ErrorClassEOF or ErrorClassNotFound
+// for the errors of those kinds anywhere in its chain, else ErrorClassOther.
+// Classes are escaped as Name escapes values, so they are safe to tag with.
+// It returns "" for a nil err.
+func ErrorClass(err error) string {
+	if nil == err {
+		return ""
+	}
+	errorClassifierMutex.RLock()
+	classifiers := errorClassifiers
+	errorClassifierMutex.RUnlock()
+	for _, f := range classifiers {
+		if class := f(err); class != "" {
+			return escapeNameValue(class)
+		}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorClassRefused
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return ErrorClassReset
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return ErrorClassEOF
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrorClassNotFound
+	}
+	return ErrorClassOther
+}
+
+// CountError increments the Counter named name in DefaultRegistry, tagged
+// with the ErrorClass of err, e.g. "timeoutTAGdb.errors".  It does nothing if
+// err is nil, so it can be called unconditionally:
+//
+//	rows, err := db.QueryContext(ctx, q)
+//	metrics.CountError("db.errors", err)
+func CountError(name string, err error) {
+	if nil == err || UseNilMetrics {
+		return
+	}
+	GetOrRegisterCounter(taggedName(name, []string{ErrorClass(err)}), nil).Inc(1)
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type codeError struct{ code string }
+
+func (e *codeError) Error() string { return e.code }
+
+func TestErrorClass(t *testing.T) {
+	for err, want := range map[error]string{
+		context.DeadlineExceeded:                  ErrorClassTimeout,
+		fmt.Errorf("query: %w", context.Canceled): ErrorClassCanceled,
+		&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}: ErrorClassRefused,
+		fmt.Errorf("read: %w", syscall.ECONNRESET):                                         ErrorClassReset,
+		io.ErrUnexpectedEOF: ErrorClassEOF,
+		&os.PathError{Op: "open", Path: "x", Err: os.ErrNotExist}: ErrorClassNotFound,
+		errors.New("boom"): ErrorClassOther,
+	} {
+		if class := ErrorClass(err); class != want {
+			t.Errorf("%v: %q, want %q", err, class, want)
+		}
+	}
+	if class := ErrorClass(nil); class != "" {
+		t.Errorf("nil: %q", class)
+	}
+}
+
+func TestErrorClassifier(t *testing.T) {
+	defer func(cs []func(error) string) { errorClassifiers = cs }(errorClassifiers)
+	RegisterErrorClassifier(func(err error) string {
+		var e *codeError
+		if errors.As(err, &e) {
+			return "code " + e.code
+		}
+		return ""
+	})
+
+	if class := ErrorClass(fmt.Errorf("wrapped: %w", &codeError{"23505|TAG"})); "code_23505_tag" != class {
+		t.Error(class)
+	}
+	if class := ErrorClass(context.Canceled); ErrorClassCanceled != class {
+		t.Error(class)
+	}
+}
+
+func TestCountError(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+
+	CountError("db.errors", nil)
+	CountError("db.errors", context.DeadlineExceeded)
+	CountError("db.errors", fmt.Errorf("dial: %w", context.DeadlineExceeded))
+	CountError("db.errors", errors.New("boom"))
+
+	for name, want := range map[string]int64{
+		"timeoutTAGdb.errors": 2,
+		"otherTAGdb.errors":   1,
+	} {
+		if c := Get(name).(Counter).Count(); c != want {
+			t.Errorf("%s: %d, want %d", name, c, want)
+		}
+	}
+	n := 0
+	DefaultRegistry.Each(func(string, interface{}) { n++ })
+	if 2 != n {
+		t.Errorf("%d metrics", n)
+	}
+}
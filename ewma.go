@@ -112,6 +112,23 @@ func (a *StandardEWMA) Tick() {
 	}
 }
 
+// maxExactTicks bounds the ticks tickN applies one by one: an hour's worth.
+const maxExactTicks = 720
+
+// tickN is n calls to Tick.  Beyond maxExactTicks, the rest decay the rate
+// in closed form, so catching up on a long idle period costs no more than
+// an hour's.
+func (a *StandardEWMA) tickN(n int64) {
+	for i := int64(0); i < n && i < maxExactTicks; i++ {
+		a.Tick()
+	}
+	if n > maxExactTicks {
+		a.mutex.Lock()
+		a.rate *= math.Pow(1-a.alpha, float64(n-maxExactTicks))
+		a.mutex.Unlock()
+	}
+}
+
 // Update adds n uncounted events.
 func (a *StandardEWMA) Update(n int64) {
 	atomic.AddInt64(&a.uncounted, n)
@@ -0,0 +1,109 @@
+package metrics
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// An Exemplar links one observation of a histogram or timer to the request
+// that made it through labels such as a trace or request ID, so exporters
+// that support exemplars can point from a latency percentile to a concrete
+// traced request.
+type Exemplar struct {
+	Value  int64             `json:"value"`
+	Labels map[string]string `json:"labels,omitempty"`
+	Time   time.Time         `json:"time"`
+}
+
+// MaxExemplars is how many of their latest exemplars histograms and timers
+// keep.
+const MaxExemplars = 8
+
+// ExemplarRecorder is implemented by the standard histograms and timers and
+// their snapshots.
+type ExemplarRecorder interface {
+	// UpdateWithExemplar records v like Update, along with an exemplar of
+	// it carrying labels.
+	UpdateWithExemplar(v int64, labels map[string]string)
+	// Exemplars returns the latest exemplars, oldest first.
+	Exemplars() []Exemplar
+}
+
+// UpdateWithExemplar records v on m, a Histogram or Timer, with an exemplar
+// carrying labels if m keeps exemplars, and plainly otherwise.
+func UpdateWithExemplar(m interface{ Update(int64) }, v int64, labels map[string]string) {
+	if e, ok := m.(ExemplarRecorder); ok {
+		e.UpdateWithExemplar(v, labels)
+		return
+	}
+	m.Update(v)
+}
+
+// NearestExemplar returns the exemplar whose value is closest to v, such as
+// a percentile or the upper bound of a bucket, or false if there are none.
+func NearestExemplar(exemplars []Exemplar, v float64) (Exemplar, bool) {
+	best, found := Exemplar{}, false
+	for _, e := range exemplars {
+		if !found || math.Abs(float64(e.Value)-v) < math.Abs(float64(best.Value)-v) {
+			best, found = e, true
+		}
+	}
+	return best, found
+}
+
+// exemplarRing keeps the latest MaxExemplars exemplars.  Its buffer is
+// allocated by the first exemplar, so metrics never given one pay nothing
+// for it.
+type exemplarRing struct {
+	mutex sync.Mutex
+	buf   []Exemplar
+	next  int
+}
+
+func (r *exemplarRing) add(e Exemplar) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.buf) < MaxExemplars {
+		r.buf = append(r.buf, e)
+		return
+	}
+	r.buf[r.next] = e
+	r.next = (r.next + 1) % MaxExemplars
+}
+
+func (r *exemplarRing) clear() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.buf, r.next = nil, 0
+}
+
+// values returns the exemplars oldest first.
+func (r *exemplarRing) values() []Exemplar {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.buf) == 0 {
+		return nil
+	}
+	values := make([]Exemplar, 0, len(r.buf))
+	values = append(values, r.buf[r.next:]...)
+	return append(values, r.buf[:r.next]...)
+}
+
+// latestExemplars returns the latest MaxExemplars of a and b, oldest first.
+func latestExemplars(a, b []Exemplar) []Exemplar {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	all := make([]Exemplar, 0, len(a)+len(b))
+	all = append(append(all, a...), b...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Time.Before(all[j].Time) })
+	if len(all) > MaxExemplars {
+		all = all[len(all)-MaxExemplars:]
+	}
+	return all
+}
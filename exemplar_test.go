@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHistogramExemplars(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	h := NewHistogram(NewUniformSample(100))
+	h.Update(1)
+	for i := 0; i < MaxExemplars+2; i++ {
+		clock.Add(time.Second)
+		UpdateWithExemplar(h, int64(100*i), map[string]string{"trace_id": fmt.Sprint(i)})
+	}
+	if count := h.Count(); MaxExemplars+3 != count {
+		t.Errorf("h.Count(): %v != %v", MaxExemplars+3, count)
+	}
+	exemplars := h.(ExemplarRecorder).Exemplars()
+	if MaxExemplars != len(exemplars) {
+		t.Fatalf("len(exemplars): %v", len(exemplars))
+	}
+	if "2" != exemplars[0].Labels["trace_id"] || fmt.Sprint(MaxExemplars+1) != exemplars[MaxExemplars-1].Labels["trace_id"] {
+		t.Errorf("exemplars aren't the latest, oldest first: %v", exemplars)
+	}
+	if e, ok := NearestExemplar(h.Snapshot().(ExemplarRecorder).Exemplars(), 520); !ok || 500 != e.Value {
+		t.Errorf("NearestExemplar: %v, %v", e, ok)
+	}
+
+	h.Clear()
+	if exemplars := h.(ExemplarRecorder).Exemplars(); 0 != len(exemplars) {
+		t.Errorf("exemplars after Clear: %v", exemplars)
+	}
+}
+
+func TestTimerExemplars(t *testing.T) {
+	a, b := NewTimer(), NewTimer()
+	UpdateWithExemplar(a, 10, map[string]string{"trace_id": "a"})
+	UpdateWithExemplar(b, 20, map[string]string{"trace_id": "b"})
+	UpdateWithExemplar(NilTimer{}, 30, nil)
+	if count := a.Count(); 1 != count {
+		t.Errorf("a.Count(): 1 != %v", count)
+	}
+	m := a.Snapshot().(*TimerSnapshot).Merge(b)
+	exemplars := m.Exemplars()
+	if 2 != len(exemplars) || 10 != exemplars[0].Value || 20 != exemplars[1].Value {
+		t.Errorf("merged exemplars: %v", exemplars)
+	}
+}
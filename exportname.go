@@ -0,0 +1,60 @@
+package metrics
+
+import "sync"
+
+var (
+	exportNameMutex sync.RWMutex
+	exportNames     = make(map[string]map[string]string) // backend, name
+)
+
+// SetExportName makes the backend, an exporter type such as "graphite",
+// "opentsdb" or "prometheus", export the metric named name, tags included,
+// as exported, so dashboards don't depend on internal naming conventions:
+//
+//	metrics.SetExportName("graphite", "grp|tgtTAGlogin_ms", "login.latency")
+//
+// The backend's sanitizer still applies to exported.  An empty exported
+// removes the override.  Overrides take effect on the next export.
+func SetExportName(backend, name, exported string) {
+	exportNameMutex.Lock()
+	defer exportNameMutex.Unlock()
+	if exported == "" {
+		delete(exportNames[backend], name)
+		return
+	}
+	if nil == exportNames[backend] {
+		exportNames[backend] = make(map[string]string)
+	}
+	exportNames[backend][name] = exported
+}
+
+// ExportName returns the name the backend exports the metric named name as:
+// the one given to SetExportName, or else name.
+func ExportName(backend, name string) string {
+	if exported, ok := exportName(backend, name); ok {
+		return exported
+	}
+	return name
+}
+
+func exportName(backend, name string) (string, bool) {
+	exportNameMutex.RLock()
+	defer exportNameMutex.RUnlock()
+	exported, ok := exportNames[backend][name]
+	return exported, ok
+}
+
+// ExportNames returns the NameSanitizer renaming metrics the way the backend
+// exports them, by ExportName, and then by s unless it is nil.  The Graphite
+// and OpenTSDB exporters apply it to their Sanitizer, so a metric exported as
+// the name of another is dropped and reported like a sanitizer collision.
+// They still scale values by the unit set for the original name.
+func ExportNames(backend string, s NameSanitizer) NameSanitizer {
+	return func(name string) string {
+		name = ExportName(backend, name)
+		if nil != s {
+			name = s(name)
+		}
+		return name
+	}
+}
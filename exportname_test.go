@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestExportName(t *testing.T) {
+	defer SetExportName("graphite", "grp|tgtTAGlogin_ms", "")
+	SetExportName("graphite", "grp|tgtTAGlogin_ms", "login.latency")
+	if name := ExportName("graphite", "grp|tgtTAGlogin_ms"); "login.latency" != name {
+		t.Errorf("graphite: %q", name)
+	}
+	if name := ExportName("opentsdb", "grp|tgtTAGlogin_ms"); "grp|tgtTAGlogin_ms" != name {
+		t.Errorf("opentsdb: %q", name)
+	}
+	s := ExportNames("graphite", SanitizeGraphite)
+	if name := s("grp|tgtTAGlogout_ms"); "grp_tgtTAGlogout_ms" != name {
+		t.Errorf("sanitized: %q", name)
+	}
+
+	r := NewRegistry()
+	NewRegisteredCounter("grp|tgtTAGlogin_ms", r)
+	NewRegisteredCounter("login.latency", r)
+	var dropped []string
+	view := NewSanitizedRegistry(r, s)
+	view.OnCollision = func(_ string, names []string) { dropped = names }
+	var names []string
+	view.Each(func(name string, _ interface{}) { names = append(names, name) })
+	if "login.latency" != strings.Join(names, " ") || 1 != len(dropped) || "login.latency" != dropped[0] {
+		t.Errorf("names %v, dropped %v", names, dropped)
+	}
+
+	SetExportName("graphite", "grp|tgtTAGlogin_ms", "")
+	if name := ExportName("graphite", "grp|tgtTAGlogin_ms"); "grp|tgtTAGlogin_ms" != name {
+		t.Errorf("removed: %q", name)
+	}
+}
+
+func TestPrometheusExportName(t *testing.T) {
+	defer SetExportName("prometheus", "grp|tgtTAGlogin_ms", "")
+	SetExportName("prometheus", "grp|tgtTAGlogin_ms", "login_total")
+	r := NewRegistry()
+	NewRegisteredCounter("grp|tgtTAGlogin_ms", r).Inc(3)
+	var b strings.Builder
+	w := bufio.NewWriter(&b)
+	writePrometheus(w, r, &PrometheusConfig{Scale: SecondsScale})
+	w.Flush()
+	if want := `login_total{ns="grp",grp="tgt"} 3`; !strings.Contains(b.String(), want) {
+		t.Errorf("%q doesn't contain %q", b.String(), want)
+	}
+}
@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// A QueuePolicy decides what an ExportQueue does with a snapshot enqueued
+// while it is full.
+type QueuePolicy int
+
+const (
+	// QueueBlock makes Enqueue wait for room, so no snapshot is lost but a
+	// slow collector holds up the goroutine taking snapshots.
+	QueueBlock QueuePolicy = iota
+
+	// QueueDropOldest drops the oldest queued snapshot to make room, so the
+	// collector receives the latest values once it catches up.
+	QueueDropOldest
+
+	// QueueDropNewest drops the snapshot being enqueued, so the collector
+	// receives an unbroken run of older values.
+	QueueDropNewest
+)
+
+// An ExportQueue decouples taking snapshots of a registry from sending them
+// to a collector, which it does from a goroutine of its own, so a slow or
+// unreachable collector never stalls the goroutines taking snapshots, or,
+// with a dropping policy, only loses snapshots.  For a queue named
+// "export.graphite" it registers:
+//
+//	export.graphite.queue.depth    a Gauge of snapshots waiting to be sent
+//	export.graphite.queue.dropped  a Counter of snapshots dropped
+//	export.graphite.queue.errors   a Counter of snapshots send failed on
+//
+// Exporters, including those added to StartPipeline with
+// RegisterPipelineExporter, queue a snapshot on every tick and send it with
+// the queue's send function:
+//
+//	q := metrics.NewExportQueue("export.collector", nil, 10, metrics.QueueDropOldest, send)
+//	go metrics.ExportQueued(r, time.Minute, q)
+type ExportQueue struct {
+	send    func(RegistrySnapshot) error
+	size    int
+	policy  QueuePolicy
+	dropped Counter
+	errors  Counter
+
+	mutex  sync.Mutex
+	cond   *sync.Cond // signalled whenever the queue changes or is closed
+	queue  []RegistrySnapshot
+	closed bool
+	done   chan struct{}
+}
+
+// NewExportQueue starts an ExportQueue holding up to size snapshots, at
+// least one, which it passes to send one at a time, in order, and whose
+// metrics are named name in r, or DefaultRegistry if r is nil.
+func NewExportQueue(name string, r Registry, size int, policy QueuePolicy, send func(RegistrySnapshot) error) *ExportQueue {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	if size < 1 {
+		size = 1
+	}
+	q := &ExportQueue{
+		send:    send,
+		size:    size,
+		policy:  policy,
+		dropped: GetOrRegisterCounter(name+".queue.dropped", r),
+		errors:  GetOrRegisterCounter(name+".queue.errors", r),
+		done:    make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mutex)
+	r.Register(name+".queue.depth", NewFunctionalGauge(q.Depth))
+	go q.loop()
+	return q
+}
+
+// ExportQueued enqueues a snapshot of r on q every d, and whenever Flush is
+// called, until the process exits.
+func ExportQueued(r Registry, d time.Duration, q *ExportQueue) {
+	RunPeriodic(d, func() {
+		q.Enqueue(SnapshotRegistry(r))
+	})
+}
+
+// Enqueue queues s to be sent, applying the queue's policy if it is full.
+// It returns false if s was dropped, by QueueDropNewest or because the
+// queue is closed.
+func (q *ExportQueue) Enqueue(s RegistrySnapshot) bool {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.policy == QueueBlock {
+		for len(q.queue) >= q.size && !q.closed {
+			q.cond.Wait()
+		}
+	}
+	if q.closed {
+		q.dropped.Inc(1)
+		return false
+	}
+	if len(q.queue) >= q.size {
+		q.dropped.Inc(1)
+		if q.policy == QueueDropNewest {
+			return false
+		}
+		q.queue[0] = nil
+		q.queue = q.queue[1:]
+	}
+	q.queue = append(q.queue, s)
+	q.cond.Broadcast()
+	return true
+}
+
+// Depth returns the number of snapshots waiting to be sent.
+func (q *ExportQueue) Depth() int64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return int64(len(q.queue))
+}
+
+// Dropped returns the number of snapshots dropped.
+func (q *ExportQueue) Dropped() int64 { return q.dropped.Count() }
+
+// Close stops accepting snapshots, waits for those queued to be sent and
+// stops the queue's goroutine.
+func (q *ExportQueue) Close() {
+	q.mutex.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mutex.Unlock()
+	<-q.done
+}
+
+func (q *ExportQueue) loop() {
+	defer close(q.done)
+	for {
+		q.mutex.Lock()
+		for 0 == len(q.queue) && !q.closed {
+			q.cond.Wait()
+		}
+		if 0 == len(q.queue) {
+			q.mutex.Unlock()
+			return
+		}
+		s := q.queue[0]
+		q.queue[0] = nil
+		q.queue = q.queue[1:]
+		q.cond.Broadcast()
+		q.mutex.Unlock()
+
+		if err := q.send(s); err != nil {
+			q.errors.Inc(1)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExportQueuePolicies(t *testing.T) {
+	for _, policy := range []QueuePolicy{QueueDropOldest, QueueDropNewest} {
+		r := NewRegistry()
+		release := make(chan struct{})
+		sent := make(chan RegistrySnapshot, 10)
+		q := NewExportQueue("export", r, 2, policy, func(s RegistrySnapshot) error {
+			<-release
+			sent <- s
+			return nil
+		})
+
+		// The first snapshot is taken by the sending goroutine, which is
+		// held up, so the queue fills with the next two.
+		q.Enqueue(RegistrySnapshot{"0": nil})
+		for 0 != q.Depth() {
+		}
+		q.Enqueue(RegistrySnapshot{"1": nil})
+		q.Enqueue(RegistrySnapshot{"2": nil})
+		if ok := q.Enqueue(RegistrySnapshot{"3": nil}); ok != (policy == QueueDropOldest) {
+			t.Errorf("policy %v: Enqueue on a full queue: %v", policy, ok)
+		}
+		if 1 != q.Dropped() || 2 != r.Get("export.queue.depth").(Gauge).Value() {
+			t.Errorf("policy %v: dropped %v, depth %v", policy, q.Dropped(), q.Depth())
+		}
+
+		close(release)
+		q.Close()
+		close(sent)
+		var got string
+		for s := range sent {
+			for name := range s {
+				got += name
+			}
+		}
+		want := map[QueuePolicy]string{QueueDropOldest: "023", QueueDropNewest: "012"}[policy]
+		if want != got {
+			t.Errorf("policy %v: sent %q, want %q", policy, got, want)
+		}
+	}
+}
+
+func TestExportQueueBlock(t *testing.T) {
+	r := NewRegistry()
+	release := make(chan struct{})
+	q := NewExportQueue("export", r, 1, QueueBlock, func(RegistrySnapshot) error {
+		<-release
+		return errors.New("collector down")
+	})
+	q.Enqueue(RegistrySnapshot{})
+	for 0 != q.Depth() {
+	}
+	q.Enqueue(RegistrySnapshot{})
+
+	enqueued := make(chan bool)
+	go func() { enqueued <- q.Enqueue(RegistrySnapshot{}) }()
+	select {
+	case <-enqueued:
+		t.Fatal("Enqueue didn't block on a full queue")
+	default:
+	}
+	close(release)
+	if !<-enqueued {
+		t.Error("blocked Enqueue dropped its snapshot")
+	}
+	q.Close()
+	if q.Enqueue(RegistrySnapshot{}) {
+		t.Error("closed queue accepted a snapshot")
+	}
+	if errs := r.Get("export.queue.errors").(Counter).Count(); 3 != errs {
+		t.Errorf("export.queue.errors: 3 != %v", errs)
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flushRequest asks a periodic exporter to export now and close done.
+type flushRequest struct {
+	done chan struct{}
+}
+
+// periodicLoop is a loop running through runPeriodic, which takes flush
+// requests on flush until it closes stopped.
+type periodicLoop struct {
+	flush   chan flushRequest
+	stopped chan struct{}
+}
+
+var (
+	flushMutex    sync.Mutex
+	flushChannels []*periodicLoop
+)
+
+// RunPeriodic calls export every d, and additionally whenever Flush is
+// called, until the process exits.  Calls never overlap, so export need not
+// be safe for concurrent use.  Exporters built on this package, such as Log,
+// Write, Syslog, WriteJSON, Graphite, OpenTSDB and optron, run their loops
+// through it so a shutdown hook can push out the final interval.
+func RunPeriodic(d time.Duration, export func()) {
+	RunPeriodicContext(context.Background(), d, export)
+}
+
+// RunPeriodicContext is like RunPeriodic but returns once ctx is done, after
+// which Flush no longer waits for it.
+func RunPeriodicContext(ctx context.Context, d time.Duration, export func()) {
+	runPeriodic(ctx, d, export, export)
+}
+
+// runPeriodic is RunPeriodicContext with separate functions for ticks and
+// flushes.
+func runPeriodic(ctx context.Context, d time.Duration, tick, flush func()) {
+	loop := &periodicLoop{flush: make(chan flushRequest, 1), stopped: make(chan struct{})}
+	flushMutex.Lock()
+	flushChannels = append(flushChannels, loop)
+	flushMutex.Unlock()
+	defer func() {
+		flushMutex.Lock()
+		for i, l := range flushChannels {
+			if l == loop {
+				flushChannels = append(flushChannels[:i], flushChannels[i+1:]...)
+				break
+			}
+		}
+		flushMutex.Unlock()
+		close(loop.stopped)
+	}()
+
+	ticker := DefaultClock.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.Chan():
+			tick()
+		case req := <-loop.flush:
+			flush()
+			close(req.done)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Flush makes every exporter running through RunPeriodic export immediately,
+// all at once, and waits for them to finish.  It returns ctx.Err() if ctx is
+// done first, leaving the remaining exports to complete on their own.
+// Exporters that stop meanwhile are skipped.
+func Flush(ctx context.Context) error {
+	flushMutex.Lock()
+	loops := append([]*periodicLoop(nil), flushChannels...)
+	flushMutex.Unlock()
+
+	dones := make([]chan struct{}, 0, len(loops))
+	stops := make([]chan struct{}, 0, len(loops))
+	for _, loop := range loops {
+		req := flushRequest{done: make(chan struct{})}
+		select {
+		case loop.flush <- req:
+			dones = append(dones, req.done)
+			stops = append(stops, loop.stopped)
+		case <-loop.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	for i, done := range dones {
+		select {
+		case <-done:
+		case <-stops[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
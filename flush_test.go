@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlush(t *testing.T) {
+	flushMutex.Lock()
+	running := len(flushChannels)
+	flushMutex.Unlock()
+
+	stop, cancelLoops := context.WithCancel(context.Background())
+	var exports int32
+	stopped := make(chan struct{}, 2)
+	go func() {
+		RunPeriodicContext(stop, time.Hour, func() { atomic.AddInt32(&exports, 1) })
+		stopped <- struct{}{}
+	}()
+	release := make(chan struct{})
+	go func() {
+		RunPeriodicContext(stop, time.Hour, func() { <-release })
+		stopped <- struct{}{}
+	}()
+
+	// Wait for both loops to register.
+	for {
+		flushMutex.Lock()
+		n := len(flushChannels)
+		flushMutex.Unlock()
+		if n >= running+2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := Flush(ctx); err != context.DeadlineExceeded {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&exports); n != 1 {
+		t.Fatal(n)
+	}
+
+	close(release)
+	if err := Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&exports); n != 2 {
+		t.Fatal(n)
+	}
+
+	cancelLoops()
+	<-stopped
+	<-stopped
+	flushMutex.Lock()
+	n := len(flushChannels)
+	flushMutex.Unlock()
+	if n != running {
+		t.Errorf("%d loops still registered, want %d", n, running)
+	}
+	if err := Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&exports); n != 2 {
+		t.Fatal(n)
+	}
+}
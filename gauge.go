@@ -15,6 +15,9 @@ func GetOrRegisterGauge(name string, r Registry) Gauge {
 	if nil == r {
 		r = DefaultRegistry
 	}
+	if m, ok := r.Get(name).(Gauge); ok {
+		return m
+	}
 	return r.GetOrRegister(name, NewGauge).(Gauge)
 }
 
@@ -23,7 +26,7 @@ func NewGauge() Gauge {
 	if UseNilMetrics {
 		return NilGauge{}
 	}
-	return &StandardGauge{0}
+	return &StandardGauge{}
 }
 
 // NewRegisteredGauge constructs and registers a new StandardGauge.
@@ -44,7 +47,6 @@ func NewFunctionalGauge(f func() int64) Gauge {
 	return &FunctionalGauge{value: f}
 }
 
-
 // NewRegisteredFunctionalGauge constructs and registers a new StandardGauge.
 func NewRegisteredFunctionalGauge(name string, r Registry, f func() int64) Gauge {
 	c := NewFunctionalGauge(f)
@@ -84,7 +86,8 @@ func (NilGauge) Value() int64 { return 0 }
 // StandardGauge is the standard implementation of a Gauge and uses the
 // sync/atomic package to manage a single int64 value.
 type StandardGauge struct {
-	value int64
+	value    int64
+	watchers watcherSet
 }
 
 // Snapshot returns a read-only copy of the gauge.
@@ -94,13 +97,19 @@ func (g *StandardGauge) Snapshot() Gauge {
 
 // Update updates the gauge's value.
 func (g *StandardGauge) Update(v int64) {
-	atomic.StoreInt64(&g.value, v)
+	old := atomic.SwapInt64(&g.value, v)
+	g.watchers.notify(old, v)
 }
 
 // Value returns the gauge's current value.
 func (g *StandardGauge) Value() int64 {
 	return atomic.LoadInt64(&g.value)
 }
+
+func (g *StandardGauge) watch(f func(old, new int64)) func() {
+	return g.watchers.add(f)
+}
+
 // FunctionalGauge returns value from given function
 type FunctionalGauge struct {
 	value func() int64
@@ -117,4 +126,26 @@ func (g FunctionalGauge) Snapshot() Gauge { return GaugeSnapshot(g.Value()) }
 // Update panics.
 func (FunctionalGauge) Update(int64) {
 	panic("Update called on a FunctionalGauge")
-}
\ No newline at end of file
+}
+
+// ownedGauge is a FunctionalGauge reading the state of an instrument such
+// as a JobStats, through which the instrument's constructor finds the one
+// already registered under a name.
+type ownedGauge struct {
+	FunctionalGauge
+	owner interface{}
+}
+
+// registerOwnedGauge registers in r a Gauge named name reading value of
+// owner, unless one is registered already, and returns the owner of the
+// registered Gauge: owner, or the instrument registered under name before.
+func registerOwnedGauge(r Registry, name string, owner interface{}, value func() int64) interface{} {
+	if UseNilMetrics {
+		r.Register(name, NilGauge{})
+		return owner
+	}
+	if g, ok := r.GetOrRegister(name, &ownedGauge{FunctionalGauge{value}, owner}).(*ownedGauge); ok {
+		return g.owner
+	}
+	return owner
+}
@@ -15,7 +15,10 @@ func GetOrRegisterGaugeFloat64(name string, r Registry) GaugeFloat64 {
 	if nil == r {
 		r = DefaultRegistry
 	}
-	return r.GetOrRegister(name, NewGaugeFloat64()).(GaugeFloat64)
+	if m, ok := r.Get(name).(GaugeFloat64); ok {
+		return m
+	}
+	return r.GetOrRegister(name, NewGaugeFloat64).(GaugeFloat64)
 }
 
 // NewGaugeFloat64 constructs a new StandardGaugeFloat64.
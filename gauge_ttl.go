@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// TTLGauges are GaugeFloat64s whose value expires: once they haven't been
+// updated for their TTL, they report their expired value instead of the last
+// one, so the gauges of a producer that died, such as a worker's heartbeat,
+// don't report its last value forever.  Pass math.NaN() as the expired value
+// for exporters to report the gauge as stale rather than as a default.
+type TTLGauge interface {
+	GaugeFloat64
+	Stale() bool
+}
+
+// GetOrRegisterTTLGauge returns an existing TTLGauge or constructs and
+// registers a new StandardTTLGauge.
+func GetOrRegisterTTLGauge(name string, r Registry, ttl time.Duration, expired float64) TTLGauge {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	if m, ok := r.Get(name).(TTLGauge); ok {
+		return m
+	}
+	return r.GetOrRegister(name, func() TTLGauge { return NewTTLGauge(ttl, expired) }).(TTLGauge)
+}
+
+// NewTTLGauge constructs a new StandardTTLGauge reporting expired until it
+// is first updated and once ttl has passed since its last update.
+func NewTTLGauge(ttl time.Duration, expired float64) TTLGauge {
+	if UseNilMetrics {
+		return NilTTLGauge{}
+	}
+	return &StandardTTLGauge{
+		ttl:     ttl,
+		expired: expired,
+		clock:   DefaultClock,
+	}
+}
+
+// NewRegisteredTTLGauge constructs and registers a new StandardTTLGauge.
+func NewRegisteredTTLGauge(name string, r Registry, ttl time.Duration, expired float64) TTLGauge {
+	c := NewTTLGauge(ttl, expired)
+	if nil == r {
+		r = DefaultRegistry
+	}
+	r.Register(name, c)
+	return c
+}
+
+// NilTTLGauge is a no-op TTLGauge.
+type NilTTLGauge struct{}
+
+// Snapshot is a no-op.
+func (NilTTLGauge) Snapshot() GaugeFloat64 { return NilGaugeFloat64{} }
+
+// Stale is a no-op.
+func (NilTTLGauge) Stale() bool { return false }
+
+// Update is a no-op.
+func (NilTTLGauge) Update(v float64) {}
+
+// Value is a no-op.
+func (NilTTLGauge) Value() float64 { return 0.0 }
+
+// StandardTTLGauge is the standard implementation of a TTLGauge.
+type StandardTTLGauge struct {
+	mutex   sync.Mutex
+	value   float64
+	updated time.Time // zero until the first update
+	ttl     time.Duration
+	expired float64
+	clock   Clock
+}
+
+// Snapshot returns a read-only copy of the gauge.
+func (g *StandardTTLGauge) Snapshot() GaugeFloat64 {
+	return GaugeFloat64Snapshot(g.Value())
+}
+
+// Stale returns whether the gauge has expired.
+func (g *StandardTTLGauge) Stale() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.stale()
+}
+
+func (g *StandardTTLGauge) stale() bool {
+	return g.updated.IsZero() || g.clock.Now().Sub(g.updated) >= g.ttl
+}
+
+// Update updates the gauge's value and restarts its TTL.
+func (g *StandardTTLGauge) Update(v float64) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.value = v
+	g.updated = g.clock.Now()
+}
+
+// Value returns the gauge's current value, or its expired value if it is
+// stale.
+func (g *StandardTTLGauge) Value() float64 {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.stale() {
+		return g.expired
+	}
+	return g.value
+}
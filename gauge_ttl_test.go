@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTTLGauge(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	g := NewTTLGauge(time.Minute, -1)
+	if v := g.Value(); -1 != v || !g.Stale() {
+		t.Errorf("never updated: %v, stale %v", v, g.Stale())
+	}
+	g.Update(47)
+	clock.Add(59 * time.Second)
+	if v := g.Value(); 47 != v || g.Stale() {
+		t.Errorf("fresh: %v, stale %v", v, g.Stale())
+	}
+	snapshot := g.Snapshot()
+	clock.Add(time.Second)
+	if v := g.Value(); -1 != v || !g.Stale() {
+		t.Errorf("expired: %v, stale %v", v, g.Stale())
+	}
+	if v := snapshot.Value(); 47 != v {
+		t.Errorf("snapshot: 47 != %v", v)
+	}
+	g.Update(3)
+	if v := g.Value(); 3 != v {
+		t.Errorf("updated again: 3 != %v", v)
+	}
+}
+
+func TestTTLGaugeNaN(t *testing.T) {
+	g := NewTTLGauge(time.Minute, math.NaN())
+	if v := g.Value(); !math.IsNaN(v) {
+		t.Errorf("stale value: %v", v)
+	}
+}
+
+func TestGetOrRegisterTTLGauge(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredTTLGauge("heartbeat", r, time.Minute, 0).Update(1)
+	if g := GetOrRegisterTTLGauge("heartbeat", r, time.Hour, 0); 1 != g.Value() {
+		t.Errorf("g.Value(): 1 != %v", g.Value())
+	}
+	if _, ok := r.Get("heartbeat").(GaugeFloat64); !ok {
+		t.Error("TTLGauge isn't a GaugeFloat64")
+	}
+}
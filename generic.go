@@ -0,0 +1,91 @@
+package metrics
+
+// The functions and types in this file are a typed layer over Registry: they
+// do the type assertions user code would otherwise repeat on the
+// interface{} values the registry deals in.  The interface-based API they
+// are built on is unchanged.
+
+// GetAs returns the metric named name in r, or DefaultRegistry if r is nil,
+// and whether it is registered as a T.  It is the typed counterpart of Get,
+// whose name it cannot share.
+//
+//	if c, ok := metrics.GetAs[metrics.Counter](r, "requests"); ok {
+//		c.Inc(1)
+//	}
+func GetAs[T any](r Registry, name string) (T, bool) {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	m, ok := r.Get(name).(T)
+	return m, ok
+}
+
+// GetOrNew returns the T named name in r, or DefaultRegistry if r is nil,
+// constructing it with f and registering it if there is none.  Like the
+// GetOrRegister functions of each metric type, it panics if name is
+// registered as a metric of another type.
+func GetOrNew[T any](r Registry, name string, f func() T) T {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	if m, ok := r.Get(name).(T); ok {
+		return m
+	}
+	return r.GetOrRegister(name, f).(T)
+}
+
+// Vec is a family of metrics of one type sharing a name and told apart by
+// their tags, each registered under the tagged name TaggedMetricName builds.
+type Vec[T Metric] struct {
+	name string
+	r    Registry
+	f    func() T
+}
+
+// NewVec returns a Vec of the metrics named name in r, or DefaultRegistry if
+// r is nil, constructing missing ones with f.
+//
+//	logins := metrics.NewVec(nil, "logins", metrics.NewCounter)
+//	logins.With("auth", "google").Inc(1)
+func NewVec[T Metric](r Registry, name string, f func() T) *Vec[T] {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return &Vec[T]{name: name, r: r, f: f}
+}
+
+// With returns the metric tagged with tags, in TagBoard order, constructing
+// and registering it if needed.  With no tags it returns the untagged metric.
+// While UseNilMetrics is set it returns what v's constructor does, unregistered,
+// without building the name.
+func (v *Vec[T]) With(tags ...string) T {
+	if UseNilMetrics {
+		return v.f()
+	}
+	return GetOrNew(v.r, taggedName(v.name, tags), v.f)
+}
+
+// Each calls f with the tags and metric of every registered member of v, in
+// name order.
+func (v *Vec[T]) Each(f func(TagBoard, T)) {
+	v.r.Each(func(name string, i interface{}) {
+		m, ok := i.(T)
+		if !ok {
+			return
+		}
+		if name == v.name {
+			f(TagBoard{}, m)
+			return
+		}
+		base, tb, err := ParseTagged(name)
+		if err != nil || base != v.name {
+			return
+		}
+		f(tb, m)
+	})
+}
+
+// Unregister removes the member of v tagged with tags.
+func (v *Vec[T]) Unregister(tags ...string) {
+	v.r.Unregister(taggedName(v.name, tags))
+}
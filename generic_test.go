@@ -0,0 +1,71 @@
+package metrics
+
+import "testing"
+
+func TestGetAs(t *testing.T) {
+	r := NewRegistry()
+	r.Register("requests", NewCounter())
+	if c, ok := GetAs[Counter](r, "requests"); !ok || nil == c {
+		t.Fatal("counter not found")
+	}
+	if _, ok := GetAs[Gauge](r, "requests"); ok {
+		t.Error("counter returned as a gauge")
+	}
+	if _, ok := GetAs[Counter](r, "missing"); ok {
+		t.Error("missing metric found")
+	}
+}
+
+func TestGetAsDefaultRegistry(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+	GetOrNew(nil, "load", NewGaugeFloat64).Update(1.5)
+	if g, ok := GetAs[GaugeFloat64](nil, "load"); !ok || 1.5 != g.Value() {
+		t.Errorf("load: %v", DefaultRegistry.Get("load"))
+	}
+}
+
+func TestGetOrNew(t *testing.T) {
+	r := NewRegistry()
+	c := GetOrNew(r, "requests", NewCounter)
+	c.Inc(2)
+	if c2 := GetOrNew(r, "requests", NewCounter); 2 != c2.Count() {
+		t.Errorf("c2.Count(): 2 != %v", c2.Count())
+	}
+	defer func() {
+		if nil == recover() {
+			t.Error("no panic for a metric of another type")
+		}
+	}()
+	GetOrNew(r, "requests", NewGauge)
+}
+
+func TestVec(t *testing.T) {
+	r := NewRegistry()
+	logins := NewVec(r, "logins", NewCounter)
+	logins.With("auth", "google").Inc(1)
+	logins.With("auth", "google").Inc(1)
+	logins.With("auth", "apple").Inc(3)
+	logins.With().Inc(4)
+	r.Register("other", NewCounter())
+	r.Register(TaggedMetricName("logins", NewTagBoard("x")), NewGauge())
+
+	counts := make(map[string]int64)
+	logins.Each(func(tb TagBoard, c Counter) {
+		counts[tb.String()] = c.Count()
+	})
+	want := map[string]int64{"": 4, "auth|google": 2, "auth|apple": 3}
+	if len(counts) != len(want) {
+		t.Fatalf("counts: %v", counts)
+	}
+	for k, v := range want {
+		if counts[k] != v {
+			t.Errorf("%q: %v != %v", k, v, counts[k])
+		}
+	}
+
+	logins.Unregister("auth", "apple")
+	if _, ok := GetAs[Counter](r, TaggedMetricName("logins", NewTagBoard("auth", "apple"))); ok {
+		t.Error("unregistered member still registered")
+	}
+}
@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"runtime"
+	"time"
+)
+
+var goroutineMetrics struct {
+	Goroutines Gauge
+	CgoCalls   Gauge
+	Threads    Gauge
+}
+
+// Capture new values for the goroutine, cgo call and OS thread gauges.  This
+// is designed to be called as a goroutine.
+func CaptureGoroutineStats(r Registry, d time.Duration) {
+	for _ = range time.Tick(d) {
+		CaptureGoroutineStatsOnce(r)
+	}
+}
+
+// Capture new values for the goroutine, cgo call and OS thread gauges.  None
+// of these reads stop the world, so unlike CaptureRuntimeMemStatsOnce this is
+// cheap enough to run every second.  Giving a registry which has not been
+// given to RegisterGoroutineStats will panic.
+func CaptureGoroutineStatsOnce(r Registry) {
+	goroutineMetrics.Goroutines.Update(int64(runtime.NumGoroutine()))
+	goroutineMetrics.CgoCalls.Update(numCgoCall())
+	goroutineMetrics.Threads.Update(int64(threadCreateProfile.Count()))
+}
+
+// Register gauges for the number of goroutines, the total number of cgo calls
+// made by the process and the number of OS threads created, named
+// runtime.Goroutines, runtime.CgoCalls and runtime.Threads.
+func RegisterGoroutineStats(r Registry) {
+	goroutineMetrics.Goroutines = NewGauge()
+	goroutineMetrics.CgoCalls = NewGauge()
+	goroutineMetrics.Threads = NewGauge()
+
+	r.Register("runtime.Goroutines", goroutineMetrics.Goroutines)
+	r.Register("runtime.CgoCalls", goroutineMetrics.CgoCalls)
+	r.Register("runtime.Threads", goroutineMetrics.Threads)
+}
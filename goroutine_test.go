@@ -0,0 +1,31 @@
+package metrics
+
+import "testing"
+
+func BenchmarkGoroutineStats(b *testing.B) {
+	r := NewRegistry()
+	RegisterGoroutineStats(r)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CaptureGoroutineStatsOnce(r)
+	}
+}
+
+func TestGoroutineStats(t *testing.T) {
+	r := NewRegistry()
+	RegisterGoroutineStats(r)
+
+	ch := make(chan struct{})
+	defer close(ch)
+	for i := 0; i < 10; i++ {
+		go func() { <-ch }()
+	}
+	CaptureGoroutineStatsOnce(r)
+
+	if v := r.Get("runtime.Goroutines").(Gauge).Value(); v < 10 {
+		t.Fatal(v)
+	}
+	if v := r.Get("runtime.Threads").(Gauge).Value(); v < 1 {
+		t.Fatal(v)
+	}
+}
@@ -2,6 +2,7 @@ package metrics
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -13,12 +14,15 @@ import (
 // GraphiteConfig provides a container with configuration parameters for
 // the Graphite exporter
 type GraphiteConfig struct {
-	Addr          *net.TCPAddr  // Network address to connect to
-	Registry      Registry      // Registry to be exported
-	FlushInterval time.Duration // Flush interval
-	DurationUnit  time.Duration // Time conversion unit for durations
-	Prefix        string        // Prefix to be prepended to metric names
-	Percentiles   []float64     // Percentiles to export from timers and histograms
+	Addr          *net.TCPAddr       // Network address to connect to
+	Registry      Registry           // Registry to be exported
+	FlushInterval time.Duration      // Flush interval
+	DurationUnit  time.Duration      // Time conversion unit for durations
+	Prefix        string             // Prefix to be prepended to metric names
+	Percentiles   []float64          // Percentiles to export from timers and histograms
+	Sanitizer     NameSanitizer      // Rewrites metric names if set, e.g. SanitizeGraphite
+	Intervals     []IntervalOverride // Export intervals of metric prefixes other than FlushInterval
+	SkipIdle      bool               // Leave out metrics not updated since they were last exported
 }
 
 // Graphite is a blocking exporter function which reports metrics in r
@@ -38,12 +42,26 @@ func Graphite(r Registry, d time.Duration, prefix string, addr *net.TCPAddr) {
 // GraphiteWithConfig is a blocking exporter function just like Graphite,
 // but it takes a GraphiteConfig instead.
 func GraphiteWithConfig(c GraphiteConfig) {
+	graphiteWithConfig(context.Background(), c)
+}
+
+// graphiteWithConfig is GraphiteWithConfig returning once ctx is done.
+func graphiteWithConfig(ctx context.Context, c GraphiteConfig) {
 	log.Printf("WARNING: This go-metrics client has been DEPRECATED! It has been moved to https://github.com/cyberdelia/go-metrics-graphite and will be removed from rcrowley/go-metrics on August 12th 2015")
-	for _ = range time.Tick(c.FlushInterval) {
-		if err := graphite(&c); nil != err {
-			log.Println(err)
-		}
+	names := NewSanitizedRegistry(c.Registry, ExportNames("graphite", c.Sanitizer))
+	var idle *idleFilter
+	if c.SkipIdle {
+		idle = newIdleFilter(c.Registry)
 	}
+	RunPeriodicIntervalsContext(ctx, c.Registry, c.FlushInterval, c.Intervals, func(r Registry) {
+		due := c
+		due.Registry = idle.view(r)
+		timeExport("graphite", func() {
+			if err := graphite(&due, names); nil != err {
+				log.Println(err)
+			}
+		})
+	})
 }
 
 // GraphiteOnce performs a single submission to Graphite, returning a
@@ -51,22 +69,25 @@ func GraphiteWithConfig(c GraphiteConfig) {
 // similar to GraphiteWithConfig for custom error handling.
 func GraphiteOnce(c GraphiteConfig) error {
 	log.Printf("WARNING: This go-metrics client has been DEPRECATED! It has been moved to https://github.com/cyberdelia/go-metrics-graphite and will be removed from rcrowley/go-metrics on August 12th 2015")
-	return graphite(&c)
+	return graphite(&c, NewSanitizedRegistry(c.Registry, ExportNames("graphite", c.Sanitizer)))
 }
 
-func graphite(c *GraphiteConfig) error {
-	now := time.Now().Unix()
-	du := float64(c.DurationUnit)
+func graphite(c *GraphiteConfig, names *SanitizedRegistry) error {
+	now := DefaultClock.Now().Unix()
+	scale := DurationScale(c.DurationUnit)
 	conn, err := net.DialTCP("tcp", nil, c.Addr)
 	if nil != err {
 		return err
 	}
 	defer conn.Close()
 	w := bufio.NewWriter(conn)
-	c.Registry.Each(func(name string, i interface{}) {
+	names.eachSanitized(c.Registry, func(name, original string, i interface{}) {
+		du := scale.Divisor(original, i)
 		switch metric := i.(type) {
 		case Counter:
 			fmt.Fprintf(w, "%s.%s.count %d %d\n", c.Prefix, name, metric.Count(), now)
+		case CounterFloat64:
+			fmt.Fprintf(w, "%s.%s.count %f %d\n", c.Prefix, name, metric.Count(), now)
 		case Gauge:
 			fmt.Fprintf(w, "%s.%s.value %d %d\n", c.Prefix, name, metric.Value(), now)
 		case GaugeFloat64:
@@ -75,13 +96,13 @@ func graphite(c *GraphiteConfig) error {
 			h := metric.Snapshot()
 			ps := h.Percentiles(c.Percentiles)
 			fmt.Fprintf(w, "%s.%s.count %d %d\n", c.Prefix, name, h.Count(), now)
-			fmt.Fprintf(w, "%s.%s.min %d %d\n", c.Prefix, name, h.Min(), now)
-			fmt.Fprintf(w, "%s.%s.max %d %d\n", c.Prefix, name, h.Max(), now)
-			fmt.Fprintf(w, "%s.%s.mean %.2f %d\n", c.Prefix, name, h.Mean(), now)
-			fmt.Fprintf(w, "%s.%s.std-dev %.2f %d\n", c.Prefix, name, h.StdDev(), now)
+			fmt.Fprintf(w, "%s.%s.min %d %d\n", c.Prefix, name, h.Min()/int64(du), now)
+			fmt.Fprintf(w, "%s.%s.max %d %d\n", c.Prefix, name, h.Max()/int64(du), now)
+			fmt.Fprintf(w, "%s.%s.mean %.2f %d\n", c.Prefix, name, h.Mean()/du, now)
+			fmt.Fprintf(w, "%s.%s.std-dev %.2f %d\n", c.Prefix, name, h.StdDev()/du, now)
 			for psIdx, psKey := range c.Percentiles {
 				key := strings.Replace(strconv.FormatFloat(psKey*100.0, 'f', -1, 64), ".", "", 1)
-				fmt.Fprintf(w, "%s.%s.%s-percentile %.2f %d\n", c.Prefix, name, key, ps[psIdx], now)
+				fmt.Fprintf(w, "%s.%s.%s-percentile %.2f %d\n", c.Prefix, name, key, ps[psIdx]/du, now)
 			}
 		case Meter:
 			m := metric.Snapshot()
@@ -100,7 +121,7 @@ func graphite(c *GraphiteConfig) error {
 			fmt.Fprintf(w, "%s.%s.std-dev %.2f %d\n", c.Prefix, name, t.StdDev()/du, now)
 			for psIdx, psKey := range c.Percentiles {
 				key := strings.Replace(strconv.FormatFloat(psKey*100.0, 'f', -1, 64), ".", "", 1)
-				fmt.Fprintf(w, "%s.%s.%s-percentile %.2f %d\n", c.Prefix, name, key, ps[psIdx], now)
+				fmt.Fprintf(w, "%s.%s.%s-percentile %.2f %d\n", c.Prefix, name, key, ps[psIdx]/du, now)
 			}
 			fmt.Fprintf(w, "%s.%s.one-minute %.2f %d\n", c.Prefix, name, t.Rate1(), now)
 			fmt.Fprintf(w, "%s.%s.five-minute %.2f %d\n", c.Prefix, name, t.Rate5(), now)
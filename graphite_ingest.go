@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MaxGraphiteLine is the longest line, in bytes, a GraphiteServer reads
+// before dropping the connection.
+var MaxGraphiteLine = 4 << 10
+
+// GraphiteServer accepts TCP connections carrying Graphite's plaintext
+// protocol, "path value timestamp" lines, and records the latest value of
+// each path in a registry, so a process can relay the metrics of others and
+// re-export them, through optron or any other exporter.  Paths IsCounter
+// accepts are recorded in Counters set to each value; the rest in
+// GaugeFloat64s.  Timestamps are ignored.
+//
+// Graphite tags, as in "logins;ns=lobby;grp=google", tag the metric with the
+// tags of the same keys as a TagBoard: ns, grp, tgt, act and sub.  Other tags
+// are dropped, as are those after the first of the five missing.
+type GraphiteServer struct {
+	// Registry receives the metrics.  It defaults to DefaultRegistry.
+	Registry Registry
+
+	// IsCounter reports whether the values of a path, tags excluded, are
+	// counts.  It defaults to accepting the paths ending in ".count", which
+	// is how GraphiteWithConfig sends the counts of every metric.
+	IsCounter func(path string) bool
+
+	// Logger, when set, is told about malformed lines and read errors.
+	Logger Logger
+
+	lines lineListener
+	mutex sync.Mutex // serializes setting counters
+}
+
+// ListenGraphite listens on the TCP address addr and serves Graphite
+// connections in the background, recording them in r, or DefaultRegistry if
+// r is nil.
+func ListenGraphite(addr string, r Registry) (*GraphiteServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &GraphiteServer{Registry: r}
+	s.lines.ln = ln
+	go s.Serve(ln)
+	return s, nil
+}
+
+// Serve accepts connections on ln until it fails or the server is closed,
+// reading each connection in its own goroutine.  It returns nil once the
+// server has been closed.
+func (s *GraphiteServer) Serve(ln net.Listener) error {
+	return s.lines.serve(ln, MaxGraphiteLine, s.Ingest, func(addr net.Addr, err error) {
+		if nil != s.Logger {
+			s.Logger.Printf("graphite: %v: %v", addr, err)
+		}
+	})
+}
+
+// Addr returns the address the server is listening on, or nil before Serve.
+func (s *GraphiteServer) Addr() net.Addr {
+	return s.lines.addr()
+}
+
+// Close stops the listener, closes every open connection and waits for
+// their goroutines to finish.  Metrics already recorded stay registered.
+func (s *GraphiteServer) Close() error {
+	return s.lines.close()
+}
+
+// Ingest records one plaintext line as though it had been read from a
+// connection.
+func (s *GraphiteServer) Ingest(line []byte) error {
+	fields := strings.Fields(string(bytes.TrimSpace(line)))
+	if len(fields) == 0 {
+		return nil
+	}
+	if len(fields) < 2 {
+		return fmt.Errorf("%q: missing value", line)
+	}
+	v, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%q: bad value %q", line, fields[1])
+	}
+	path, name, err := graphiteName(fields[0])
+	if err != nil {
+		return fmt.Errorf("%q: %v", line, err)
+	}
+
+	r := s.Registry
+	if nil == r {
+		r = DefaultRegistry
+	}
+	isCounter := s.IsCounter
+	if nil == isCounter {
+		isCounter = func(path string) bool { return strings.HasSuffix(path, ".count") }
+	}
+	if isCounter(path) {
+		c, ok := r.GetOrRegister(name, NewCounter).(Counter)
+		if !ok {
+			return fmt.Errorf("%s is registered as another type", name)
+		}
+		// Counters can only be moved by a delta, so concurrent connections
+		// setting the same one must not interleave reading and moving it.
+		s.mutex.Lock()
+		c.Inc(int64(math.Round(v)) - c.Count())
+		s.mutex.Unlock()
+		return nil
+	}
+	g, ok := r.GetOrRegister(name, NewGaugeFloat64).(GaugeFloat64)
+	if !ok {
+		return fmt.Errorf("%s is registered as another type", name)
+	}
+	g.Update(v)
+	return nil
+}
+
+// graphiteName splits a Graphite path with optional ";key=value" tags into
+// the bare path and the name of the metric it is recorded in.
+func graphiteName(series string) (string, string, error) {
+	parts := strings.Split(series, ";")
+	path := parts[0]
+	if path == "" {
+		return "", "", errors.New("empty path")
+	}
+	name := graphitePathEscaper.Replace(path)
+	if len(parts) == 1 {
+		return path, name, nil
+	}
+	var tags [len(tagKeys)]string
+	for _, tag := range parts[1:] {
+		kv := strings.SplitN(tag, "=", 2)
+		if len(kv) != 2 {
+			return "", "", fmt.Errorf("tag %q is not of the form key=value", tag)
+		}
+		for i, k := range tagKeys {
+			if k == kv[0] {
+				tags[i] = escapeNameValue(kv[1])
+			}
+		}
+	}
+	return path, taggedName(name, tags[:]), nil
+}
+
+// graphitePathEscaper keeps paths from passing for tagged names.
+var graphitePathEscaper = strings.NewReplacer(TAG_DELIMITER, "_", TAG_METRIC_DELIMITER, strings.ToLower(TAG_METRIC_DELIMITER))
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"net"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGraphiteServerIngest(t *testing.T) {
+	r := NewRegistry()
+	s := &GraphiteServer{Registry: r}
+	for _, line := range []string{
+		"db.load 1.5 1700000000",
+		"db.load 2.5 1700000010",
+		"http.requests.count 10 1700000000",
+		"http.requests.count 25 1700000010",
+		"logins;grp=google;ns=lobby;region=eu 3 1700000000",
+		"",
+	} {
+		if err := s.Ingest([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if v := r.Get("db.load").(GaugeFloat64).Value(); 2.5 != v {
+		t.Errorf("db.load: %v", v)
+	}
+	if c := r.Get("http.requests.count").(Counter).Count(); 25 != c {
+		t.Errorf("http.requests.count: %d", c)
+	}
+	if v := r.Get("lobby|googleTAGlogins").(GaugeFloat64).Value(); 3 != v {
+		t.Errorf("logins: %v", v)
+	}
+
+	for _, line := range []string{"db.load", "db.load x 1", "db.load NaN 1", "logins;ns 1 1", " ;ns=x 1 1"} {
+		if err := s.Ingest([]byte(line)); nil == err {
+			t.Errorf("%q ingested", line)
+		}
+	}
+}
+
+// yieldingCounter yields between reading and moving its count, widening
+// the window in which concurrent sets can interleave.
+type yieldingCounter struct {
+	Counter
+}
+
+func (c yieldingCounter) Count() int64 {
+	n := c.Counter.Count()
+	runtime.Gosched()
+	return n
+}
+
+func TestGraphiteServerConcurrentCounter(t *testing.T) {
+	r := NewRegistry()
+	c := yieldingCounter{NewCounter()}
+	r.Register("http.requests.count", c)
+	s := &GraphiteServer{Registry: r}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				s.Ingest([]byte("http.requests.count 7 1700000000"))
+			}
+		}()
+	}
+	wg.Wait()
+	if n := c.Count(); 7 != n {
+		t.Errorf("http.requests.count: 7 != %d", n)
+	}
+}
+
+func TestGraphiteServerListen(t *testing.T) {
+	r := NewRegistry()
+	s, err := ListenGraphite("127.0.0.1:0", r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bufio.NewWriter(conn)
+	w.WriteString("queue.depth 7 1700000000\n")
+	w.Flush()
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if g, ok := r.Get("queue.depth").(GaugeFloat64); ok && 7 == g.Value() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("queue.depth not recorded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -1,7 +1,10 @@
 package metrics
 
 import (
+	"bufio"
 	"net"
+	"strings"
+	"testing"
 	"time"
 )
 
@@ -20,3 +23,70 @@ func ExampleGraphiteWithConfig() {
 		Percentiles:   []float64{0.5, 0.75, 0.99, 0.999},
 	})
 }
+
+// graphiteOnce runs GraphiteOnce with c against a local listener and
+// returns the lines sent, without their timestamps.
+func graphiteOnce(t *testing.T, c GraphiteConfig) []string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	c.Addr = ln.Addr().(*net.TCPAddr)
+	errs := make(chan error, 1)
+	go func() { errs <- GraphiteOnce(c) }()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var lines []string
+	for s := bufio.NewScanner(conn); s.Scan(); {
+		lines = append(lines, s.Text()[:strings.LastIndexByte(s.Text(), ' ')])
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	return lines
+}
+
+func TestGraphiteUnitSanitized(t *testing.T) {
+	name := TaggedMetricName("latency", NewTagBoard("lobby", "login"))
+	defer SetUnit(name, UnitCount)
+	SetUnit(name, UnitNanoseconds)
+	r := NewRegistry()
+	NewRegisteredHistogram(name, r, NewUniformSample(10)).Update(int64(3 * time.Millisecond))
+
+	for _, s := range []NameSanitizer{nil, SanitizeGraphite} {
+		lines := graphiteOnce(t, GraphiteConfig{Registry: r, DurationUnit: time.Millisecond, Prefix: "game", Sanitizer: s})
+		want := "game." + ExportNames("graphite", s)(name) + ".max 3"
+		if !contains(lines, want) {
+			t.Errorf("%q not in %q", want, lines)
+		}
+	}
+}
+
+func TestGraphiteUnitExportName(t *testing.T) {
+	name := TaggedMetricName("latency", NewTagBoard("lobby", "login"))
+	defer SetUnit(name, UnitCount)
+	SetUnit(name, UnitNanoseconds)
+	defer SetExportName("graphite", name, "")
+	SetExportName("graphite", name, "login.latency")
+	r := NewRegistry()
+	NewRegisteredHistogram(name, r, NewUniformSample(10)).Update(int64(3 * time.Millisecond))
+
+	lines := graphiteOnce(t, GraphiteConfig{Registry: r, DurationUnit: time.Millisecond, Prefix: "game"})
+	if !contains(lines, "game.login.latency.max 3") {
+		t.Errorf("%q", lines)
+	}
+}
+
+func contains(lines []string, line string) bool {
+	for _, l := range lines {
+		if l == line {
+			return true
+		}
+	}
+	return false
+}
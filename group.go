@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+)
+
+// A RegistryGroup keeps a registry per game for processes that host several
+// games, alongside a global registry for the metrics they share, such as
+// runtime and process statistics.  Exporters that understand groups, like
+// optron's NewForGroup, label each game's metrics with the game they came
+// from.
+type RegistryGroup struct {
+	Global Registry
+
+	mutex sync.RWMutex
+	games map[string]*GameRegistry
+}
+
+// GameRegistry is the registry of one game in a RegistryGroup.
+type GameRegistry struct {
+	Registry
+	game string
+}
+
+// Game returns the name of the game the registry belongs to.
+func (r *GameRegistry) Game() string {
+	return r.game
+}
+
+// NewRegistryGroup constructs a RegistryGroup whose shared metrics live in
+// global, or DefaultRegistry if global is nil.
+func NewRegistryGroup(global Registry) *RegistryGroup {
+	if nil == global {
+		global = DefaultRegistry
+	}
+	return &RegistryGroup{Global: global, games: make(map[string]*GameRegistry)}
+}
+
+// Game returns the registry of the given game, creating it if necessary.
+func (g *RegistryGroup) Game(game string) *GameRegistry {
+	g.mutex.RLock()
+	r, ok := g.games[game]
+	g.mutex.RUnlock()
+	if ok {
+		return r
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if r, ok := g.games[game]; ok {
+		return r
+	}
+	r = &GameRegistry{Registry: NewRegistry(), game: game}
+	g.games[game] = r
+	return r
+}
+
+// Remove drops the registry of the given game, for instance once the process
+// stops hosting it.
+func (g *RegistryGroup) Remove(game string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.games, game)
+}
+
+// Each calls f for the registry of each game in game order.
+func (g *RegistryGroup) Each(f func(*GameRegistry)) {
+	g.mutex.RLock()
+	games := make([]*GameRegistry, 0, len(g.games))
+	for _, r := range g.games {
+		games = append(games, r)
+	}
+	g.mutex.RUnlock()
+	sort.Sort(gameRegistrySlice(games))
+	for _, r := range games {
+		f(r)
+	}
+}
+
+type gameRegistrySlice []*GameRegistry
+
+func (s gameRegistrySlice) Len() int           { return len(s) }
+func (s gameRegistrySlice) Less(i, j int) bool { return s[i].game < s[j].game }
+func (s gameRegistrySlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,40 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRegistryGroup(t *testing.T) {
+	g := NewRegistryGroup(NewRegistry())
+	GetOrRegisterCounter("logins", g.Game("teenpatti")).Inc(1)
+	GetOrRegisterCounter("logins", g.Game("poker")).Inc(2)
+	GetOrRegisterCounter("logins", g.Game("teenpatti")).Inc(3)
+	GetOrRegisterGauge("goroutines", g.Global).Update(10)
+
+	if r := g.Game("poker"); r != g.Game("poker") || "poker" != r.Game() {
+		t.Fatal(r)
+	}
+	var games []string
+	counts := make(map[string]int64)
+	g.Each(func(r *GameRegistry) {
+		games = append(games, r.Game())
+		counts[r.Game()] = r.Get("logins").(Counter).Count()
+		if nil != r.Get("goroutines") {
+			t.Errorf("%s sees the global metrics", r.Game())
+		}
+	})
+	if !reflect.DeepEqual([]string{"poker", "teenpatti"}, games) {
+		t.Errorf("games: %v", games)
+	}
+	if 2 != counts["poker"] || 4 != counts["teenpatti"] {
+		t.Errorf("counts: %v", counts)
+	}
+
+	g.Remove("poker")
+	games = nil
+	g.Each(func(r *GameRegistry) { games = append(games, r.Game()) })
+	if !reflect.DeepEqual([]string{"teenpatti"}, games) {
+		t.Errorf("games after Remove: %v", games)
+	}
+}
@@ -0,0 +1,116 @@
+// Package grpcmetrics records gRPC calls into a go-metrics registry.
+//
+// Every call updates metrics tagged ns=grpc, grp=<service>, tgt=<method>:
+//
+//	server.calls / client.calls       a Counter of calls
+//	server.latency / client.latency   a Timer of call durations
+//	server.errors / client.errors     a Counter of failed calls, additionally
+//	                                  tagged act=<status code>
+package grpcmetrics
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const namespace = "grpc"
+
+// UnaryServerInterceptor returns an interceptor recording unary calls served
+// by a grpc.Server into r.
+func UnaryServerInterceptor(r metrics.Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		record(r, "server.", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns an interceptor recording streaming calls
+// served by a grpc.Server into r.  The latency covers the whole stream.
+func StreamServerInterceptor(r metrics.Registry) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		record(r, "server.", info.FullMethod, start, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor returns an interceptor recording unary calls made by
+// a grpc.ClientConn into r.
+func UnaryClientInterceptor(r metrics.Registry) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		record(r, "client.", method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns an interceptor recording streaming calls
+// made by a grpc.ClientConn into r.  The call is recorded when the stream
+// fails to open, or when RecvMsg returns io.EOF or an error, or, for streams
+// whose server sends a single response, once RecvMsg has received it.
+func StreamClientInterceptor(r metrics.Registry) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			record(r, "client.", method, start, err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, registry: r, method: method, start: start, single: !desc.ServerStreams}, nil
+	}
+}
+
+// clientStream records its call once the stream has ended.
+type clientStream struct {
+	grpc.ClientStream
+	registry metrics.Registry
+	method   string
+	start    time.Time
+	single   bool // the server sends one response, and callers needn't read on to io.EOF
+	done     bool
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if (err != nil || s.single) && !s.done {
+		s.done = true
+		if err == io.EOF {
+			record(s.registry, "client.", s.method, s.start, nil)
+		} else {
+			record(s.registry, "client.", s.method, s.start, err)
+		}
+	}
+	return err
+}
+
+func record(r metrics.Registry, prefix, fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	tb := metrics.NewTagBoard(namespace, service, method)
+	metrics.GetOrRegisterTimer(metrics.TaggedMetricName(prefix+"latency", tb), r).UpdateSince(start)
+	metrics.GetOrRegisterCounter(metrics.TaggedMetricName(prefix+"calls", tb), r).Inc(1)
+
+	if code := status.Code(err); code != codes.OK {
+		tb = metrics.NewTagBoard(namespace, service, method, code.String())
+		metrics.GetOrRegisterCounter(metrics.TaggedMetricName(prefix+"errors", tb), r).Inc(1)
+	}
+}
+
+// splitMethod splits a full method name like /pkg.Service/Method.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
@@ -0,0 +1,150 @@
+package grpcmetrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/moonfrog/go-metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func count(r metrics.Registry, name string, tags ...string) int64 {
+	tb := metrics.NewTagBoard(append([]string{namespace}, tags...)...)
+	switch m := r.Get(metrics.TaggedMetricName(name, tb)).(type) {
+	case metrics.Counter:
+		return m.Count()
+	case metrics.Timer:
+		return m.Count()
+	}
+	return 0
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	i := UnaryServerInterceptor(r)
+	info := &grpc.UnaryServerInfo{FullMethod: "/lobby.Matchmaker/Join"}
+	ok := func(context.Context, interface{}) (interface{}, error) { return "joined", nil }
+	full := func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.ResourceExhausted, "lobby full")
+	}
+
+	if resp, err := i(context.Background(), nil, info, ok); "joined" != resp || nil != err {
+		t.Fatal(resp, err)
+	}
+	i(context.Background(), nil, info, full)
+	i(context.Background(), nil, info, full)
+
+	if n := count(r, "server.calls", "lobby.Matchmaker", "Join"); 3 != n {
+		t.Errorf("calls: %d", n)
+	}
+	if n := count(r, "server.latency", "lobby.Matchmaker", "Join"); 3 != n {
+		t.Errorf("latency: %d", n)
+	}
+	if n := count(r, "server.errors", "lobby.Matchmaker", "Join", "ResourceExhausted"); 2 != n {
+		t.Errorf("errors: %d", n)
+	}
+	if n := count(r, "server.errors", "lobby.Matchmaker", "Join", "OK"); 0 != n {
+		t.Errorf("OK counted as an error: %d", n)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	err := StreamServerInterceptor(r)(nil, nil, &grpc.StreamServerInfo{FullMethod: "/lobby.Chat/Listen"},
+		func(interface{}, grpc.ServerStream) error { return errors.New("broken pipe") })
+	if nil == err {
+		t.Fatal("error swallowed")
+	}
+	// Errors without a status are reported as Unknown.
+	if n := count(r, "server.errors", "lobby.Chat", "Listen", "Unknown"); 1 != n {
+		t.Errorf("errors: %d", n)
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		return status.Error(codes.NotFound, "no such player")
+	}
+	UnaryClientInterceptor(r)(context.Background(), "Ping", nil, nil, nil, invoker)
+	if n := count(r, "client.calls", "unknown", "Ping"); 1 != n {
+		t.Errorf("calls: %d", n)
+	}
+	if n := count(r, "client.errors", "unknown", "Ping", "NotFound"); 1 != n {
+		t.Errorf("errors: %d", n)
+	}
+}
+
+// stubClientStream returns the errors in recv from RecvMsg in turn.
+type stubClientStream struct {
+	grpc.ClientStream
+	recv []error
+}
+
+func (s *stubClientStream) RecvMsg(interface{}) error {
+	err := s.recv[0]
+	if len(s.recv) > 1 {
+		s.recv = s.recv[1:]
+	}
+	return err
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	r := metrics.NewRegistry()
+	i := StreamClientInterceptor(r)
+	streamer := func(recv ...error) grpc.Streamer {
+		return func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &stubClientStream{recv: recv}, nil
+		}
+	}
+
+	// A stream ending in io.EOF is recorded once, as a success.
+	listen := &grpc.StreamDesc{ServerStreams: true}
+	cs, err := i(context.Background(), listen, nil, "/lobby.Chat/Listen", streamer(nil, io.EOF))
+	if nil != err {
+		t.Fatal(err)
+	}
+	for cs.RecvMsg(nil) == nil {
+	}
+	cs.RecvMsg(nil)
+	if n := count(r, "client.calls", "lobby.Chat", "Listen"); 1 != n {
+		t.Errorf("calls: %d", n)
+	}
+
+	// A stream failing midway is recorded with its code.
+	cs, _ = i(context.Background(), listen, nil, "/lobby.Chat/Listen", streamer(status.Error(codes.Unavailable, "")))
+	cs.RecvMsg(nil)
+	if n := count(r, "client.errors", "lobby.Chat", "Listen", "Unavailable"); 1 != n {
+		t.Errorf("errors: %d", n)
+	}
+
+	// A stream failing to open is recorded at once.
+	failed := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, status.Error(codes.PermissionDenied, "")
+	}
+	if _, err := i(context.Background(), listen, nil, "/lobby.Chat/Say", failed); nil == err {
+		t.Fatal("error swallowed")
+	}
+	if n := count(r, "client.errors", "lobby.Chat", "Say", "PermissionDenied"); 1 != n {
+		t.Errorf("errors: %d", n)
+	}
+	if n := count(r, "client.calls", "lobby.Chat", "Listen"); 2 != n {
+		t.Errorf("calls: %d", n)
+	}
+
+	// A client-streaming call is recorded on its single response, which
+	// callers such as CloseAndRecv don't follow with another RecvMsg.
+	cs, _ = i(context.Background(), &grpc.StreamDesc{ClientStreams: true}, nil, "/lobby.Chat/Upload", streamer(nil))
+	cs.RecvMsg(nil)
+	if n := count(r, "client.calls", "lobby.Chat", "Upload"); 1 != n {
+		t.Errorf("calls: %d", n)
+	}
+	cs.RecvMsg(nil)
+	if n := count(r, "client.calls", "lobby.Chat", "Upload"); 1 != n {
+		t.Errorf("calls recorded twice: %d", n)
+	}
+}
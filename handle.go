@@ -0,0 +1,48 @@
+package metrics
+
+// Handles resolve a tagged metric in DefaultRegistry once and return the
+// metric itself, so hot paths can update it without building the tagged name
+// or looking it up on every call:
+//
+//	var loginErrors = metrics.CounterHandle("errors", metrics.NewTagBoard("lobby", "login"))
+//
+//	loginErrors.Inc(1)
+//
+// A handle keeps pointing at the metric it resolved; if that metric is later
+// unregistered the handle's updates are no longer reported.
+
+// CounterHandle returns the Counter for name tagged with tb, registering it if
+// necessary.
+func CounterHandle(name string, tb TagBoard) Counter {
+	return GetOrRegisterCounter(TaggedMetricName(name, tb), nil)
+}
+
+// GaugeHandle returns the Gauge for name tagged with tb, registering it if
+// necessary.
+func GaugeHandle(name string, tb TagBoard) Gauge {
+	return GetOrRegisterGauge(TaggedMetricName(name, tb), nil)
+}
+
+// HistogramHandle returns the Histogram for name tagged with tb, registering
+// it with the given Sample if necessary.
+func HistogramHandle(name string, tb TagBoard, s Sample) Histogram {
+	return GetOrRegisterHistogram(TaggedMetricName(name, tb), nil, s)
+}
+
+// InstantHandle returns the Instant counter for name tagged with tb,
+// registering it if necessary.
+func InstantHandle(name string, tb TagBoard) Instant {
+	return GetOrRegisterInstantCounter(TaggedMetricName(name, tb), nil)
+}
+
+// MeterHandle returns the Meter for name tagged with tb, registering it if
+// necessary.
+func MeterHandle(name string, tb TagBoard) Meter {
+	return GetOrRegisterMeter(TaggedMetricName(name, tb), nil)
+}
+
+// TimerHandle returns the Timer for name tagged with tb, registering it if
+// necessary.
+func TimerHandle(name string, tb TagBoard) Timer {
+	return GetOrRegisterTimer(TaggedMetricName(name, tb), nil)
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import "testing"
+
+func BenchmarkCounterHandle(b *testing.B) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+	h := CounterHandle("bench.handle", NewTagBoard("ns", "grp"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Inc(1)
+	}
+}
+
+func BenchmarkCounterByTaggedName(b *testing.B) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+	tb := NewTagBoard("ns", "grp")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetOrRegisterCounter(TaggedMetricName("bench.name", tb), nil).Inc(1)
+	}
+}
+
+func TestCounterHandle(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+	tb := NewTagBoard("lobby", "login")
+	h := CounterHandle("errors", tb)
+	h.Inc(2)
+	if c := GetOrRegisterCounter("lobby|loginTAGerrors", nil); c != h || c.Count() != 2 {
+		t.Fatal(c)
+	}
+	if h2 := CounterHandle("errors", tb); h2 != h {
+		t.Fatal(h2)
+	}
+}
+
+func TestTimerHandle(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+	h := TimerHandle("latency", NewTagBoard("api", "get"))
+	h.Update(1)
+	if tm, ok := DefaultRegistry.Get("api|getTAGlatency").(Timer); !ok || tm.Count() != 1 {
+		t.Fatal(tm)
+	}
+}
@@ -1,5 +1,11 @@
 package metrics
 
+import "sync"
+
+// HealthcheckGaugeSuffix is appended to the name of each healthcheck to name
+// the Gauge RunHealthchecks records its result in.
+var HealthcheckGaugeSuffix = ".healthy"
+
 // Healthchecks hold an error value describing an arbitrary up/down status.
 type Healthcheck interface {
 	Check()
@@ -14,7 +20,7 @@ func NewHealthcheck(f func(Healthcheck)) Healthcheck {
 	if UseNilMetrics {
 		return NilHealthcheck{}
 	}
-	return &StandardHealthcheck{nil, f}
+	return &StandardHealthcheck{f: f}
 }
 
 // NilHealthcheck is a no-op.
@@ -35,8 +41,9 @@ func (NilHealthcheck) Unhealthy(error) {}
 // StandardHealthcheck is the standard implementation of a Healthcheck and
 // stores the status and a function to call to update the status.
 type StandardHealthcheck struct {
-	err error
-	f   func(Healthcheck)
+	mutex sync.Mutex
+	err   error
+	f     func(Healthcheck)
 }
 
 // Check runs the healthcheck function to update the healthcheck's status.
@@ -46,16 +53,22 @@ func (h *StandardHealthcheck) Check() {
 
 // Error returns the healthcheck's status, which will be nil if it is healthy.
 func (h *StandardHealthcheck) Error() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 	return h.err
 }
 
 // Healthy marks the healthcheck as healthy.
 func (h *StandardHealthcheck) Healthy() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 	h.err = nil
 }
 
 // Unhealthy marks the healthcheck as unhealthy.  The error is stored and
 // may be retrieved by the Error method.
 func (h *StandardHealthcheck) Unhealthy(err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
 	h.err = err
 }
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// DefaultHealthcheckTimeout bounds each healthcheck run by a handler from
+// NewHealthHandler when no timeout is given.
+const DefaultHealthcheckTimeout = 5 * time.Second
+
+// ErrHealthcheckTimeout is reported for healthchecks that don't finish within
+// the handler's timeout.
+var ErrHealthcheckTimeout = errors.New("healthcheck timed out")
+
+// HealthStatus is the result of one healthcheck as reported by the handler.
+type HealthStatus struct {
+	Healthy  bool          `json:"healthy"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// HealthReport is the JSON body served by the handler.
+type HealthReport struct {
+	Healthy bool                    `json:"healthy"`
+	Checks  map[string]HealthStatus `json:"checks"`
+}
+
+// NewHealthHandler returns an http.Handler that runs every healthcheck in r,
+// or DefaultRegistry if r is nil, concurrently and answers 200 if all of them
+// pass or 503 otherwise, with a HealthReport as the body.  Checks still
+// running after timeout, or DefaultHealthcheckTimeout if timeout is zero,
+// fail with ErrHealthcheckTimeout; they are left to finish in the background.
+func NewHealthHandler(r Registry, timeout time.Duration) http.Handler {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	if timeout <= 0 {
+		timeout = DefaultHealthcheckTimeout
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report := CheckHealth(r, timeout)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-cache")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+// CheckHealth runs every healthcheck in r concurrently, giving each up to
+// timeout, and reports their results.
+func CheckHealth(r Registry, timeout time.Duration) *HealthReport {
+	type result struct {
+		name   string
+		status HealthStatus
+	}
+	pending := make(map[string]bool)
+	results := make(chan result)
+	start := time.Now()
+	r.EachUnsorted(func(name string, i interface{}) {
+		h, ok := i.(Healthcheck)
+		if !ok {
+			return
+		}
+		pending[name] = true
+		go func() {
+			h.Check()
+			status := HealthStatus{Healthy: true, Duration: time.Since(start)}
+			if err := h.Error(); err != nil {
+				status.Healthy = false
+				status.Error = err.Error()
+			}
+			select {
+			case results <- result{name, status}:
+			case <-time.After(timeout):
+			}
+		}()
+	})
+
+	report := &HealthReport{Healthy: true, Checks: make(map[string]HealthStatus, len(pending))}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	for len(pending) > 0 {
+		select {
+		case res := <-results:
+			delete(pending, res.name)
+			report.Checks[res.name] = res.status
+			report.Healthy = report.Healthy && res.status.Healthy
+		case <-deadline.C:
+			for name := range pending {
+				report.Checks[name] = HealthStatus{Error: ErrHealthcheckTimeout.Error(), Duration: timeout}
+			}
+			report.Healthy = false
+			return report
+		}
+	}
+	return report
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthHandler(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("counter", r)
+	r.Register("db", NewHealthcheck(func(h Healthcheck) { h.Healthy() }))
+	r.Register("cache", NewHealthcheck(func(h Healthcheck) { h.Healthy() }))
+	h := NewHealthHandler(r, time.Second)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatal(w.Code, w.Body.String())
+	}
+	var report HealthReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.Healthy || len(report.Checks) != 2 || !report.Checks["db"].Healthy {
+		t.Fatal(report)
+	}
+
+	r.Register("queue", NewHealthcheck(func(h Healthcheck) { h.Unhealthy(errors.New("backlog")) }))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatal(w.Code)
+	}
+	report = HealthReport{}
+	json.Unmarshal(w.Body.Bytes(), &report)
+	if report.Healthy || report.Checks["queue"].Error != "backlog" || !report.Checks["cache"].Healthy {
+		t.Fatal(report)
+	}
+}
+
+func TestCheckHealthTimeout(t *testing.T) {
+	r := NewRegistry()
+	release := make(chan struct{})
+	defer close(release)
+	r.Register("slow", NewHealthcheck(func(h Healthcheck) { <-release }))
+	r.Register("fast", NewHealthcheck(func(h Healthcheck) { h.Healthy() }))
+
+	report := CheckHealth(r, 20*time.Millisecond)
+	if report.Healthy {
+		t.Fatal(report)
+	}
+	if s := report.Checks["slow"]; s.Healthy || s.Error != ErrHealthcheckTimeout.Error() {
+		t.Fatal(s)
+	}
+	if s := report.Checks["fast"]; !s.Healthy {
+		t.Fatal(s)
+	}
+}
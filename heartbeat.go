@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// HeartbeatGaugeSuffix is appended to the name of a heartbeat to name the
+// Gauge of the Unix time of its last beat.
+var HeartbeatGaugeSuffix = ".last_beat"
+
+// A Beater is the heartbeat of a goroutine or loop, which calls Beat every
+// interval while it makes progress.  Its healthcheck fails once beats stop,
+// so a stalled loop is detectable from the exported metrics.
+type Beater struct {
+	clock    Clock
+	interval time.Duration
+
+	mutex  sync.Mutex
+	last   time.Time // of the last beat, or construction
+	beaten bool
+}
+
+// Heartbeat constructs a Beater expecting a beat every interval and
+// registers its metrics in DefaultRegistry, like NewRegisteredHeartbeat.
+func Heartbeat(name string, interval time.Duration) *Beater {
+	return NewRegisteredHeartbeat(name, DefaultRegistry, interval)
+}
+
+// NewRegisteredHeartbeat constructs a Beater expecting a beat every interval
+// and registers a Healthcheck named name, which fails once more than two
+// intervals have passed since the last beat, or since the Beater was
+// constructed if it has never beaten, and a Gauge named name with
+// HeartbeatGaugeSuffix appended of the Unix time of the last beat, 0 before
+// the first.
+func NewRegisteredHeartbeat(name string, r Registry, interval time.Duration) *Beater {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	b := &Beater{clock: DefaultClock, interval: interval}
+	b.last = b.clock.Now()
+	r.Register(name, NewHealthcheck(func(h Healthcheck) {
+		if err := b.Err(); nil != err {
+			h.Unhealthy(err)
+		} else {
+			h.Healthy()
+		}
+	}))
+	r.Register(name+HeartbeatGaugeSuffix, NewFunctionalGauge(func() int64 {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		if !b.beaten {
+			return 0
+		}
+		return b.last.Unix()
+	}))
+	return b
+}
+
+// Beat records that the goroutine or loop is making progress.
+func (b *Beater) Beat() {
+	now := b.clock.Now()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.last, b.beaten = now, true
+}
+
+// Last returns the time of the last beat, or when b was constructed if it
+// has never beaten.
+func (b *Beater) Last() time.Time {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.last
+}
+
+// Err returns an error if more than two intervals have passed since the last
+// beat, and nil otherwise.
+func (b *Beater) Err() error {
+	if since := b.clock.Now().Sub(b.Last()); since > 2*b.interval {
+		return fmt.Errorf("no heartbeat for %v, expected every %v", since, b.interval)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHeartbeat(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(1000, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	b := NewRegisteredHeartbeat("worker", r, 10*time.Second)
+	check := r.Get("worker").(Healthcheck)
+	gauge := r.Get("worker" + HeartbeatGaugeSuffix).(Gauge)
+	check.Check()
+	if err := check.Error(); nil != err || 0 != gauge.Value() {
+		t.Errorf("new heartbeat: %v, last beat %v", err, gauge.Value())
+	}
+
+	clock.Add(15 * time.Second)
+	b.Beat()
+	clock.Add(20 * time.Second)
+	check.Check()
+	if err := check.Error(); nil != err || 1015 != gauge.Value() {
+		t.Errorf("beating: %v, last beat %v", err, gauge.Value())
+	}
+
+	clock.Add(time.Second)
+	check.Check()
+	if nil == check.Error() {
+		t.Error("stalled heartbeat is healthy")
+	}
+	r.RunHealthchecks()
+	if g := r.Get("worker" + HealthcheckGaugeSuffix).(Gauge); 0 != g.Value() {
+		t.Errorf("healthy gauge: 0 != %v", g.Value())
+	}
+
+	b.Beat()
+	check.Check()
+	if err := check.Error(); nil != err {
+		t.Errorf("resumed: %v", err)
+	}
+}
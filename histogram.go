@@ -1,5 +1,10 @@
 package metrics
 
+import (
+	"sync"
+	"time"
+)
+
 // Histograms calculate distribution statistics from a series of int64 values.
 type Histogram interface {
 	Clear()
@@ -14,6 +19,7 @@ type Histogram interface {
 	StdDev() float64
 	Sum() int64
 	Update(int64)
+	UpdateAll([]int64)
 	Variance() float64
 }
 
@@ -23,6 +29,9 @@ func GetOrRegisterHistogram(name string, r Registry, s Sample) Histogram {
 	if nil == r {
 		r = DefaultRegistry
 	}
+	if m, ok := r.Get(name).(Histogram); ok {
+		return m
+	}
 	return r.GetOrRegister(name, func() Histogram { return NewHistogram(s) }).(Histogram)
 }
 
@@ -34,6 +43,46 @@ func NewHistogram(s Sample) Histogram {
 	return &StandardHistogram{sample: s}
 }
 
+// NewDurationHistogram constructs a new StandardHistogram from a Sample
+// whose ObserveDuration records durations in multiples of unit, such as
+// time.Millisecond.  Durations are truncated to whole units.
+func NewDurationHistogram(s Sample, unit time.Duration) Histogram {
+	if UseNilMetrics {
+		return NilHistogram{}
+	}
+	return &StandardHistogram{sample: s, unit: unit}
+}
+
+// GetOrRegisterDurationHistogram returns an existing Histogram or constructs
+// and registers a new StandardHistogram recording durations in unit.
+func GetOrRegisterDurationHistogram(name string, r Registry, s Sample, unit time.Duration) Histogram {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	if m, ok := r.Get(name).(Histogram); ok {
+		return m
+	}
+	return r.GetOrRegister(name, func() Histogram { return NewDurationHistogram(s, unit) }).(Histogram)
+}
+
+// DurationObserver is implemented by the standard histograms, which record
+// durations in their unit: nanoseconds unless constructed by
+// NewDurationHistogram.
+type DurationObserver interface {
+	ObserveDuration(d time.Duration)
+}
+
+// ObserveDuration records d on h in h's unit if h is a DurationObserver and
+// in nanoseconds otherwise, like a Timer.  Give histograms of nanoseconds
+// UnitNanoseconds with SetUnit for exporters to scale them like timers.
+func ObserveDuration(h Histogram, d time.Duration) {
+	if o, ok := h.(DurationObserver); ok {
+		o.ObserveDuration(d)
+		return
+	}
+	h.Update(int64(d))
+}
+
 // NewRegisteredHistogram constructs and registers a new StandardHistogram from
 // a Sample.
 func NewRegisteredHistogram(name string, r Registry, s Sample) Histogram {
@@ -47,7 +96,39 @@ func NewRegisteredHistogram(name string, r Registry, s Sample) Histogram {
 
 // HistogramSnapshot is a read-only copy of another Histogram.
 type HistogramSnapshot struct {
-	sample *SampleSnapshot
+	sample    *SampleSnapshot
+	exemplars []Exemplar
+
+	namedOnce sync.Once
+	named     []float64 // the namedPercentiles, computed once
+}
+
+// namedPercentiles are the percentiles P50, P95 and P99 return, in order.
+var namedPercentiles = []float64{0.5, 0.95, 0.99}
+
+// Merge returns a snapshot combining h with a snapshot of other, for folding
+// per-shard or per-goroutine histograms into one series before export.  See
+// SampleSnapshot.Merge for how the samples are combined.
+func (h *HistogramSnapshot) Merge(other Histogram) *HistogramSnapshot {
+	o := other.Snapshot()
+	merged := &HistogramSnapshot{sample: h.sample.Merge(o.Sample()), exemplars: h.exemplars}
+	if e, ok := o.(ExemplarRecorder); ok {
+		merged.exemplars = latestExemplars(h.exemplars, e.Exemplars())
+	}
+	return merged
+}
+
+// ObserveDuration panics.
+func (*HistogramSnapshot) ObserveDuration(time.Duration) {
+	panic("ObserveDuration called on a HistogramSnapshot")
+}
+
+// Exemplars returns the exemplars at the time the snapshot was taken.
+func (h *HistogramSnapshot) Exemplars() []Exemplar { return h.exemplars }
+
+// UpdateWithExemplar panics.
+func (*HistogramSnapshot) UpdateWithExemplar(int64, map[string]string) {
+	panic("UpdateWithExemplar called on a HistogramSnapshot")
 }
 
 // Clear panics.
@@ -83,6 +164,26 @@ func (h *HistogramSnapshot) Percentiles(ps []float64) []float64 {
 	return h.sample.Percentiles(ps)
 }
 
+// P50 returns the median of the values in the sample at the time the
+// snapshot was taken.  P50, P95 and P99 share one sorted pass over the
+// sample, made by whichever is called first.
+func (h *HistogramSnapshot) P50() float64 { return h.namedPercentiles()[0] }
+
+// P95 returns the 95th percentile of the values in the sample at the time
+// the snapshot was taken.
+func (h *HistogramSnapshot) P95() float64 { return h.namedPercentiles()[1] }
+
+// P99 returns the 99th percentile of the values in the sample at the time
+// the snapshot was taken.
+func (h *HistogramSnapshot) P99() float64 { return h.namedPercentiles()[2] }
+
+func (h *HistogramSnapshot) namedPercentiles() []float64 {
+	h.namedOnce.Do(func() {
+		h.named = h.sample.Percentiles(namedPercentiles)
+	})
+	return h.named
+}
+
 // Sample returns the Sample underlying the histogram.
 func (h *HistogramSnapshot) Sample() Sample { return h.sample }
 
@@ -101,6 +202,11 @@ func (*HistogramSnapshot) Update(int64) {
 	panic("Update called on a HistogramSnapshot")
 }
 
+// UpdateAll panics.
+func (*HistogramSnapshot) UpdateAll([]int64) {
+	panic("UpdateAll called on a HistogramSnapshot")
+}
+
 // Variance returns the variance of inputs at the time the snapshot was taken.
 func (h *HistogramSnapshot) Variance() float64 { return h.sample.Variance() }
 
@@ -142,20 +248,31 @@ func (NilHistogram) StdDev() float64 { return 0.0 }
 // Sum is a no-op.
 func (NilHistogram) Sum() int64 { return 0 }
 
+// ObserveDuration is a no-op.
+func (NilHistogram) ObserveDuration(time.Duration) {}
+
 // Update is a no-op.
 func (NilHistogram) Update(v int64) {}
 
+// UpdateAll is a no-op.
+func (NilHistogram) UpdateAll([]int64) {}
+
 // Variance is a no-op.
 func (NilHistogram) Variance() float64 { return 0.0 }
 
 // StandardHistogram is the standard implementation of a Histogram and uses a
 // Sample to bound its memory use.
 type StandardHistogram struct {
-	sample Sample
+	sample    Sample
+	exemplars exemplarRing
+	unit      time.Duration // of ObserveDuration, nanoseconds if zero
 }
 
-// Clear clears the histogram and its sample.
-func (h *StandardHistogram) Clear() { h.sample.Clear() }
+// Clear clears the histogram, its sample and its exemplars.
+func (h *StandardHistogram) Clear() {
+	h.sample.Clear()
+	h.exemplars.clear()
+}
 
 // Count returns the number of samples recorded since the histogram was last
 // cleared.
@@ -186,7 +303,16 @@ func (h *StandardHistogram) Sample() Sample { return h.sample }
 
 // Snapshot returns a read-only copy of the histogram.
 func (h *StandardHistogram) Snapshot() Histogram {
-	return &HistogramSnapshot{sample: h.sample.Snapshot().(*SampleSnapshot)}
+	return &HistogramSnapshot{sample: h.sample.Snapshot().(*SampleSnapshot), exemplars: h.exemplars.values()}
+}
+
+// Exemplars returns the latest exemplars, oldest first.
+func (h *StandardHistogram) Exemplars() []Exemplar { return h.exemplars.values() }
+
+// UpdateWithExemplar samples a new value and keeps an exemplar of it.
+func (h *StandardHistogram) UpdateWithExemplar(v int64, labels map[string]string) {
+	h.sample.Update(v)
+	h.exemplars.add(Exemplar{Value: v, Labels: labels, Time: DefaultClock.Now()})
 }
 
 // StdDev returns the standard deviation of the values in the sample.
@@ -198,5 +324,17 @@ func (h *StandardHistogram) Sum() int64 { return h.sample.Sum() }
 // Update samples a new value.
 func (h *StandardHistogram) Update(v int64) { h.sample.Update(v) }
 
+// UpdateAll samples a batch of values, taking the sample's lock once rather
+// than once per value.
+func (h *StandardHistogram) UpdateAll(values []int64) { h.sample.UpdateAll(values) }
+
+// ObserveDuration samples d in the histogram's unit.
+func (h *StandardHistogram) ObserveDuration(d time.Duration) {
+	if h.unit > 0 {
+		d /= h.unit
+	}
+	h.sample.Update(int64(d))
+}
+
 // Variance returns the variance of the values in the sample.
 func (h *StandardHistogram) Variance() float64 { return h.sample.Variance() }
@@ -1,6 +1,9 @@
 package metrics
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func BenchmarkHistogram(b *testing.B) {
 	h := NewHistogram(NewUniformSample(100))
@@ -93,3 +96,108 @@ func testHistogram10000(t *testing.T, h Histogram) {
 		t.Errorf("99th percentile: 9900.99 != %v\n", ps[2])
 	}
 }
+
+func TestHistogramSnapshotMerge(t *testing.T) {
+	a := NewHistogram(NewUniformSample(100))
+	b := NewHistogram(NewUniformSample(100))
+	for i := 1; i <= 10; i++ {
+		a.Update(int64(i))
+		b.Update(int64(100 + i))
+	}
+	m := a.Snapshot().(*HistogramSnapshot).Merge(b)
+	if count := m.Count(); 20 != count {
+		t.Errorf("m.Count(): 20 != %v", count)
+	}
+	if min, max := m.Min(), m.Max(); 1 != min || 110 != max {
+		t.Errorf("m.Min(), m.Max(): %v, %v", min, max)
+	}
+	if size := m.Sample().Size(); 20 != size {
+		t.Errorf("m.Sample().Size(): 20 != %v", size)
+	}
+}
+
+func TestHistogramSnapshotMergeWeighted(t *testing.T) {
+	// a kept every value it saw; b kept one in ten, so a is thinned to match.
+	a := NewHistogram(NewUniformSample(100))
+	b := NewHistogram(NewUniformSample(100))
+	for i := 0; i < 100; i++ {
+		a.Update(1)
+	}
+	for i := 0; i < 1000; i++ {
+		b.Update(1000)
+	}
+	m := a.Snapshot().(*HistogramSnapshot).Merge(b)
+	if count := m.Count(); 1100 != count {
+		t.Errorf("m.Count(): 1100 != %v", count)
+	}
+	if size := m.Sample().Size(); 110 != size {
+		t.Errorf("m.Sample().Size(): 110 != %v", size)
+	}
+	if p := m.Percentile(0.5); 1000 != p {
+		t.Errorf("m.Percentile(0.5): 1000 != %v", p)
+	}
+}
+
+func TestHistogramObserveDuration(t *testing.T) {
+	h := NewHistogram(NewUniformSample(100))
+	ObserveDuration(h, 3*time.Microsecond)
+	if max := h.Max(); 3000 != max {
+		t.Errorf("h.Max(): 3000 != %v", max)
+	}
+
+	r := NewRegistry()
+	h = GetOrRegisterDurationHistogram("latency", r, NewUniformSample(100), time.Millisecond)
+	ObserveDuration(h, 2500*time.Microsecond)
+	ObserveDuration(GetOrRegisterDurationHistogram("latency", r, NewUniformSample(100), time.Second), 7*time.Millisecond)
+	if sum := h.Sum(); 9 != sum {
+		t.Errorf("h.Sum(): 9 != %v", sum)
+	}
+
+	ObserveDuration(NilHistogram{}, time.Second)
+}
+
+func TestHistogramUpdateAll(t *testing.T) {
+	values := make([]int64, 1000)
+	for i := range values {
+		values[i] = int64(i + 1)
+	}
+	for _, s := range []Sample{NewUniformSample(2000), NewExpDecaySample(2000, 0.015), NewShardedExpDecaySample(2000, 0.015)} {
+		h := NewHistogram(s)
+		h.Update(0)
+		h.UpdateAll(values)
+		if count := h.Count(); 1001 != count {
+			t.Errorf("%T: h.Count(): 1001 != %v", s, count)
+		}
+		if sum := h.Sum(); 500500 != sum {
+			t.Errorf("%T: h.Sum(): 500500 != %v", s, sum)
+		}
+	}
+
+	h := &sampledHistogram{Histogram: NewHistogram(NewUniformSample(100)), every: 3}
+	h.Update(1)
+	h.UpdateAll([]int64{2, 3, 4, 5, 6})
+	if sum := h.Sum(); 9 != sum {
+		t.Errorf("sampled h.Sum(): 9 != %v", sum)
+	}
+
+	NilHistogram{}.UpdateAll(values)
+}
+
+func TestHistogramSnapshotNamedPercentiles(t *testing.T) {
+	h := NewHistogram(NewUniformSample(100))
+	for i := 1; i <= 100; i++ {
+		h.Update(int64(i))
+	}
+	s := h.Snapshot().(*HistogramSnapshot)
+	ps := h.Percentiles([]float64{0.5, 0.95, 0.99})
+	if ps[0] != s.P50() || ps[1] != s.P95() || ps[2] != s.P99() {
+		t.Errorf("P50, P95, P99: %v != %v, %v, %v", ps, s.P50(), s.P95(), s.P99())
+	}
+
+	timer := NewTimer()
+	timer.Update(10)
+	timer.Update(20)
+	if p := timer.Snapshot().(*TimerSnapshot).P50(); 15 != p {
+		t.Errorf("timer P50: 15 != %v", p)
+	}
+}
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// HistoryPoint is one sample a HistoryRecorder took of a metric.
+type HistoryPoint struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+// HistoryRecorder keeps the recent history of the metrics of a registry in
+// memory: every interval it samples the same headline value of each metric
+// the dashboard plots into a ring buffer of a fixed number of points per
+// metric.  While it runs, the admin and stream handlers of its registry
+// serve its history when asked with ?history=<duration>.
+type HistoryRecorder struct {
+	registry Registry
+	clock    Clock
+	points   int
+
+	mutex  sync.Mutex
+	series map[string]*historyRing
+	quit   chan struct{}
+	once   sync.Once
+}
+
+var (
+	historyMutex     sync.Mutex
+	historyRecorders []*HistoryRecorder
+)
+
+// NewHistoryRecorder constructs a HistoryRecorder keeping points samples of
+// every metric in r, or DefaultRegistry if r is nil, taken every interval by
+// DefaultClock.  It samples in the background until Stop is called.
+func NewHistoryRecorder(r Registry, interval time.Duration, points int) *HistoryRecorder {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	h := &HistoryRecorder{
+		registry: r,
+		clock:    DefaultClock,
+		points:   points,
+		series:   make(map[string]*historyRing),
+		quit:     make(chan struct{}),
+	}
+	h.sample()
+	ticker := h.clock.NewTicker(interval)
+	go h.loop(ticker)
+
+	historyMutex.Lock()
+	historyRecorders = append(historyRecorders, h)
+	historyMutex.Unlock()
+	return h
+}
+
+// History returns the samples of the metric named name in DefaultRegistry
+// taken at or after since, oldest first, by the most recently started
+// HistoryRecorder of DefaultRegistry.  It returns nil if there is none or it
+// has no samples of the metric.
+func History(name string, since time.Time) []HistoryPoint {
+	if h := historyOf(DefaultRegistry); nil != h {
+		return h.History(name, since)
+	}
+	return nil
+}
+
+// historyOf returns the most recently started HistoryRecorder of r still
+// running, or nil.
+func historyOf(r Registry) *HistoryRecorder {
+	historyMutex.Lock()
+	defer historyMutex.Unlock()
+	for i := len(historyRecorders) - 1; i >= 0; i-- {
+		if historyRecorders[i].registry == r {
+			return historyRecorders[i]
+		}
+	}
+	return nil
+}
+
+// History returns the samples of the metric named name taken at or after
+// since, oldest first, or nil if there are none.
+func (h *HistoryRecorder) History(name string, since time.Time) []HistoryPoint {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if ring, ok := h.series[name]; ok {
+		return ring.points(since)
+	}
+	return nil
+}
+
+// Names returns the names of the metrics h has samples of, sorted.
+func (h *HistoryRecorder) Names() []string {
+	h.mutex.Lock()
+	names := make([]string, 0, len(h.series))
+	for name := range h.series {
+		names = append(names, name)
+	}
+	h.mutex.Unlock()
+	sort.Strings(names)
+	return names
+}
+
+// Stop stops sampling the registry.  The samples taken stay queryable from h
+// but no longer from the handlers or History.
+func (h *HistoryRecorder) Stop() {
+	h.once.Do(func() {
+		close(h.quit)
+		historyMutex.Lock()
+		defer historyMutex.Unlock()
+		for i, r := range historyRecorders {
+			if r == h {
+				historyRecorders = append(historyRecorders[:i], historyRecorders[i+1:]...)
+				break
+			}
+		}
+	})
+}
+
+func (h *HistoryRecorder) loop(ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.Chan():
+			h.sample()
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// sample appends the headline value of every metric to its series and drops
+// the series of metrics that have been unregistered.
+func (h *HistoryRecorder) sample() {
+	now := h.clock.Now()
+	seen := make(map[string]bool)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.registry.EachUnsorted(func(name string, i interface{}) {
+		v, ok := headlineValue(i)
+		if !ok {
+			return
+		}
+		ring := h.series[name]
+		if ring == nil {
+			ring = newHistoryRing(h.points)
+			h.series[name] = ring
+		}
+		ring.addAt(now, v)
+		seen[name] = true
+	})
+	for name := range h.series {
+		if !seen[name] {
+			delete(h.series, name)
+		}
+	}
+}
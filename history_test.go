@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHistoryRecorder(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	start := time.Unix(1000, 0)
+	clock := NewMockClock(start)
+	DefaultClock = clock
+	DefaultRegistry = NewRegistry()
+
+	c := GetOrRegisterCounter("requests", nil)
+	h := NewHistoryRecorder(nil, time.Hour, 3)
+	defer h.Stop()
+	for i := 1; i <= 3; i++ {
+		clock.Add(time.Second)
+		c.Inc(1)
+		h.sample()
+	}
+
+	want := []HistoryPoint{
+		{start.Add(1 * time.Second), 1},
+		{start.Add(2 * time.Second), 2},
+		{start.Add(3 * time.Second), 3},
+	}
+	if points := History("requests", time.Time{}); !reflect.DeepEqual(points, want) {
+		t.Fatal(points)
+	}
+	if points := History("requests", start.Add(2*time.Second)); !reflect.DeepEqual(points, want[1:]) {
+		t.Fatal(points)
+	}
+	if points := History("missing", time.Time{}); nil != points {
+		t.Fatal(points)
+	}
+
+	w := httptest.NewRecorder()
+	NewAdminHandler(nil).ServeHTTP(w, httptest.NewRequest("GET", "/?history=1500ms", nil))
+	var data map[string][]HistoryPoint
+	if err := json.Unmarshal(w.Body.Bytes(), &data); err != nil {
+		t.Fatal(err, w.Body.String())
+	}
+	if got := data["requests"]; 2 != len(got) || !got[0].Time.Equal(want[1].Time) || 3 != got[1].Value {
+		t.Fatal(data)
+	}
+
+	h.Stop()
+	if points := History("requests", time.Time{}); nil != points {
+		t.Fatal(points)
+	}
+	w = httptest.NewRecorder()
+	NewAdminHandler(nil).ServeHTTP(w, httptest.NewRequest("GET", "/?history=1m", nil))
+	if 404 != w.Code {
+		t.Fatal(w.Code)
+	}
+}
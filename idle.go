@@ -0,0 +1,104 @@
+package metrics
+
+import "sync"
+
+// idleFilter remembers, between flushes of an exporter, how far each metric
+// it exported had got, so the next flush can leave out the metrics that
+// haven't been updated since: counters, meters, histograms and timers whose
+// counts haven't moved and gauges whose values haven't changed.  A gauge set
+// again to the same value therefore counts as idle.  Metrics of other types,
+// such as healthchecks, are always exported, as is every metric the first
+// time it is seen.
+type idleFilter struct {
+	registry Registry // the exporter's whole registry, to forget unregistered metrics
+
+	mutex sync.Mutex
+	last  map[string]float64
+}
+
+func newIdleFilter(r Registry) *idleFilter {
+	return &idleFilter{registry: r, last: make(map[string]float64)}
+}
+
+// view returns a read-only view of r, which holds the metrics due on a flush
+// of the exporter, leaving out those that are idle.  Iterating it marks the
+// metrics it visits as exported, so each flush should iterate it once.
+func (f *idleFilter) view(r Registry) Registry {
+	if nil == f {
+		return r
+	}
+	return &idleRegistry{Registry: r, filter: f}
+}
+
+// updateMark returns the value whose change shows that the metric i was
+// updated, or false if i isn't a metric that can be idle.
+func updateMark(i interface{}) (float64, bool) {
+	switch m := i.(type) {
+	case Counter:
+		return float64(m.Count()), true
+	case CounterFloat64:
+		return m.Count(), true
+	case Instant:
+		return float64(m.Count()), true
+	case Gauge:
+		return float64(m.Value()), true
+	case GaugeFloat64:
+		return m.Value(), true
+	case Meter:
+		return float64(m.Count()), true
+	case Histogram:
+		return float64(m.Count()), true
+	case Timer:
+		return float64(m.Count()), true
+	}
+	return 0, false
+}
+
+// idleRegistry is the view of a Registry an idleFilter returns.
+type idleRegistry struct {
+	Registry
+	filter *idleFilter
+}
+
+func (r *idleRegistry) Each(f func(string, interface{})) {
+	r.each(r.Registry.Each, f)
+}
+
+func (r *idleRegistry) EachUnsorted(f func(string, interface{})) {
+	r.each(r.Registry.EachUnsorted, f)
+}
+
+func (r *idleRegistry) each(each func(func(string, interface{})), f func(string, interface{})) {
+	filter := r.filter
+	filter.mutex.Lock()
+	defer filter.mutex.Unlock()
+	seen := make(map[string]bool)
+	each(func(name string, i interface{}) {
+		mark, ok := updateMark(i)
+		if !ok {
+			f(name, i)
+			return
+		}
+		seen[name] = true
+		if last, ok := filter.last[name]; ok && last == mark {
+			return
+		}
+		filter.last[name] = mark
+		f(name, i)
+	})
+	// The view may hold only the metrics due on this flush, so those it
+	// didn't visit are forgotten only once they're unregistered.
+	for name := range filter.last {
+		if !seen[name] && nil == filter.registry.Get(name) {
+			delete(filter.last, name)
+		}
+	}
+}
+
+func (r *idleRegistry) GetCurrent() string {
+	return renderCurrent(r.Each)
+}
+
+func (r *idleRegistry) MarshalJSON() ([]byte, error) {
+	return marshalRegistry(r)
+}
@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIdleFilter(t *testing.T) {
+	r := NewRegistry()
+	c := NewRegisteredCounter("requests", r)
+	g := NewRegisteredGaugeFloat64("load", r)
+	NewRegisteredTimer("latency", r)
+	r.Register("db", NewHealthcheck(func(Healthcheck) {}))
+	idle := newIdleFilter(r)
+	flush := func() string {
+		var names []string
+		idle.view(r).Each(func(name string, _ interface{}) { names = append(names, name) })
+		return strings.Join(names, " ")
+	}
+
+	if got := flush(); "db latency load requests" != got {
+		t.Errorf("first flush: %q", got)
+	}
+	if got := flush(); "db" != got {
+		t.Errorf("idle flush: %q", got)
+	}
+	c.Inc(1)
+	g.Update(0)
+	if got := flush(); "db requests" != got {
+		t.Errorf("after updates: %q", got)
+	}
+	g.Update(2)
+	if got := flush(); "db load" != got {
+		t.Errorf("after gauge change: %q", got)
+	}
+
+	r.Unregister("requests")
+	flush()
+	if _, ok := idle.last["requests"]; ok {
+		t.Error("unregistered metric remembered")
+	}
+	NewRegisteredCounter("requests", r)
+	if got := flush(); "db requests" != got {
+		t.Errorf("re-registered: %q", got)
+	}
+}
+
+func TestIdleFilterNil(t *testing.T) {
+	r := NewRegistry()
+	var idle *idleFilter
+	if idle.view(r) != r {
+		t.Error("nil filter wraps the registry")
+	}
+}
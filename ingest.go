@@ -0,0 +1,404 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxOptronLine is the longest line, in bytes, an OptronServer reads before
+// dropping the connection.  Bulk senders batch many objects into one line.
+var MaxOptronLine = 16 << 20
+
+// DefaultOptronSourceTTL is how long an OptronServer keeps the values of a
+// sender that has stopped reporting when its SourceTTL is zero.
+var DefaultOptronSourceTTL = 10 * time.Minute
+
+// optronSourceKeys are the keys optron uses to identify the sender of an
+// object rather than a metric.
+var optronSourceKeys = [...]string{"hostName", "id", "game"}
+
+// OptronServer accepts TCP connections carrying the newline-delimited JSON
+// objects optron sends, or JSON arrays of them from bulk senders, and merges
+// their values into a registry as GaugeFloat64s named after each value's key,
+// tagged with the object's tags.  The last value from each sender, identified
+// by its hostName and id, is kept and the gauge reports their sum, or their
+// mean for the _avg and percentile keys of histograms and timers.  Values
+// that are not numbers, such as healthcheck errors, are ignored.  Senders
+// that stop reporting drop out of the merge after SourceTTL, and a gauge
+// left without senders is unregistered.
+type OptronServer struct {
+	// Registry receives the ingested metrics.  It defaults to
+	// DefaultRegistry.
+	Registry Registry
+
+	// Group, when set, receives the metrics of objects labelled with a game
+	// in that game's registry and the rest in its Global registry, in place
+	// of Registry.
+	Group *RegistryGroup
+
+	// Logger, when set, is told about malformed lines and read errors.
+	Logger Logger
+
+	// Cipher, when set, opens every line, which senders seal with the same
+	// key, and rejects those that don't authenticate.
+	Cipher *PayloadCipher
+
+	// SourceTTL is how long the values of a sender are kept after its last
+	// report.  It defaults to DefaultOptronSourceTTL.
+	SourceTTL time.Duration
+
+	lines   lineListener
+	mutex   sync.Mutex // guards sources and swept
+	sources map[optronKey]*optronSources
+	swept   time.Time
+}
+
+// optronSources holds the last value of one key from each sender.
+type optronSources struct {
+	key    string
+	values map[string]optronValue
+}
+
+// optronValue is a sender's last value and when it was reported.
+type optronValue struct {
+	v    float64
+	seen time.Time
+}
+
+// optronKey identifies one merged metric within one registry.
+type optronKey struct {
+	r    Registry
+	name string
+}
+
+// ListenOptron listens on the TCP address addr and serves optron connections
+// in the background, merging them into r, or DefaultRegistry if r is nil.
+func ListenOptron(addr string, r Registry) (*OptronServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &OptronServer{Registry: r}
+	s.lines.ln = ln
+	go s.Serve(ln)
+	return s, nil
+}
+
+// Serve accepts connections on ln until it fails or the server is closed,
+// reading each connection in its own goroutine.  It returns nil once the
+// server has been closed.
+func (s *OptronServer) Serve(ln net.Listener) error {
+	return s.lines.serve(ln, MaxOptronLine, s.Ingest, func(addr net.Addr, err error) {
+		s.logf("optron: %v: %v", addr, err)
+	})
+}
+
+// Addr returns the address the server is listening on, or nil before Serve.
+func (s *OptronServer) Addr() net.Addr {
+	return s.lines.addr()
+}
+
+// Close stops the listener, closes every open connection and waits for
+// their goroutines to finish.  Metrics already merged stay registered.
+func (s *OptronServer) Close() error {
+	return s.lines.close()
+}
+
+// Ingest merges one line of optron output, either an object or an array of
+// objects, as though it had been read from a connection.
+func (s *OptronServer) Ingest(line []byte) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
+	}
+	if nil != s.Cipher {
+		var err error
+		if line, err = s.Cipher.Open(line); err != nil {
+			return err
+		}
+	}
+	var objs []map[string]interface{}
+	if line[0] == '[' {
+		if err := json.Unmarshal(line, &objs); err != nil {
+			return err
+		}
+	} else {
+		var obj map[string]interface{}
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return err
+		}
+		objs = append(objs, obj)
+	}
+	for _, obj := range objs {
+		s.merge(obj)
+	}
+	return nil
+}
+
+// merge records the values of obj against its sender and updates the gauges
+// they are merged into.
+func (s *OptronServer) merge(obj map[string]interface{}) {
+	r := s.registry(optronString(obj, "game"))
+	source := optronString(obj, "hostName") + "/" + optronString(obj, "id")
+	var tags []string
+	for _, k := range tagKeys {
+		tags = append(tags, optronString(obj, k))
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	now := DefaultClock.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if nil == s.sources {
+		s.sources = make(map[optronKey]*optronSources)
+		s.swept = now
+	}
+	for _, k := range keys {
+		v, ok := obj[k].(float64)
+		if !ok || isOptronMetaKey(k) {
+			continue
+		}
+		key := optronKey{r, taggedName(k, tags)}
+		sources, ok := s.sources[key]
+		if !ok {
+			sources = &optronSources{key: k, values: make(map[string]optronValue)}
+			s.sources[key] = sources
+		}
+		sources.values[source] = optronValue{v, now}
+		s.update(key, sources)
+	}
+	if ttl := s.sourceTTL(); now.Sub(s.swept) >= ttl {
+		s.evict(now.Add(-ttl))
+		s.swept = now
+	}
+}
+
+// evict drops the values of senders last seen before deadline, updating the
+// gauges they were merged into and unregistering those left without any.
+// The caller holds s.mutex.
+func (s *OptronServer) evict(deadline time.Time) {
+	for key, sources := range s.sources {
+		n := len(sources.values)
+		for source, v := range sources.values {
+			if v.seen.Before(deadline) {
+				delete(sources.values, source)
+			}
+		}
+		switch {
+		case len(sources.values) == 0:
+			delete(s.sources, key)
+			if _, ok := key.r.Get(key.name).(GaugeFloat64); ok {
+				key.r.Unregister(key.name)
+			}
+		case len(sources.values) < n:
+			s.update(key, sources)
+		}
+	}
+}
+
+// update sets the gauge of key to the merge of its senders' values.  The
+// caller holds s.mutex.
+func (s *OptronServer) update(key optronKey, sources *optronSources) {
+	g, ok := key.r.GetOrRegister(key.name, NewGaugeFloat64).(GaugeFloat64)
+	if !ok {
+		s.logf("optron: %s is registered as another type", key.name)
+		return
+	}
+	g.Update(combineOptron(sources.key, sources.values))
+}
+
+func (s *OptronServer) sourceTTL() time.Duration {
+	if s.SourceTTL > 0 {
+		return s.SourceTTL
+	}
+	return DefaultOptronSourceTTL
+}
+
+func (s *OptronServer) registry(game string) Registry {
+	if nil != s.Group {
+		if game == "" {
+			return s.Group.Global
+		}
+		return s.Group.Game(game)
+	}
+	if nil == s.Registry {
+		return DefaultRegistry
+	}
+	return s.Registry
+}
+
+func (s *OptronServer) logf(format string, v ...interface{}) {
+	if nil != s.Logger {
+		s.Logger.Printf(format, v...)
+	}
+}
+
+// combineOptron merges the values of key from every sender: averages and
+// percentiles by their mean and everything else by their sum.
+func combineOptron(key string, values map[string]optronValue) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v.v
+	}
+	if isOptronMeanKey(key) {
+		return sum / float64(len(values))
+	}
+	return sum
+}
+
+func isOptronMeanKey(key string) bool {
+	for _, suffix := range []string{"_avg", "_80", "_90", "_95", "_99"} {
+		if strings.HasSuffix(key, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isOptronMetaKey(key string) bool {
+	for _, k := range optronSourceKeys {
+		if k == key {
+			return true
+		}
+	}
+	for _, k := range tagKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// optronString returns the string value of key in obj, or "" if there is
+// none.
+func optronString(obj map[string]interface{}, key string) string {
+	switch v := obj[key].(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// lineListener serves the TCP connections of a line-based protocol for the
+// servers in this package, handing every line read to an ingest function.
+type lineListener struct {
+	mutex  sync.Mutex
+	ln     net.Listener
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+// serve accepts connections on ln until it fails or the listener is closed,
+// reading lines of up to maxLine bytes from each in its own goroutine.
+// Errors from ingest and reads are passed to logf with the address of the
+// connection.  It returns nil once the listener has been closed.
+func (l *lineListener) serve(ln net.Listener, maxLine int, ingest func([]byte) error, logf func(net.Addr, error)) error {
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		ln.Close()
+		return nil
+	}
+	l.ln = ln
+	l.mutex.Unlock()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			l.mutex.Lock()
+			closed := l.closed
+			l.mutex.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		if !l.track(conn) {
+			conn.Close()
+			return nil
+		}
+		l.wg.Add(1)
+		go l.serveConn(conn, maxLine, ingest, logf)
+	}
+}
+
+func (l *lineListener) addr() net.Addr {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if nil == l.ln {
+		return nil
+	}
+	return l.ln.Addr()
+}
+
+func (l *lineListener) close() error {
+	l.mutex.Lock()
+	if l.closed {
+		l.mutex.Unlock()
+		return nil
+	}
+	l.closed = true
+	var err error
+	if nil != l.ln {
+		err = l.ln.Close()
+	}
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mutex.Unlock()
+	l.wg.Wait()
+	return err
+}
+
+func (l *lineListener) track(conn net.Conn) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.closed {
+		return false
+	}
+	if nil == l.conns {
+		l.conns = make(map[net.Conn]struct{})
+	}
+	l.conns[conn] = struct{}{}
+	return true
+}
+
+func (l *lineListener) serveConn(conn net.Conn, maxLine int, ingest func([]byte) error, logf func(net.Addr, error)) {
+	defer l.wg.Done()
+	defer func() {
+		l.mutex.Lock()
+		delete(l.conns, conn)
+		l.mutex.Unlock()
+		conn.Close()
+	}()
+	size := 64 << 10
+	if maxLine < size {
+		size = maxLine
+	}
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 0, size), maxLine)
+	for scanner.Scan() {
+		if err := ingest(scanner.Bytes()); err != nil {
+			logf(conn.RemoteAddr(), err)
+		}
+	}
+	if err := scanner.Err(); err != nil && !errors.Is(err, net.ErrClosed) {
+		logf(conn.RemoteAddr(), err)
+	}
+}
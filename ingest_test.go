@@ -0,0 +1,113 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestOptronServerIngest(t *testing.T) {
+	r := NewRegistry()
+	s := &OptronServer{Registry: r}
+	if err := s.Ingest([]byte(`{"hostName":"a","id":"svc","game":"","ns":"app","grp":"db","requests":3,"latency_avg":0.2,"health":{}}`)); nil != err {
+		t.Fatal(err)
+	}
+	if err := s.Ingest([]byte(`[{"hostName":"b","id":"svc","ns":"app","grp":"db","requests":4,"latency_avg":0.4}]` + "\r\n")); nil != err {
+		t.Fatal(err)
+	}
+	if v := r.Get("app|dbTAGrequests").(GaugeFloat64).Value(); 7 != v {
+		t.Errorf("requests: 7 != %v", v)
+	}
+	if v := r.Get("app|dbTAGlatency_avg").(GaugeFloat64).Value(); v < 0.2999 || v > 0.3001 {
+		t.Errorf("latency_avg: 0.3 != %v", v)
+	}
+	if nil != r.Get("app|dbTAGhealth") || nil != r.Get("hostName") {
+		t.Error("non-numeric or source keys were registered")
+	}
+
+	// A sender's later value replaces its earlier one.
+	s.Ingest([]byte(`{"hostName":"a","id":"svc","ns":"app","grp":"db","requests":1}`))
+	if v := r.Get("app|dbTAGrequests").(GaugeFloat64).Value(); 5 != v {
+		t.Errorf("requests: 5 != %v", v)
+	}
+	if err := s.Ingest([]byte(`{"requests":`)); nil == err {
+		t.Error("malformed line was accepted")
+	}
+}
+
+func TestOptronServerGroup(t *testing.T) {
+	g := NewRegistryGroup(NewRegistry())
+	s := &OptronServer{Group: g}
+	s.Ingest([]byte(`{"hostName":"a","game":"poker","players":10}`))
+	s.Ingest([]byte(`{"hostName":"a","game":"","goroutines":20}`))
+	if m, ok := g.Game("poker").Get("players").(GaugeFloat64); !ok || 10 != m.Value() {
+		t.Errorf("poker players: %v", g.Game("poker").Get("players"))
+	}
+	if m, ok := g.Global.Get("goroutines").(GaugeFloat64); !ok || 20 != m.Value() {
+		t.Errorf("global goroutines: %v", g.Global.Get("goroutines"))
+	}
+}
+
+func TestOptronServerSourceTTL(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	s := &OptronServer{Registry: r, SourceTTL: time.Minute}
+	s.Ingest([]byte(`{"hostName":"a","requests":3,"queued":1}`))
+	s.Ingest([]byte(`{"hostName":"b","requests":4}`))
+	clock.Add(30 * time.Second)
+	s.Ingest([]byte(`{"hostName":"b","requests":5}`))
+	clock.Add(45 * time.Second)
+
+	// a has been silent for over a minute, b has not.
+	s.Ingest([]byte(`{"hostName":"b","requests":6}`))
+	if v := r.Get("requests").(GaugeFloat64).Value(); 6 != v {
+		t.Errorf("requests: 6 != %v", v)
+	}
+	if nil != r.Get("queued") {
+		t.Error("gauge of expired senders still registered")
+	}
+	if n := len(s.sources); 1 != n {
+		t.Errorf("len(s.sources): 1 != %v", n)
+	}
+}
+
+func TestListenOptron(t *testing.T) {
+	r := NewRegistry()
+	s, err := ListenOptron("127.0.0.1:0", r)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var buf bytes.Buffer
+	for i := 1; i <= 3; i++ {
+		fmt.Fprintf(&buf, `{"hostName":"h","id":"x","queued":%d}`+"\r\n", i)
+	}
+	conn.Write(buf.Bytes())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if g, ok := r.Get("queued").(GaugeFloat64); ok && 3 == g.Value() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("queued: %v", r.Get("queued"))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := s.Close(); nil != err {
+		t.Error(err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); nil == err {
+		t.Error("connection still open after Close")
+	}
+}
@@ -19,23 +19,49 @@ func GetOrRegisterInstantCounter(name string, r Registry) Instant {
 	if nil == r {
 		r = DefaultRegistry
 	}
+	if m, ok := r.Get(name).(Instant); ok {
+		return m
+	}
 	return r.GetOrRegister(name, NewInstantCounter).(Instant)
 }
 
 // NewInstantCounter constructs a new InstantCounter.
 func NewInstantCounter() Instant {
-	return &InstantCounter{0}
+	if UseNilMetrics {
+		return NilInstant{}
+	}
+	return &InstantCounter{}
 }
 
+// NilInstant is a no-op Instant.
+type NilInstant struct{}
+
+// Clear is a no-op.
+func (NilInstant) Clear() {}
+
+// Count is a no-op.
+func (NilInstant) Count() int64 { return 0 }
+
+// Dec is a no-op.
+func (NilInstant) Dec(i int64) {}
+
+// Inc is a no-op.
+func (NilInstant) Inc(i int64) {}
+
+// Update is a no-op.
+func (NilInstant) Update(i int64) {}
+
 // InstantCounter is the standard implementation of a Instant and uses the
 // sync/atomic package to manage a single int64 value.
 type InstantCounter struct {
-	count int64
+	count    int64
+	watchers watcherSet
 }
 
 // Clear sets the counter to zero.
 func (c *InstantCounter) Clear() {
-	atomic.StoreInt64(&c.count, 0)
+	old := atomic.SwapInt64(&c.count, 0)
+	c.watchers.notify(old, 0)
 }
 
 // Count returns the current count.
@@ -45,14 +71,20 @@ func (c *InstantCounter) Count() int64 {
 
 // Dec decrements the counter by the given amount.
 func (c *InstantCounter) Dec(i int64) {
-	atomic.AddInt64(&c.count, -i)
+	n := atomic.AddInt64(&c.count, -i)
+	c.watchers.notify(n+i, n)
 }
 
 // Inc increments the counter by the given amount.
 func (c *InstantCounter) Inc(i int64) {
-	atomic.AddInt64(&c.count, i)
+	n := atomic.AddInt64(&c.count, i)
+	c.watchers.notify(n-i, n)
 }
 
 func (c *InstantCounter) Update(i int64) {
 	c.Inc(i)
 }
+
+func (c *InstantCounter) watch(f func(old, new int64)) func() {
+	return c.watchers.add(f)
+}
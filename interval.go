@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+)
+
+// An IntervalOverride exports the metrics whose names start with Prefix
+// every Interval instead of at the exporter's own interval, such as errors
+// every 5s and runtime statistics every minute.  When several prefixes
+// match a name the longest wins.
+type IntervalOverride struct {
+	Prefix   string
+	Interval time.Duration
+}
+
+// RunPeriodicIntervals is like RunPeriodic for exporters of r whose metrics
+// have their own intervals.  It ticks at the greatest common divisor of d and
+// the overrides' intervals and calls export with a read-only view of r
+// holding the metrics due on that tick: those of each override every
+// Interval and the rest every d.  Ticks on which no interval is due are
+// skipped.  A Flush exports all of r.
+func RunPeriodicIntervals(r Registry, d time.Duration, overrides []IntervalOverride, export func(Registry)) {
+	RunPeriodicIntervalsContext(context.Background(), r, d, overrides, export)
+}
+
+// RunPeriodicIntervalsContext is like RunPeriodicIntervals but returns once
+// ctx is done.
+func RunPeriodicIntervalsContext(ctx context.Context, r Registry, d time.Duration, overrides []IntervalOverride, export func(Registry)) {
+	if len(overrides) == 0 {
+		RunPeriodicContext(ctx, d, func() { export(r) })
+		return
+	}
+	s := newIntervalSchedule(d, overrides)
+	var elapsed time.Duration
+	runPeriodic(ctx, s.tick, func() {
+		elapsed += s.tick
+		if !s.due(elapsed) {
+			return
+		}
+		export(&filteredRegistry{Registry: r, keep: func(name string) bool {
+			return elapsed%s.interval(name) == 0
+		}})
+	}, func() {
+		export(r)
+	})
+}
+
+// intervalSchedule finds the interval of each metric name.
+type intervalSchedule struct {
+	d         time.Duration
+	tick      time.Duration
+	overrides []IntervalOverride // longest prefix first
+}
+
+func newIntervalSchedule(d time.Duration, overrides []IntervalOverride) *intervalSchedule {
+	s := &intervalSchedule{d: d, tick: d}
+	for _, o := range overrides {
+		if o.Interval <= 0 {
+			continue
+		}
+		s.overrides = append(s.overrides, o)
+		s.tick = gcdDuration(s.tick, o.Interval)
+	}
+	sort.SliceStable(s.overrides, func(i, j int) bool {
+		return len(s.overrides[i].Prefix) > len(s.overrides[j].Prefix)
+	})
+	return s
+}
+
+func (s *intervalSchedule) interval(name string) time.Duration {
+	for _, o := range s.overrides {
+		if strings.HasPrefix(name, o.Prefix) {
+			return o.Interval
+		}
+	}
+	return s.d
+}
+
+// due reports whether any interval ends elapsed after the schedule began.
+func (s *intervalSchedule) due(elapsed time.Duration) bool {
+	if elapsed%s.d == 0 {
+		return true
+	}
+	for _, o := range s.overrides {
+		if elapsed%o.Interval == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func gcdDuration(a, b time.Duration) time.Duration {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
+// filteredRegistry is a read-only view of the metrics of a Registry for which
+// keep returns true.
+type filteredRegistry struct {
+	Registry
+	keep func(name string) bool
+}
+
+func (r *filteredRegistry) Each(f func(string, interface{})) {
+	r.Registry.Each(func(name string, i interface{}) {
+		if r.keep(name) {
+			f(name, i)
+		}
+	})
+}
+
+func (r *filteredRegistry) EachUnsorted(f func(string, interface{})) {
+	r.Registry.EachUnsorted(func(name string, i interface{}) {
+		if r.keep(name) {
+			f(name, i)
+		}
+	})
+}
+
+func (r *filteredRegistry) Get(name string) interface{} {
+	if !r.keep(name) {
+		return nil
+	}
+	return r.Registry.Get(name)
+}
+
+func (r *filteredRegistry) GetCurrent() string {
+	return renderCurrent(r.Each)
+}
+
+func (r *filteredRegistry) MarshalJSON() ([]byte, error) {
+	return marshalRegistry(r)
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntervalSchedule(t *testing.T) {
+	s := newIntervalSchedule(time.Minute, []IntervalOverride{
+		{Prefix: "runtime.", Interval: 2 * time.Minute},
+		{Prefix: "runtime.gc.", Interval: 15 * time.Second},
+		{Prefix: "ignored.", Interval: 0},
+	})
+	if s.tick != 15*time.Second {
+		t.Fatal(s.tick)
+	}
+	for name, want := range map[string]time.Duration{
+		"runtime.gc.pauses":    15 * time.Second,
+		"runtime.heap":         2 * time.Minute,
+		"requests":             time.Minute,
+		"ignored.requests":     time.Minute,
+		"runtime_not_prefixed": time.Minute,
+	} {
+		if d := s.interval(name); d != want {
+			t.Errorf("%s: %v != %v", name, want, d)
+		}
+	}
+
+	// Ticking every 5s for intervals of 10s and 15s, 5s has nothing due.
+	s = newIntervalSchedule(10*time.Second, []IntervalOverride{{Prefix: "errors.", Interval: 15 * time.Second}})
+	for elapsed, want := range map[time.Duration]bool{
+		5 * time.Second:  false,
+		10 * time.Second: true,
+		15 * time.Second: true,
+		25 * time.Second: false,
+		30 * time.Second: true,
+	} {
+		if due := s.due(elapsed); due != want {
+			t.Errorf("due(%v): %v != %v", elapsed, want, due)
+		}
+	}
+}
+
+func TestRunPeriodicIntervals(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	for _, name := range []string{"errors.db", "requests", "runtime.heap"} {
+		NewRegisteredCounter(name, r)
+	}
+	exports := make(chan string)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go RunPeriodicIntervalsContext(ctx, r, 10*time.Second, []IntervalOverride{
+		{Prefix: "errors.", Interval: 5 * time.Second},
+		{Prefix: "runtime.", Interval: 20 * time.Second},
+	}, func(due Registry) {
+		var names []string
+		due.Each(func(name string, _ interface{}) { names = append(names, name) })
+		select {
+		case exports <- strings.Join(names, " "):
+		case <-ctx.Done():
+		}
+	})
+
+	// Advance until the loop has started its ticker and exported.
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) == 0 {
+		clock.Add(5 * time.Second)
+		select {
+		case names := <-exports:
+			got = append(got, names)
+		case <-time.After(time.Millisecond):
+		case <-timeout:
+			t.Fatal("RunPeriodicIntervals never exported")
+		}
+	}
+	for len(got) < 4 {
+		clock.Add(5 * time.Second)
+		got = append(got, <-exports)
+	}
+	want := []string{"errors.db", "errors.db requests", "errors.db", "errors.db requests runtime.heap"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("export %d: %q != %q", i, want[i], got[i])
+		}
+	}
+}
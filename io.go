@@ -0,0 +1,72 @@
+package metrics
+
+import "io"
+
+// MeteredReader is an io.Reader that marks the bytes it reads on a Meter
+// named name.bytes and counts read errors other than io.EOF on a Counter
+// named name.errors.
+type MeteredReader struct {
+	reader io.Reader
+	bytes  Meter
+	errors Counter
+}
+
+// MeterReader wraps rd in a MeteredReader registered with DefaultRegistry.
+func MeterReader(name string, rd io.Reader) io.Reader {
+	return NewMeteredReader(name, rd, nil)
+}
+
+// NewMeteredReader wraps rd in a MeteredReader registered with r.
+func NewMeteredReader(name string, rd io.Reader, r Registry) *MeteredReader {
+	return &MeteredReader{
+		reader: rd,
+		bytes:  GetOrRegisterMeter(name+".bytes", r),
+		errors: GetOrRegisterCounter(name+".errors", r),
+	}
+}
+
+// Read reads from the underlying reader.
+func (m *MeteredReader) Read(p []byte) (int, error) {
+	n, err := m.reader.Read(p)
+	if n > 0 {
+		m.bytes.Mark(int64(n))
+	}
+	if err != nil && err != io.EOF {
+		m.errors.Inc(1)
+	}
+	return n, err
+}
+
+// MeteredWriter is an io.Writer that marks the bytes it writes on a Meter
+// named name.bytes and counts write errors on a Counter named name.errors.
+type MeteredWriter struct {
+	writer io.Writer
+	bytes  Meter
+	errors Counter
+}
+
+// MeterWriter wraps w in a MeteredWriter registered with DefaultRegistry.
+func MeterWriter(name string, w io.Writer) io.Writer {
+	return NewMeteredWriter(name, w, nil)
+}
+
+// NewMeteredWriter wraps w in a MeteredWriter registered with r.
+func NewMeteredWriter(name string, w io.Writer, r Registry) *MeteredWriter {
+	return &MeteredWriter{
+		writer: w,
+		bytes:  GetOrRegisterMeter(name+".bytes", r),
+		errors: GetOrRegisterCounter(name+".errors", r),
+	}
+}
+
+// Write writes to the underlying writer.
+func (m *MeteredWriter) Write(p []byte) (int, error) {
+	n, err := m.writer.Write(p)
+	if n > 0 {
+		m.bytes.Mark(int64(n))
+	}
+	if err != nil {
+		m.errors.Inc(1)
+	}
+	return n, err
+}
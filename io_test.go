@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestMeteredReader(t *testing.T) {
+	r := NewRegistry()
+	rd := NewMeteredReader("upload", strings.NewReader("hello, world"), r)
+	if _, err := ioutil.ReadAll(rd); err != nil {
+		t.Fatal(err)
+	}
+	if c := r.Get("upload.bytes").(Meter).Count(); c != 12 {
+		t.Fatal(c)
+	}
+	if c := r.Get("upload.errors").(Counter).Count(); c != 0 {
+		t.Fatal(c)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 2, errors.New("disk full")
+}
+
+func TestMeteredWriter(t *testing.T) {
+	r := NewRegistry()
+	b := &bytes.Buffer{}
+	w := NewMeteredWriter("logs", b, r)
+	io.WriteString(w, "hello")
+	if c := r.Get("logs.bytes").(Meter).Count(); c != 5 {
+		t.Fatal(c)
+	}
+
+	w = NewMeteredWriter("logs", failingWriter{}, r)
+	if _, err := io.WriteString(w, "hello"); err == nil {
+		t.Fatal(err)
+	}
+	if c := r.Get("logs.bytes").(Meter).Count(); c != 7 {
+		t.Fatal(c)
+	}
+	if c := r.Get("logs.errors").(Counter).Count(); c != 1 {
+		t.Fatal(c)
+	}
+}
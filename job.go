@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// JobStats instruments a periodic or background job.  For a job named
+// "cleanup" it registers:
+//
+//	cleanup.duration      a Timer of run durations
+//	cleanup.success       a Counter of runs returning nil
+//	cleanup.failure       a Counter of runs returning an error or panicking
+//	cleanup.lastSuccess   a Gauge of the unix time the last successful run ended
+//	cleanup.running       a Gauge of runs in progress
+//	cleanup.overlaps      a Counter of runs started while another was running
+type JobStats struct {
+	running     int64
+	duration    Timer
+	success     Counter
+	failure     Counter
+	lastSuccess Gauge
+	overlaps    Counter
+}
+
+// Job returns the JobStats for the named job in DefaultRegistry,
+// constructing it if needed.
+func Job(name string) *JobStats {
+	return NewJobStats(name, nil)
+}
+
+// NewJobStats constructs a JobStats and registers its metrics under the given
+// name, or returns the JobStats already registered under it in r, so runs
+// of a job overlap however often its JobStats is looked up.
+func NewJobStats(name string, r Registry) *JobStats {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	j := &JobStats{
+		duration:    GetOrRegisterTimer(name+".duration", r),
+		success:     GetOrRegisterCounter(name+".success", r),
+		failure:     GetOrRegisterCounter(name+".failure", r),
+		lastSuccess: GetOrRegisterGauge(name+".lastSuccess", r),
+		overlaps:    GetOrRegisterCounter(name+".overlaps", r),
+	}
+	return registerOwnedGauge(r, name+".running", j, j.Running).(*JobStats)
+}
+
+// Running returns the number of runs in progress.
+func (j *JobStats) Running() int64 {
+	return atomic.LoadInt64(&j.running)
+}
+
+// Run runs f and records the outcome.  A run that starts while another is
+// still in progress is counted as an overlap but is not prevented.  If f
+// panics the run is counted as a failure and the panic continues.
+func (j *JobStats) Run(f func() error) (err error) {
+	if atomic.AddInt64(&j.running, 1) > 1 {
+		j.overlaps.Inc(1)
+	}
+	start := time.Now()
+	ok := false
+	defer func() {
+		atomic.AddInt64(&j.running, -1)
+		j.duration.UpdateSince(start)
+		if ok {
+			j.success.Inc(1)
+			j.lastSuccess.Update(time.Now().Unix())
+		} else {
+			j.failure.Inc(1)
+		}
+	}()
+
+	err = f()
+	ok = err == nil
+	return err
+}
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestJobStats(t *testing.T) {
+	r := NewRegistry()
+	j := NewJobStats("cleanup", r)
+
+	j.Run(func() error { return nil })
+	j.Run(func() error { return errors.New("failed") })
+	func() {
+		defer func() { recover() }()
+		j.Run(func() error { panic("boom") })
+	}()
+
+	for name, want := range map[string]int64{
+		"cleanup.success":  1,
+		"cleanup.failure":  2,
+		"cleanup.overlaps": 0,
+	} {
+		if c := r.Get(name).(Counter).Count(); c != want {
+			t.Fatalf("%s: got %d, want %d", name, c, want)
+		}
+	}
+	if c := r.Get("cleanup.duration").(Timer).Count(); c != 3 {
+		t.Fatal(c)
+	}
+	if v := r.Get("cleanup.lastSuccess").(Gauge).Value(); v < time.Now().Add(-time.Minute).Unix() {
+		t.Fatal(v)
+	}
+	if v := r.Get("cleanup.running").(Gauge).Value(); v != 0 {
+		t.Fatal(v)
+	}
+}
+
+func TestJobStatsOverlap(t *testing.T) {
+	r := NewRegistry()
+	j := NewJobStats("cleanup", r)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		j.Run(func() error {
+			close(started)
+			<-release
+			return nil
+		})
+		close(done)
+	}()
+	<-started
+	if v := r.Get("cleanup.running").(Gauge).Value(); v != 1 {
+		t.Fatal(v)
+	}
+	// Looking the job up again finds the run in progress.
+	NewJobStats("cleanup", r).Run(func() error { return nil })
+	close(release)
+	<-done
+
+	if c := r.Get("cleanup.overlaps").(Counter).Count(); c != 1 {
+		t.Fatal(c)
+	}
+	if c := r.Get("cleanup.success").(Counter).Count(); c != 2 {
+		t.Fatal(c)
+	}
+}
+
+func TestJobReused(t *testing.T) {
+	r := NewRegistry()
+	if j := NewJobStats("cleanup", r); j != NewJobStats("cleanup", r) {
+		t.Error("JobStats not reused")
+	}
+}
@@ -3,77 +3,105 @@ package metrics
 import (
 	"encoding/json"
 	"io"
+	"math"
+	"strconv"
 	"time"
 )
 
+// DefaultJSONPercentiles are the percentiles MarshalJSON reports for
+// histograms and timers.
+var DefaultJSONPercentiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+
 // MarshalJSON returns a byte slice containing a JSON representation of all
 // the metrics in the Registry.
 func (r *StandardRegistry) MarshalJSON() ([]byte, error) {
+	return marshalRegistry(r)
+}
+
+func marshalRegistry(r Registry) ([]byte, error) {
 	data := make(map[string]map[string]interface{})
-	r.Each(func(name string, i interface{}) {
-		values := make(map[string]interface{})
-		switch metric := i.(type) {
-		case Counter:
-			values["count"] = metric.Count()
-		case Gauge:
-			values["value"] = metric.Value()
-		case GaugeFloat64:
-			values["value"] = metric.Value()
-		case Healthcheck:
-			values["error"] = nil
-			metric.Check()
-			if err := metric.Error(); nil != err {
-				values["error"] = metric.Error().Error()
-			}
-		case Histogram:
-			h := metric.Snapshot()
-			ps := h.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
-			values["count"] = h.Count()
-			values["min"] = h.Min()
-			values["max"] = h.Max()
-			values["mean"] = h.Mean()
-			values["stddev"] = h.StdDev()
-			values["median"] = ps[0]
-			values["75%"] = ps[1]
-			values["95%"] = ps[2]
-			values["99%"] = ps[3]
-			values["99.9%"] = ps[4]
-		case Meter:
-			m := metric.Snapshot()
-			values["count"] = m.Count()
-			values["1m.rate"] = m.Rate1()
-			values["5m.rate"] = m.Rate5()
-			values["15m.rate"] = m.Rate15()
-			values["mean.rate"] = m.RateMean()
-		case Timer:
-			t := metric.Snapshot()
-			ps := t.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
-			values["count"] = t.Count()
-			values["min"] = t.Min()
-			values["max"] = t.Max()
-			values["mean"] = t.Mean()
-			values["stddev"] = t.StdDev()
-			values["median"] = ps[0]
-			values["75%"] = ps[1]
-			values["95%"] = ps[2]
-			values["99%"] = ps[3]
-			values["99.9%"] = ps[4]
-			values["1m.rate"] = t.Rate1()
-			values["5m.rate"] = t.Rate5()
-			values["15m.rate"] = t.Rate15()
-			values["mean.rate"] = t.RateMean()
-		}
-		data[name] = values
+	r.EachUnsorted(func(name string, i interface{}) {
+		data[name] = jsonValues(i, DefaultJSONPercentiles)
 	})
 	return json.Marshal(data)
 }
 
+// jsonValues returns the values MarshalJSON reports for a single metric,
+// with the given percentiles for histograms and timers.
+func jsonValues(i interface{}, ps []float64) map[string]interface{} {
+	values := make(map[string]interface{})
+	switch metric := i.(type) {
+	case Counter:
+		values["count"] = metric.Count()
+	case CounterFloat64:
+		values["count"] = metric.Count()
+	case Instant:
+		values["count"] = metric.Count()
+	case Gauge:
+		values["value"] = metric.Value()
+	case GaugeFloat64:
+		values["value"] = metric.Value()
+	case Healthcheck:
+		values["error"] = nil
+		metric.Check()
+		if err := metric.Error(); nil != err {
+			values["error"] = metric.Error().Error()
+		}
+	case Histogram:
+		h := metric.Snapshot()
+		values["count"] = h.Count()
+		values["min"] = h.Min()
+		values["max"] = h.Max()
+		values["mean"] = h.Mean()
+		values["stddev"] = h.StdDev()
+		for j, v := range h.Percentiles(ps) {
+			values[percentileKey(ps[j])] = v
+		}
+	case Meter:
+		m := metric.Snapshot()
+		values["count"] = m.Count()
+		values["1m.rate"] = m.Rate1()
+		values["5m.rate"] = m.Rate5()
+		values["15m.rate"] = m.Rate15()
+		values["mean.rate"] = m.RateMean()
+	case Timer:
+		t := metric.Snapshot()
+		values["count"] = t.Count()
+		values["min"] = t.Min()
+		values["max"] = t.Max()
+		values["mean"] = t.Mean()
+		values["stddev"] = t.StdDev()
+		for j, v := range t.Percentiles(ps) {
+			values[percentileKey(ps[j])] = v
+		}
+		values["1m.rate"] = t.Rate1()
+		values["5m.rate"] = t.Rate5()
+		values["15m.rate"] = t.Rate15()
+		values["mean.rate"] = t.RateMean()
+		if o, ok := t.(OutlierRecorder); ok {
+			if outliers := o.Outliers(); len(outliers) > 0 {
+				values["outliers"] = outliers
+			}
+		}
+	}
+	return values
+}
+
+// percentileKey names percentile p the way MarshalJSON does: "median" for
+// 0.5 and "99.9%" for 0.999.
+func percentileKey(p float64) string {
+	if p == 0.5 {
+		return "median"
+	}
+	return strconv.FormatFloat(math.Round(p*1e6)/1e4, 'f', -1, 64) + "%"
+}
+
 // WriteJSON writes metrics from the given registry  periodically to the
 // specified io.Writer as JSON.
 func WriteJSON(r Registry, d time.Duration, w io.Writer) {
-	for _ = range time.Tick(d) {
-		WriteJSONOnce(r, w)
-	}
+	RunPeriodic(d, func() {
+		timeExport("json", func() { WriteJSONOnce(r, w) })
+	})
 }
 
 // WriteJSONOnce writes metrics from the given registry to the specified
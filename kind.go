@@ -0,0 +1,108 @@
+package metrics
+
+// A Kind names the kind of a metric: the interface of this package it
+// implements.
+type Kind string
+
+// The kinds of the metrics of this package, in the order MetricKind tests
+// them.
+const (
+	KindCounter        Kind = "Counter"
+	KindCounterFloat64 Kind = "CounterFloat64"
+	KindInstant        Kind = "Instant"
+	KindGauge          Kind = "Gauge"
+	KindGaugeFloat64   Kind = "GaugeFloat64"
+	KindHealthcheck    Kind = "Healthcheck"
+	KindHistogram      Kind = "Histogram"
+	KindMeter          Kind = "Meter"
+	KindTimer          Kind = "Timer"
+)
+
+// Temporality describes what the value of a metric covers, so generic
+// exporters can tell whether to report it as is, take the difference
+// between exports or sum it.
+type Temporality int
+
+const (
+	// TemporalityCumulative metrics count from when they were created or
+	// last cleared: Counters, CounterFloat64s and the counts of Meters,
+	// Histograms and Timers.
+	TemporalityCumulative Temporality = iota
+
+	// TemporalityDelta metrics count since the last export, which clears
+	// them: Instants.
+	TemporalityDelta
+
+	// TemporalityInstantaneous metrics hold a current value: Gauges,
+	// GaugeFloat64s and Healthchecks.
+	TemporalityInstantaneous
+)
+
+func (t Temporality) String() string {
+	switch t {
+	case TemporalityCumulative:
+		return "cumulative"
+	case TemporalityDelta:
+		return "delta"
+	case TemporalityInstantaneous:
+		return "instantaneous"
+	}
+	return "unknown"
+}
+
+// A KindReporter is a metric that reports its own Kind, overriding the one
+// MetricKind finds from the interfaces it implements.
+type KindReporter interface {
+	Kind() Kind
+}
+
+// A TemporalityReporter is a metric that reports its own Temporality, such
+// as a Counter that an exporter should clear, overriding the one
+// MetricTemporality gives its Kind.
+type TemporalityReporter interface {
+	Temporality() Temporality
+}
+
+// MetricKind returns the Kind of the metric or snapshot i, or "" if i isn't
+// a metric.
+func MetricKind(i interface{}) Kind {
+	switch m := i.(type) {
+	case KindReporter:
+		return m.Kind()
+	case Counter:
+		return KindCounter
+	case CounterFloat64:
+		return KindCounterFloat64
+	case Instant:
+		return KindInstant
+	case Gauge:
+		return KindGauge
+	case GaugeFloat64:
+		return KindGaugeFloat64
+	case Healthcheck:
+		return KindHealthcheck
+	case Histogram:
+		return KindHistogram
+	case Meter:
+		return KindMeter
+	case Timer:
+		return KindTimer
+	}
+	return ""
+}
+
+// MetricTemporality returns the Temporality of the metric or snapshot i:
+// the one it reports if it is a TemporalityReporter and that of its Kind
+// otherwise.
+func MetricTemporality(i interface{}) Temporality {
+	if t, ok := i.(TemporalityReporter); ok {
+		return t.Temporality()
+	}
+	switch MetricKind(i) {
+	case KindInstant:
+		return TemporalityDelta
+	case KindGauge, KindGaugeFloat64, KindHealthcheck:
+		return TemporalityInstantaneous
+	}
+	return TemporalityCumulative
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import "testing"
+
+type deltaCounter struct{ StandardCounter }
+
+func (*deltaCounter) Temporality() Temporality { return TemporalityDelta }
+
+func TestMetricTemporality(t *testing.T) {
+	for _, c := range []struct {
+		m           interface{}
+		kind        Kind
+		temporality Temporality
+	}{
+		{NewCounter(), KindCounter, TemporalityCumulative},
+		{NewCounter().Snapshot(), KindCounter, TemporalityCumulative},
+		{NewInstantCounter(), KindInstant, TemporalityDelta},
+		{NewGauge().Snapshot(), KindGauge, TemporalityInstantaneous},
+		{NewHealthcheck(func(Healthcheck) {}), KindHealthcheck, TemporalityInstantaneous},
+		{NewTimer().Snapshot(), KindTimer, TemporalityCumulative},
+		{&deltaCounter{}, KindCounter, TemporalityDelta},
+		{"not a metric", "", TemporalityCumulative},
+	} {
+		if kind := MetricKind(c.m); c.kind != kind {
+			t.Errorf("MetricKind(%T): %v != %v", c.m, c.kind, kind)
+		}
+		if temporality := MetricTemporality(c.m); c.temporality != temporality {
+			t.Errorf("MetricTemporality(%T): %v != %v", c.m, c.temporality, temporality)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LoadGenerator registers a synthetic population of metrics and drives
+// updates against them, for benchmarks and load tests of registries,
+// reservoirs and exporters.
+type LoadGenerator struct {
+	Registry Registry
+	Names    []string
+	metrics  []interface{}
+}
+
+// NewLoadGenerator registers n metrics in r, cycling through counters,
+// gauges, meters, histograms and timers.  A nil r means DefaultRegistry.  It
+// panics if n is less than 1, as Update and Run need a metric to pick.
+func NewLoadGenerator(r Registry, n int) *LoadGenerator {
+	if n < 1 {
+		panic("non-positive population for NewLoadGenerator")
+	}
+	if nil == r {
+		r = DefaultRegistry
+	}
+	g := &LoadGenerator{
+		Registry: r,
+		Names:    make([]string, n),
+		metrics:  make([]interface{}, n),
+	}
+	for i := 0; i < n; i++ {
+		var m interface{}
+		switch i % 5 {
+		case 0:
+			g.Names[i] = fmt.Sprintf("load.counter.%d", i)
+			m = GetOrRegisterCounter(g.Names[i], r)
+		case 1:
+			g.Names[i] = fmt.Sprintf("load.gauge.%d", i)
+			m = GetOrRegisterGauge(g.Names[i], r)
+		case 2:
+			g.Names[i] = fmt.Sprintf("load.meter.%d", i)
+			m = GetOrRegisterMeter(g.Names[i], r)
+		case 3:
+			g.Names[i] = fmt.Sprintf("load.histogram.%d", i)
+			m = GetOrRegisterHistogram(g.Names[i], r, NewExpDecaySample(1028, 0.015))
+		case 4:
+			g.Names[i] = fmt.Sprintf("load.timer.%d", i)
+			m = GetOrRegisterTimer(g.Names[i], r)
+		}
+		g.metrics[i] = m
+	}
+	return g
+}
+
+// Update records v against the i'th metric, modulo the population size.
+func (g *LoadGenerator) Update(i int, v int64) {
+	switch m := g.metrics[i%len(g.metrics)].(type) {
+	case Counter:
+		m.Inc(v)
+	case Gauge:
+		m.Update(v)
+	case Meter:
+		m.Mark(v)
+	case Timer:
+		m.Update(v)
+	case Histogram:
+		m.Update(v)
+	}
+}
+
+// Run performs ops updates spread over the given number of goroutines, each
+// picking metrics and values at random, and returns how long they took.
+func (g *LoadGenerator) Run(goroutines, ops int) time.Duration {
+	if goroutines < 1 {
+		goroutines = 1
+	}
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < goroutines; w++ {
+		n := ops / goroutines
+		if w < ops%goroutines {
+			n++
+		}
+		wg.Add(1)
+		go func(seed int64, n int) {
+			defer wg.Done()
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < n; i++ {
+				g.Update(rnd.Intn(len(g.metrics)), rnd.Int63n(1e6))
+			}
+		}(int64(w), n)
+	}
+	wg.Wait()
+	return time.Since(start)
+}
+
+// Fill performs one update on every metric so exporters have values to
+// format.
+func (g *LoadGenerator) Fill() {
+	for i := range g.metrics {
+		g.Update(i, int64(i))
+	}
+}
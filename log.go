@@ -1,6 +1,12 @@
 package metrics
 
-import "time"
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
 
 type Logger interface {
 	Printf(format string, v ...interface{})
@@ -11,9 +17,29 @@ func LogPeriodic(interval time.Duration, l Logger) {
 }
 
 func LogPeriodicRegistry(r Registry, interval time.Duration, l Logger) {
-	for _ = range time.Tick(interval) {
-		l.Printf("%s", GetCurrent())
+	RunPeriodic(logInterval(interval), func() {
+		timeExport("log", func() { l.Printf("%s", r.GetCurrent()) })
+	})
+}
+
+// LogPeriodicRegistryLimit is like LogPeriodicRegistry but prints at most
+// limit.Max metrics of r per tick, in the GetCurrent format.
+func LogPeriodicRegistryLimit(r Registry, interval time.Duration, l Logger, limit *LogLimit) {
+	RunPeriodic(logInterval(interval), func() {
+		timeExport("log", func() {
+			l.Printf("%s", renderCurrent(func(f func(string, interface{})) {
+				limit.Each(r, f)
+			}))
+		})
+	})
+}
+
+// logInterval returns d, or LogInterval if d is zero.
+func logInterval(d time.Duration) time.Duration {
+	if d <= 0 {
+		return LogInterval
 	}
+	return d
 }
 
 func Log(r Registry, freq time.Duration, l Logger) {
@@ -23,66 +49,204 @@ func Log(r Registry, freq time.Duration, l Logger) {
 // Output each metric in the given registry periodically using the given
 // logger. Print timings in `scale` units (eg time.Millisecond) rather than nanos.
 func LogScaled(r Registry, freq time.Duration, scale time.Duration, l Logger) {
-	du := float64(scale)
+	LogScaledLimit(r, freq, scale, l, nil)
+}
+
+// LogScaledLimit is like LogScaled but logs at most limit.Max metrics per
+// tick.  A nil limit logs every metric.
+func LogScaledLimit(r Registry, freq time.Duration, scale time.Duration, l Logger, limit *LogLimit) {
+	logScaledLimit(context.Background(), r, freq, scale, l, limit)
+}
+
+// logScaledLimit is LogScaledLimit returning once ctx is done.
+func logScaledLimit(ctx context.Context, r Registry, freq time.Duration, scale time.Duration, l Logger, limit *LogLimit) {
+	units := DurationScale(scale)
 	duSuffix := scale.String()[1:]
 
-	for _ = range time.Tick(freq) {
-		r.Each(func(name string, i interface{}) {
-			switch metric := i.(type) {
-			case Counter:
-				l.Printf("counter %s\n", name)
-				l.Printf("  count:       %9d\n", metric.Count())
-			case Gauge:
-				l.Printf("gauge %s\n", name)
-				l.Printf("  value:       %9d\n", metric.Value())
-			case GaugeFloat64:
-				l.Printf("gauge %s\n", name)
-				l.Printf("  value:       %f\n", metric.Value())
-			case Healthcheck:
-				metric.Check()
-				l.Printf("healthcheck %s\n", name)
-				l.Printf("  error:       %v\n", metric.Error())
-			case Histogram:
-				h := metric.Snapshot()
-				ps := h.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
-				l.Printf("histogram %s\n", name)
-				l.Printf("  count:       %9d\n", h.Count())
+	logMetric := func(display, name string, i interface{}) {
+		du := units.Divisor(name, i)
+		switch metric := i.(type) {
+		case Counter:
+			l.Printf("counter %s\n", display)
+			l.Printf("  count:       %9d\n", metric.Count())
+		case CounterFloat64:
+			l.Printf("counter %s\n", display)
+			l.Printf("  count:       %f\n", metric.Count())
+		case Gauge:
+			l.Printf("gauge %s\n", display)
+			l.Printf("  value:       %9d\n", metric.Value())
+		case GaugeFloat64:
+			l.Printf("gauge %s\n", display)
+			l.Printf("  value:       %f\n", metric.Value())
+		case Healthcheck:
+			metric.Check()
+			l.Printf("healthcheck %s\n", display)
+			l.Printf("  error:       %v\n", metric.Error())
+		case Histogram:
+			h := metric.Snapshot()
+			ps := h.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
+			suffix := ""
+			if MetricUnit(name, i) == UnitNanoseconds {
+				suffix = duSuffix
+			}
+			l.Printf("histogram %s\n", display)
+			l.Printf("  count:       %9d\n", h.Count())
+			if suffix == "" {
 				l.Printf("  min:         %9d\n", h.Min())
 				l.Printf("  max:         %9d\n", h.Max())
-				l.Printf("  mean:        %12.2f\n", h.Mean())
-				l.Printf("  stddev:      %12.2f\n", h.StdDev())
-				l.Printf("  median:      %12.2f\n", ps[0])
-				l.Printf("  80%%:         %12.2f\n", ps[1])
-				l.Printf("  90%%:         %12.2f\n", ps[2])
-				l.Printf("  99%%:         %12.2f\n", ps[3])
-				l.Printf("  99.9%%:       %12.2f\n", ps[4])
-			case Meter:
-				m := metric.Snapshot()
-				l.Printf("meter %s\n", name)
-				l.Printf("  count:       %9d\n", m.Count())
-				l.Printf("  1-min rate:  %12.2f\n", m.Rate1())
-				l.Printf("  5-min rate:  %12.2f\n", m.Rate5())
-				l.Printf("  15-min rate: %12.2f\n", m.Rate15())
-				l.Printf("  mean rate:   %12.2f\n", m.RateMean())
-			case Timer:
-				t := metric.Snapshot()
-				ps := t.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
-				l.Printf("timer %s\n", name)
-				l.Printf("  count:       %9d\n", t.Count())
-				l.Printf("  min:         %12.2f%s\n", float64(t.Min())/du, duSuffix)
-				l.Printf("  max:         %12.2f%s\n", float64(t.Max())/du, duSuffix)
-				l.Printf("  mean:        %12.2f%s\n", t.Mean()/du, duSuffix)
-				l.Printf("  stddev:      %12.2f%s\n", t.StdDev()/du, duSuffix)
-				l.Printf("  median:      %12.2f%s\n", ps[0]/du, duSuffix)
-				l.Printf("  80%%:         %12.2f%s\n", ps[1]/du, duSuffix)
-				l.Printf("  90%%:         %12.2f%s\n", ps[2]/du, duSuffix)
-				l.Printf("  99%%:         %12.2f%s\n", ps[3]/du, duSuffix)
-				l.Printf("  99.9%%:       %12.2f%s\n", ps[4]/du, duSuffix)
-				l.Printf("  1-min rate:  %12.2f\n", t.Rate1())
-				l.Printf("  5-min rate:  %12.2f\n", t.Rate5())
-				l.Printf("  15-min rate: %12.2f\n", t.Rate15())
-				l.Printf("  mean rate:   %12.2f\n", t.RateMean())
+			} else {
+				l.Printf("  min:         %12.2f%s\n", float64(h.Min())/du, suffix)
+				l.Printf("  max:         %12.2f%s\n", float64(h.Max())/du, suffix)
+			}
+			l.Printf("  mean:        %12.2f%s\n", h.Mean()/du, suffix)
+			l.Printf("  stddev:      %12.2f%s\n", h.StdDev()/du, suffix)
+			l.Printf("  median:      %12.2f%s\n", ps[0]/du, suffix)
+			l.Printf("  80%%:         %12.2f%s\n", ps[1]/du, suffix)
+			l.Printf("  90%%:         %12.2f%s\n", ps[2]/du, suffix)
+			l.Printf("  99%%:         %12.2f%s\n", ps[3]/du, suffix)
+			l.Printf("  99.9%%:       %12.2f%s\n", ps[4]/du, suffix)
+		case Meter:
+			m := metric.Snapshot()
+			l.Printf("meter %s\n", display)
+			l.Printf("  count:       %9d\n", m.Count())
+			l.Printf("  1-min rate:  %12.2f\n", m.Rate1())
+			l.Printf("  5-min rate:  %12.2f\n", m.Rate5())
+			l.Printf("  15-min rate: %12.2f\n", m.Rate15())
+			l.Printf("  mean rate:   %12.2f\n", m.RateMean())
+		case Timer:
+			t := metric.Snapshot()
+			ps := t.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
+			l.Printf("timer %s\n", display)
+			l.Printf("  count:       %9d\n", t.Count())
+			l.Printf("  min:         %12.2f%s\n", float64(t.Min())/du, duSuffix)
+			l.Printf("  max:         %12.2f%s\n", float64(t.Max())/du, duSuffix)
+			l.Printf("  mean:        %12.2f%s\n", t.Mean()/du, duSuffix)
+			l.Printf("  stddev:      %12.2f%s\n", t.StdDev()/du, duSuffix)
+			l.Printf("  median:      %12.2f%s\n", ps[0]/du, duSuffix)
+			l.Printf("  80%%:         %12.2f%s\n", ps[1]/du, duSuffix)
+			l.Printf("  90%%:         %12.2f%s\n", ps[2]/du, duSuffix)
+			l.Printf("  99%%:         %12.2f%s\n", ps[3]/du, duSuffix)
+			l.Printf("  99.9%%:       %12.2f%s\n", ps[4]/du, duSuffix)
+			l.Printf("  1-min rate:  %12.2f\n", t.Rate1())
+			l.Printf("  5-min rate:  %12.2f\n", t.Rate5())
+			l.Printf("  15-min rate: %12.2f\n", t.Rate15())
+			l.Printf("  mean rate:   %12.2f\n", t.RateMean())
+		}
+	}
+
+	logOnce := func() {
+		if !TextBreakdown {
+			limit.Each(r, func(name string, i interface{}) {
+				logMetric(name, name, i)
+			})
+			return
+		}
+		for _, item := range breakdownItems(func(f func(string, interface{})) {
+			limit.Each(r, f)
+		}) {
+			if item.header != "" {
+				l.Printf("%s\n", item.header)
 			}
+			logMetric(item.display, item.name, item.m)
+		}
+	}
+	RunPeriodicContext(ctx, logInterval(freq), func() { timeExport("log", logOnce) })
+}
+
+// LogLimitMode selects which metrics survive when a log tick is capped.
+type LogLimitMode int
+
+const (
+	// LogTopN logs the Max metrics with the largest magnitude: the count of
+	// counters, meters, histograms and timers and the absolute value of
+	// gauges.
+	LogTopN LogLimitMode = iota
+
+	// LogRoundRobin logs the next Max metrics in name order on every tick,
+	// wrapping around, so every metric is eventually logged.
+	LogRoundRobin
+)
+
+// LogLimit caps the number of metrics the log writers emit per tick, so
+// registries with tens of thousands of tagged metrics don't flood the logs.
+// A nil *LogLimit or a Max of zero or less disables the cap.
+type LogLimit struct {
+	Max  int
+	Mode LogLimitMode
+
+	mutex sync.Mutex
+	next  int // round-robin cursor
+}
+
+// NewLogLimit constructs a LogLimit keeping max metrics per tick.
+func NewLogLimit(max int, mode LogLimitMode) *LogLimit {
+	return &LogLimit{Max: max, Mode: mode}
+}
+
+// Each calls f for the metrics of r selected for this tick, in name order.
+func (ll *LogLimit) Each(r Registry, f func(string, interface{})) {
+	if ll == nil || ll.Max <= 0 {
+		r.Each(f)
+		return
+	}
+
+	var namedMetrics namedMetricSlice
+	r.Each(func(name string, i interface{}) {
+		namedMetrics = append(namedMetrics, namedMetric{name, i})
+	})
+	if len(namedMetrics) <= ll.Max {
+		for _, nm := range namedMetrics {
+			f(nm.name, nm.m)
+		}
+		return
+	}
+
+	var selected namedMetricSlice
+	switch ll.Mode {
+	case LogRoundRobin:
+		ll.mutex.Lock()
+		start := ll.next % len(namedMetrics)
+		ll.next = (start + ll.Max) % len(namedMetrics)
+		ll.mutex.Unlock()
+		for i := 0; i < ll.Max; i++ {
+			selected = append(selected, namedMetrics[(start+i)%len(namedMetrics)])
+		}
+	default:
+		magnitudes := make(map[string]float64, len(namedMetrics))
+		for _, nm := range namedMetrics {
+			magnitudes[nm.name] = logMagnitude(nm.m)
+		}
+		sort.SliceStable(namedMetrics, func(i, j int) bool {
+			return magnitudes[namedMetrics[i].name] > magnitudes[namedMetrics[j].name]
 		})
+		selected = namedMetrics[:ll.Max]
+	}
+
+	sort.Sort(selected)
+	for _, nm := range selected {
+		f(nm.name, nm.m)
+	}
+}
+
+// logMagnitude returns the value used to rank a metric for LogTopN.
+func logMagnitude(i interface{}) float64 {
+	switch metric := i.(type) {
+	case Counter:
+		return math.Abs(float64(metric.Count()))
+	case CounterFloat64:
+		return math.Abs(metric.Count())
+	case Instant:
+		return math.Abs(float64(metric.Count()))
+	case Gauge:
+		return math.Abs(float64(metric.Value()))
+	case GaugeFloat64:
+		return math.Abs(metric.Value())
+	case Histogram:
+		return float64(metric.Count())
+	case Meter:
+		return float64(metric.Count())
+	case Timer:
+		return float64(metric.Count())
 	}
+	return 0
 }
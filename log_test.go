@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLogLimitTopN(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("a", r).Inc(1)
+	NewRegisteredCounter("b", r).Inc(30)
+	NewRegisteredGauge("c", r).Update(-20)
+	NewRegisteredCounter("d", r).Inc(10)
+
+	var names []string
+	NewLogLimit(2, LogTopN).Each(r, func(name string, i interface{}) {
+		names = append(names, name)
+	})
+	if got := strings.Join(names, ","); got != "b,c" {
+		t.Fatal(got)
+	}
+}
+
+func TestLogLimitRoundRobin(t *testing.T) {
+	r := NewRegistry()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		NewRegisteredCounter(name, r)
+	}
+
+	ll := NewLogLimit(2, LogRoundRobin)
+	for _, want := range []string{"a,b", "c,d", "a,e", "b,c"} {
+		var names []string
+		ll.Each(r, func(name string, i interface{}) {
+			names = append(names, name)
+		})
+		if got := strings.Join(names, ","); got != want {
+			t.Fatalf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestLogLimitNil(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("a", r)
+	NewRegisteredCounter("b", r)
+
+	var ll *LogLimit
+	i := 0
+	ll.Each(r, func(string, interface{}) { i++ })
+	if i != 2 {
+		t.Fatal(i)
+	}
+}
+
+func TestWriteOnceLimit(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("a", r).Inc(1)
+	NewRegisteredCounter("b", r).Inc(2)
+
+	b := &bytes.Buffer{}
+	WriteOnceLimit(r, b, NewLogLimit(1, LogTopN))
+	if s := b.String(); s != "counter b\n  count:               2\n" {
+		t.Fatal(s)
+	}
+}
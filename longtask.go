@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// LongTaskTimer tracks operations while they run rather than when they end,
+// as a Timer does: how many are active and how long the oldest of them has
+// been running.  A stuck matchmaking round or batch job shows up as a
+// duration that keeps growing.
+type LongTaskTimer interface {
+	Active() int
+	Max() time.Duration
+	Start() *LongTask
+	Time(func())
+}
+
+// NewLongTaskTimer constructs a new StandardLongTaskTimer.
+func NewLongTaskTimer() LongTaskTimer {
+	if UseNilMetrics {
+		return NilLongTaskTimer{}
+	}
+	return &StandardLongTaskTimer{clock: DefaultClock, active: make(map[uint64]time.Time)}
+}
+
+// NewRegisteredLongTaskTimer constructs a new LongTaskTimer and registers
+// gauges of it: name.active, the number of active tasks, and name.max, the
+// nanoseconds the longest-running one has been running.
+func NewRegisteredLongTaskTimer(name string, r Registry) LongTaskTimer {
+	t := NewLongTaskTimer()
+	if nil == r {
+		r = DefaultRegistry
+	}
+	r.Register(name+".active", NewFunctionalGauge(func() int64 { return int64(t.Active()) }))
+	r.Register(name+".max", NewFunctionalGauge(func() int64 { return int64(t.Max()) }))
+	return t
+}
+
+// NilLongTaskTimer is a no-op LongTaskTimer.
+type NilLongTaskTimer struct{}
+
+// Active is a no-op.
+func (NilLongTaskTimer) Active() int { return 0 }
+
+// Max is a no-op.
+func (NilLongTaskTimer) Max() time.Duration { return 0 }
+
+// Start returns a LongTask whose Stop is a no-op.
+func (NilLongTaskTimer) Start() *LongTask { return &LongTask{} }
+
+// Time calls f.
+func (NilLongTaskTimer) Time(f func()) { f() }
+
+// StandardLongTaskTimer is the standard implementation of a LongTaskTimer.
+type StandardLongTaskTimer struct {
+	clock Clock
+
+	mutex  sync.Mutex
+	next   uint64
+	active map[uint64]time.Time
+}
+
+// LongTask is one operation being tracked by a LongTaskTimer.
+type LongTask struct {
+	timer *StandardLongTaskTimer // nil for the tasks of a NilLongTaskTimer
+	id    uint64
+}
+
+// Start starts tracking a task.
+func (t *StandardLongTaskTimer) Start() *LongTask {
+	now := t.clock.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.next++
+	t.active[t.next] = now
+	return &LongTask{timer: t, id: t.next}
+}
+
+// Time tracks f while it runs.
+func (t *StandardLongTaskTimer) Time(f func()) {
+	task := t.Start()
+	defer task.Stop()
+	f()
+}
+
+// Active returns the number of tasks running.
+func (t *StandardLongTaskTimer) Active() int {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return len(t.active)
+}
+
+// Max returns how long the longest-running task has been running, or zero
+// if none is.
+func (t *StandardLongTaskTimer) Max() time.Duration {
+	now := t.clock.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	var max time.Duration
+	for _, start := range t.active {
+		if d := now.Sub(start); d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+// Stop stops tracking the task and returns how long it ran.  Stopping a task
+// twice has no effect the second time and returns zero.
+func (task *LongTask) Stop() time.Duration {
+	t := task.timer
+	if nil == t {
+		return 0
+	}
+	now := t.clock.Now()
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	start, ok := t.active[task.id]
+	if !ok {
+		return 0
+	}
+	delete(t.active, task.id)
+	return now.Sub(start)
+}
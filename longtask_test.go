@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLongTaskTimer(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	lt := NewRegisteredLongTaskTimer("matchmaking", r)
+	first := lt.Start()
+	clock.Add(time.Minute)
+	second := lt.Start()
+	clock.Add(time.Second)
+
+	if n := r.Get("matchmaking.active").(Gauge).Value(); 2 != n {
+		t.Errorf("matchmaking.active: 2 != %v", n)
+	}
+	if max := r.Get("matchmaking.max").(Gauge).Value(); int64(61*time.Second) != max {
+		t.Errorf("matchmaking.max: 61s != %v", time.Duration(max))
+	}
+
+	if d := first.Stop(); 61*time.Second != d {
+		t.Errorf("first.Stop(): 61s != %v", d)
+	}
+	if d := first.Stop(); 0 != d {
+		t.Errorf("first.Stop() again: 0 != %v", d)
+	}
+	if max := lt.Max(); time.Second != max {
+		t.Errorf("lt.Max(): 1s != %v", max)
+	}
+	second.Stop()
+	if n, max := lt.Active(), lt.Max(); 0 != n || 0 != max {
+		t.Errorf("after stopping: %v, %v", n, max)
+	}
+
+	lt.Time(func() {
+		clock.Add(time.Second)
+		if 1 != lt.Active() {
+			t.Errorf("lt.Active() in Time: %v", lt.Active())
+		}
+	})
+	if 0 != lt.Active() {
+		t.Errorf("lt.Active() after Time: %v", lt.Active())
+	}
+}
@@ -16,6 +16,7 @@ type Meter interface {
 	Rate15() float64
 	RateMean() float64
 	Snapshot() Meter
+	Stop()
 }
 
 // GetOrRegisterMeter returns an existing Meter or constructs and registers a
@@ -24,27 +25,21 @@ func GetOrRegisterMeter(name string, r Registry) Meter {
 	if nil == r {
 		r = DefaultRegistry
 	}
+	if m, ok := r.Get(name).(Meter); ok {
+		return m
+	}
 	return r.GetOrRegister(name, NewMeter).(Meter)
 }
 
-// NewMeter constructs a new StandardMeter and launches a goroutine.
+// NewMeter constructs a new StandardMeter.
 func NewMeter() Meter {
 	if UseNilMetrics {
 		return NilMeter{}
 	}
-	m := newStandardMeter()
-	arbiter.Lock()
-	defer arbiter.Unlock()
-	arbiter.meters = append(arbiter.meters, m)
-	if !arbiter.started {
-		arbiter.started = true
-		go arbiter.tick()
-	}
-	return m
+	return newStandardMeter()
 }
 
-// NewMeter constructs and registers a new StandardMeter and launches a
-// goroutine.
+// NewRegisteredMeter constructs and registers a new StandardMeter.
 func NewRegisteredMeter(name string, r Registry) Meter {
 	c := NewMeter()
 	if nil == r {
@@ -92,6 +87,9 @@ func (m *MeterSnapshot) RateMean() float64 { return m.rateMean }
 // Snapshot returns the snapshot.
 func (m *MeterSnapshot) Snapshot() Meter { return m }
 
+// Stop is a no-op.
+func (m *MeterSnapshot) Stop() {}
+
 // NilMeter is a no-op Meter.
 type NilMeter struct{}
 
@@ -119,21 +117,38 @@ func (NilMeter) RateMean() float64 { return 0.0 }
 // Snapshot is a no-op.
 func (NilMeter) Snapshot() Meter { return NilMeter{} }
 
-// StandardMeter is the standard implementation of a Meter.
+// Stop is a no-op.
+func (NilMeter) Stop() {}
+
+// meterTickInterval is the period the EWMAs of a StandardMeter assume between
+// ticks.
+const meterTickInterval = 5 * time.Second
+
+// StandardMeter is the standard implementation of a Meter.  Rather than being
+// ticked by a background goroutine, it catches up on the ticks it missed
+// whenever it is marked or read, so idle meters cost nothing no matter how
+// many are registered.
 type StandardMeter struct {
 	lock        sync.RWMutex
 	snapshot    *MeterSnapshot
 	a1, a5, a15 EWMA
 	startTime   time.Time
+	lastTick    time.Time
+	clock       Clock
+	stopped     bool
 }
 
 func newStandardMeter() *StandardMeter {
+	clock := DefaultClock
+	now := clock.Now()
 	return &StandardMeter{
 		snapshot:  &MeterSnapshot{},
 		a1:        NewEWMA1(),
 		a5:        NewEWMA5(),
 		a15:       NewEWMA15(),
-		startTime: time.Now(),
+		startTime: now,
+		lastTick:  now,
+		clock:     clock,
 	}
 }
 
@@ -147,13 +162,18 @@ func (m *StandardMeter) Count() int64 {
 
 // Mark records the occurance of n events.
 func (m *StandardMeter) Mark(n int64) {
+	now := m.clock.Now()
 	m.lock.Lock()
 	defer m.lock.Unlock()
+	if m.stopped {
+		return
+	}
+	m.tick(now)
 	m.snapshot.count += n
 	m.a1.Update(n)
 	m.a5.Update(n)
 	m.a15.Update(n)
-	m.updateSnapshot()
+	m.updateSnapshot(now)
 }
 
 // Update records the occurance of n events.
@@ -163,85 +183,92 @@ func (m *StandardMeter) Update(n int64) {
 
 // Rate1 returns the one-minute moving average rate of events per second.
 func (m *StandardMeter) Rate1() float64 {
-	m.lock.RLock()
-	rate1 := m.snapshot.rate1
-	m.lock.RUnlock()
-	return rate1
+	return m.current().rate1
 }
 
 // Rate5 returns the five-minute moving average rate of events per second.
 func (m *StandardMeter) Rate5() float64 {
-	m.lock.RLock()
-	rate5 := m.snapshot.rate5
-	m.lock.RUnlock()
-	return rate5
+	return m.current().rate5
 }
 
 // Rate15 returns the fifteen-minute moving average rate of events per second.
 func (m *StandardMeter) Rate15() float64 {
-	m.lock.RLock()
-	rate15 := m.snapshot.rate15
-	m.lock.RUnlock()
-	return rate15
+	return m.current().rate15
 }
 
 // RateMean returns the meter's mean rate of events per second.
 func (m *StandardMeter) RateMean() float64 {
-	m.lock.RLock()
-	rateMean := m.snapshot.rateMean
-	m.lock.RUnlock()
-	return rateMean
+	return m.current().rateMean
 }
 
 // Snapshot returns a read-only copy of the meter.
 func (m *StandardMeter) Snapshot() Meter {
-	m.lock.RLock()
-	snapshot := *m.snapshot
-	m.lock.RUnlock()
+	snapshot := m.current()
 	return &snapshot
 }
 
-func (m *StandardMeter) updateSnapshot() {
-	// should run with write lock held on m.lock
-	snapshot := m.snapshot
-	snapshot.rate1 = m.a1.Rate()
-	snapshot.rate5 = m.a5.Rate()
-	snapshot.rate15 = m.a15.Rate()
-	snapshot.rateMean = float64(snapshot.count) / time.Since(m.startTime).Seconds()
+// Stop stops the meter: later events are ignored and its rates stay as they
+// were when it stopped.
+func (m *StandardMeter) Stop() {
+	now := m.clock.Now()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if !m.stopped {
+		m.tick(now)
+		m.stopped = true
+	}
 }
 
-func (m *StandardMeter) tick() {
+// current returns a copy of the snapshot, first catching up on missed ticks
+// if any are due.
+func (m *StandardMeter) current() MeterSnapshot {
+	now := m.clock.Now()
+	m.lock.RLock()
+	if m.stopped || now.Sub(m.lastTick) < meterTickInterval {
+		snapshot := *m.snapshot
+		m.lock.RUnlock()
+		return snapshot
+	}
+	m.lock.RUnlock()
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	m.a1.Tick()
-	m.a5.Tick()
-	m.a15.Tick()
-	m.updateSnapshot()
+	if !m.stopped {
+		m.tick(now)
+	}
+	return *m.snapshot
 }
 
-type meterArbiter struct {
-	sync.RWMutex
-	started bool
-	meters  []*StandardMeter
-	ticker  *time.Ticker
+func (m *StandardMeter) updateSnapshot(now time.Time) {
+	// should run with write lock held on m.lock
+	snapshot := m.snapshot
+	snapshot.rate1 = m.a1.Rate()
+	snapshot.rate5 = m.a5.Rate()
+	snapshot.rate15 = m.a15.Rate()
+	snapshot.rateMean = float64(snapshot.count) / now.Sub(m.startTime).Seconds()
 }
 
-var arbiter = meterArbiter{ticker: time.NewTicker(5e9)}
-
-// Ticks meters on the scheduled interval
-func (ma *meterArbiter) tick() {
-	for {
-		select {
-		case <-ma.ticker.C:
-			ma.tickMeters()
-		}
+// tick applies every tick due between the last one and now.  It should run
+// with write lock held on m.lock.
+func (m *StandardMeter) tick(now time.Time) {
+	n := int64(now.Sub(m.lastTick) / meterTickInterval)
+	if n <= 0 {
+		return
 	}
+	tickEWMA(m.a1, n)
+	tickEWMA(m.a5, n)
+	tickEWMA(m.a15, n)
+	m.lastTick = m.lastTick.Add(time.Duration(n) * meterTickInterval)
+	m.updateSnapshot(now)
 }
 
-func (ma *meterArbiter) tickMeters() {
-	ma.RLock()
-	defer ma.RUnlock()
-	for _, meter := range ma.meters {
-		meter.tick()
+// tickEWMA ticks a n times, in constant time for a StandardEWMA.
+func tickEWMA(a EWMA, n int64) {
+	if s, ok := a.(*StandardEWMA); ok {
+		s.tickN(n)
+		return
+	}
+	for i := int64(0); i < n; i++ {
+		a.Tick()
 	}
 }
@@ -13,6 +13,17 @@ func BenchmarkMeter(b *testing.B) {
 	}
 }
 
+// BenchmarkMeterCatchUp measures reading a meter that has been idle for an
+// hour, the worst case of lazy ticking an exporter sees.
+func BenchmarkMeterCatchUp(b *testing.B) {
+	m := newStandardMeter()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.lastTick = m.lastTick.Add(-time.Hour)
+		m.Rate1()
+	}
+}
+
 func TestGetOrRegisterMeter(t *testing.T) {
 	r := NewRegistry()
 	NewRegisteredMeter("foo", r).Mark(47)
@@ -22,18 +33,54 @@ func TestGetOrRegisterMeter(t *testing.T) {
 }
 
 func TestMeterDecay(t *testing.T) {
-	ma := meterArbiter{
-		ticker: time.NewTicker(time.Millisecond),
-	}
 	m := newStandardMeter()
-	ma.meters = append(ma.meters, m)
-	go ma.tick()
 	m.Mark(1)
 	rateMean := m.RateMean()
-	time.Sleep(100 * time.Millisecond)
+
+	// Pretend a minute has passed without any ticks being applied.
+	m.lock.Lock()
+	m.startTime = m.startTime.Add(-time.Minute)
+	m.lastTick = m.lastTick.Add(-time.Minute)
+	m.lock.Unlock()
+
 	if m.RateMean() >= rateMean {
 		t.Error("m.RateMean() didn't decrease")
 	}
+	rate1 := m.Rate1()
+	if rate1 <= 0 || rate1 >= 0.2 {
+		t.Errorf("m.Rate1(): %v", rate1)
+	}
+}
+
+func TestMeterCatchUp(t *testing.T) {
+	m := newStandardMeter()
+	m.Mark(5)
+
+	m.lock.Lock()
+	m.lastTick = m.lastTick.Add(-3 * meterTickInterval)
+	m.lock.Unlock()
+
+	ticked := newStandardMeter()
+	ticked.Mark(5)
+	for i := 0; i < 3; i++ {
+		ticked.a1.Tick()
+	}
+	if rate, want := m.Rate1(), ticked.a1.Rate(); rate != want {
+		t.Errorf("m.Rate1(): %v != %v", rate, want)
+	}
+}
+
+func TestMeterCatchUpYears(t *testing.T) {
+	m := newStandardMeter()
+	m.Mark(5)
+	m.lock.Lock()
+	m.lastTick = m.lastTick.Add(-10 * 365 * 24 * time.Hour)
+	m.lock.Unlock()
+
+	// Ten years of ticks are applied at once rather than one by one.
+	if rate := m.Rate15(); 0 != rate {
+		t.Errorf("m.Rate15(): %v", rate)
+	}
 }
 
 func TestMeterNonzero(t *testing.T) {
@@ -58,3 +105,23 @@ func TestMeterZero(t *testing.T) {
 		t.Errorf("m.Count(): 0 != %v\n", count)
 	}
 }
+
+func TestMeterStop(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	m := NewMeter()
+	m.Mark(5)
+	clock.Add(meterTickInterval)
+	rate := m.Rate1()
+	m.Stop()
+	m.Mark(5)
+	clock.Add(time.Minute)
+	if count := m.Count(); 5 != count {
+		t.Errorf("m.Count(): 5 != %v", count)
+	}
+	if r := m.Rate1(); rate != r {
+		t.Errorf("m.Rate1(): %v != %v", rate, r)
+	}
+}
@@ -8,7 +8,9 @@ package metrics
 import "time"
 
 // UseNilMetrics is checked by the constructor functions for all of the
-// standard metrics.  If it is true, the metric returned is a stub.
+// standard metrics.  If it is true, the metric returned is a stub.  The
+// helpers that build tagged names, such as Measure, Error, CountError and
+// Vec.With, check it too, so that nothing allocates while it is set.
 //
 // This global kill-switch helps quantify the observer effect and makes
 // for less cluttered pprof profiles.
@@ -18,6 +20,15 @@ var TimerWindow int = 100000
 
 var MeterRescaleThreshold time.Duration = 5 * time.Minute
 
+// LogInterval is how often the Log functions print when given an interval
+// of zero.
+var LogInterval time.Duration = time.Minute
+
+// GlobalTags are attached to every data point by exporters that support
+// key=value tags, currently OpenTSDB, for labelling a whole process with
+// e.g. its datacenter or environment.
+var GlobalTags map[string]string
+
 // names for general metrics
 const (
 	RSTAT_ERROR = "error"
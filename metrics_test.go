@@ -1,11 +1,15 @@
 package metrics
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"reflect"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 const FANOUT = 128
@@ -122,3 +126,47 @@ func Example() {
 	// Output: 17
 	// 1
 }
+
+func TestUseNilMetrics(t *testing.T) {
+	defer func(b bool) { UseNilMetrics = b }(UseNilMetrics)
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	UseNilMetrics = true
+	DefaultRegistry = NewRegistry()
+
+	for _, m := range []interface{}{
+		NewCounter(), NewCounterFloat64(), NewGauge(), NewGaugeFloat64(),
+		NewFunctionalGauge(nil), NewFunctionalGaugeFloat64(nil), NewHealthcheck(nil),
+		NewHistogram(nil), NewInstantCounter(), NewMeter(), NewTimer(), NewEWMA(0.5),
+		NewExpDecaySample(10, 0.5), NewUniformSample(10), SampledTimer(NewTimer(), 0.5),
+		NewLongTaskTimer(), NewRate(NewCounter(), time.Second),
+	} {
+		if !strings.HasPrefix(reflect.TypeOf(m).Name(), "Nil") {
+			t.Errorf("%T is not a Nil metric", m)
+		}
+	}
+
+	logins := NewVec(nil, "logins", NewCounter)
+	err := errors.New("boom")
+	GetOrRegisterCounter("requests", nil)
+	GetOrRegisterTimer("latency", nil)
+	if n := testing.AllocsPerRun(100, func() {
+		NewCounter().Inc(1)
+		NewCounterFloat64().Inc(1)
+		NewGauge().Update(1)
+		NewGaugeFloat64().Update(1)
+		NewHistogram(nil).Update(1)
+		NewInstantCounter().Inc(1)
+		NewMeter().Mark(1)
+		NewTimer().Update(1)
+		SampledTimer(NewTimer(), 0.5).Update(1)
+		GetOrRegisterCounter("requests", nil).Inc(1)
+		GetOrRegisterTimer("latency", nil).Update(1)
+		logins.With("lobby", "google").Inc(1)
+		Measure("db.query", "lobby")()
+		NewLongTaskTimer().Time(func() {})
+		Error("lobby", "matchmaking")
+		CountError("db.errors", err)
+	}); 0 != n {
+		t.Errorf("%v allocations", n)
+	}
+}
@@ -0,0 +1,182 @@
+// Package metricstest helps unit-test code that records go-metrics metrics:
+//
+//	func TestLogin(t *testing.T) {
+//		r := metricstest.UseRegistry(t)
+//		clock := metricstest.UseMockClock(t, time.Unix(0, 0))
+//
+//		changes := metrics.Changes(r, func() {
+//			login("alice")
+//			clock.Add(time.Minute)
+//		})
+//
+//		metricstest.AssertCounter(t, r, "login.attempts", 1)
+//		for _, c := range changes {
+//			t.Log(c)
+//		}
+//	}
+//
+// UseRegistry and UseMockClock swap the package globals, so tests using them
+// must not run in parallel.
+package metricstest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+)
+
+// UseRegistry replaces metrics.DefaultRegistry with an empty registry for the
+// rest of the test and returns it.
+func UseRegistry(t testing.TB) metrics.Registry {
+	old := metrics.DefaultRegistry
+	r := metrics.NewRegistry()
+	metrics.DefaultRegistry = r
+	t.Cleanup(func() { metrics.DefaultRegistry = old })
+	return r
+}
+
+// UseMockClock replaces metrics.DefaultClock with a MockClock reading start
+// for the rest of the test and returns it.  Only metrics and exporters
+// constructed after the call use it.
+func UseMockClock(t testing.TB, start time.Time) *metrics.MockClock {
+	old := metrics.DefaultClock
+	clock := metrics.NewMockClock(start)
+	metrics.DefaultClock = clock
+	t.Cleanup(func() { metrics.DefaultClock = old })
+	return clock
+}
+
+// AssertCounter fails the test unless r has a Counter or Instant counter
+// named name with count want.
+func AssertCounter(t testing.TB, r metrics.Registry, name string, want int64) {
+	t.Helper()
+	switch m := r.Get(name).(type) {
+	case metrics.Counter:
+		if got := m.Count(); got != want {
+			t.Errorf("counter %s: count %d, want %d", name, got, want)
+		}
+	case metrics.Instant:
+		if got := m.Count(); got != want {
+			t.Errorf("counter %s: count %d, want %d", name, got, want)
+		}
+	default:
+		t.Errorf("counter %s: got %s", name, describe(m))
+	}
+}
+
+// AssertGauge fails the test unless r has a Gauge named name with value want.
+func AssertGauge(t testing.TB, r metrics.Registry, name string, want int64) {
+	t.Helper()
+	m, ok := r.Get(name).(metrics.Gauge)
+	if !ok {
+		t.Errorf("gauge %s: got %s", name, describe(r.Get(name)))
+		return
+	}
+	if got := m.Value(); got != want {
+		t.Errorf("gauge %s: value %d, want %d", name, got, want)
+	}
+}
+
+// AssertCount fails the test unless r has a meter, timer or histogram named
+// name that has recorded want events.
+func AssertCount(t testing.TB, r metrics.Registry, name string, want int64) {
+	t.Helper()
+	var got int64
+	switch m := r.Get(name).(type) {
+	case metrics.Meter:
+		got = m.Count()
+	case metrics.Timer:
+		got = m.Count()
+	case metrics.Histogram:
+		got = m.Count()
+	default:
+		t.Errorf("%s: got %s, want a meter, timer or histogram", name, describe(m))
+		return
+	}
+	if got != want {
+		t.Errorf("%s: count %d, want %d", name, got, want)
+	}
+}
+
+// AssertRegistered fails the test unless r has a metric named name.
+func AssertRegistered(t testing.TB, r metrics.Registry, name string) {
+	t.Helper()
+	if nil == r.Get(name) {
+		t.Errorf("%s: not registered", name)
+	}
+}
+
+// AssertNotRegistered fails the test if r has a metric named name.
+func AssertNotRegistered(t testing.TB, r metrics.Registry, name string) {
+	t.Helper()
+	if m := r.Get(name); nil != m {
+		t.Errorf("%s: registered as %s", name, describe(m))
+	}
+}
+
+func describe(m interface{}) string {
+	if nil == m {
+		return "no metric"
+	}
+	return fmt.Sprintf("a %T", m)
+}
+
+// Exporter is an in-memory exporter that keeps a metrics.RegistrySnapshot of
+// its registry per export, for testing code that drives exports, such as Flush calls or a
+// MockClock's ticks.
+type Exporter struct {
+	registry metrics.Registry
+
+	mutex     sync.Mutex
+	snapshots []metrics.RegistrySnapshot
+}
+
+// NewExporter constructs an Exporter of r, or metrics.DefaultRegistry if r is
+// nil.
+func NewExporter(r metrics.Registry) *Exporter {
+	if nil == r {
+		r = metrics.DefaultRegistry
+	}
+	return &Exporter{registry: r}
+}
+
+// Export takes a snapshot of the registry now.
+func (e *Exporter) Export() {
+	s := metrics.SnapshotRegistry(e.registry)
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.snapshots = append(e.snapshots, s)
+}
+
+// Run exports every d through metrics.RunPeriodic, so metrics.Flush and the
+// tickers of metrics.DefaultClock drive it.  Like the other exporters it
+// blocks forever; run it in a goroutine.
+func (e *Exporter) Run(d time.Duration) {
+	metrics.RunPeriodic(d, e.Export)
+}
+
+// RunContext is like Run but returns once ctx is done.
+func (e *Exporter) RunContext(ctx context.Context, d time.Duration) {
+	metrics.RunPeriodicContext(ctx, d, e.Export)
+}
+
+// Snapshots returns the snapshots taken so far, oldest first.
+func (e *Exporter) Snapshots() []metrics.RegistrySnapshot {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]metrics.RegistrySnapshot(nil), e.snapshots...)
+}
+
+// Last returns the latest snapshot, or nil if there is none yet.
+func (e *Exporter) Last() metrics.RegistrySnapshot {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if len(e.snapshots) == 0 {
+		return nil
+	}
+	return e.snapshots[len(e.snapshots)-1]
+}
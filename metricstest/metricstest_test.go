@@ -0,0 +1,137 @@
+package metricstest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+)
+
+// fakeTB records the failures of the assertions under test instead of
+// failing the test.
+type fakeTB struct {
+	testing.TB
+	errors []string
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// check fails t unless f made exactly the given assertion failures.
+func check(t *testing.T, want []string, f func(tb testing.TB)) {
+	t.Helper()
+	tb := &fakeTB{TB: t}
+	f(tb)
+	if len(want) != len(tb.errors) {
+		t.Fatalf("%q, want %q", tb.errors, want)
+	}
+	for i := range want {
+		if want[i] != tb.errors[i] {
+			t.Errorf("%q, want %q", tb.errors[i], want[i])
+		}
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	r := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("counter", r).Inc(2)
+	metrics.GetOrRegisterInstantCounter("instant", r).Inc(3)
+	metrics.NewRegisteredGauge("gauge", r).Update(4)
+	metrics.NewRegisteredTimer("timer", r).Update(1)
+
+	check(t, nil, func(tb testing.TB) {
+		AssertCounter(tb, r, "counter", 2)
+		AssertCounter(tb, r, "instant", 3)
+		AssertGauge(tb, r, "gauge", 4)
+		AssertCount(tb, r, "timer", 1)
+		AssertRegistered(tb, r, "gauge")
+		AssertNotRegistered(tb, r, "missing")
+	})
+	check(t, []string{
+		"counter counter: count 2, want 1",
+		"counter instant: count 3, want 1",
+		"counter gauge: got a *metrics.StandardGauge",
+		"counter missing: got no metric",
+		"gauge gauge: value 4, want 5",
+		"gauge counter: got a *metrics.StandardCounter",
+		"timer: count 1, want 2",
+		"gauge: got a *metrics.StandardGauge, want a meter, timer or histogram",
+		"missing: not registered",
+		"gauge: registered as a *metrics.StandardGauge",
+	}, func(tb testing.TB) {
+		AssertCounter(tb, r, "counter", 1)
+		AssertCounter(tb, r, "instant", 1)
+		AssertCounter(tb, r, "gauge", 1)
+		AssertCounter(tb, r, "missing", 1)
+		AssertGauge(tb, r, "gauge", 5)
+		AssertGauge(tb, r, "counter", 5)
+		AssertCount(tb, r, "timer", 2)
+		AssertCount(tb, r, "gauge", 1)
+		AssertRegistered(tb, r, "missing")
+		AssertNotRegistered(tb, r, "gauge")
+	})
+}
+
+func TestUseRegistryAndClock(t *testing.T) {
+	registry, clock := metrics.DefaultRegistry, metrics.DefaultClock
+	t.Run("swapped", func(t *testing.T) {
+		r := UseRegistry(t)
+		c := UseMockClock(t, time.Unix(10, 0))
+		if metrics.DefaultRegistry != r || metrics.DefaultClock != metrics.Clock(c) {
+			t.Error("globals not swapped")
+		}
+		if now := metrics.DefaultClock.Now(); !now.Equal(time.Unix(10, 0)) {
+			t.Error(now)
+		}
+	})
+	if metrics.DefaultRegistry != registry || metrics.DefaultClock != clock {
+		t.Error("globals not restored")
+	}
+}
+
+func TestExporter(t *testing.T) {
+	r := metrics.NewRegistry()
+	c := metrics.NewRegisteredCounter("requests", r)
+	e := NewExporter(r)
+	if nil != e.Last() {
+		t.Error("snapshot before any export")
+	}
+
+	e.Export()
+	c.Inc(3)
+	e.Export()
+	snapshots := e.Snapshots()
+	if 2 != len(snapshots) {
+		t.Fatal(snapshots)
+	}
+	if v := e.Last()["requests"]["count"]; 3 != v {
+		t.Errorf("last count: %v", v)
+	}
+	deltas := metrics.Diff(snapshots[0], snapshots[1])
+	if 1 != len(deltas) || "requests" != deltas[0].Name || 3 != deltas[0].Change("count") {
+		t.Errorf("diff: %v", deltas)
+	}
+	if deltas := metrics.Diff(snapshots[1], snapshots[1]); 0 != len(deltas) {
+		t.Errorf("diff of equal snapshots: %v", deltas)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.RunContext(ctx, time.Hour)
+		close(done)
+	}()
+	for 3 != len(e.Snapshots()) {
+		if err := metrics.Flush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,144 @@
+package metrics
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// NameMissing stands in for a template variable that has no value.
+var NameMissing = "unknown"
+
+// NameOther stands in for the values of a template variable beyond the
+// first MaxNameValues distinct ones.
+var NameOther = "other"
+
+// MaxNameValues caps the distinct values each variable of a NameTemplate
+// expands to, so a variable fed by user input can't create metrics without
+// bound.
+var MaxNameValues = 100
+
+// MaxNameValueLen is the length, in bytes, values are truncated to.
+var MaxNameValueLen = 64
+
+// nameTemplates caches the templates Name parses, by their text.
+var nameTemplates sync.Map
+
+// A NameTemplate builds metric names such as "http.{method}.{route}.latency"
+// from variables.  Values are escaped so they can't add name segments or
+// tags: every character but letters, digits, '-' and '_' becomes '_' and
+// TAG_METRIC_DELIMITER is lowercased.
+type NameTemplate struct {
+	text     string
+	literals []string // len(vars)+1, around the variables
+	vars     []string
+	mutex    sync.Mutex
+	seen     map[string]map[string]struct{}
+}
+
+// ParseNameTemplate parses text, in which each {name} is a variable.  Braces
+// can't be nested or escaped and variable names can't be empty.
+func ParseNameTemplate(text string) (*NameTemplate, error) {
+	t := &NameTemplate{text: text, seen: make(map[string]map[string]struct{})}
+	rest := text
+	for {
+		open := strings.IndexAny(rest, "{}")
+		if open < 0 {
+			t.literals = append(t.literals, rest)
+			return t, nil
+		}
+		if rest[open] == '}' {
+			return nil, fmt.Errorf("metrics: name template %q: unopened }", text)
+		}
+		end := strings.IndexAny(rest[open+1:], "{}")
+		if end < 0 || rest[open+1+end] == '{' {
+			return nil, fmt.Errorf("metrics: name template %q: unclosed {", text)
+		}
+		v := rest[open+1 : open+1+end]
+		if v == "" {
+			return nil, fmt.Errorf("metrics: name template %q: empty variable", text)
+		}
+		t.literals = append(t.literals, rest[:open])
+		t.vars = append(t.vars, v)
+		rest = rest[open+2+end:]
+	}
+}
+
+// MustParseNameTemplate is like ParseNameTemplate but panics if text can't be
+// parsed.
+func MustParseNameTemplate(text string) *NameTemplate {
+	t, err := ParseNameTemplate(text)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Name expands template with vars, parsing it on first use and caching it
+// after.  It panics if template can't be parsed.
+//
+//	metrics.GetOrRegisterTimer(metrics.Name("http.{method}.{route}.latency",
+//		map[string]string{"method": req.Method, "route": route}), nil)
+func Name(template string, vars map[string]string) string {
+	if t, ok := nameTemplates.Load(template); ok {
+		return t.(*NameTemplate).Expand(vars)
+	}
+	t, _ := nameTemplates.LoadOrStore(template, MustParseNameTemplate(template))
+	return t.(*NameTemplate).Expand(vars)
+}
+
+// Expand returns the name with each variable replaced by its escaped value
+// in vars, NameMissing if it has none or NameOther if the variable has
+// already had MaxNameValues others.
+func (t *NameTemplate) Expand(vars map[string]string) string {
+	var b strings.Builder
+	b.WriteString(t.literals[0])
+	for i, v := range t.vars {
+		b.WriteString(t.value(v, vars[v]))
+		b.WriteString(t.literals[i+1])
+	}
+	return b.String()
+}
+
+// String returns the template's text.
+func (t *NameTemplate) String() string { return t.text }
+
+// Vars returns the names of the template's variables in order.
+func (t *NameTemplate) Vars() []string {
+	return append([]string(nil), t.vars...)
+}
+
+func (t *NameTemplate) value(name, v string) string {
+	if v == "" {
+		return NameMissing
+	}
+	if len(v) > MaxNameValueLen {
+		v = v[:MaxNameValueLen]
+	}
+	v = escapeNameValue(v)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	seen, ok := t.seen[name]
+	if !ok {
+		seen = make(map[string]struct{})
+		t.seen[name] = seen
+	}
+	if _, ok := seen[v]; ok {
+		return v
+	}
+	if len(seen) >= MaxNameValues {
+		return NameOther
+	}
+	seen[v] = struct{}{}
+	return v
+}
+
+// escapeNameValue escapes v for use as part of a name or as a tag, as
+// NameTemplate describes.
+func escapeNameValue(v string) string {
+	v = replaceInvalid(v, func(_ int, r rune) bool {
+		return isAlnum(r) || r == '-' || r == '_'
+	})
+	return strings.Replace(v, TAG_METRIC_DELIMITER, strings.ToLower(TAG_METRIC_DELIMITER), -1)
+}
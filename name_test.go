@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	name := Name("http.{method}.{route}.latency", map[string]string{"method": "GET", "route": "users"})
+	if name != "http.GET.users.latency" {
+		t.Fatal(name)
+	}
+	if _, ok := nameTemplates.Load("http.{method}.{route}.latency"); !ok {
+		t.Error("template not cached")
+	}
+	if name := Name("plain", nil); name != "plain" {
+		t.Fatal(name)
+	}
+}
+
+func TestNameEscaping(t *testing.T) {
+	tmpl := MustParseNameTemplate("{a}.{b}.{c}")
+	name := tmpl.Expand(map[string]string{"a": "x.y|z", "b": "fooTAGbar"})
+	if name != "x_y_z.footagbar.unknown" {
+		t.Fatal(name)
+	}
+	if IsTagged(name) {
+		t.Error("value injected a tag")
+	}
+	defer func(n int) { MaxNameValueLen = n }(MaxNameValueLen)
+	MaxNameValueLen = 3
+	if name := tmpl.Expand(map[string]string{"a": "abcdef", "b": "b", "c": "c"}); name != "abc.b.c" {
+		t.Fatal(name)
+	}
+}
+
+func TestNameCardinality(t *testing.T) {
+	defer func(n int) { MaxNameValues = n }(MaxNameValues)
+	MaxNameValues = 3
+	tmpl := MustParseNameTemplate("users.{id}")
+	for i := 0; i < 3; i++ {
+		if name := tmpl.Expand(map[string]string{"id": strconv.Itoa(i)}); name != "users."+strconv.Itoa(i) {
+			t.Fatal(name)
+		}
+	}
+	if name := tmpl.Expand(map[string]string{"id": "99"}); name != "users.other" {
+		t.Fatal(name)
+	}
+	if name := tmpl.Expand(map[string]string{"id": "1"}); name != "users.1" {
+		t.Fatal(name)
+	}
+}
+
+func TestParseNameTemplateErrors(t *testing.T) {
+	for _, text := range []string{"a.{b", "a.}b", "a.{}", "a.{b{c}}"} {
+		if _, err := ParseNameTemplate(text); err == nil {
+			t.Errorf("%q parsed", text)
+		}
+	}
+	tmpl, err := ParseNameTemplate("{x}{y}.z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vars := tmpl.Vars(); len(vars) != 2 || vars[0] != "x" || vars[1] != "y" {
+		t.Fatal(vars)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("Name didn't panic")
+		}
+	}()
+	Name("bad.{", nil)
+}
@@ -0,0 +1,138 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcNetDev is the file NetworkStats reads interface counters from.
+var ProcNetDev = "/proc/net/dev"
+
+// NetworkStats publishes the traffic of network interfaces from /proc/net/dev
+// under a common prefix.  For a prefix of "net." and the interface eth0 it
+// registers the meters net.eth0.ReceiveBytes, .ReceivePackets, .TransmitBytes
+// and .TransmitPackets and the counters net.eth0.ReceiveErrors,
+// .ReceiveDrops, .TransmitErrors and .TransmitDrops.  The first capture only
+// records a baseline, so counts start from the moment capturing began.  It is
+// only available on Linux.
+type NetworkStats struct {
+	registry   Registry
+	prefix     string
+	interfaces map[string]bool
+	errors     Counter
+	last       map[string][]int64
+}
+
+// NewNetworkStats constructs a NetworkStats for the given interfaces, or for
+// all interfaces if none are given.
+func NewNetworkStats(r Registry, prefix string, interfaces ...string) *NetworkStats {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	ns := &NetworkStats{
+		registry: r,
+		prefix:   prefix,
+		errors:   GetOrRegisterCounter(prefix+"ReadErrors", r),
+		last:     make(map[string][]int64),
+	}
+	if len(interfaces) > 0 {
+		ns.interfaces = make(map[string]bool, len(interfaces))
+		for _, iface := range interfaces {
+			ns.interfaces[iface] = true
+		}
+	}
+	return ns
+}
+
+// Capture new values for the interface counters.  This is designed to be
+// called as a goroutine.
+func (ns *NetworkStats) Capture(d time.Duration) {
+	for _ = range time.Tick(d) {
+		ns.CaptureOnce()
+	}
+}
+
+// CaptureOnce captures new values for the interface counters.
+func (ns *NetworkStats) CaptureOnce() {
+	ns.Collect(ns.registry)
+}
+
+// Collect captures new values for the interface counters into r.
+func (ns *NetworkStats) Collect(r Registry) {
+	f, err := os.Open(ProcNetDev)
+	if err != nil {
+		ns.errors.Inc(1)
+		return
+	}
+	defer f.Close()
+	if err := ns.update(r, f); err != nil {
+		ns.errors.Inc(1)
+	}
+}
+
+// Columns of /proc/net/dev after the interface name.
+const (
+	netRxBytes   = 0
+	netRxPackets = 1
+	netRxErrs    = 2
+	netRxDrop    = 3
+	netTxBytes   = 8
+	netTxPackets = 9
+	netTxErrs    = 10
+	netTxDrop    = 11
+	netColumns   = 16
+)
+
+func (ns *NetworkStats) update(r Registry, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := scanner.Text()
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue // header
+		}
+		iface := strings.TrimSpace(line[:i])
+		if ns.interfaces != nil && !ns.interfaces[iface] {
+			continue
+		}
+		fields := strings.Fields(line[i+1:])
+		if len(fields) < netColumns {
+			continue
+		}
+		values := make([]int64, netColumns)
+		for j := range values {
+			v, err := strconv.ParseInt(fields[j], 10, 64)
+			if err != nil {
+				return err
+			}
+			values[j] = v
+		}
+
+		last, ok := ns.last[iface]
+		ns.last[iface] = values
+		if !ok {
+			continue
+		}
+		delta := func(column int) int64 {
+			// Counters reset when an interface is recreated.
+			if values[column] < last[column] {
+				return values[column]
+			}
+			return values[column] - last[column]
+		}
+		name := ns.prefix + iface
+		GetOrRegisterMeter(name+".ReceiveBytes", r).Mark(delta(netRxBytes))
+		GetOrRegisterMeter(name+".ReceivePackets", r).Mark(delta(netRxPackets))
+		GetOrRegisterCounter(name+".ReceiveErrors", r).Inc(delta(netRxErrs))
+		GetOrRegisterCounter(name+".ReceiveDrops", r).Inc(delta(netRxDrop))
+		GetOrRegisterMeter(name+".TransmitBytes", r).Mark(delta(netTxBytes))
+		GetOrRegisterMeter(name+".TransmitPackets", r).Mark(delta(netTxPackets))
+		GetOrRegisterCounter(name+".TransmitErrors", r).Inc(delta(netTxErrs))
+		GetOrRegisterCounter(name+".TransmitDrops", r).Inc(delta(netTxDrop))
+	}
+	return scanner.Err()
+}
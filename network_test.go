@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+const testNetDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+
+func TestNetworkStats(t *testing.T) {
+	r := NewRegistry()
+	ns := NewNetworkStats(r, "net.", "eth0")
+
+	if err := ns.update(r, strings.NewReader(testNetDevHeader+
+		"    lo:  100 10 0 0 0 0 0 0  100 10 0 0 0 0 0 0\n"+
+		"  eth0: 1000 20 1 2 0 0 0 0 3000 30 0 1 0 0 0 0\n")); err != nil {
+		t.Fatal(err)
+	}
+	if r.Get("net.eth0.ReceiveBytes") != nil {
+		t.Fatal("first capture should only record a baseline")
+	}
+
+	if err := ns.update(r, strings.NewReader(testNetDevHeader+
+		"    lo:  200 20 0 0 0 0 0 0  200 20 0 0 0 0 0 0\n"+
+		"  eth0: 1500 25 4 2 0 0 0 0 3900 33 0 3 0 0 0 0\n")); err != nil {
+		t.Fatal(err)
+	}
+	if r.Get("net.lo.ReceiveBytes") != nil {
+		t.Fatal("unselected interface exported")
+	}
+	for name, want := range map[string]int64{
+		"net.eth0.ReceiveBytes":    500,
+		"net.eth0.ReceivePackets":  5,
+		"net.eth0.TransmitBytes":   900,
+		"net.eth0.TransmitPackets": 3,
+	} {
+		if c := r.Get(name).(Meter).Count(); c != want {
+			t.Fatalf("%s: got %d, want %d", name, c, want)
+		}
+	}
+	for name, want := range map[string]int64{
+		"net.eth0.ReceiveErrors":  3,
+		"net.eth0.ReceiveDrops":   0,
+		"net.eth0.TransmitErrors": 0,
+		"net.eth0.TransmitDrops":  2,
+	} {
+		if c := r.Get(name).(Counter).Count(); c != want {
+			t.Fatalf("%s: got %d, want %d", name, c, want)
+		}
+	}
+}
@@ -10,6 +10,31 @@ type ConfigOptronDef struct {
 	Address        string
 	HasBulkSupport bool `json:",string"`
 	BatchSize      int  `json:",string"`
+
+	// EncryptionKey, when set, is the base64 AES key payloads are sealed
+	// with by a metrics.PayloadCipher, for collectors holding the same key.
+	EncryptionKey string
+
+	// Routes send the metrics whose tags match to other collectors, such
+	// as those of the payments namespace to a PCI collector, each with a
+	// connection of its own.  A metric goes to the first route it matches
+	// and to Address if it matches none:
+	//
+	//	"Routes": [{"Tags": {"ns": "payments"}, "Address": "pci-optron:7000"}]
+	Routes []RouteDef
+}
+
+// RouteDef declares a route of an optron config.
+type RouteDef struct {
+	// Tags are the tags, keyed ns, grp, tgt, act and sub, a metric must
+	// all have to be sent to Address.
+	Tags map[string]string
+
+	Address string
+
+	// EncryptionKey seals the payloads of the route like the config's, and
+	// isn't inherited from it.
+	EncryptionKey string
 }
 
 func getOptronConfig(configUri string) (*ConfigOptronDef, error) {
@@ -1,9 +1,11 @@
 package optron
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/moonfrog/nucleus/utils"
@@ -18,11 +20,32 @@ type Optron struct {
 	name     string
 	game     string
 	config   *ConfigOptronDef
-	conn     *net.TCPConn
+	routes   []*route // the config's Routes, then the default route
 	interval time.Duration
-	working  bool
 	l        Logger
-	builder  *OptronObjBuilder
+	group    *metrics.RegistryGroup
+	registry metrics.Registry
+}
+
+// route is a collector, with a connection of its own, that the metrics whose
+// tags match are sent to.
+type route struct {
+	address string
+	tags    map[string]string // all must match; none for the default route
+	conn    *net.TCPConn
+	working bool
+	cipher  *metrics.PayloadCipher // seals payloads if the route has a key
+	builder *OptronObjBuilder
+}
+
+// matches reports whether a metric with the given tags goes to r.
+func (r *route) matches(tags map[string]string) bool {
+	for k, v := range r.tags {
+		if tags[k] != v {
+			return false
+		}
+	}
+	return true
 }
 
 type OptronObjBuilder struct {
@@ -67,59 +90,157 @@ func (ob *OptronObjBuilder) flush() []interface{} {
 }
 
 func (this *Optron) init(configUri string) error {
-	var err error
-	this.config, err = getOptronConfig(configUri)
+	config, err := getOptronConfig(configUri)
 	if err != nil {
 		return fmt.Errorf("optron config: get: %v", err)
 	}
+	return this.configure(config)
+}
 
+// configure sets the config of the optron and builds its routes.
+func (this *Optron) configure(config *ConfigOptronDef) error {
+	this.config = config
 	if this.config.HasBulkSupport && this.config.BatchSize < 1 {
 		return fmt.Errorf("optron config: Invalid batch size: %v", this.config.BatchSize)
 	}
 
-	this.builder = &OptronObjBuilder{
-		hasBulkSupport: this.config.HasBulkSupport,
-		batchSize:      this.config.BatchSize,
-		standaloneObj:  make(map[string]interface{}),
+	for i, def := range this.config.Routes {
+		if def.Address == "" || len(def.Tags) == 0 {
+			return fmt.Errorf("optron config: route %d: Address and Tags are required", i)
+		}
+		r, err := this.newRoute(def.Address, def.EncryptionKey, def.Tags)
+		if err != nil {
+			return fmt.Errorf("optron config: route %d: %v", i, err)
+		}
+		this.routes = append(this.routes, r)
 	}
+	r, err := this.newRoute(this.config.Address, this.config.EncryptionKey, nil)
+	if err != nil {
+		return fmt.Errorf("optron config: %v", err)
+	}
+	this.routes = append(this.routes, r)
 	return nil
 }
 
+func (this *Optron) newRoute(address, encryptionKey string, tags map[string]string) (*route, error) {
+	r := &route{
+		address: address,
+		tags:    tags,
+		builder: &OptronObjBuilder{
+			hasBulkSupport: this.config.HasBulkSupport,
+			batchSize:      this.config.BatchSize,
+			standaloneObj:  make(map[string]interface{}),
+		},
+	}
+	if encryptionKey != "" {
+		key, err := metrics.ParsePayloadKey(encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		if r.cipher, err = metrics.NewPayloadCipher(key); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
 func (this *Optron) Start() {
-	for range time.Tick(this.interval) {
-		this.send()
+	this.StartContext(context.Background())
+}
+
+// StartContext is Start returning once ctx is done, closing the connections
+// of the routes.
+func (this *Optron) StartContext(ctx context.Context) {
+	metrics.RunPeriodicContext(ctx, this.interval, this.send)
+	for _, r := range this.routes {
+		if r.conn != nil {
+			r.conn.Close()
+			r.conn, r.working = nil, false
+		}
 	}
 }
 
-func (this *Optron) connect() {
-	this.working = false
-	this.l.Printf("Connecting to : %v\n", this.config.Address)
-	conn, err := net.Dial("tcp", this.config.Address)
+// connectTimeout bounds the dial of a route, so a collector that doesn't
+// answer delays a flush by at most this long.
+const connectTimeout = 5 * time.Second
+
+// connect replaces the connection of r, closing the one it had.
+func (this *Optron) connect(r *route) {
+	r.working = false
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	this.l.Printf("Connecting to : %v\n", r.address)
+	conn, err := net.DialTimeout("tcp", r.address, connectTimeout)
 	if err != nil {
 		this.l.Printf("Warn: optron: connect: %v", err)
 	} else {
-		this.conn = conn.(*net.TCPConn)
-		this.working = true
+		r.conn = conn.(*net.TCPConn)
+		r.working = true
 	}
 }
 
+// send sends the metrics to every route, skipping the whole flush while no
+// route is connected.  Routes that are down are reconnected concurrently,
+// so one that doesn't answer doesn't hold up the others, and their metrics
+// are dropped.
 func (this *Optron) send() {
-	if !this.working {
-		this.connect()
-		if !this.working {
-			return
+	var wg sync.WaitGroup
+	for _, r := range this.routes {
+		if !r.working {
+			wg.Add(1)
+			go func(r *route) {
+				defer wg.Done()
+				this.connect(r)
+			}(r)
 		}
 	}
+	wg.Wait()
+	working := false
+	for _, r := range this.routes {
+		working = working || r.working
+	}
+	if !working {
+		return
+	}
 
-	metrics.DefaultRegistry.Each(func(name string, m interface{}) {
+	if this.group == nil {
+		r := this.registry
+		if r == nil {
+			r = metrics.DefaultRegistry
+		}
+		this.sendRegistry(r, this.game)
+		return
+	}
+	this.sendRegistry(this.group.Global, this.game)
+	this.group.Each(func(r *metrics.GameRegistry) {
+		this.sendRegistry(r, r.Game())
+	})
+}
+
+// route returns the first route the metric with the given tags matches.
+func (this *Optron) route(tags map[string]string) *route {
+	for _, r := range this.routes {
+		if r.matches(tags) {
+			return r
+		}
+	}
+	return this.routes[len(this.routes)-1]
+}
+
+// sendRegistry sends the metrics of r labelled with game.
+func (this *Optron) sendRegistry(r metrics.Registry, game string) {
+	r.EachUnsorted(func(name string, m interface{}) {
 
 		optronObj := map[string]interface{}{
 			"hostName": utils.GetIpAddress(),
 			"id":       this.name,
-			"game":     this.game}
+			"game":     game}
 
+		scale := metrics.SecondsScale.Divisor(name, m)
+		var tagMap map[string]string
 		if metrics.IsTagged(name) {
-			var tagMap map[string]string
 			name, tagMap = metrics.ParseTaggedMetric(name)
 			for k, v := range tagMap {
 				optronObj[k] = v
@@ -132,6 +253,8 @@ func (this *Optron) send() {
 			metric.Clear()
 		case metrics.Counter:
 			optronObj[name] = metric.Count()
+		case metrics.CounterFloat64:
+			optronObj[name] = metric.Count()
 		case metrics.Gauge:
 			optronObj[name] = metric.Value()
 		case metrics.GaugeFloat64:
@@ -141,7 +264,7 @@ func (this *Optron) send() {
 			optronObj[name] = metric.Error()
 		case metrics.Histogram:
 			h := metric.Snapshot()
-			optronObj[name+"_avg"] = h.Mean()
+			optronObj[name+"_avg"] = h.Mean() / scale
 		case metrics.Meter:
 			m := metric.Snapshot()
 			optronObj[name+"_1MR"] = m.Rate1()
@@ -149,7 +272,6 @@ func (this *Optron) send() {
 			optronObj[name+"_15MR"] = m.Rate15()
 			optronObj[name+"_avg"] = m.RateMean()
 		case metrics.Timer:
-			scale := float64(time.Second)
 			t := metric.Snapshot()
 			ps := t.Percentiles([]float64{0.5, 0.80, 0.90, 0.95, 0.99})
 			optronObj[name+"_avg"] = ps[0] / scale
@@ -159,22 +281,38 @@ func (this *Optron) send() {
 			optronObj[name+"_99"] = ps[4] / scale
 		}
 
-		this.builder.append(optronObj)
+		this.route(tagMap).builder.append(optronObj)
 	})
 
-	content := this.builder.flush()
+	for _, r := range this.routes {
+		this.write(r)
+	}
+}
+
+// write sends the metrics appended to r's builder to r's collector.
+func (this *Optron) write(r *route) {
+	content := r.builder.flush()
+	if !r.working {
+		return
+	}
 	for _, data := range content {
+		if obj, ok := data.(map[string]interface{}); ok && len(obj) == 0 {
+			continue
+		}
 		dataToPost, err := json.Marshal(data)
 		if err != nil {
 			this.l.Printf("ERROR: optron: marshal: %#v %v", data, err)
 			return
 		}
 
+		if r.cipher != nil {
+			dataToPost = r.cipher.Seal(dataToPost)
+		}
 		dataToPost = append(dataToPost, []byte("\r\n")...)
-		_, err = this.conn.Write(dataToPost)
+		_, err = r.conn.Write(dataToPost)
 		if err != nil {
 			this.l.Printf("Warn: optron: send: %v", err)
-			this.connect()
+			this.connect(r)
 		}
 	}
 }
@@ -197,3 +335,16 @@ func NewForGame(game, name, configUri string, interval time.Duration, l Logger)
 	}
 	return o, o.init(configUri)
 }
+
+// NewForGroup is like New but sends the metrics of every game in group, each
+// labelled with its game, along with the group's global metrics, which are
+// labelled with no game.
+func NewForGroup(group *metrics.RegistryGroup, name, configUri string, interval time.Duration, l Logger) (*Optron, error) {
+	o := &Optron{
+		group:    group,
+		name:     name,
+		interval: interval,
+		l:        l,
+	}
+	return o, o.init(configUri)
+}
@@ -0,0 +1,103 @@
+package optron
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type nopLogger struct{}
+
+func (nopLogger) Printf(string, ...interface{}) {}
+
+func newTestOptron(t *testing.T, config *ConfigOptronDef) *Optron {
+	o := &Optron{name: "test", interval: time.Minute, l: nopLogger{}}
+	if err := o.configure(config); err != nil {
+		t.Fatal(err)
+	}
+	return o
+}
+
+func TestRouteMatches(t *testing.T) {
+	r := &route{tags: map[string]string{"ns": "payments", "grp": "pci"}}
+	for _, c := range []struct {
+		tags map[string]string
+		want bool
+	}{
+		{map[string]string{"ns": "payments", "grp": "pci"}, true},
+		{map[string]string{"ns": "payments", "grp": "pci", "tgt": "card"}, true},
+		{map[string]string{"ns": "payments"}, false},
+		{map[string]string{"ns": "lobby", "grp": "pci"}, false},
+		{nil, false},
+	} {
+		if got := r.matches(c.tags); c.want != got {
+			t.Errorf("%v: %v, want %v", c.tags, got, c.want)
+		}
+	}
+	if !(&route{}).matches(nil) {
+		t.Error("default route doesn't match untagged metrics")
+	}
+}
+
+func TestOptronRoute(t *testing.T) {
+	o := newTestOptron(t, &ConfigOptronDef{
+		Address: "default:7000",
+		Routes: []RouteDef{
+			{Tags: map[string]string{"ns": "payments"}, Address: "pci:7000"},
+			{Tags: map[string]string{"ns": "payments", "grp": "refunds"}, Address: "refunds:7000"},
+			{Tags: map[string]string{"ns": "lobby"}, Address: "lobby:7000"},
+		},
+	})
+	for _, c := range []struct {
+		tags map[string]string
+		want string
+	}{
+		{map[string]string{"ns": "payments", "grp": "refunds"}, "pci:7000"},
+		{map[string]string{"ns": "lobby", "grp": "login"}, "lobby:7000"},
+		{map[string]string{"ns": "match"}, "default:7000"},
+		{nil, "default:7000"},
+	} {
+		if got := o.route(c.tags).address; c.want != got {
+			t.Errorf("%v: %s, want %s", c.tags, got, c.want)
+		}
+	}
+
+	o = &Optron{l: nopLogger{}}
+	err := o.configure(&ConfigOptronDef{Address: "default:7000", Routes: []RouteDef{{Address: "pci:7000"}}})
+	if err == nil {
+		t.Error("route without tags accepted")
+	}
+}
+
+func TestOptronConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	o := newTestOptron(t, &ConfigOptronDef{Address: ln.Addr().String()})
+	r := o.routes[0]
+
+	o.connect(r)
+	first, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	o.connect(r)
+	second, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if !r.working {
+		t.Fatal("route not working")
+	}
+
+	// Reconnecting closes the replaced connection.
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := first.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("replaced connection not closed: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package optron
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/moonfrog/go-metrics"
+	"github.com/moonfrog/nucleus/zootils"
+)
+
+func init() {
+	metrics.RegisterPipelineExporter("optron", startPipelineExporter)
+}
+
+// LoadPipelineConfig loads a metrics.PipelineConfig from zookeeper at
+// configUri.  Importing this package also makes "optron" exporters
+// available to metrics.StartPipeline, with the options "name" and "config",
+// the zookeeper URI of the optron config, and optionally "game".
+func LoadPipelineConfig(configUri string) (*metrics.PipelineConfig, error) {
+	config := &metrics.PipelineConfig{}
+	err := zootils.GetInstance().LoadConfig(config, configUri, func(string) {})
+	if err != nil {
+		return nil, fmt.Errorf("optron: pipeline config: load: %v", err)
+	}
+	return config, nil
+}
+
+func startPipelineExporter(ctx context.Context, c metrics.ExporterConfig, r metrics.Registry) error {
+	if c.Addr != "" || c.Prefix != "" || len(c.Tags) > 0 {
+		return fmt.Errorf("addr, prefix and tags aren't supported; the optron config sets the address")
+	}
+	for k := range c.Options {
+		if k != "name" && k != "config" && k != "game" {
+			return fmt.Errorf("unknown option %q", k)
+		}
+	}
+	if c.Options["name"] == "" || c.Options["config"] == "" {
+		return fmt.Errorf("options name and config are required")
+	}
+	d, err := c.IntervalDuration()
+	if err != nil {
+		return err
+	}
+	o, err := NewForGame(c.Options["game"], c.Options["name"], c.Options["config"], d,
+		log.New(os.Stderr, "optron: ", log.Lmicroseconds))
+	if err != nil {
+		return err
+	}
+	o.registry = r
+	go o.StartContext(ctx)
+	return nil
+}
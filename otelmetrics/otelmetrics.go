@@ -0,0 +1,69 @@
+// Package otelmetrics records the durations of OpenTelemetry spans into a
+// go-metrics registry, so services that already trace get latency metrics
+// without timing the same work twice.
+//
+// Every ended span updates a Timer named after the span and tagged ns=otel,
+// grp=<status code>, tgt=<span kind>:
+//
+//	tp := sdktrace.NewTracerProvider(
+//		sdktrace.WithSpanProcessor(otelmetrics.NewSpanProcessor(nil)),
+//	)
+//
+// A span named "GET /users" that ended with an error is recorded in
+// otel|Error|serverTAGGET /users.  Span names should be low-cardinality, as
+// the OpenTelemetry conventions ask, since each one is a Timer.
+package otelmetrics
+
+import (
+	"context"
+	"strings"
+
+	"github.com/moonfrog/go-metrics"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+const namespace = "otel"
+
+// nameEscaper keeps span names from adding tags to the metric names.
+var nameEscaper = strings.NewReplacer(metrics.TAG_DELIMITER, "_", metrics.TAG_METRIC_DELIMITER, strings.ToLower(metrics.TAG_METRIC_DELIMITER))
+
+// SpanProcessor is an sdktrace.SpanProcessor recording every span that ends
+// with RecordSpan.  It keeps nothing, so its Shutdown and ForceFlush do
+// nothing.
+type SpanProcessor struct {
+	registry metrics.Registry
+}
+
+var _ sdktrace.SpanProcessor = (*SpanProcessor)(nil)
+
+// NewSpanProcessor returns a SpanProcessor recording spans into r, or
+// metrics.DefaultRegistry if r is nil.
+func NewSpanProcessor(r metrics.Registry) *SpanProcessor {
+	return &SpanProcessor{registry: r}
+}
+
+// OnStart does nothing; spans are recorded once they end.
+func (p *SpanProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd records s.
+func (p *SpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	RecordSpan(p.registry, s)
+}
+
+// Shutdown does nothing.
+func (p *SpanProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing.
+func (p *SpanProcessor) ForceFlush(context.Context) error { return nil }
+
+// RecordSpan records the duration of the ended span s on its Timer in r, or
+// metrics.DefaultRegistry if r is nil.  It is for span processors and
+// exporters of an application's own that want to record spans as well.
+func RecordSpan(r metrics.Registry, s sdktrace.ReadOnlySpan) {
+	name := s.Name()
+	if name == "" {
+		name = "unnamed"
+	}
+	tb := metrics.NewTagBoard(namespace, s.Status().Code.String(), s.SpanKind().String())
+	metrics.GetOrRegisterTimer(metrics.TaggedMetricName(nameEscaper.Replace(name), tb), r).UpdateTime(s.EndTime().Sub(s.StartTime()))
+}
@@ -0,0 +1,65 @@
+package otelmetrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func timer(r metrics.Registry, name, status, kind string) metrics.Timer {
+	t, _ := r.Get(metrics.TaggedMetricName(name, metrics.NewTagBoard(namespace, status, kind))).(metrics.Timer)
+	return t
+}
+
+func names(r metrics.Registry) []string {
+	var names []string
+	r.Each(func(name string, _ interface{}) { names = append(names, name) })
+	return names
+}
+
+func TestRecordSpan(t *testing.T) {
+	r := metrics.NewRegistry()
+	start := time.Unix(1500000000, 0)
+	for _, s := range []tracetest.SpanStub{
+		{Name: "GET /users", SpanKind: trace.SpanKindServer, StartTime: start, EndTime: start.Add(20 * time.Millisecond), Status: sdktrace.Status{Code: codes.Error}},
+		{Name: "lobby|matchTAGjoin", SpanKind: trace.SpanKindClient, StartTime: start, EndTime: start.Add(time.Millisecond)},
+		{SpanKind: trace.SpanKindInternal, StartTime: start, EndTime: start},
+	} {
+		RecordSpan(r, s.Snapshot())
+	}
+
+	if m := timer(r, "GET /users", "Error", "server"); nil == m || 1 != m.Count() || int64(20*time.Millisecond) != m.Max() {
+		t.Errorf("GET /users: %v", m)
+	}
+	if m := timer(r, "lobby_matchtagjoin", "Unset", "client"); nil == m || 1 != m.Count() {
+		t.Errorf("escaped name: %v", names(r))
+	}
+	if m := timer(r, "unnamed", "Unset", "internal"); nil == m || 1 != m.Count() {
+		t.Errorf("unnamed: %v", names(r))
+	}
+	if 3 != len(names(r)) {
+		t.Errorf("%v, want 3 metrics", names(r))
+	}
+}
+
+func TestSpanProcessor(t *testing.T) {
+	r := metrics.NewRegistry()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(NewSpanProcessor(r)))
+	defer tp.Shutdown(context.Background())
+
+	_, span := tp.Tracer("test").Start(context.Background(), "checkout", trace.WithSpanKind(trace.SpanKindServer))
+	span.SetStatus(codes.Ok, "")
+	if 0 != len(names(r)) {
+		t.Error("span recorded before it ended")
+	}
+	span.End()
+	if m := timer(r, "checkout", "Ok", "server"); nil == m || 1 != m.Count() {
+		t.Errorf("checkout: %v", names(r))
+	}
+}
@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultOutlierInterval is the interval timers keep outliers over when
+// their TimerConfig sets Outliers but no OutlierInterval.
+const DefaultOutlierInterval = time.Minute
+
+// OutlierRecorder is implemented by the standard timers and their snapshots.
+type OutlierRecorder interface {
+	// Outliers returns the largest observations of the last complete
+	// outlier interval, largest first, as exemplars carrying the time each
+	// was recorded at and the labels of those recorded by
+	// UpdateWithExemplar.  It returns nil if the timer keeps no outliers.
+	Outliers() []Exemplar
+}
+
+// outlierSet keeps the k largest observations of the current interval and
+// of the one before it.  Intervals are aligned to multiples of their length,
+// so those of timers with the same interval end together.  It is guarded by
+// the lock of its timer.
+type outlierSet struct {
+	k        int
+	interval time.Duration
+	end      time.Time // of the current interval
+	current  []Exemplar
+	last     []Exemplar
+}
+
+func newOutlierSet(k int, interval time.Duration) *outlierSet {
+	if interval <= 0 {
+		interval = DefaultOutlierInterval
+	}
+	return &outlierSet{k: k, interval: interval}
+}
+
+// roll moves on to the interval holding now, the current interval becoming
+// the last one if it has just ended and both being dropped if they ended
+// longer ago.
+func (o *outlierSet) roll(now time.Time) {
+	if now.Before(o.end) {
+		return
+	}
+	if !now.Before(o.end.Add(o.interval)) {
+		o.last = nil
+	} else {
+		o.last = o.current
+	}
+	o.current = nil
+	o.end = now.Truncate(o.interval).Add(o.interval)
+}
+
+// add records e if it is among the k largest of its interval.
+func (o *outlierSet) add(e Exemplar) {
+	o.roll(e.Time)
+	if len(o.current) == o.k && e.Value <= o.current[o.k-1].Value {
+		return
+	}
+	i := sort.Search(len(o.current), func(i int) bool { return o.current[i].Value < e.Value })
+	if len(o.current) < o.k {
+		o.current = append(o.current, Exemplar{})
+	}
+	copy(o.current[i+1:], o.current[i:])
+	o.current[i] = e
+}
+
+// values returns the outliers of the last interval before now, largest
+// first.
+func (o *outlierSet) values(now time.Time) []Exemplar {
+	o.roll(now)
+	if len(o.last) == 0 {
+		return nil
+	}
+	return append([]Exemplar(nil), o.last...)
+}
+
+// largestExemplars returns the n largest of a and b, largest first.
+func largestExemplars(a, b []Exemplar, n int) []Exemplar {
+	if len(b) == 0 {
+		return a
+	}
+	if len(a) == 0 {
+		return b
+	}
+	all := make([]Exemplar, 0, len(a)+len(b))
+	all = append(append(all, a...), b...)
+	sort.SliceStable(all, func(i, j int) bool { return all[i].Value > all[j].Value })
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimerOutliers(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	tm := NewTimerWithConfig(TimerConfig{Outliers: 3, OutlierInterval: time.Minute})
+	for _, v := range []int64{5, 1, 9, 7, 3} {
+		tm.Update(v)
+	}
+	tm.(ExemplarRecorder).UpdateWithExemplar(8, map[string]string{"trace_id": "abc"})
+	if o := tm.(OutlierRecorder).Outliers(); nil != o {
+		t.Errorf("outliers of an incomplete interval: %v", o)
+	}
+
+	clock.Add(time.Minute)
+	tm.Update(100)
+	o := tm.(OutlierRecorder).Outliers()
+	if 3 != len(o) || 9 != o[0].Value || 8 != o[1].Value || 7 != o[2].Value {
+		t.Fatalf("outliers: %v", o)
+	}
+	if "abc" != o[1].Labels["trace_id"] || !o[1].Time.Equal(time.Unix(0, 0)) {
+		t.Errorf("outlier 8: %+v", o[1])
+	}
+	if s := tm.Snapshot().(OutlierRecorder).Outliers(); 3 != len(s) || 9 != s[0].Value {
+		t.Errorf("snapshot outliers: %v", s)
+	}
+
+	clock.Add(time.Minute)
+	if o := tm.(OutlierRecorder).Outliers(); 1 != len(o) || 100 != o[0].Value {
+		t.Errorf("next interval: %v", o)
+	}
+	clock.Add(2 * time.Minute)
+	if o := tm.(OutlierRecorder).Outliers(); nil != o {
+		t.Errorf("after idle intervals: %v", o)
+	}
+}
+
+func TestTimerOutliersJSON(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	tm := NewTimerWithConfig(TimerConfig{Outliers: 2})
+	r.Register("latency", tm)
+	NewRegisteredTimer("plain", r).Update(1)
+	tm.(ExemplarRecorder).UpdateWithExemplar(42, map[string]string{"trace_id": "abc"})
+	clock.Add(DefaultOutlierInterval)
+
+	b, err := json.Marshal(r)
+	if nil != err {
+		t.Fatal(err)
+	}
+	var data map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(b, &data); nil != err {
+		t.Fatal(err)
+	}
+	if got := string(data["latency"]["outliers"]); !strings.Contains(got, `"value":42`) || !strings.Contains(got, `"trace_id":"abc"`) {
+		t.Errorf("latency outliers: %s", got)
+	}
+	if _, ok := data["plain"]["outliers"]; ok {
+		t.Error("outliers of a timer keeping none")
+	}
+}
+
+func TestTimerSnapshotMergeOutliers(t *testing.T) {
+	a := &TimerSnapshot{histogram: NewHistogram(NewUniformSample(10)).Snapshot().(*HistogramSnapshot), meter: &MeterSnapshot{}, outliers: []Exemplar{{Value: 5}, {Value: 2}}}
+	b := &TimerSnapshot{histogram: NewHistogram(NewUniformSample(10)).Snapshot().(*HistogramSnapshot), meter: &MeterSnapshot{}, outliers: []Exemplar{{Value: 4}}}
+	if o := a.Merge(b).Outliers(); 2 != len(o) || 5 != o[0].Value || 4 != o[1].Value {
+		t.Errorf("merged outliers: %v", o)
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import "time"
+
+// A PhaseTimer times one run of an operation made of named phases, such as
+// the auth, db and serialize phases of a request, recording each phase in a
+// Timer of its own and the whole run in a total Timer:
+//
+//	p := metrics.NewPhaseTimer("login", nil)
+//	defer p.Done()
+//	done := p.Phase("auth")
+//	...
+//	done()
+//	defer p.Phase("db")()
+//
+// The total Timer is named name and the timer of each phase is name tagged
+// with the phase, after any tags name already has, so the phases of an
+// operation are grouped under its name by TextBreakdown and exported with
+// the phase as a tag.  Construct a PhaseTimer per run; they aren't meant to
+// be shared between goroutines.
+type PhaseTimer struct {
+	name  string
+	r     Registry
+	clock Clock
+	start time.Time
+}
+
+// NewPhaseTimer starts timing a run of the operation called name, whose
+// timers are in r, or DefaultRegistry if r is nil.
+func NewPhaseTimer(name string, r Registry) *PhaseTimer {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	p := &PhaseTimer{name: name, r: r, clock: DefaultClock}
+	p.start = p.clock.Now()
+	return p
+}
+
+// Phase starts timing the phase called phase and returns a function that
+// records the time elapsed since on the phase's Timer.
+func (p *PhaseTimer) Phase(phase string) func() {
+	if UseNilMetrics {
+		return nopMeasure
+	}
+	name, start := phaseName(p.name, phase), p.clock.Now()
+	return func() {
+		GetOrRegisterTimer(name, p.r).UpdateTime(p.clock.Now().Sub(start))
+	}
+}
+
+// Done records the time elapsed since the PhaseTimer was constructed on the
+// operation's total Timer.
+func (p *PhaseTimer) Done() {
+	if UseNilMetrics {
+		return
+	}
+	GetOrRegisterTimer(p.name, p.r).UpdateTime(p.clock.Now().Sub(p.start))
+}
+
+// phaseName returns the name of the timer of the given phase of the
+// operation called name: name with phase as its first unset tag.  Phases of
+// names with all five tags set replace the last.
+func phaseName(name, phase string) string {
+	base, tb, err := ParseTagged(name)
+	if err != nil {
+		return taggedName(name, []string{phase})
+	}
+	tags := []string{tb.Ns, tb.Grp, tb.Tgt, tb.Act, tb.Sub}
+	n := 0
+	for n < len(tags)-1 && tags[n] != "" {
+		n++
+	}
+	tags[n] = phase
+	return TaggedMetricName(base, NewTagBoard(tags...))
+}
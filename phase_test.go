@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhaseTimer(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	p := NewPhaseTimer("login", r)
+	done := p.Phase("auth")
+	clock.Add(2 * time.Millisecond)
+	done()
+	db := p.Phase("db")
+	clock.Add(5 * time.Millisecond)
+	db()
+	clock.Add(time.Millisecond)
+	p.Done()
+
+	for name, want := range map[string]time.Duration{
+		"authTAGlogin": 2 * time.Millisecond,
+		"dbTAGlogin":   5 * time.Millisecond,
+		"login":        8 * time.Millisecond,
+	} {
+		tm, ok := r.Get(name).(Timer)
+		if !ok {
+			t.Errorf("%s isn't registered", name)
+			continue
+		}
+		if 1 != tm.Count() || int64(want) != tm.Max() {
+			t.Errorf("%s: count %v, max %v", name, tm.Count(), time.Duration(tm.Max()))
+		}
+	}
+}
+
+func TestPhaseName(t *testing.T) {
+	for name, want := range map[string]string{
+		"login":             "dbTAGlogin",
+		"lobby|apiTAGlogin": "lobby|api|dbTAGlogin",
+		"a|b|c|d|eTAGlogin": "a|b|c|d|dbTAGlogin",
+	} {
+		if got := phaseName(name, "db"); want != got {
+			t.Errorf("phaseName(%q): %q != %q", name, want, got)
+		}
+	}
+}
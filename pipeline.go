@@ -0,0 +1,357 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PipelineConfig declares the exporters StartPipeline starts.  It is
+// usually loaded from JSON by LoadPipelineConfig:
+//
+//	{"exporters": [
+//		{"type": "graphite", "addr": "graphite:2003", "interval": "10s", "prefix": "game"},
+//		{"type": "log", "interval": "1m", "include": ["errors."]},
+//		{"type": "admin", "addr": ":9100"}
+//	]}
+type PipelineConfig struct {
+	Exporters []ExporterConfig `json:"exporters"`
+
+	// Registry is the registry exported.  It defaults to DefaultRegistry.
+	Registry Registry `json:"-"`
+}
+
+// ExporterConfig declares one exporter of a PipelineConfig.
+type ExporterConfig struct {
+	// Type is the kind of exporter: "graphite", "opentsdb", "log",
+	// "admin", "prometheus", "push" or one added by
+	// RegisterPipelineExporter.
+	Type string `json:"type"`
+
+	// Addr is the address the exporter sends to or, for "admin" and
+	// "prometheus", serves on.
+	Addr string `json:"addr,omitempty"`
+
+	// Interval is how often the exporter exports, as a time.Duration string.
+	// It defaults to one minute.
+	Interval string `json:"interval,omitempty"`
+
+	// Prefix is prepended to the exported metric names.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Include, when not empty, limits the exporter to the metrics whose names
+	// start with one of its prefixes, and Exclude drops those whose names
+	// start with one of its.
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	// Tags are added to the exported metrics by the exporters that support
+	// tags: "opentsdb" and any added that do.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// SkipIdle makes the pushing exporters, "graphite", "opentsdb" and
+	// "log", leave out the metrics that haven't been updated since their
+	// previous flush, for registries dominated by idle tagged series.
+	SkipIdle bool `json:"skip_idle,omitempty"`
+
+	// Options holds settings particular to the exporter, such as
+	// "percentiles" for "graphite", "scale" for "log", "buckets", the
+	// comma-separated default bucket bounds, for "prometheus" and "format",
+	// the name of a format added by RegisterEncoder, for "push".
+	Options map[string]string `json:"options,omitempty"`
+}
+
+// A PipelineExporter starts the exporter c declares, exporting r, in the
+// background until ctx is done, when it closes any listener it opened and
+// ends its loop.  It returns an error if c is invalid or the exporter can't
+// start.
+type PipelineExporter func(ctx context.Context, c ExporterConfig, r Registry) error
+
+// A Pipeline is the exporters started by StartPipeline.
+type Pipeline struct {
+	cancel context.CancelFunc
+}
+
+// Close stops the exporters of the pipeline: listeners are closed and
+// periodic loops end after any export in progress.
+func (p *Pipeline) Close() {
+	p.cancel()
+}
+
+var (
+	pipelineMutex     sync.Mutex
+	pipelineExporters = map[string]PipelineExporter{
+		"graphite":   startGraphiteExporter,
+		"opentsdb":   startOpenTSDBExporter,
+		"log":        startLogExporter,
+		"admin":      startAdminExporter,
+		"prometheus": startPrometheusExporter,
+		"push":       startPushExporter,
+	}
+)
+
+// RegisterPipelineExporter makes exporters of the given type available to
+// StartPipeline, replacing any registered before.  Packages whose exporters
+// this package can't import, such as optron, register them from init.
+func RegisterPipelineExporter(typ string, start PipelineExporter) {
+	pipelineMutex.Lock()
+	defer pipelineMutex.Unlock()
+	pipelineExporters[typ] = start
+}
+
+// LoadPipelineConfig decodes a JSON PipelineConfig from r, rejecting unknown
+// fields.
+func LoadPipelineConfig(r io.Reader) (*PipelineConfig, error) {
+	cfg := &PipelineConfig{}
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("metrics: pipeline config: %v", err)
+	}
+	return cfg, nil
+}
+
+// LoadPipelineConfigFile is LoadPipelineConfig on the named file.
+func LoadPipelineConfigFile(path string) (*PipelineConfig, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadPipelineConfig(f)
+}
+
+// StartPipeline starts every exporter cfg declares, each on its own view of
+// cfg.Registry limited by its Include and Exclude, and returns the Pipeline
+// that stops them.  It checks that every exporter's type is known before
+// starting any.  If any exporter fails to start, it stops those started and
+// returns their errors, joined.
+func StartPipeline(cfg *PipelineConfig) (*Pipeline, error) {
+	r := cfg.Registry
+	if nil == r {
+		r = DefaultRegistry
+	}
+	pipelineMutex.Lock()
+	starts := make([]PipelineExporter, len(cfg.Exporters))
+	var unknown []string
+	for i, c := range cfg.Exporters {
+		if starts[i] = pipelineExporters[c.Type]; nil == starts[i] {
+			unknown = append(unknown, fmt.Sprintf("%q", c.Type))
+		}
+	}
+	pipelineMutex.Unlock()
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("metrics: pipeline: unknown exporter types %s", strings.Join(unknown, ", "))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var errs []string
+	for i, c := range cfg.Exporters {
+		if err := starts[i](ctx, c, pipelineRegistry(r, c)); err != nil {
+			errs = append(errs, fmt.Sprintf("%s exporter %d: %v", c.Type, i, err))
+		}
+	}
+	if len(errs) > 0 {
+		cancel()
+		return nil, fmt.Errorf("metrics: pipeline: %s", strings.Join(errs, "; "))
+	}
+	return &Pipeline{cancel: cancel}, nil
+}
+
+// servePipeline serves h on ln until ctx is done.
+func servePipeline(ctx context.Context, ln net.Listener, h http.Handler) {
+	srv := &http.Server{Handler: h}
+	go srv.Serve(ln)
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+}
+
+// IntervalDuration returns the parsed Interval, or one minute if it is
+// empty.
+func (c ExporterConfig) IntervalDuration() (time.Duration, error) {
+	if c.Interval == "" {
+		return time.Minute, nil
+	}
+	d, err := parsePositiveDuration(c.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("interval %q: %v", c.Interval, err)
+	}
+	return d, nil
+}
+
+// pipelineRegistry returns the view of r an exporter declared by c exports.
+func pipelineRegistry(r Registry, c ExporterConfig) Registry {
+	if len(c.Include) == 0 && len(c.Exclude) == 0 {
+		return r
+	}
+	return &filteredRegistry{Registry: r, keep: func(name string) bool {
+		return (len(c.Include) == 0 || hasAnyPrefix(name, c.Include)) && !hasAnyPrefix(name, c.Exclude)
+	}}
+}
+
+func hasAnyPrefix(name string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(name, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPipelineConfig returns the interval of c and an error if c sets any
+// of the fields named by unsupported or has no Addr when needsAddr is set.
+func checkPipelineConfig(c ExporterConfig, needsAddr bool, unsupported ...string) (time.Duration, error) {
+	if needsAddr && c.Addr == "" {
+		return 0, fmt.Errorf("addr is required")
+	}
+	for _, field := range unsupported {
+		switch {
+		case field == "tags" && len(c.Tags) > 0,
+			field == "prefix" && c.Prefix != "",
+			field == "skip_idle" && c.SkipIdle:
+			return 0, fmt.Errorf("%s isn't supported", field)
+		}
+	}
+	var options []string
+	for k := range c.Options {
+		options = append(options, k)
+	}
+	sort.Strings(options)
+	for _, k := range options {
+		if !pipelineOptionKnown(c.Type, k) {
+			return 0, fmt.Errorf("unknown option %q", k)
+		}
+	}
+	return c.IntervalDuration()
+}
+
+func pipelineOptionKnown(typ, option string) bool {
+	switch typ {
+	case "graphite":
+		return option == "percentiles"
+	case "log":
+		return option == "scale"
+	case "prometheus":
+		return option == "buckets"
+	case "push":
+		return option == "format"
+	}
+	return false
+}
+
+func startGraphiteExporter(ctx context.Context, c ExporterConfig, r Registry) error {
+	d, err := checkPipelineConfig(c, true, "tags")
+	if err != nil {
+		return err
+	}
+	addr, err := net.ResolveTCPAddr("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	ps := []float64{0.5, 0.75, 0.95, 0.99, 0.999}
+	if s, ok := c.Options["percentiles"]; ok {
+		if ps, err = parsePercentiles(s); err != nil {
+			return fmt.Errorf("percentiles %q: %v", s, err)
+		}
+	}
+	go graphiteWithConfig(ctx, GraphiteConfig{
+		Addr:          addr,
+		Registry:      r,
+		FlushInterval: d,
+		DurationUnit:  time.Nanosecond,
+		Prefix:        c.Prefix,
+		Percentiles:   ps,
+		SkipIdle:      c.SkipIdle,
+	})
+	return nil
+}
+
+func startOpenTSDBExporter(ctx context.Context, c ExporterConfig, r Registry) error {
+	d, err := checkPipelineConfig(c, true)
+	if err != nil {
+		return err
+	}
+	addr, err := net.ResolveTCPAddr("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	go openTSDBWithConfig(ctx, OpenTSDBConfig{
+		Addr:          addr,
+		Registry:      r,
+		FlushInterval: d,
+		DurationUnit:  time.Nanosecond,
+		Prefix:        c.Prefix,
+		Tags:          c.Tags,
+		SkipIdle:      c.SkipIdle,
+	})
+	return nil
+}
+
+func startLogExporter(ctx context.Context, c ExporterConfig, r Registry) error {
+	d, err := checkPipelineConfig(c, false, "tags", "prefix")
+	if err != nil {
+		return err
+	}
+	scale := time.Millisecond
+	if s, ok := c.Options["scale"]; ok {
+		if scale, err = parsePositiveDuration(s); err != nil {
+			return fmt.Errorf("scale %q: %v", s, err)
+		}
+	}
+	if c.SkipIdle {
+		r = newIdleFilter(r).view(r)
+	}
+	go logScaledLimit(ctx, r, d, scale, log.New(os.Stderr, "metrics: ", log.Lmicroseconds), nil)
+	return nil
+}
+
+func startAdminExporter(ctx context.Context, c ExporterConfig, r Registry) error {
+	if _, err := checkPipelineConfig(c, true, "tags", "prefix", "skip_idle"); err != nil {
+		return err
+	}
+	ln, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	servePipeline(ctx, ln, NewAdminHandler(r))
+	return nil
+}
+
+func startPrometheusExporter(ctx context.Context, c ExporterConfig, r Registry) error {
+	if _, err := checkPipelineConfig(c, true, "tags", "skip_idle"); err != nil {
+		return err
+	}
+	var buckets []float64
+	if s, ok := c.Options["buckets"]; ok {
+		for _, f := range strings.Split(s, ",") {
+			b, err := strconv.ParseFloat(strings.TrimSpace(f), 64)
+			if err != nil {
+				return fmt.Errorf("buckets %q: %v", s, err)
+			}
+			buckets = append(buckets, b)
+		}
+	}
+	ln, err := net.Listen("tcp", c.Addr)
+	if err != nil {
+		return err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", NewPrometheusHandler(r, &PrometheusConfig{
+		Namespace:      c.Prefix,
+		DefaultBuckets: buckets,
+	}))
+	servePipeline(ctx, ln, mux)
+	return nil
+}
@@ -0,0 +1,136 @@
+package metrics
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadPipelineConfig(t *testing.T) {
+	cfg, err := LoadPipelineConfig(strings.NewReader(`{"exporters": [
+		{"type": "graphite", "addr": "graphite:2003", "interval": "10s", "prefix": "game",
+		 "options": {"percentiles": "0.5,0.99"}},
+		{"type": "log", "include": ["errors."], "exclude": ["errors.debug"]}
+	]}`))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if 2 != len(cfg.Exporters) {
+		t.Fatal(cfg.Exporters)
+	}
+	g := cfg.Exporters[0]
+	if "graphite" != g.Type || "graphite:2003" != g.Addr || "game" != g.Prefix || "0.5,0.99" != g.Options["percentiles"] {
+		t.Errorf("%+v", g)
+	}
+	if d, err := g.IntervalDuration(); nil != err || 10*time.Second != d {
+		t.Errorf("interval: %v, %v", d, err)
+	}
+	if d, err := cfg.Exporters[1].IntervalDuration(); nil != err || time.Minute != d {
+		t.Errorf("default interval: %v, %v", d, err)
+	}
+
+	if _, err := LoadPipelineConfig(strings.NewReader(`{"exporters": [{"kind": "log"}]}`)); nil == err {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestStartPipeline(t *testing.T) {
+	defer func(m map[string]PipelineExporter) { pipelineExporters = m }(pipelineExporters)
+	pipelineExporters = map[string]PipelineExporter{"admin": startAdminExporter}
+	var got Registry
+	var stopped <-chan struct{}
+	RegisterPipelineExporter("test", func(ctx context.Context, c ExporterConfig, r Registry) error {
+		got, stopped = r, ctx.Done()
+		return nil
+	})
+
+	r := NewRegistry()
+	for _, name := range []string{"errors.db", "errors.debug.x", "requests"} {
+		NewRegisteredCounter(name, r)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if nil != err {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	p, err := StartPipeline(&PipelineConfig{Registry: r, Exporters: []ExporterConfig{
+		{Type: "test", Include: []string{"errors."}, Exclude: []string{"errors.debug"}},
+		{Type: "admin", Addr: addr},
+	}})
+	if nil != err {
+		t.Fatal(err)
+	}
+	var names []string
+	got.Each(func(name string, _ interface{}) { names = append(names, name) })
+	if 1 != len(names) || "errors.db" != names[0] {
+		t.Errorf("filtered names: %v", names)
+	}
+	if nil != got.Get("requests") {
+		t.Error("excluded metric visible")
+	}
+
+	p.Close()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("exporter not stopped")
+	}
+	for i := 0; ; i++ {
+		conn, err := net.Dial("tcp", addr)
+		if nil != err {
+			break
+		}
+		conn.Close()
+		if 100 == i {
+			t.Fatal("admin listener not closed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartPipelineErrors(t *testing.T) {
+	started := false
+	defer func(m map[string]PipelineExporter) { pipelineExporters = m }(pipelineExporters)
+	builtin := pipelineExporters
+	pipelineExporters = make(map[string]PipelineExporter)
+	for typ, start := range builtin {
+		pipelineExporters[typ] = start
+	}
+	var done <-chan struct{}
+	RegisterPipelineExporter("test", func(ctx context.Context, c ExporterConfig, r Registry) error {
+		started, done = true, ctx.Done()
+		return nil
+	})
+	_, err := StartPipeline(&PipelineConfig{Exporters: []ExporterConfig{{Type: "test"}, {Type: "bogus"}}})
+	if nil == err || !strings.Contains(err.Error(), `"bogus"`) || started {
+		t.Errorf("unknown type: %v, started %v", err, started)
+	}
+
+	_, err = StartPipeline(&PipelineConfig{Exporters: []ExporterConfig{{Type: "test"}, {Type: "opentsdb"}}})
+	if nil == err || !started {
+		t.Fatalf("failed exporter: %v, started %v", err, started)
+	}
+	select {
+	case <-done:
+	default:
+		t.Error("started exporter not stopped when another failed")
+	}
+
+	for _, c := range []ExporterConfig{
+		{Type: "graphite"},
+		{Type: "graphite", Addr: "localhost:2003", Interval: "-1s"},
+		{Type: "graphite", Addr: "localhost:2003", Tags: map[string]string{"a": "b"}},
+		{Type: "graphite", Addr: "localhost:2003", Options: map[string]string{"percentiles": "2"}},
+		{Type: "log", Options: map[string]string{"bogus": "1"}},
+		{Type: "opentsdb"},
+		{Type: "admin", Addr: "127.0.0.1:0", Prefix: "x"},
+		{Type: "prometheus", Addr: "127.0.0.1:0", SkipIdle: true},
+	} {
+		if _, err := StartPipeline(&PipelineConfig{Exporters: []ExporterConfig{c}}); nil == err {
+			t.Errorf("%+v started", c)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Metric policies are a finer-grained UseNilMetrics: DisableMetrics and
+// SampleMetrics apply to the metrics whose names start with a prefix, so an
+// expensive family of metrics can be dialed down in production without
+// touching the rest.  Names are matched in full, tags included, and the
+// longest matching prefix decides.
+//
+// Policies are applied when a registry constructs a metric in GetOrRegister,
+// which is how the GetOrRegister* helpers and handles create theirs, so set
+// them before the metrics they govern are first used.  Register stores
+// nothing for a disabled name; metrics registered that way are otherwise
+// left as they are.
+
+type metricPolicy struct {
+	prefix   string
+	disabled bool
+	every    uint64
+}
+
+var (
+	policyMutex sync.Mutex
+	policies    atomic.Value // []metricPolicy
+)
+
+// DisableMetrics makes registries hand out stubs in place of new metrics
+// whose names start with prefix, and keep them out of the registry.
+func DisableMetrics(prefix string) {
+	setPolicy(metricPolicy{prefix: prefix, disabled: true})
+}
+
+// SampleMetrics makes registries record only one in every n updates of new
+// histograms and timers whose names start with prefix.  Timers are sampled
+// by SampledTimer, so their counts and rates still include every update.
+// Histograms have no rates, and their counts and sums describe the recorded
+// updates only.  An n of 1 or less records every update, which also
+// overrides a shorter prefix.
+func SampleMetrics(prefix string, n int) {
+	if n < 1 {
+		n = 1
+	}
+	setPolicy(metricPolicy{prefix: prefix, every: uint64(n)})
+}
+
+// SetDisabledMetrics makes prefixes the whole set of prefixes disabled as
+// by DisableMetrics, re-enabling those disabled before but no longer listed,
+// and unregisters the metrics of r, or DefaultRegistry if r is nil, it
+// disables, so a list of noisy metrics maintained at runtime mutes them at
+// once.  Re-enabled metrics are constructed afresh when next looked up with
+// GetOrRegister; the stubs handed out while they were disabled stay stubs.
+func SetDisabledMetrics(r Registry, prefixes []string) {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	listed := make(map[string]bool, len(prefixes))
+	for _, prefix := range prefixes {
+		listed[prefix] = true
+	}
+	policyMutex.Lock()
+	old, _ := policies.Load().([]metricPolicy)
+	ps := make([]metricPolicy, 0, len(old)+len(listed))
+	for _, p := range old {
+		if !p.disabled && !listed[p.prefix] {
+			ps = append(ps, p)
+		}
+	}
+	for prefix := range listed {
+		ps = append(ps, metricPolicy{prefix: prefix, disabled: true})
+	}
+	policies.Store(ps)
+	policyMutex.Unlock()
+
+	var names []string
+	r.EachUnsorted(func(name string, _ interface{}) {
+		if metricDisabled(name) {
+			names = append(names, name)
+		}
+	})
+	for _, name := range names {
+		r.Unregister(name)
+	}
+}
+
+// ResetMetricPolicies removes every policy set by DisableMetrics and
+// SampleMetrics.  Metrics already constructed keep the policy they got.
+func ResetMetricPolicies() {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+	policies.Store([]metricPolicy(nil))
+}
+
+func setPolicy(p metricPolicy) {
+	policyMutex.Lock()
+	defer policyMutex.Unlock()
+	old, _ := policies.Load().([]metricPolicy)
+	ps := make([]metricPolicy, 0, len(old)+1)
+	for _, q := range old {
+		if q.prefix != p.prefix {
+			ps = append(ps, q)
+		}
+	}
+	policies.Store(append(ps, p))
+}
+
+// policyFor returns the policy with the longest prefix of name.
+func policyFor(name string) (metricPolicy, bool) {
+	var best metricPolicy
+	found := false
+	ps, _ := policies.Load().([]metricPolicy)
+	for _, p := range ps {
+		if strings.HasPrefix(name, p.prefix) && (!found || len(p.prefix) > len(best.prefix)) {
+			best, found = p, true
+		}
+	}
+	return best, found
+}
+
+// metricDisabled reports whether name is disabled by DisableMetrics.
+func metricDisabled(name string) bool {
+	p, ok := policyFor(name)
+	return ok && p.disabled
+}
+
+// applyPolicy returns the metric a registry should hand out for name in
+// place of i, and whether to register it.
+func applyPolicy(name string, i interface{}) (interface{}, bool) {
+	p, ok := policyFor(name)
+	switch {
+	case !ok:
+		return i, true
+	case p.disabled:
+		return stubMetric(i), false
+	case p.every > 1:
+		switch m := i.(type) {
+		case Timer:
+			return SampledTimer(m, 1/float64(p.every)), true
+		case Histogram:
+			return &sampledHistogram{Histogram: m, every: p.every}, true
+		}
+	}
+	return i, true
+}
+
+// stubMetric returns the no-op metric of the same kind as i.
+func stubMetric(i interface{}) interface{} {
+	switch i.(type) {
+	case Counter:
+		return NilCounter{}
+	case Instant:
+		return NilInstant{}
+	case Gauge:
+		return NilGauge{}
+	case GaugeFloat64:
+		return NilGaugeFloat64{}
+	case CounterFloat64:
+		return NilCounterFloat64{}
+	case Meter:
+		return NilMeter{}
+	case Timer:
+		return NilTimer{}
+	case Histogram:
+		return NilHistogram{}
+	case Healthcheck:
+		return NilHealthcheck{}
+	}
+	return i
+}
+
+// sampledHistogram records one in every n updates of a Histogram.
+type sampledHistogram struct {
+	Histogram
+	every uint64
+	n     uint64
+}
+
+// Update records v if it is the nth update since the last one recorded.
+func (h *sampledHistogram) Update(v int64) {
+	if atomic.AddUint64(&h.n, 1)%h.every == 0 {
+		h.Histogram.Update(v)
+	}
+}
+
+// UpdateAll records the values of the batch that are nth updates, as if
+// each had been passed to Update.
+func (h *sampledHistogram) UpdateAll(values []int64) {
+	n := atomic.AddUint64(&h.n, uint64(len(values))) - uint64(len(values))
+	var recorded []int64
+	for _, v := range values {
+		if n++; n%h.every == 0 {
+			recorded = append(recorded, v)
+		}
+	}
+	if len(recorded) > 0 {
+		h.Histogram.UpdateAll(recorded)
+	}
+}
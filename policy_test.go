@@ -0,0 +1,103 @@
+package metrics
+
+import "testing"
+
+func TestDisableMetrics(t *testing.T) {
+	defer ResetMetricPolicies()
+	DisableMetrics("debug.")
+	r := NewRegistry()
+
+	c := GetOrRegisterCounter("debug.requests", r)
+	if _, ok := c.(NilCounter); !ok {
+		t.Fatalf("GetOrRegisterCounter: %T", c)
+	}
+	c.Inc(1)
+	if _, ok := GetOrRegisterInstantCounter("debug.hits", r).(NilInstant); !ok {
+		t.Error("GetOrRegisterInstantCounter didn't return a stub")
+	}
+	if _, ok := GetOrRegisterTimer("debug.latency", r).(NilTimer); !ok {
+		t.Error("GetOrRegisterTimer didn't return a stub")
+	}
+	if err := r.Register("debug.gauge", NewGauge()); nil != err {
+		t.Fatal(err)
+	}
+	GetOrRegisterCounter("requests", r).Inc(1)
+
+	n := 0
+	r.Each(func(name string, _ interface{}) {
+		if "requests" != name {
+			t.Errorf("unexpected metric %s", name)
+		}
+		n++
+	})
+	if 1 != n {
+		t.Errorf("registry has %d metrics", n)
+	}
+}
+
+func TestSetDisabledMetrics(t *testing.T) {
+	defer ResetMetricPolicies()
+	DisableMetrics("old.")
+	SampleMetrics("db.", 10)
+	r := NewRegistry()
+	GetOrRegisterCounter("noisy.requests", r).Inc(1)
+	GetOrRegisterCounter("requests", r).Inc(1)
+
+	SetDisabledMetrics(r, []string{"noisy."})
+	if nil != r.Get("noisy.requests") || nil == r.Get("requests") {
+		t.Error("disabled metrics weren't unregistered alone")
+	}
+	if _, ok := GetOrRegisterCounter("noisy.requests", r).(NilCounter); !ok {
+		t.Error("noisy. isn't disabled")
+	}
+	if _, ok := GetOrRegisterCounter("old.requests", r).(NilCounter); ok {
+		t.Error("old. is still disabled")
+	}
+	if _, ok := GetOrRegisterTimer("db.query", r).(*fractionTimer); !ok {
+		t.Error("sampling policy was dropped")
+	}
+
+	SetDisabledMetrics(r, nil)
+	if _, ok := GetOrRegisterCounter("noisy.requests", r).(NilCounter); ok {
+		t.Error("noisy. is still disabled")
+	}
+}
+
+func TestSampleMetrics(t *testing.T) {
+	defer ResetMetricPolicies()
+	SampleMetrics("db.", 10)
+	SampleMetrics("db.critical.", 1)
+	r := NewRegistry()
+
+	h := GetOrRegisterHistogram("db.rows", r, NewUniformSample(1000))
+	tm := GetOrRegisterTimer("db.query", r)
+	critical := GetOrRegisterTimer("db.critical.query", r)
+	for i := 0; i < 100; i++ {
+		h.Update(int64(i))
+		tm.Update(int64(i))
+		critical.Update(int64(i))
+	}
+	if count := h.Count(); 10 != count {
+		t.Errorf("h.Count(): 10 != %v", count)
+	}
+	// Timers count every update, histograms only the recorded ones.
+	if count := tm.Count(); 100 != count {
+		t.Errorf("tm.Count(): 100 != %v", count)
+	}
+	if count := tm.(*fractionTimer).Timer.Count(); 10 != count {
+		t.Errorf("recorded: 10 != %v", count)
+	}
+	if count := critical.Count(); 100 != count {
+		t.Errorf("critical.Count(): 100 != %v", count)
+	}
+	if h != r.Get("db.rows") {
+		t.Error("sampled histogram isn't the registered one")
+	}
+	ran := 0
+	for i := 0; i < 20; i++ {
+		tm.Time(func() { ran++ })
+	}
+	if 20 != ran {
+		t.Errorf("Time ran f %d times", ran)
+	}
+}
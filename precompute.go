@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// PrecomputePercentiles returns a Collector that snapshots the top
+// StandardTimers of the registry it collects, those with the largest counts,
+// and sorts their samples, once.  Until ttl has passed, their Snapshot
+// methods return those snapshots, so the exporters and handlers reading them
+// in the meantime share the sort instead of each repeating it.  Snapshots
+// are therefore up to ttl old; schedule the collector every ttl, just before
+// the exporters run, for them to see fresh data.
+func PrecomputePercentiles(top int, ttl time.Duration) Collector {
+	return CollectorFunc(func(r Registry) {
+		var timers []*StandardTimer
+		var counts []int64
+		r.EachUnsorted(func(_ string, i interface{}) {
+			if t, ok := i.(*StandardTimer); ok {
+				timers = append(timers, t)
+				counts = append(counts, t.Count())
+			}
+		})
+		sort.Sort(timersByCount{timers, counts})
+		if len(timers) > top {
+			timers = timers[:top]
+		}
+		for _, t := range timers {
+			t.precompute(ttl)
+		}
+	})
+}
+
+// CachePercentiles adds PrecomputePercentiles(top, interval) to
+// DefaultScheduler, to run every interval on DefaultRegistry.
+func CachePercentiles(top int, interval time.Duration) {
+	AddCollector("percentiles", PrecomputePercentiles(top, interval), interval)
+}
+
+// timersByCount sorts timers by their counts, largest first.
+type timersByCount struct {
+	timers []*StandardTimer
+	counts []int64
+}
+
+func (s timersByCount) Len() int { return len(s.timers) }
+
+func (s timersByCount) Less(i, j int) bool { return s.counts[i] > s.counts[j] }
+
+func (s timersByCount) Swap(i, j int) {
+	s.timers[i], s.timers[j] = s.timers[j], s.timers[i]
+	s.counts[i], s.counts[j] = s.counts[j], s.counts[i]
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPrecomputePercentiles(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(1000, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	hot, cold := NewRegisteredTimer("hot", r), NewRegisteredTimer("cold", r)
+	for i := int64(100); i > 0; i-- {
+		hot.Update(i)
+	}
+	cold.Update(1)
+
+	PrecomputePercentiles(1, time.Minute).Collect(r)
+	s := hot.Snapshot()
+	if s != hot.Snapshot() {
+		t.Fatal("hot snapshot not cached")
+	}
+	if cold.Snapshot() == cold.Snapshot() {
+		t.Fatal("cold snapshot cached")
+	}
+	if p := s.Percentile(0.5); 50.5 != p {
+		t.Errorf("median: %v", p)
+	}
+
+	// Updates show once the cached snapshot expires.
+	hot.Update(1000)
+	if c := hot.Snapshot().Count(); 100 != c {
+		t.Errorf("cached count: %d", c)
+	}
+	clock.Add(time.Minute)
+	if c := hot.Snapshot().Count(); 101 != c {
+		t.Errorf("count after expiry: %d", c)
+	}
+}
+
+func TestSampleSnapshotSorted(t *testing.T) {
+	s := &SampleSnapshot{count: 5, values: []int64{5, 1, 4, 2, 3}}
+	s.sort()
+	if ps := s.Percentiles([]float64{0, 0.5, 1}); 1 != ps[0] || 3 != ps[1] || 5 != ps[2] {
+		t.Error(ps)
+	}
+}
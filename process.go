@@ -0,0 +1,75 @@
+package metrics
+
+import "time"
+
+// processStats holds the raw process statistics read from the operating
+// system by readProcessStats.
+type processStats struct {
+	residentMemory int64   // bytes
+	virtualMemory  int64   // bytes
+	openFDs        int64   //
+	maxFDs         int64   // soft RLIMIT_NOFILE
+	cpuUser        float64 // seconds
+	cpuSystem      float64 // seconds
+}
+
+var (
+	processMetrics struct {
+		ResidentMemory Gauge
+		VirtualMemory  Gauge
+		OpenFDs        Gauge
+		MaxFDs         Gauge
+		CPUUser        GaugeFloat64
+		CPUSystem      GaugeFloat64
+		ReadErrors     Counter
+	}
+	procStats processStats
+)
+
+// Capture new values for the process statistics.  This is designed to be
+// called as a goroutine.
+func CaptureProcessStats(r Registry, d time.Duration) {
+	for _ = range time.Tick(d) {
+		CaptureProcessStatsOnce(r)
+	}
+}
+
+// Capture new values for the resident and virtual memory, open file
+// descriptors and CPU time of the current process.  On Linux these are read
+// from /proc; on darwin, resident memory is the peak reported by getrusage
+// and virtual memory is not available.  Failed reads increment
+// process.ReadErrors and leave the previous values in place.  Giving a
+// registry which has not been given to RegisterProcessStats will panic.
+func CaptureProcessStatsOnce(r Registry) {
+	if err := readProcessStats(&procStats); err != nil {
+		processMetrics.ReadErrors.Inc(1)
+		return
+	}
+
+	processMetrics.ResidentMemory.Update(procStats.residentMemory)
+	processMetrics.VirtualMemory.Update(procStats.virtualMemory)
+	processMetrics.OpenFDs.Update(procStats.openFDs)
+	processMetrics.MaxFDs.Update(procStats.maxFDs)
+	processMetrics.CPUUser.Update(procStats.cpuUser)
+	processMetrics.CPUSystem.Update(procStats.cpuSystem)
+}
+
+// Register metrics for the statistics of the current process.  Memory is in
+// bytes and CPU time in seconds.
+func RegisterProcessStats(r Registry) {
+	processMetrics.ResidentMemory = NewGauge()
+	processMetrics.VirtualMemory = NewGauge()
+	processMetrics.OpenFDs = NewGauge()
+	processMetrics.MaxFDs = NewGauge()
+	processMetrics.CPUUser = NewGaugeFloat64()
+	processMetrics.CPUSystem = NewGaugeFloat64()
+	processMetrics.ReadErrors = NewCounter()
+
+	r.Register("process.ResidentMemory", processMetrics.ResidentMemory)
+	r.Register("process.VirtualMemory", processMetrics.VirtualMemory)
+	r.Register("process.OpenFDs", processMetrics.OpenFDs)
+	r.Register("process.MaxFDs", processMetrics.MaxFDs)
+	r.Register("process.CPUUser", processMetrics.CPUUser)
+	r.Register("process.CPUSystem", processMetrics.CPUSystem)
+	r.Register("process.ReadErrors", processMetrics.ReadErrors)
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"syscall"
+)
+
+func readProcessStats(s *processStats) error {
+	fds, err := ioutil.ReadDir("/dev/fd")
+	if err != nil {
+		return err
+	}
+	// The listing includes the descriptor ReadDir opened to read it.
+	s.openFDs = int64(len(fds)) - 1
+
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return err
+	}
+	s.maxFDs = int64(rlimit.Cur)
+
+	var rusage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &rusage); err != nil {
+		return err
+	}
+	// Maxrss is the peak resident set size, in bytes on darwin; the current
+	// one would take a Mach task_info call, which needs cgo.
+	s.residentMemory = rusage.Maxrss
+	s.cpuUser = float64(rusage.Utime.Nano()) / 1e9
+	s.cpuSystem = float64(rusage.Stime.Nano()) / 1e9
+	return nil
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func readProcessStats(s *processStats) error {
+	stat, err := ioutil.ReadFile("/proc/self/stat")
+	if err != nil {
+		return err
+	}
+	// The command name may contain spaces, so split after its closing paren.
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return fmt.Errorf("process: malformed /proc/self/stat")
+	}
+	// fields[0] is the state, the third field of /proc/[pid]/stat.
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 22 {
+		return fmt.Errorf("process: short /proc/self/stat")
+	}
+	vsize, err := strconv.ParseInt(fields[20], 10, 64)
+	if err != nil {
+		return err
+	}
+	rss, err := strconv.ParseInt(fields[21], 10, 64)
+	if err != nil {
+		return err
+	}
+	s.virtualMemory = vsize
+	s.residentMemory = rss * int64(os.Getpagesize())
+
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return err
+	}
+	// The listing includes the descriptor ReadDir opened to read it.
+	s.openFDs = int64(len(fds)) - 1
+
+	return readRusageAndLimits(s)
+}
+
+func readRusageAndLimits(s *processStats) error {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return err
+	}
+	s.maxFDs = int64(rlimit.Cur)
+
+	var rusage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &rusage); err != nil {
+		return err
+	}
+	s.cpuUser = float64(rusage.Utime.Nano()) / 1e9
+	s.cpuSystem = float64(rusage.Stime.Nano()) / 1e9
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package metrics
+
+import "errors"
+
+func readProcessStats(s *processStats) error {
+	return errors.New("process: stats not supported on this platform")
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func BenchmarkProcessStats(b *testing.B) {
+	r := NewRegistry()
+	RegisterProcessStats(r)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CaptureProcessStatsOnce(r)
+	}
+}
+
+func TestProcessStats(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skipf("skipping TestProcessStats on %s", runtime.GOOS)
+	}
+	r := NewRegistry()
+	RegisterProcessStats(r)
+	CaptureProcessStatsOnce(r)
+
+	if c := r.Get("process.ReadErrors").(Counter).Count(); c != 0 {
+		t.Fatal(c)
+	}
+	if v := r.Get("process.ResidentMemory").(Gauge).Value(); v <= 0 {
+		t.Fatal(v)
+	}
+	open := r.Get("process.OpenFDs").(Gauge).Value()
+	max := r.Get("process.MaxFDs").(Gauge).Value()
+	if open <= 0 || max < open {
+		t.Fatalf("open: %d, max: %d", open, max)
+	}
+
+	// Count the descriptors that are open without opening any.
+	var want int64
+	for fd := 0; fd < int(max) && fd < 1<<16; fd++ {
+		var st syscall.Stat_t
+		if nil == syscall.Fstat(fd, &st) {
+			want++
+		}
+	}
+	if open != want {
+		t.Errorf("open: %d != %d", want, open)
+	}
+}
@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusAutoQuantiles are the quantiles of their samples that histograms
+// and timers without a bucket layout are bucketed at.
+var PrometheusAutoQuantiles = []float64{0.5, 0.75, 0.9, 0.95, 0.99}
+
+// PrometheusConfig configures a handler from NewPrometheusHandler.
+type PrometheusConfig struct {
+	// Namespace, when set, is prepended to every name with an underscore.
+	Namespace string
+
+	// Scale divides the values of histograms and timers by unit.  It
+	// defaults to SecondsScale, so durations are exported in seconds as
+	// Prometheus expects.
+	Scale UnitScale
+
+	// Buckets maps metric names, without their tags, to the upper bounds of
+	// the buckets their histograms and timers are exported with, in
+	// exported units.  Those it doesn't list use DefaultBuckets or, if that
+	// is empty too, are bucketed at PrometheusAutoQuantiles of their samples.
+	Buckets        map[string][]float64
+	DefaultBuckets []float64
+}
+
+// NewPrometheusHandler returns an http.Handler serving the metrics of r, or
+// DefaultRegistry if r is nil, in the Prometheus text exposition format.
+// The tags of tagged metrics become the labels ns, grp, tgt, act and sub and
+// names are sanitized by SanitizePrometheus; metrics given an export name
+// for "prometheus" by SetExportName are exported under it, still labelled
+// with their tags.  Counters and meters are counters, gauges and Instant
+// counters are gauges, and histograms and timers are histograms with
+// cumulative _bucket, _sum and _count series.  Of the
+// metrics that sanitize to the same name and labels, or to the same name but
+// differ in type, the first in name order is kept.
+//
+// Bucket counts are estimated from the metric's sample: each is the share of
+// the sample at or below its bound times the metric's count.  Automatic
+// bounds move as the sample does, so give latency metrics a fixed layout in
+// c.Buckets or c.DefaultBuckets if they are aggregated across instances.
+func NewPrometheusHandler(r Registry, c *PrometheusConfig) http.Handler {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	cfg := PrometheusConfig{}
+	if nil != c {
+		cfg = *c
+	}
+	if nil == cfg.Scale {
+		cfg.Scale = SecondsScale
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		bw := bufio.NewWriter(w)
+		writePrometheus(bw, r, &cfg)
+		bw.Flush()
+	})
+}
+
+// promFamily is the samples of the metrics sharing a name, keyed by their
+// labels.
+type promFamily struct {
+	typ     string
+	samples map[string][]string
+}
+
+func writePrometheus(w *bufio.Writer, r Registry, c *PrometheusConfig) {
+	families := make(map[string]*promFamily)
+	r.Each(func(name string, i interface{}) {
+		base, labels := name, ""
+		if b, tb, err := ParseTagged(name); err == nil {
+			base, labels = b, promTagLabels(tb)
+		}
+		family := SanitizePrometheus(base)
+		if exported, ok := exportName("prometheus", name); ok {
+			family = SanitizePrometheus(exported)
+		}
+		if c.Namespace != "" {
+			family = SanitizePrometheus(c.Namespace + "_" + family)
+		}
+		typ, lines := promSamples(family, labels, base, name, i, c)
+		if typ == "" {
+			return
+		}
+		f, ok := families[family]
+		if !ok {
+			f = &promFamily{typ: typ, samples: make(map[string][]string)}
+			families[family] = f
+		} else if _, dup := f.samples[labels]; dup || f.typ != typ {
+			return
+		}
+		f.samples[labels] = lines
+	})
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := families[name]
+		w.WriteString("# TYPE " + name + " " + f.typ + "\n")
+		labels := make([]string, 0, len(f.samples))
+		for l := range f.samples {
+			labels = append(labels, l)
+		}
+		sort.Strings(labels)
+		for _, l := range labels {
+			for _, line := range f.samples[l] {
+				w.WriteString(line)
+			}
+		}
+	}
+}
+
+// promSamples returns the type of the metric i, named name in the registry,
+// and its sample lines in exposition order.  It returns "" for metrics
+// Prometheus has no type for.
+func promSamples(family, labels, base, name string, i interface{}, c *PrometheusConfig) (string, []string) {
+	switch m := i.(type) {
+	case Counter:
+		return "counter", []string{promLine(family, labels, float64(m.Count()))}
+	case CounterFloat64:
+		return "counter", []string{promLine(family, labels, m.Count())}
+	case Instant:
+		return "gauge", []string{promLine(family, labels, float64(m.Count()))}
+	case Gauge:
+		return "gauge", []string{promLine(family, labels, float64(m.Value()))}
+	case GaugeFloat64:
+		return "gauge", []string{promLine(family, labels, m.Value())}
+	case Meter:
+		return "counter", []string{promLine(family, labels, float64(m.Count()))}
+	case Histogram:
+		h := m.Snapshot()
+		return "histogram", promHistogram(family, labels, h.Count(), h.Sum(), h.Sample().Values(), h, c.Scale.Divisor(name, i), c.bounds(base))
+	case Timer:
+		t := m.Snapshot()
+		var values []int64
+		if s, ok := t.(*TimerSnapshot); ok {
+			values = s.histogram.Sample().Values()
+		}
+		return "histogram", promHistogram(family, labels, t.Count(), t.Sum(), values, t, c.Scale.Divisor(name, i), c.bounds(base))
+	}
+	return "", nil
+}
+
+// bounds returns the bucket layout of the metric named base, or nil for
+// automatic bounds.
+func (c *PrometheusConfig) bounds(base string) []float64 {
+	if b, ok := c.Buckets[base]; ok {
+		return b
+	}
+	return c.DefaultBuckets
+}
+
+// promHistogram returns the bucket, sum and count lines of a histogram or
+// timer.  values is its sample; when it is nil, as for timers that don't
+// expose theirs, the sample is approximated by percentiles of p.
+func promHistogram(family, labels string, count, sum int64, values []int64, p interface{ Percentiles([]float64) []float64 }, du float64, bounds []float64) []string {
+	scaled := make([]float64, 0, len(values))
+	for _, v := range values {
+		scaled = append(scaled, float64(v)/du)
+	}
+	if nil == values && count > 0 {
+		grid := make([]float64, 99)
+		for i := range grid {
+			grid[i] = float64(i+1) / 100
+		}
+		for _, v := range p.Percentiles(grid) {
+			scaled = append(scaled, v/du)
+		}
+	}
+	sort.Float64s(scaled)
+	if len(bounds) == 0 {
+		for _, v := range p.Percentiles(PrometheusAutoQuantiles) {
+			bounds = append(bounds, v/du)
+		}
+	}
+	bounds = append([]float64(nil), bounds...)
+	sort.Float64s(bounds)
+
+	lines := make([]string, 0, len(bounds)+3)
+	for i, b := range bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) || i > 0 && b == bounds[i-1] {
+			continue
+		}
+		n := 0.0
+		if len(scaled) > 0 {
+			below := sort.Search(len(scaled), func(j int) bool { return scaled[j] > b })
+			n = math.Round(float64(count) * float64(below) / float64(len(scaled)))
+		}
+		lines = append(lines, promLine(family+"_bucket", promLabel(labels, "le", promFloat(b)), n))
+	}
+	lines = append(lines,
+		promLine(family+"_bucket", promLabel(labels, "le", "+Inf"), float64(count)),
+		promLine(family+"_count", labels, float64(count)),
+		promLine(family+"_sum", labels, float64(sum)/du),
+	)
+	return lines
+}
+
+// promTagLabels renders the tags of tb as labels.
+func promTagLabels(tb TagBoard) string {
+	labels := ""
+	for i, v := range []string{tb.Ns, tb.Grp, tb.Tgt, tb.Act, tb.Sub} {
+		if v != "" {
+			labels = promLabel(labels, tagKeys[i], v)
+		}
+	}
+	return labels
+}
+
+// promLabel adds the label k="v" to the rendered labels.
+func promLabel(labels, k, v string) string {
+	pair := k + `="` + promEscaper.Replace(v) + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLine(name, labels string, v float64) string {
+	return name + labels + " " + promFloat(v) + "\n"
+}
+
+func promFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPrometheusHandler(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("requests", r).Inc(3)
+	NewRegisteredCounter(taggedName("logins", []string{"lobby", "google"}), r).Inc(2)
+	NewRegisteredCounter(taggedName("logins", []string{"lobby"}), r).Inc(1)
+	NewRegisteredGaugeFloat64("queue.depth", r).Update(1.5)
+	r.Register("ignored", NewHealthcheck(func(Healthcheck) {}))
+	h := NewRegisteredHistogram("sizes", r, NewUniformSample(1000))
+	for i := int64(1); i <= 100; i++ {
+		h.Update(i)
+	}
+	tm := NewRegisteredTimer("latency", r)
+	for i := 1; i <= 4; i++ {
+		tm.UpdateTime(time.Duration(i) * 100 * time.Millisecond)
+	}
+
+	w := httptest.NewRecorder()
+	NewPrometheusHandler(r, &PrometheusConfig{
+		Namespace: "game",
+		Buckets:   map[string][]float64{"sizes": {50, 10}, "latency": {0.25}},
+	}).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	want := `# TYPE game_latency histogram
+game_latency_bucket{le="0.25"} 2
+game_latency_bucket{le="+Inf"} 4
+game_latency_count 4
+game_latency_sum 1
+# TYPE game_logins counter
+game_logins{ns="lobby",grp="google"} 2
+game_logins{ns="lobby"} 1
+# TYPE game_queue_depth gauge
+game_queue_depth 1.5
+# TYPE game_requests counter
+game_requests 3
+# TYPE game_sizes histogram
+game_sizes_bucket{le="10"} 10
+game_sizes_bucket{le="50"} 50
+game_sizes_bucket{le="+Inf"} 100
+game_sizes_count 100
+game_sizes_sum 5050
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Error(ct)
+	}
+}
+
+func TestPrometheusAutoBuckets(t *testing.T) {
+	r := NewRegistry()
+	h := NewRegisteredHistogram("sizes", r, NewUniformSample(1000))
+	for i := int64(1); i <= 100; i++ {
+		h.Update(i)
+	}
+	w := httptest.NewRecorder()
+	NewPrometheusHandler(r, nil).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	for _, line := range []string{
+		`sizes_bucket{le="50.5"} 50`,
+		`sizes_bucket{le="99.99"} 99`,
+		`sizes_bucket{le="+Inf"} 100`,
+	} {
+		if !strings.Contains(w.Body.String(), line+"\n") {
+			t.Errorf("missing %s in\n%s", line, w.Body.String())
+		}
+	}
+}
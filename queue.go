@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// QueueStats instruments a work queue or worker pool.  For a queue named
+// "jobs" it registers:
+//
+//	jobs.depth        a Gauge of items enqueued but not yet dequeued
+//	jobs.wait         a Timer of the time items spent in the queue
+//	jobs.processing   a Timer of the time workers spent on items
+//	jobs.throughput   a Meter of processed items
+//
+// The producer calls Enqueue and carries the returned time along with the
+// item; the worker calls Dequeue with it when picking the item up and Done
+// when finished:
+//
+//	job.enqueued = stats.Enqueue()
+//	...
+//	start := stats.Dequeue(job.enqueued)
+//	process(job)
+//	stats.Done(start)
+type QueueStats struct {
+	depth      int64
+	wait       Timer
+	processing Timer
+	throughput Meter
+}
+
+// NewQueueStats constructs a QueueStats and registers its metrics under the
+// given name, or returns the QueueStats already registered under it in r.
+func NewQueueStats(name string, r Registry) *QueueStats {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	q := &QueueStats{
+		wait:       GetOrRegisterTimer(name+".wait", r),
+		processing: GetOrRegisterTimer(name+".processing", r),
+		throughput: GetOrRegisterMeter(name+".throughput", r),
+	}
+	return registerOwnedGauge(r, name+".depth", q, q.Depth).(*QueueStats)
+}
+
+// Depth returns the number of items enqueued but not yet dequeued.
+func (q *QueueStats) Depth() int64 {
+	return atomic.LoadInt64(&q.depth)
+}
+
+// Enqueue records an item entering the queue and returns the time to pass to
+// Dequeue.
+func (q *QueueStats) Enqueue() time.Time {
+	atomic.AddInt64(&q.depth, 1)
+	return time.Now()
+}
+
+// Dequeue records an item enqueued at the given time leaving the queue and
+// returns the time to pass to Done.
+func (q *QueueStats) Dequeue(enqueued time.Time) time.Time {
+	atomic.AddInt64(&q.depth, -1)
+	now := time.Now()
+	q.wait.Update(int64(now.Sub(enqueued)))
+	return now
+}
+
+// Done records an item dequeued at the given time as processed.
+func (q *QueueStats) Done(started time.Time) {
+	q.processing.UpdateSince(started)
+	q.throughput.Mark(1)
+}
+
+// Process dequeues an item enqueued at the given time, runs f and marks the
+// item done.
+func (q *QueueStats) Process(enqueued time.Time, f func()) {
+	defer q.Done(q.Dequeue(enqueued))
+	f()
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueueStats(t *testing.T) {
+	r := NewRegistry()
+	q := NewQueueStats("jobs", r)
+
+	first := q.Enqueue()
+	second := NewQueueStats("jobs", r).Enqueue()
+	if v := r.Get("jobs.depth").(Gauge).Value(); v != 2 {
+		t.Fatal(v)
+	}
+
+	time.Sleep(time.Millisecond)
+	q.Process(first, func() { time.Sleep(time.Millisecond) })
+	if v := r.Get("jobs.depth").(Gauge).Value(); v != 1 {
+		t.Fatal(v)
+	}
+	q.Done(q.Dequeue(second))
+
+	if v := q.Depth(); v != 0 {
+		t.Fatal(v)
+	}
+	wait := r.Get("jobs.wait").(Timer)
+	if c := wait.Count(); c != 2 {
+		t.Fatal(c)
+	}
+	if max := wait.Max(); max < int64(time.Millisecond) {
+		t.Fatal(max)
+	}
+	processing := r.Get("jobs.processing").(Timer)
+	if max := processing.Max(); max < int64(time.Millisecond) {
+		t.Fatal(max)
+	}
+	if c := r.Get("jobs.throughput").(Meter).Count(); c != 2 {
+		t.Fatal(c)
+	}
+}
@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Rate is a GaugeFloat64 whose value is the per-second rate at which a
+// counter grew over the last window.  It reacts as soon as a window ends,
+// where a Meter's moving averages lag, and it makes a raw counter graphable
+// as a rate.  It samples the counter in the background, on DefaultClock,
+// until Stop is called.
+type Rate interface {
+	Snapshot() GaugeFloat64
+	Stop()
+	Update(float64)
+	Value() float64
+}
+
+// NewRate constructs a StandardRate sampling c every window.
+func NewRate(c Counter, window time.Duration) Rate {
+	if UseNilMetrics {
+		return NilRate{}
+	}
+	r := &StandardRate{
+		counter:   c,
+		clock:     DefaultClock,
+		lastCount: c.Count(),
+		quit:      make(chan struct{}),
+	}
+	r.lastTime = r.clock.Now()
+	ticker := r.clock.NewTicker(window)
+	go r.loop(ticker)
+	return r
+}
+
+// NewRegisteredRate constructs and registers a Rate sampling c every window.
+func NewRegisteredRate(name string, r Registry, c Counter, window time.Duration) Rate {
+	rate := NewRate(c, window)
+	if nil == r {
+		r = DefaultRegistry
+	}
+	r.Register(name, rate)
+	return rate
+}
+
+// NilRate is a no-op Rate.
+type NilRate struct{}
+
+// Snapshot is a no-op.
+func (NilRate) Snapshot() GaugeFloat64 { return NilGaugeFloat64{} }
+
+// Stop is a no-op.
+func (NilRate) Stop() {}
+
+// Update is a no-op.
+func (NilRate) Update(float64) {}
+
+// Value is a no-op.
+func (NilRate) Value() float64 { return 0.0 }
+
+// StandardRate is the standard implementation of a Rate.
+type StandardRate struct {
+	counter Counter
+	clock   Clock
+
+	mutex     sync.Mutex
+	rate      float64
+	lastCount int64
+	lastTime  time.Time
+
+	quit chan struct{}
+	once sync.Once
+}
+
+// Snapshot returns a read-only copy of the rate.
+func (r *StandardRate) Snapshot() GaugeFloat64 { return GaugeFloat64Snapshot(r.Value()) }
+
+// Stop stops sampling the counter, freezing the rate.
+func (r *StandardRate) Stop() {
+	r.once.Do(func() { close(r.quit) })
+}
+
+// Update panics.
+func (*StandardRate) Update(float64) {
+	panic("Update called on a Rate")
+}
+
+// Value returns the rate over the last complete window, or zero before the
+// first window ends.
+func (r *StandardRate) Value() float64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.rate
+}
+
+func (r *StandardRate) loop(ticker Ticker) {
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.Chan():
+			r.sample()
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+func (r *StandardRate) sample() {
+	now, count := r.clock.Now(), r.counter.Count()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if elapsed := now.Sub(r.lastTime).Seconds(); elapsed > 0 {
+		r.rate = float64(count-r.lastCount) / elapsed
+	}
+	r.lastCount, r.lastTime = count, now
+}
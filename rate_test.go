@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRate(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	c := NewCounter()
+	r := NewRegistry()
+	rate := NewRegisteredRate("requests.rate", r, c, 10*time.Second)
+	defer rate.Stop()
+	if _, ok := r.Get("requests.rate").(GaugeFloat64); !ok {
+		t.Fatal("not registered as a GaugeFloat64")
+	}
+
+	c.Inc(50)
+	clock.Add(10 * time.Second)
+	waitForRate(t, rate, 5)
+
+	c.Inc(200)
+	clock.Add(10 * time.Second)
+	waitForRate(t, rate, 20)
+}
+
+// waitForRate waits for the sampling goroutine to catch up with the clock.
+func waitForRate(t *testing.T, rate Rate, want float64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for rate.Value() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("rate.Value(): %v != %v", want, rate.Value())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,551 @@
+// Package metrics is a drop-in replacement for the API of
+// github.com/rcrowley/go-metrics that delegates to
+// github.com/moonfrog/go-metrics, so services can switch by changing the
+// import path alone:
+//
+//	import "github.com/moonfrog/go-metrics/rcrowley"
+//
+// The types are aliases of this repository's, so metrics and registries pass
+// freely between code using either import.  This package keeps its own
+// DefaultRegistry and UseNilMetrics variables, as upstream declares them:
+// DefaultRegistry starts out as the underlying package's and the package-level
+// functions here use whichever registry it holds, while UseNilMetrics makes
+// the constructors here return Nil metrics.  Set
+// github.com/moonfrog/go-metrics.UseNilMetrics as well to stub the metrics
+// constructed by registries.
+package metrics
+
+import (
+	"io"
+	"net"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+)
+
+// Types of the upstream API, aliased to their implementations here.
+type (
+	Counter                = metrics.Counter
+	CounterSnapshot        = metrics.CounterSnapshot
+	StandardCounter        = metrics.StandardCounter
+	NilCounter             = metrics.NilCounter
+	CounterFloat64         = metrics.CounterFloat64
+	CounterFloat64Snapshot = metrics.CounterFloat64Snapshot
+	StandardCounterFloat64 = metrics.StandardCounterFloat64
+	NilCounterFloat64      = metrics.NilCounterFloat64
+	EWMA                   = metrics.EWMA
+	EWMASnapshot           = metrics.EWMASnapshot
+	StandardEWMA           = metrics.StandardEWMA
+	NilEWMA                = metrics.NilEWMA
+	Gauge                  = metrics.Gauge
+	GaugeSnapshot          = metrics.GaugeSnapshot
+	StandardGauge          = metrics.StandardGauge
+	NilGauge               = metrics.NilGauge
+	FunctionalGauge        = metrics.FunctionalGauge
+	GaugeFloat64           = metrics.GaugeFloat64
+	GaugeFloat64Snapshot   = metrics.GaugeFloat64Snapshot
+	StandardGaugeFloat64   = metrics.StandardGaugeFloat64
+	NilGaugeFloat64        = metrics.NilGaugeFloat64
+	FunctionalGaugeFloat64 = metrics.FunctionalGaugeFloat64
+	Healthcheck            = metrics.Healthcheck
+	StandardHealthcheck    = metrics.StandardHealthcheck
+	NilHealthcheck         = metrics.NilHealthcheck
+	Histogram              = metrics.Histogram
+	HistogramSnapshot      = metrics.HistogramSnapshot
+	StandardHistogram      = metrics.StandardHistogram
+	NilHistogram           = metrics.NilHistogram
+	Meter                  = metrics.Meter
+	MeterSnapshot          = metrics.MeterSnapshot
+	StandardMeter          = metrics.StandardMeter
+	NilMeter               = metrics.NilMeter
+	Timer                  = metrics.Timer
+	TimerSnapshot          = metrics.TimerSnapshot
+	StandardTimer          = metrics.StandardTimer
+	NilTimer               = metrics.NilTimer
+	Registry               = metrics.Registry
+	StandardRegistry       = metrics.StandardRegistry
+	PrefixedRegistry       = metrics.PrefixedRegistry
+	DuplicateMetric        = metrics.DuplicateMetric
+	Sample                 = metrics.Sample
+	SampleSnapshot         = metrics.SampleSnapshot
+	ExpDecaySample         = metrics.ExpDecaySample
+	UniformSample          = metrics.UniformSample
+	NilSample              = metrics.NilSample
+	GraphiteConfig         = metrics.GraphiteConfig
+	OpenTSDBConfig         = metrics.OpenTSDBConfig
+	Logger                 = metrics.Logger
+)
+
+// DefaultRegistry is the registry the package-level functions use.
+var DefaultRegistry Registry = metrics.DefaultRegistry
+
+// UseNilMetrics makes the constructors of this package return Nil metrics.
+var UseNilMetrics = false
+
+// NewCounter constructs a new StandardCounter.
+func NewCounter() Counter {
+	if UseNilMetrics {
+		return NilCounter{}
+	}
+	return metrics.NewCounter()
+}
+
+// NewRegisteredCounter constructs and registers a new StandardCounter.
+func NewRegisteredCounter(name string, r Registry) Counter {
+	if UseNilMetrics {
+		return NilCounter{}
+	}
+	return metrics.NewRegisteredCounter(name, registry(r))
+}
+
+// GetOrRegisterCounter returns an existing Counter or constructs and registers a
+// new StandardCounter.
+func GetOrRegisterCounter(name string, r Registry) Counter {
+	if UseNilMetrics {
+		return NilCounter{}
+	}
+	return metrics.GetOrRegisterCounter(name, registry(r))
+}
+
+// NewCounterFloat64 constructs a new StandardCounterFloat64.
+func NewCounterFloat64() CounterFloat64 {
+	if UseNilMetrics {
+		return NilCounterFloat64{}
+	}
+	return metrics.NewCounterFloat64()
+}
+
+// NewRegisteredCounterFloat64 constructs and registers a new StandardCounterFloat64.
+func NewRegisteredCounterFloat64(name string, r Registry) CounterFloat64 {
+	if UseNilMetrics {
+		return NilCounterFloat64{}
+	}
+	return metrics.NewRegisteredCounterFloat64(name, registry(r))
+}
+
+// GetOrRegisterCounterFloat64 returns an existing CounterFloat64 or constructs and registers a
+// new StandardCounterFloat64.
+func GetOrRegisterCounterFloat64(name string, r Registry) CounterFloat64 {
+	if UseNilMetrics {
+		return NilCounterFloat64{}
+	}
+	return metrics.GetOrRegisterCounterFloat64(name, registry(r))
+}
+
+// NewGauge constructs a new StandardGauge.
+func NewGauge() Gauge {
+	if UseNilMetrics {
+		return NilGauge{}
+	}
+	return metrics.NewGauge()
+}
+
+// NewRegisteredGauge constructs and registers a new StandardGauge.
+func NewRegisteredGauge(name string, r Registry) Gauge {
+	if UseNilMetrics {
+		return NilGauge{}
+	}
+	return metrics.NewRegisteredGauge(name, registry(r))
+}
+
+// GetOrRegisterGauge returns an existing Gauge or constructs and registers a
+// new StandardGauge.
+func GetOrRegisterGauge(name string, r Registry) Gauge {
+	if UseNilMetrics {
+		return NilGauge{}
+	}
+	return metrics.GetOrRegisterGauge(name, registry(r))
+}
+
+// NewGaugeFloat64 constructs a new StandardGaugeFloat64.
+func NewGaugeFloat64() GaugeFloat64 {
+	if UseNilMetrics {
+		return NilGaugeFloat64{}
+	}
+	return metrics.NewGaugeFloat64()
+}
+
+// NewRegisteredGaugeFloat64 constructs and registers a new StandardGaugeFloat64.
+func NewRegisteredGaugeFloat64(name string, r Registry) GaugeFloat64 {
+	if UseNilMetrics {
+		return NilGaugeFloat64{}
+	}
+	return metrics.NewRegisteredGaugeFloat64(name, registry(r))
+}
+
+// GetOrRegisterGaugeFloat64 returns an existing GaugeFloat64 or constructs and registers a
+// new StandardGaugeFloat64.
+func GetOrRegisterGaugeFloat64(name string, r Registry) GaugeFloat64 {
+	if UseNilMetrics {
+		return NilGaugeFloat64{}
+	}
+	return metrics.GetOrRegisterGaugeFloat64(name, registry(r))
+}
+
+// NewMeter constructs a new StandardMeter.
+func NewMeter() Meter {
+	if UseNilMetrics {
+		return NilMeter{}
+	}
+	return metrics.NewMeter()
+}
+
+// NewRegisteredMeter constructs and registers a new StandardMeter.
+func NewRegisteredMeter(name string, r Registry) Meter {
+	if UseNilMetrics {
+		return NilMeter{}
+	}
+	return metrics.NewRegisteredMeter(name, registry(r))
+}
+
+// GetOrRegisterMeter returns an existing Meter or constructs and registers a
+// new StandardMeter.
+func GetOrRegisterMeter(name string, r Registry) Meter {
+	if UseNilMetrics {
+		return NilMeter{}
+	}
+	return metrics.GetOrRegisterMeter(name, registry(r))
+}
+
+// NewTimer constructs a new StandardTimer.
+func NewTimer() Timer {
+	if UseNilMetrics {
+		return NilTimer{}
+	}
+	return metrics.NewTimer()
+}
+
+// NewRegisteredTimer constructs and registers a new StandardTimer.
+func NewRegisteredTimer(name string, r Registry) Timer {
+	if UseNilMetrics {
+		return NilTimer{}
+	}
+	return metrics.NewRegisteredTimer(name, registry(r))
+}
+
+// GetOrRegisterTimer returns an existing Timer or constructs and registers a
+// new StandardTimer.
+func GetOrRegisterTimer(name string, r Registry) Timer {
+	if UseNilMetrics {
+		return NilTimer{}
+	}
+	return metrics.GetOrRegisterTimer(name, registry(r))
+}
+
+// NewFunctionalGauge constructs a new FunctionalGauge.
+func NewFunctionalGauge(f func() int64) Gauge {
+	if UseNilMetrics {
+		return NilGauge{}
+	}
+	return metrics.NewFunctionalGauge(f)
+}
+
+// NewRegisteredFunctionalGauge constructs and registers a new FunctionalGauge.
+func NewRegisteredFunctionalGauge(name string, r Registry, f func() int64) Gauge {
+	if UseNilMetrics {
+		return NilGauge{}
+	}
+	return metrics.NewRegisteredFunctionalGauge(name, registry(r), f)
+}
+
+// NewFunctionalGaugeFloat64 constructs a new FunctionalGaugeFloat64.
+func NewFunctionalGaugeFloat64(f func() float64) GaugeFloat64 {
+	if UseNilMetrics {
+		return NilGaugeFloat64{}
+	}
+	return metrics.NewFunctionalGaugeFloat64(f)
+}
+
+// NewRegisteredFunctionalGaugeFloat64 constructs and registers a new
+// FunctionalGaugeFloat64.
+func NewRegisteredFunctionalGaugeFloat64(name string, r Registry, f func() float64) GaugeFloat64 {
+	if UseNilMetrics {
+		return NilGaugeFloat64{}
+	}
+	return metrics.NewRegisteredFunctionalGaugeFloat64(name, registry(r), f)
+}
+
+// NewHealthcheck constructs a new Healthcheck which will use the given
+// function to update its status.
+func NewHealthcheck(f func(Healthcheck)) Healthcheck {
+	if UseNilMetrics {
+		return NilHealthcheck{}
+	}
+	return metrics.NewHealthcheck(f)
+}
+
+// NewHistogram constructs a new StandardHistogram from a Sample.
+func NewHistogram(s Sample) Histogram {
+	if UseNilMetrics {
+		return NilHistogram{}
+	}
+	return metrics.NewHistogram(s)
+}
+
+// NewRegisteredHistogram constructs and registers a new StandardHistogram from
+// a Sample.
+func NewRegisteredHistogram(name string, r Registry, s Sample) Histogram {
+	if UseNilMetrics {
+		return NilHistogram{}
+	}
+	return metrics.NewRegisteredHistogram(name, registry(r), s)
+}
+
+// GetOrRegisterHistogram returns an existing Histogram or constructs and
+// registers a new StandardHistogram.
+func GetOrRegisterHistogram(name string, r Registry, s Sample) Histogram {
+	if UseNilMetrics {
+		return NilHistogram{}
+	}
+	return metrics.GetOrRegisterHistogram(name, registry(r), s)
+}
+
+// NewCustomTimer constructs a new StandardTimer from a Histogram and a Meter.
+func NewCustomTimer(h Histogram, m Meter) Timer {
+	if UseNilMetrics {
+		return NilTimer{}
+	}
+	return metrics.NewCustomTimer(h, m)
+}
+
+// NewEWMA constructs a new EWMA with the given alpha.
+func NewEWMA(alpha float64) EWMA {
+	if UseNilMetrics {
+		return NilEWMA{}
+	}
+	return metrics.NewEWMA(alpha)
+}
+
+// NewEWMA1 constructs a new EWMA for a one-minute moving average.
+func NewEWMA1() EWMA {
+	if UseNilMetrics {
+		return NilEWMA{}
+	}
+	return metrics.NewEWMA1()
+}
+
+// NewEWMA5 constructs a new EWMA for a five-minute moving average.
+func NewEWMA5() EWMA {
+	if UseNilMetrics {
+		return NilEWMA{}
+	}
+	return metrics.NewEWMA5()
+}
+
+// NewEWMA15 constructs a new EWMA for a fifteen-minute moving average.
+func NewEWMA15() EWMA {
+	if UseNilMetrics {
+		return NilEWMA{}
+	}
+	return metrics.NewEWMA15()
+}
+
+// NewExpDecaySample constructs a new exponentially-decaying sample with the
+// given reservoir size and alpha.
+func NewExpDecaySample(reservoirSize int, alpha float64) Sample {
+	if UseNilMetrics {
+		return NilSample{}
+	}
+	return metrics.NewExpDecaySample(reservoirSize, alpha)
+}
+
+// NewUniformSample constructs a new uniform sample with the given reservoir
+// size.
+func NewUniformSample(reservoirSize int) Sample {
+	if UseNilMetrics {
+		return NilSample{}
+	}
+	return metrics.NewUniformSample(reservoirSize)
+}
+
+// NewRegistry creates a new registry.
+func NewRegistry() Registry {
+	return metrics.NewRegistry()
+}
+
+// NewPrefixedRegistry creates a registry prefixing the names of its metrics.
+func NewPrefixedRegistry(prefix string) Registry {
+	return metrics.NewPrefixedRegistry(prefix)
+}
+
+// NewPrefixedChildRegistry creates a view of parent prefixing the names of
+// its metrics.
+func NewPrefixedChildRegistry(parent Registry, prefix string) Registry {
+	return metrics.NewPrefixedChildRegistry(parent, prefix)
+}
+
+// Each calls the given function for each registered metric.
+func Each(f func(string, interface{})) {
+	DefaultRegistry.Each(f)
+}
+
+// Get returns the metric by the given name or nil if none is registered.
+func Get(name string) interface{} {
+	return DefaultRegistry.Get(name)
+}
+
+// GetOrRegister gets an existing metric or creates and registers a new one.
+func GetOrRegister(name string, i interface{}) interface{} {
+	return DefaultRegistry.GetOrRegister(name, i)
+}
+
+// Register registers the given metric under the given name.  It returns a
+// DuplicateMetric if a metric by the given name is already registered.
+func Register(name string, i interface{}) error {
+	return DefaultRegistry.Register(name, i)
+}
+
+// MustRegister registers the given metric under the given name.  It panics
+// if a metric by the given name is already registered.
+func MustRegister(name string, i interface{}) {
+	if err := Register(name, i); err != nil {
+		panic(err)
+	}
+}
+
+// RunHealthchecks runs all registered healthchecks.
+func RunHealthchecks() {
+	DefaultRegistry.RunHealthchecks()
+}
+
+// Unregister unregisters the metric with the given name.
+func Unregister(name string) {
+	DefaultRegistry.Unregister(name)
+}
+
+// SampleMax returns the maximum value of the slice of int64.
+func SampleMax(values []int64) int64 {
+	return metrics.SampleMax(values)
+}
+
+// SampleMean returns the mean value of the slice of int64.
+func SampleMean(values []int64) float64 {
+	return metrics.SampleMean(values)
+}
+
+// SampleMin returns the minimum value of the slice of int64.
+func SampleMin(values []int64) int64 {
+	return metrics.SampleMin(values)
+}
+
+// SampleStdDev returns the standard deviation of the slice of int64.
+func SampleStdDev(values []int64) float64 {
+	return metrics.SampleStdDev(values)
+}
+
+// SampleSum returns the sum of the slice of int64.
+func SampleSum(values []int64) int64 {
+	return metrics.SampleSum(values)
+}
+
+// SampleVariance returns the variance of the slice of int64.
+func SampleVariance(values []int64) float64 {
+	return metrics.SampleVariance(values)
+}
+
+// SamplePercentile returns an arbitrary percentile of the slice of int64.
+func SamplePercentile(values []int64, p float64) float64 {
+	return metrics.SamplePercentile(values, p)
+}
+
+// SamplePercentiles returns a slice of arbitrary percentiles of the slice of
+// int64.
+func SamplePercentiles(values []int64, ps []float64) []float64 {
+	return metrics.SamplePercentiles(values, ps)
+}
+
+// Log logs the metrics of r to l every freq.
+func Log(r Registry, freq time.Duration, l Logger) {
+	metrics.Log(registry(r), freq, l)
+}
+
+// LogScaled is Log with durations in units of scale.
+func LogScaled(r Registry, freq time.Duration, scale time.Duration, l Logger) {
+	metrics.LogScaled(registry(r), freq, scale, l)
+}
+
+// Write writes the metrics of r to w every d.
+func Write(r Registry, d time.Duration, w io.Writer) {
+	metrics.Write(registry(r), d, w)
+}
+
+// WriteOnce writes the metrics of r to w once.
+func WriteOnce(r Registry, w io.Writer) {
+	metrics.WriteOnce(registry(r), w)
+}
+
+// WriteJSON writes the metrics of r to w as JSON every d.
+func WriteJSON(r Registry, d time.Duration, w io.Writer) {
+	metrics.WriteJSON(registry(r), d, w)
+}
+
+// WriteJSONOnce writes the metrics of r to w as JSON once.
+func WriteJSONOnce(r Registry, w io.Writer) {
+	metrics.WriteJSONOnce(registry(r), w)
+}
+
+// Graphite reports the metrics of r to the Graphite server at addr every d,
+// prefixing their names with prefix.
+func Graphite(r Registry, d time.Duration, prefix string, addr *net.TCPAddr) {
+	metrics.Graphite(registry(r), d, prefix, addr)
+}
+
+// GraphiteWithConfig is Graphite configured by c.
+func GraphiteWithConfig(c GraphiteConfig) {
+	metrics.GraphiteWithConfig(c)
+}
+
+// GraphiteOnce reports the metrics of c.Registry to Graphite once.
+func GraphiteOnce(c GraphiteConfig) error {
+	return metrics.GraphiteOnce(c)
+}
+
+// OpenTSDB reports the metrics of r to the OpenTSDB server at addr every d,
+// prefixing their names with prefix.
+func OpenTSDB(r Registry, d time.Duration, prefix string, addr *net.TCPAddr) {
+	metrics.OpenTSDB(registry(r), d, prefix, addr)
+}
+
+// OpenTSDBWithConfig is OpenTSDB configured by c.
+func OpenTSDBWithConfig(c OpenTSDBConfig) {
+	metrics.OpenTSDBWithConfig(c)
+}
+
+// CaptureRuntimeMemStats captures runtime.MemStats into r every d.
+func CaptureRuntimeMemStats(r Registry, d time.Duration) {
+	metrics.CaptureRuntimeMemStats(registry(r), d)
+}
+
+// CaptureRuntimeMemStatsOnce captures runtime.MemStats into r once.
+func CaptureRuntimeMemStatsOnce(r Registry) {
+	metrics.CaptureRuntimeMemStatsOnce(registry(r))
+}
+
+// RegisterRuntimeMemStats registers the runtime.MemStats metrics in r.
+func RegisterRuntimeMemStats(r Registry) {
+	metrics.RegisterRuntimeMemStats(registry(r))
+}
+
+// CaptureDebugGCStats captures debug.GCStats into r every d.
+func CaptureDebugGCStats(r Registry, d time.Duration) {
+	metrics.CaptureDebugGCStats(registry(r), d)
+}
+
+// CaptureDebugGCStatsOnce captures debug.GCStats into r once.
+func CaptureDebugGCStatsOnce(r Registry) {
+	metrics.CaptureDebugGCStatsOnce(registry(r))
+}
+
+// RegisterDebugGCStats registers the debug.GCStats metrics in r.
+func RegisterDebugGCStats(r Registry) {
+	metrics.RegisterDebugGCStats(registry(r))
+}
+
+// registry returns r, or DefaultRegistry if r is nil, so the functions that
+// default to the default registry use this package's.
+func registry(r Registry) Registry {
+	if nil == r {
+		return DefaultRegistry
+	}
+	return r
+}
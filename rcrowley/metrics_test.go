@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	moonfrog "github.com/moonfrog/go-metrics"
+)
+
+// The signatures of upstream call sites, which must keep compiling.
+var (
+	_ func() Counter                                      = NewCounter
+	_ func(string, Registry) Counter                      = GetOrRegisterCounter
+	_ func(string, Registry, func() int64) Gauge          = NewRegisteredFunctionalGauge
+	_ func(string, Registry, func() float64) GaugeFloat64 = NewRegisteredFunctionalGaugeFloat64
+	_ func(string, Registry, Sample) Histogram            = GetOrRegisterHistogram
+	_ func(int, float64) Sample                           = NewExpDecaySample
+	_ func(string, Registry) Timer                        = GetOrRegisterTimer
+	_ func(string) Registry                               = NewPrefixedRegistry
+	_ func(Registry, io.Writer)                           = WriteJSONOnce
+	_ func(Registry, time.Duration, io.Writer)            = WriteJSON
+	_ func(Registry, time.Duration, Logger)               = Log
+	_ func(Registry, time.Duration, string, *net.TCPAddr) = Graphite
+	_ func(GraphiteConfig)                                = GraphiteWithConfig
+	_ func(GraphiteConfig) error                          = GraphiteOnce
+	_ func(Registry, time.Duration, string, *net.TCPAddr) = OpenTSDB
+	_ func(string, interface{}) error                     = Register
+	_ func(Registry)                                      = RegisterRuntimeMemStats
+	_ Registry                                            = &StandardRegistry{}
+	_ Counter                                             = &StandardCounter{}
+	_ Gauge                                               = FunctionalGauge{}
+	_ func(values []int64, ps []float64) []float64        = SamplePercentiles
+)
+
+// useRegistry replaces DefaultRegistry with an empty registry for the rest
+// of the test and returns it.
+func useRegistry(t *testing.T) Registry {
+	old := DefaultRegistry
+	r := NewRegistry()
+	DefaultRegistry = r
+	t.Cleanup(func() { DefaultRegistry = old })
+	return r
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	r := useRegistry(t)
+	GetOrRegisterCounter("requests", nil).Inc(2)
+	NewRegisteredFunctionalGauge("players", nil, func() int64 { return 7 })
+
+	if c, ok := r.Get("requests").(Counter); !ok || 2 != c.Count() {
+		t.Errorf("requests: %v", r.Get("requests"))
+	}
+	if g, ok := Get("players").(Gauge); !ok || 7 != g.Value() {
+		t.Errorf("players: %v", Get("players"))
+	}
+	if nil != moonfrog.DefaultRegistry.Get("players") {
+		t.Error("registered in the underlying DefaultRegistry")
+	}
+}
+
+func TestTypesInterchange(t *testing.T) {
+	r := moonfrog.NewRegistry()
+	c := NewRegisteredCounter("requests", r)
+	c.Inc(1)
+	if moonfrog.GetOrRegisterCounter("requests", r) != c {
+		t.Error("counter not shared with the underlying package")
+	}
+	var s *moonfrog.StandardCounter = c.(*StandardCounter)
+	if 1 != s.Count() {
+		t.Error(s.Count())
+	}
+}
+
+func TestWriteJSONOnce(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("requests", r).Inc(3)
+	NewRegisteredFunctionalGauge("players", r, func() int64 { return 7 })
+
+	var buf bytes.Buffer
+	WriteJSONOnce(r, &buf)
+	var got map[string]map[string]float64
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err, buf.String())
+	}
+	if 3 != got["requests"]["count"] || 7 != got["players"]["value"] {
+		t.Error(got)
+	}
+}
+
+func TestGraphiteOnce(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	r := NewRegistry()
+	NewRegisteredCounter("requests", r).Inc(3)
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- GraphiteOnce(GraphiteConfig{
+			Addr:          ln.Addr().(*net.TCPAddr),
+			Registry:      r,
+			FlushInterval: time.Second,
+			DurationUnit:  time.Millisecond,
+			Prefix:        "game",
+			Percentiles:   []float64{0.5},
+		})
+	}()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "game.requests.count 3 ") {
+		t.Errorf("%q, %v", line, err)
+	}
+	if err := <-errs; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUseNilMetrics(t *testing.T) {
+	defer func(b bool) { UseNilMetrics = b }(UseNilMetrics)
+	UseNilMetrics = true
+	r := useRegistry(t)
+
+	if _, ok := NewRegisteredFunctionalGauge("players", nil, func() int64 { return 7 }).(NilGauge); !ok {
+		t.Error("NewRegisteredFunctionalGauge didn't return a NilGauge")
+	}
+	if _, ok := GetOrRegisterTimer("latency", nil).(NilTimer); !ok {
+		t.Error("GetOrRegisterTimer didn't return a NilTimer")
+	}
+	if nil != r.Get("players") {
+		t.Error("Nil metric registered")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package metrics
+
+import (
+	"log/syslog"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+)
+
+// Syslog writes the metrics of r to w every d.
+func Syslog(r Registry, d time.Duration, w *syslog.Writer) {
+	metrics.Syslog(registry(r), d, w)
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+)
+
+// A Warmup is a condition a ReadinessGate waits for: the value of Metric,
+// extracted by Value, reaching Threshold, such as a cache fill gauge
+// reaching 0.9.
+type Warmup struct {
+	Metric    string
+	Value     AlertValue // nil compares the dashboard's headline value
+	Threshold float64
+}
+
+// A ReadinessGate is a Healthcheck that passes once a process is ready to
+// take traffic: when the healthchecks it requires pass and its warm-up
+// metrics have each reached their threshold.  Register it in the registry
+// served by NewHealthHandler, or serve a registry holding only it, so
+// rolling deploys wait for new instances to warm up:
+//
+//	g := metrics.NewRegisteredReadinessGate("ready", nil)
+//	g.RequireHealthchecks("db", "cache")
+//	g.RequireWarmup(metrics.Warmup{Metric: "cache.fill", Threshold: 0.9})
+//
+// Warm-ups latch: once a warm-up metric has reached its threshold it stays
+// warm, so a cache evicting entries doesn't take the process out of
+// rotation.  Required healthchecks are run on every check.
+type ReadinessGate struct {
+	*StandardHealthcheck
+	r Registry
+
+	mutex   sync.Mutex
+	checks  []string
+	warmups []*warmupState
+}
+
+type warmupState struct {
+	Warmup
+	warm bool
+}
+
+// NewReadinessGate constructs a ReadinessGate on the metrics of r, or
+// DefaultRegistry if r is nil, which is ready until it requires anything.
+func NewReadinessGate(r Registry) *ReadinessGate {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	g := &ReadinessGate{r: r}
+	g.StandardHealthcheck = &StandardHealthcheck{f: g.check}
+	return g
+}
+
+// NewRegisteredReadinessGate constructs a ReadinessGate on the metrics of r
+// and registers it in r as a Healthcheck named name.
+func NewRegisteredReadinessGate(name string, r Registry) *ReadinessGate {
+	g := NewReadinessGate(r)
+	g.r.Register(name, g)
+	return g
+}
+
+// RequireHealthchecks makes the gate wait for the healthchecks registered
+// under the given names to pass.  A healthcheck that isn't registered
+// counts as failing.
+func (g *ReadinessGate) RequireHealthchecks(names ...string) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.checks = append(g.checks, names...)
+}
+
+// RequireWarmup makes the gate wait for w's metric to reach its threshold.
+func (g *ReadinessGate) RequireWarmup(w Warmup) {
+	if nil == w.Value {
+		w.Value = headlineValue
+	}
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.warmups = append(g.warmups, &warmupState{Warmup: w})
+}
+
+// Ready checks the gate and returns whether it passes.
+func (g *ReadinessGate) Ready() bool {
+	g.Check()
+	return nil == g.Error()
+}
+
+// check marks h unhealthy with the first requirement that isn't met.
+func (g *ReadinessGate) check(h Healthcheck) {
+	g.mutex.Lock()
+	checks := append([]string(nil), g.checks...)
+	var err error
+	for _, w := range g.warmups {
+		if w.warm {
+			continue
+		}
+		v, ok := 0.0, false
+		if m := g.r.Get(w.Metric); nil != m {
+			v, ok = w.Value(m)
+		}
+		if w.warm = ok && v >= w.Threshold; !w.warm && nil == err {
+			err = fmt.Errorf("warming up: %s at %v of %v", w.Metric, v, w.Threshold)
+		}
+	}
+	g.mutex.Unlock()
+	if nil != err {
+		h.Unhealthy(err)
+		return
+	}
+
+	for _, name := range checks {
+		c, ok := g.r.Get(name).(Healthcheck)
+		if !ok {
+			h.Unhealthy(fmt.Errorf("healthcheck %s isn't registered", name))
+			return
+		}
+		if c == Healthcheck(g) {
+			continue
+		}
+		c.Check()
+		if err := c.Error(); nil != err {
+			h.Unhealthy(fmt.Errorf("%s: %v", name, err))
+			return
+		}
+	}
+	h.Healthy()
+}
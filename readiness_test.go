@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadinessGate(t *testing.T) {
+	r := NewRegistry()
+	fill := NewRegisteredGaugeFloat64("cache.fill", r)
+	var dbErr error
+	r.Register("db", NewHealthcheck(func(h Healthcheck) {
+		if nil != dbErr {
+			h.Unhealthy(dbErr)
+		} else {
+			h.Healthy()
+		}
+	}))
+
+	g := NewRegisteredReadinessGate("ready", r)
+	if !g.Ready() {
+		t.Fatalf("gate without requirements isn't ready: %v", g.Error())
+	}
+	g.RequireHealthchecks("db")
+	g.RequireWarmup(Warmup{Metric: "cache.fill", Threshold: 0.9})
+
+	fill.Update(0.5)
+	if g.Ready() {
+		t.Error("ready before warming up")
+	}
+	fill.Update(0.95)
+	dbErr = errors.New("connecting")
+	if g.Ready() {
+		t.Error("ready with a failing healthcheck")
+	}
+	dbErr = nil
+	fill.Update(0.1)
+	if !g.Ready() {
+		t.Errorf("not ready once warm: %v", g.Error())
+	}
+
+	g.RequireHealthchecks("queue")
+	w := httptest.NewRecorder()
+	NewHealthHandler(r, time.Second).ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if http.StatusServiceUnavailable != w.Code {
+		t.Errorf("health handler with a missing required healthcheck: %v", w.Code)
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"sort"
 	"strings"
 	"sync"
-	"time"
+	"sync/atomic"
 )
 
 // DuplicateMetric is the error returned by Registry.Register when a metric
@@ -18,6 +18,14 @@ func (err DuplicateMetric) Error() string {
 	return fmt.Sprintf("duplicate metric: %s", string(err))
 }
 
+// MissingMetric is the error returned by Registry.Reset when no metric is
+// registered under the given name.
+type MissingMetric string
+
+func (err MissingMetric) Error() string {
+	return fmt.Sprintf("missing metric: %s", string(err))
+}
+
 // A Registry holds references to a set of metrics by name and can iterate
 // over them, calling callback functions provided by the user.
 //
@@ -25,9 +33,17 @@ func (err DuplicateMetric) Error() string {
 // the Registry API as appropriate.
 type Registry interface {
 
+	// Make oldName another name for the metric registered as newName, so
+	// that callers of oldName share its metric and both names are iterated.
+	Alias(oldName, newName string) error
+
 	// Call the given function for each registered metric.
 	Each(func(string, interface{}))
 
+	// Call the given function for each registered metric in no particular
+	// order, which is cheaper than Each for large registries.
+	EachUnsorted(func(string, interface{}))
+
 	// Get the metric by the given name or nil if none is registered.
 	Get(string) interface{}
 
@@ -39,18 +55,29 @@ type Registry interface {
 	// Register the given metric under the given name.
 	Register(string, interface{}) error
 
-	// Run all registered healthchecks.
+	// Zero the counter or histogram with the given name.
+	Reset(string) error
+
+	// Run all registered healthchecks, recording their results as metrics.
 	RunHealthchecks()
 
 	// Unregister the metric with the given name.
 	Unregister(string)
 
-	// Unregister all metrics.  (Mostly for testing.)
+	// Unregister all metrics, stopping those that can be.  (Mostly for
+	// testing.)
 	UnregisterAll()
 
-	// updates the metric name with val, doesn't work for gaugeFloat64
+	// Stop and unregister all metrics and stop the collectors scheduled on
+	// the registry.
+	Close() error
+
+	// updates the metric name with val, creating a counter if it doesn't exist
 	Update(name string, val int64)
 
+	// applies Update to every name in the batch under a single lock
+	UpdateBatch(map[string]int64)
+
 	// current stats string
 	GetCurrent() string
 }
@@ -58,13 +85,61 @@ type Registry interface {
 // The standard implementation of a Registry is a mutex-protected map
 // of names to metrics.
 type StandardRegistry struct {
-	metrics map[string]Metric
-	mutex   sync.RWMutex
+	metrics     map[string]interface{}
+	aliases     map[string]string
+	closers     []func()
+	mutex       sync.RWMutex
+	timerConfig TimerConfig
+	duplicates  DuplicatePolicy
+
+	// generation is incremented whenever a name may stop resolving to the
+	// metric it did, for caches of resolved metrics to notice.
+	generation uint64
+
+	stats registryStats // reported by SelfMetrics
 }
 
 // Create a new registry.
 func NewRegistry() Registry {
-	return &StandardRegistry{metrics: make(map[string]Metric)}
+	return &StandardRegistry{metrics: make(map[string]interface{})}
+}
+
+// Alias makes oldName another name for the metric registered as newName,
+// for renaming a metric while exporters keep reporting the old name too.
+// Getting, registering, updating or resetting oldName acts on newName, and
+// Each and EachUnsorted visit the metric under both names once it exists.
+// Unregister oldName to end the alias.  Alias returns a DuplicateMetric if
+// a metric is registered as oldName.
+func (r *StandardRegistry) Alias(oldName, newName string) error {
+	r.lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.metrics[oldName]; ok {
+		return DuplicateMetric(oldName)
+	}
+	newName = r.resolve(newName)
+	if oldName == newName {
+		return fmt.Errorf("metric %s can't alias itself", oldName)
+	}
+	if nil == r.aliases {
+		r.aliases = make(map[string]string)
+	}
+	atomic.AddUint64(&r.generation, 1)
+	for name, target := range r.aliases {
+		if target == oldName {
+			r.aliases[name] = newName
+		}
+	}
+	r.aliases[oldName] = newName
+	return nil
+}
+
+// resolve returns the name the metric called name is registered as.
+// assumes lock is taken
+func (r *StandardRegistry) resolve(name string) string {
+	if target, ok := r.aliases[name]; ok {
+		return target
+	}
+	return name
 }
 
 // Call the given function for each registered metric.
@@ -77,7 +152,27 @@ func (r *StandardRegistry) Each(f func(string, interface{})) {
 	sort.Strings(keys)
 
 	for _, name := range keys {
-		f(name, r.registered()[name])
+		f(name, registeredMetrics[name])
+	}
+}
+
+// Call the given function for each registered metric in no particular order.
+// The metrics are copied out under the read lock so f may use the registry.
+func (r *StandardRegistry) EachUnsorted(f func(string, interface{})) {
+	r.mutex.RLock()
+	metrics := make([]namedMetric, 0, len(r.metrics)+len(r.aliases))
+	for name, i := range r.metrics {
+		metrics = append(metrics, namedMetric{name, i})
+	}
+	for name, target := range r.aliases {
+		if i, ok := r.metrics[target]; ok {
+			metrics = append(metrics, namedMetric{name, i})
+		}
+	}
+	r.mutex.RUnlock()
+
+	for _, nm := range metrics {
+		f(nm.name, nm.m)
 	}
 }
 
@@ -85,7 +180,7 @@ func (r *StandardRegistry) Each(f func(string, interface{})) {
 func (r *StandardRegistry) Get(name string) interface{} {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
-	return r.metrics[name]
+	return r.metrics[r.resolve(name)]
 }
 
 // Gets an existing metric or creates and registers a new one. Threadsafe
@@ -93,137 +188,499 @@ func (r *StandardRegistry) Get(name string) interface{} {
 // The interface can be the metric to register if not found in registry,
 // or a function returning the metric for lazy instantiation.
 func (r *StandardRegistry) GetOrRegister(name string, i interface{}) interface{} {
-	r.mutex.Lock()
+	r.mutex.RLock()
+	name = r.resolve(name)
+	metric, ok := r.metrics[name]
+	r.mutex.RUnlock()
+	if ok {
+		return metric
+	}
+	if metricDisabled(name) {
+		return stubMetric(instantiate(i))
+	}
+
+	r.lock()
 	defer r.mutex.Unlock()
+	name = r.resolve(name)
 	if metric, ok := r.metrics[name]; ok {
 		return metric
 	}
+	i, ok = applyPolicy(name, instantiate(i))
+	if ok {
+		r.register(name, i)
+	}
+	return i
+}
+
+// instantiate calls i if it is a constructor and returns i unchanged
+// otherwise.  The constructors used by this package are matched without
+// reflection.
+func instantiate(i interface{}) interface{} {
+	switch f := i.(type) {
+	case func() Counter:
+		return f()
+	case func() Gauge:
+		return f()
+	case func() GaugeFloat64:
+		return f()
+	case func() CounterFloat64:
+		return f()
+	case func() Histogram:
+		return f()
+	case func() Instant:
+		return f()
+	case func() Meter:
+		return f()
+	case func() Timer:
+		return f()
+	case func() interface{}:
+		return f()
+	}
 	if v := reflect.ValueOf(i); v.Kind() == reflect.Func {
-		i = v.Call(nil)[0].Interface()
+		return v.Call(nil)[0].Interface()
 	}
-	r.register(name, i)
 	return i
 }
 
 // creates a counter if metric doesn't exist
 func (r *StandardRegistry) Update(name string, val int64) {
 	r.mutex.RLock()
-	m := r.metrics[name]
+	m := r.metrics[r.resolve(name)]
 	r.mutex.RUnlock()
 	if m == nil {
 		m = NewRegisteredCounter(name, r)
 	}
 
-	m.Update(val)
+	updateMetric(m, val)
+}
+
+func updateMetric(m interface{}, val int64) {
+	switch metric := m.(type) {
+	case Metric:
+		metric.Update(val)
+	case GaugeFloat64:
+		metric.Update(float64(val))
+	case CounterFloat64:
+		metric.Inc(float64(val))
+	}
+}
+
+// UpdateBatch applies Update to every name in batch, looking the metrics up
+// under one read lock and taking the write lock at most once to create the
+// missing counters.  The metrics are updated once the locks are released, so
+// the functions watching them may use the registry.
+func (r *StandardRegistry) UpdateBatch(batch map[string]int64) {
+	metrics := make(map[string]interface{}, len(batch))
+	var missing []string
+	r.mutex.RLock()
+	for name := range batch {
+		if m := r.metrics[r.resolve(name)]; m != nil {
+			metrics[name] = m
+		} else {
+			missing = append(missing, name)
+		}
+	}
+	r.mutex.RUnlock()
+
+	if len(missing) > 0 {
+		r.lock()
+		for _, name := range missing {
+			m := r.metrics[r.resolve(name)]
+			if m == nil {
+				m = NewCounter()
+				r.register(name, m)
+			}
+			metrics[name] = m
+		}
+		r.mutex.Unlock()
+	}
+	for name, m := range metrics {
+		updateMetric(m, batch[name])
+	}
 }
 
 // Register the given metric under the given name.  Returns a DuplicateMetric
-// if a metric by the given name is already registered.
+// if a metric by the given name is already registered, unless the registry's
+// DuplicatePolicy says otherwise.
 func (r *StandardRegistry) Register(name string, i interface{}) error {
-	r.mutex.Lock()
+	r.lock()
 	defer r.mutex.Unlock()
 	return r.register(name, i)
 }
 
-// Run all registered healthchecks.
-func (r *StandardRegistry) RunHealthchecks() {
-	r.mutex.Lock()
+// DefaultTimerConfig returns the TimerConfig of the timers
+// GetOrRegisterTimer and NewRegisteredTimer construct in the registry.
+func (r *StandardRegistry) DefaultTimerConfig() TimerConfig {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.timerConfig
+}
+
+// SetDefaultTimerConfig sets the TimerConfig of the timers
+// GetOrRegisterTimer and NewRegisteredTimer construct in the registry from
+// now on.
+func (r *StandardRegistry) SetDefaultTimerConfig(c TimerConfig) {
+	r.lock()
 	defer r.mutex.Unlock()
-	for _, i := range r.metrics {
+	r.timerConfig = c
+}
+
+// Reset zeroes the counter, Instant counter or histogram with the given name.
+// It returns a MissingMetric if there is none and an error for metrics that
+// can't be reset, such as meters and timers.
+func (r *StandardRegistry) Reset(name string) error {
+	r.mutex.RLock()
+	i := r.metrics[r.resolve(name)]
+	r.mutex.RUnlock()
+	return resetMetric(name, i)
+}
+
+func resetMetric(name string, i interface{}) error {
+	switch metric := i.(type) {
+	case nil:
+		return MissingMetric(name)
+	case Counter:
+		metric.Clear()
+	case CounterFloat64:
+		metric.Clear()
+	case Instant:
+		metric.Clear()
+	case Histogram:
+		metric.Clear()
+	default:
+		return fmt.Errorf("metric %s of type %T can't be reset", name, i)
+	}
+	return nil
+}
+
+// Run all registered healthchecks and record whether each passed in the
+// Gauge named after it with HealthcheckGaugeSuffix appended: 1 if it did, 0
+// if not.  The checks run without the registry's lock held, so slow ones
+// don't block other users of the registry and checks may use it themselves.
+func (r *StandardRegistry) RunHealthchecks() {
+	r.mutex.RLock()
+	checks := make(map[string]Healthcheck)
+	for name, i := range r.metrics {
 		if h, ok := i.(Healthcheck); ok {
-			h.Check()
+			checks[name] = h
+		}
+	}
+	r.mutex.RUnlock()
+	for name, h := range checks {
+		h.Check()
+		var healthy int64
+		if nil == h.Error() {
+			healthy = 1
+		}
+		if g, ok := r.GetOrRegister(name+HealthcheckGaugeSuffix, NewGauge).(Gauge); ok {
+			g.Update(healthy)
 		}
 	}
 }
 
-// Unregister the metric with the given name.
+// Unregister the metric with the given name, stopping it if it can be, or
+// the alias if name is one.
 func (r *StandardRegistry) Unregister(name string) {
-	r.mutex.Lock()
+	r.lock()
 	defer r.mutex.Unlock()
-	delete(r.metrics, name)
+	atomic.AddUint64(&r.generation, 1)
+	if _, ok := r.aliases[name]; ok {
+		delete(r.aliases, name)
+		return
+	}
+	if i, ok := r.metrics[name]; ok {
+		stopMetric(i)
+		delete(r.metrics, name)
+		atomic.AddUint64(&r.stats.unregistrations, 1)
+	}
 }
 
-// Unregister all metrics.  (Mostly for testing.)
+// Unregister all metrics, stopping those that can be, such as meters and
+// timers.  (Mostly for testing.)
 func (r *StandardRegistry) UnregisterAll() {
-	r.mutex.Lock()
+	r.lock()
 	defer r.mutex.Unlock()
-	for name, _ := range r.metrics {
+	atomic.AddUint64(&r.generation, 1)
+	atomic.AddUint64(&r.stats.unregistrations, uint64(len(r.metrics)))
+	for name, i := range r.metrics {
+		stopMetric(i)
 		delete(r.metrics, name)
 	}
+	r.aliases = nil
+}
+
+// Close stops and unregisters all metrics like UnregisterAll and stops the
+// collectors scheduled on the registry, for tearing down test suites and
+// short-lived tools.  The registry can be used again afterwards, and closing
+// it again stops only the schedulers created on it since.
+func (r *StandardRegistry) Close() error {
+	r.lock()
+	closers := r.closers
+	r.closers = nil
+	r.mutex.Unlock()
+	for _, f := range closers {
+		f()
+	}
+	r.UnregisterAll()
+	return nil
+}
+
+// onClose arranges for f to be called when the registry is closed.
+func (r *StandardRegistry) onClose(f func()) {
+	r.lock()
+	defer r.mutex.Unlock()
+	r.closers = append(r.closers, f)
+}
+
+// stopMetric stops i if it runs in the background or can otherwise be
+// stopped, such as meters, timers and rates.
+func stopMetric(i interface{}) {
+	if s, ok := i.(interface {
+		Stop()
+	}); ok {
+		s.Stop()
+	}
 }
 
 // assumes lock is taken
 func (r *StandardRegistry) register(name string, i interface{}) error {
-	if _, ok := r.metrics[name]; ok {
-		return DuplicateMetric(name)
+	name = r.resolve(name)
+	if existing, ok := r.metrics[name]; ok {
+		return r.duplicate(name, existing, i)
 	}
-	switch i.(type) {
-	// TODO: add gaugefloat
-	case Counter, Gauge, Healthcheck, Histogram, Meter, Timer, Instant:
-		r.metrics[name] = i.(Metric)
-	case GaugeFloat64:
-		// TODO: fix
-		r.metrics[name] = NewGauge()
+	if metricDisabled(name) {
+		return nil
+	}
+	if registrable(i) {
+		r.metrics[name] = i
+		atomic.AddUint64(&r.stats.registrations, 1)
 	}
 	return nil
 }
 
-func (r *StandardRegistry) registered() map[string]Metric {
+// registrable returns whether i is of a type the registry keeps.
+func registrable(i interface{}) bool {
+	switch i.(type) {
+	case Counter, CounterFloat64, Gauge, GaugeFloat64, Healthcheck, Histogram, Meter, Timer, Instant:
+		return true
+	}
+	return false
+}
+
+func (r *StandardRegistry) registered() map[string]interface{} {
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
-	metrics := make(map[string]Metric, len(r.metrics))
+	metrics := make(map[string]interface{}, len(r.metrics)+len(r.aliases))
 	for name, i := range r.metrics {
 		metrics[name] = i
 	}
+	for name, target := range r.aliases {
+		if i, ok := r.metrics[target]; ok {
+			metrics[name] = i
+		}
+	}
 	return metrics
 }
 
 func (r *StandardRegistry) GetCurrent() string {
+	return renderCurrent(r.Each)
+}
+
+// CurrentGroupByNamespace makes GetCurrent print tagged metrics in sections
+// by namespace, after the untagged ones, leaving the namespace out of their
+// tags.
+var CurrentGroupByNamespace = false
+
+// renderCurrent renders the metrics each visits the way GetCurrent prints
+// them, with tagged names such as ns|grpTAGlatency shown as
+// latency{ns=ns,grp=grp}.
+func renderCurrent(each func(func(string, interface{}))) string {
 	result := "<--------Metrics--------->\n"
-	r.Each(func(name string, m interface{}) {
-		val := ""
-		switch metric := m.(type) {
-		case Instant:
-			val = fmt.Sprintf("%d", metric.Count())
-		case Counter:
-			val = fmt.Sprintf("%d", metric.Count())
-		case Gauge:
-			val = fmt.Sprintf("%d", metric.Value())
-		case GaugeFloat64:
-			val = fmt.Sprintf("%f", metric.Value())
-		case Healthcheck:
-			metric.Check()
-			val = fmt.Sprintf("%v", metric.Error())
-		case Histogram:
-			h := metric.Snapshot()
-			ps := h.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
-			val = fmt.Sprintf("count: %d, min: %d, max: %d, mean: %f, stddev: %f, median: %f, 80%%: %f, 90%%: %f, 99%%: %f, 99.9%%: %f",
-				h.Count(), h.Min(), h.Max(), h.Mean(), h.StdDev(), ps[0], ps[1], ps[2], ps[3], ps[4])
-		case Meter:
-			m := metric.Snapshot()
-			val = fmt.Sprintf("count: %d, 1MR: %f, 5MR: %f, 15MR: %f, mean: %f", m.Count(), m.Rate1(), m.Rate5(), m.Rate15(), m.RateMean())
-		case Timer:
-			scale := float64(time.Second)
-			t := metric.Snapshot()
-			ps := t.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
-			val = fmt.Sprintf("count: %d, min: %f, max: %f, mean: %f, stddev: %f, median: %f, 80%%: %f, 90%%: %f, 99%%: %f, 99.9%%: %f 1MR: %f, 5MR: %f, 15MR: %f, meanRate: %f", t.Count(), float64(t.Min())/scale, float64(t.Max())/scale, t.Mean()/scale, t.StdDev()/scale, ps[0]/scale, ps[1]/scale, ps[2]/scale, ps[3]/scale, ps[4]/scale, t.Rate1(), t.Rate5(), t.Rate15(), t.RateMean())
+	if TextBreakdown {
+		for _, item := range breakdownItems(each) {
+			if item.header != "" {
+				result += "Metrics: " + item.header + "\n"
+			}
+			result += currentLine(item.display, item.name, item.m)
 		}
+		return result
+	}
+	if !CurrentGroupByNamespace {
+		each(func(name string, m interface{}) {
+			result += currentLine(currentName(name, false), name, m)
+		})
+		return result
+	}
 
-		result += fmt.Sprintf("Metrics: %s: %v\n", name, val)
+	sections := make(map[string]string)
+	each(func(name string, m interface{}) {
+		ns := ""
+		if IsTagged(name) {
+			_, tags := ParseTaggedMetric(name)
+			ns = tags["ns"]
+		}
+		sections[ns] += currentLine(currentName(name, true), name, m)
 	})
-
+	result += sections[""]
+	namespaces := make([]string, 0, len(sections))
+	for ns := range sections {
+		if ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	for _, ns := range namespaces {
+		result += "[" + ns + "]\n" + sections[ns]
+	}
 	return result
 }
 
+// currentName renders a tagged metric name as its name followed by its
+// non-empty tags in braces, leaving out the namespace if omitNs is set.
+func currentName(name string, omitNs bool) string {
+	if !IsTagged(name) {
+		return name
+	}
+	base, tags := ParseTaggedMetric(name)
+	var pairs []string
+	for i, k := range tagKeys {
+		if i == 0 && omitNs || tags[k] == "" {
+			continue
+		}
+		pairs = append(pairs, k+"="+tags[k])
+	}
+	if len(pairs) == 0 {
+		return base
+	}
+	return base + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// currentLine renders a single metric the way GetCurrent prints it, under
+// the name display.
+func currentLine(display, name string, m interface{}) string {
+	val := ""
+	scale := SecondsScale.Divisor(name, m)
+	switch metric := m.(type) {
+	case Instant:
+		val = fmt.Sprintf("%d", metric.Count())
+	case Counter:
+		val = fmt.Sprintf("%d", metric.Count())
+	case CounterFloat64:
+		val = fmt.Sprintf("%f", metric.Count())
+	case Gauge:
+		val = fmt.Sprintf("%d", metric.Value())
+	case GaugeFloat64:
+		val = fmt.Sprintf("%f", metric.Value())
+	case Healthcheck:
+		metric.Check()
+		val = fmt.Sprintf("%v", metric.Error())
+	case Histogram:
+		h := metric.Snapshot()
+		ps := h.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
+		if scale == 1 {
+			val = fmt.Sprintf("count: %d, min: %d, max: %d, mean: %f, stddev: %f, median: %f, 80%%: %f, 90%%: %f, 99%%: %f, 99.9%%: %f",
+				h.Count(), h.Min(), h.Max(), h.Mean(), h.StdDev(), ps[0], ps[1], ps[2], ps[3], ps[4])
+		} else {
+			val = fmt.Sprintf("count: %d, min: %f, max: %f, mean: %f, stddev: %f, median: %f, 80%%: %f, 90%%: %f, 99%%: %f, 99.9%%: %f",
+				h.Count(), float64(h.Min())/scale, float64(h.Max())/scale, h.Mean()/scale, h.StdDev()/scale, ps[0]/scale, ps[1]/scale, ps[2]/scale, ps[3]/scale, ps[4]/scale)
+		}
+	case Meter:
+		m := metric.Snapshot()
+		val = fmt.Sprintf("count: %d, 1MR: %f, 5MR: %f, 15MR: %f, mean: %f", m.Count(), m.Rate1(), m.Rate5(), m.Rate15(), m.RateMean())
+	case Timer:
+		t := metric.Snapshot()
+		ps := t.Percentiles([]float64{0.5, 0.80, 0.90, 0.99, 0.999})
+		val = fmt.Sprintf("count: %d, min: %f, max: %f, mean: %f, stddev: %f, median: %f, 80%%: %f, 90%%: %f, 99%%: %f, 99.9%%: %f 1MR: %f, 5MR: %f, 15MR: %f, meanRate: %f", t.Count(), float64(t.Min())/scale, float64(t.Max())/scale, t.Mean()/scale, t.StdDev()/scale, ps[0]/scale, ps[1]/scale, ps[2]/scale, ps[3]/scale, ps[4]/scale, t.Rate1(), t.Rate5(), t.Rate15(), t.RateMean())
+	}
+
+	return fmt.Sprintf("Metrics: %s: %v\n", display, val)
+}
+
 type PrefixedRegistry struct {
 	underlying Registry
 	prefix     string
+	rollup     *rollupCache // set by NewRollupChildRegistry
+	owned      bool         // underlying was created by NewPrefixedRegistry
+
+	// names caches the prefixed names looked up and, when the base registry
+	// is a StandardRegistry, the metrics they resolved to.
+	namesMutex sync.RWMutex
+	names      map[string]prefixedName
+}
+
+// prefixedName is a name cached by a PrefixedRegistry: the name prefixed and
+// the metric it resolved to in the base registry's generation, or nil.
+type prefixedName struct {
+	name       string
+	metric     interface{}
+	generation uint64
+}
+
+// maxPrefixedNames bounds the names a PrefixedRegistry caches, so those
+// looking up unbounded sets of names don't grow without limit.  Names
+// beyond it are prefixed on every call.
+const maxPrefixedNames = 4096
+
+// lookup returns the prefixed name and the cached metric of name, nil if it
+// has none or it may have been unregistered since.
+func (r *PrefixedRegistry) lookup(name string) (string, interface{}) {
+	r.namesMutex.RLock()
+	n, ok := r.names[name]
+	full := len(r.names) >= maxPrefixedNames
+	r.namesMutex.RUnlock()
+	if !ok {
+		n.name = r.prefix + name
+		if !full {
+			r.cache(name, n)
+		}
+		return n.name, nil
+	}
+	if nil == n.metric {
+		return n.name, nil
+	}
+	if base, ok := r.base(); !ok || atomic.LoadUint64(&base.generation) != n.generation {
+		return n.name, nil
+	}
+	return n.name, n.metric
+}
+
+// prefixed returns the prefixed name of name.
+func (r *PrefixedRegistry) prefixed(name string) string {
+	realName, _ := r.lookup(name)
+	return realName
+}
+
+func (r *PrefixedRegistry) cache(name string, n prefixedName) {
+	r.namesMutex.Lock()
+	defer r.namesMutex.Unlock()
+	if _, ok := r.names[name]; !ok && len(r.names) >= maxPrefixedNames {
+		return
+	}
+	if nil == r.names {
+		r.names = make(map[string]prefixedName)
+	}
+	r.names[name] = n
+}
+
+// base returns the StandardRegistry under r, whose generation validates the
+// cached metrics, or false if there is none.
+func (r *PrefixedRegistry) base() (*StandardRegistry, bool) {
+	return standardBase(r)
+}
+
+// standardBase returns r if it is a StandardRegistry, or the one under the
+// PrefixedRegistry r, or false if there is none.
+func standardBase(r Registry) (*StandardRegistry, bool) {
+	base, _ := findPrefix(r, "")
+	s, ok := base.(*StandardRegistry)
+	return s, ok
 }
 
 func NewPrefixedRegistry(prefix string) Registry {
 	return &PrefixedRegistry{
 		underlying: NewRegistry(),
 		prefix:     prefix,
+		owned:      true,
 	}
 }
 
@@ -250,10 +707,34 @@ func (r *PrefixedRegistry) Each(fn func(string, interface{})) {
 	baseRegistry.Each(wrappedFn(prefix))
 }
 
+// Call the given function for each registered metric in no particular order.
+func (r *PrefixedRegistry) EachUnsorted(fn func(string, interface{})) {
+	baseRegistry, prefix := findPrefix(r, "")
+	baseRegistry.EachUnsorted(func(name string, iface interface{}) {
+		if strings.HasPrefix(name, prefix) {
+			fn(name, iface)
+		}
+	})
+}
+
 func (r *PrefixedRegistry) Update(name string, val int64) {
+	if nil != r.rollup {
+		r.underlying.Update(r.prefixed(name), val)
+	}
 	r.underlying.Update(name, val)
 }
 
+func (r *PrefixedRegistry) UpdateBatch(batch map[string]int64) {
+	if nil != r.rollup {
+		prefixed := make(map[string]int64, len(batch))
+		for name, val := range batch {
+			prefixed[r.prefix+name] = val
+		}
+		r.underlying.UpdateBatch(prefixed)
+	}
+	r.underlying.UpdateBatch(batch)
+}
+
 func findPrefix(registry Registry, prefix string) (Registry, string) {
 	switch r := registry.(type) {
 	case *PrefixedRegistry:
@@ -264,24 +745,79 @@ func findPrefix(registry Registry, prefix string) (Registry, string) {
 	return nil, ""
 }
 
-// Get the metric by the given name or nil if none is registered.
+// Get the metric by the given name or nil if none is registered.  The
+// prefixed names and the metrics they resolve to are cached, so repeated
+// lookups of a name neither allocate nor search the registry.
 func (r *PrefixedRegistry) Get(name string) interface{} {
-	realName := r.prefix + name
-	return r.underlying.Get(realName)
+	realName, m := r.lookup(name)
+	if nil == m {
+		if base, ok := r.base(); ok {
+			// The generation is read first, so m is cached as stale if it
+			// is unregistered before it is cached.
+			generation := atomic.LoadUint64(&base.generation)
+			if m = r.underlying.Get(realName); nil != m {
+				r.cache(name, prefixedName{name: realName, metric: m, generation: generation})
+			}
+		} else {
+			m = r.underlying.Get(realName)
+		}
+	}
+	if nil == r.rollup || nil == m {
+		return m
+	}
+	return r.rollup.get(r.underlying, name, m)
 }
 
 // Gets an existing metric or registers the given one.
 // The interface can be the metric to register if not found in registry,
 // or a function returning the metric for lazy instantiation.
 func (r *PrefixedRegistry) GetOrRegister(name string, metric interface{}) interface{} {
-	realName := r.prefix + name
-	return r.underlying.GetOrRegister(realName, metric)
+	realName, m := r.lookup(name)
+	if nil == m {
+		m = r.underlying.GetOrRegister(realName, metric)
+		if base, ok := r.base(); ok {
+			// What is registered is cached as in Get, rather than m, which
+			// is a stub if the name is disabled.
+			generation := atomic.LoadUint64(&base.generation)
+			if registered := r.underlying.Get(realName); nil != registered {
+				r.cache(name, prefixedName{name: realName, metric: registered, generation: generation})
+			}
+		}
+	}
+	if nil == r.rollup {
+		return m
+	}
+	return r.rollup.get(r.underlying, name, m)
+}
+
+// Alias makes the prefixed oldName another name for the prefixed newName.
+func (r *PrefixedRegistry) Alias(oldName, newName string) error {
+	return r.underlying.Alias(r.prefixed(oldName), r.prefixed(newName))
 }
 
 // Register the given metric under the given name. The name will be prefixed.
 func (r *PrefixedRegistry) Register(name string, metric interface{}) error {
-	realName := r.prefix + name
-	return r.underlying.Register(realName, metric)
+	return r.underlying.Register(r.prefixed(name), metric)
+}
+
+// DefaultTimerConfig returns the underlying registry's TimerConfig.
+func (r *PrefixedRegistry) DefaultTimerConfig() TimerConfig {
+	return registryTimerConfig(r.underlying)
+}
+
+// SetDefaultTimerConfig sets the underlying registry's TimerConfig, if it
+// has one.
+func (r *PrefixedRegistry) SetDefaultTimerConfig(c TimerConfig) {
+	if u, ok := r.underlying.(interface {
+		SetDefaultTimerConfig(TimerConfig)
+	}); ok {
+		u.SetDefaultTimerConfig(c)
+	}
+}
+
+// Reset zeroes the metric with the given name. The name will be prefixed.
+func (r *PrefixedRegistry) Reset(name string) error {
+	return r.underlying.Reset(r.prefixed(name))
 }
 
 // Run all registered healthchecks.
@@ -291,8 +827,7 @@ func (r *PrefixedRegistry) RunHealthchecks() {
 
 // Unregister the metric with the given name. The name will be prefixed.
 func (r *PrefixedRegistry) Unregister(name string) {
-	realName := r.prefix + name
-	r.underlying.Unregister(realName)
+	r.underlying.Unregister(r.prefixed(name))
 }
 
 // Unregister all metrics.  (Mostly for testing.)
@@ -300,6 +835,34 @@ func (r *PrefixedRegistry) UnregisterAll() {
 	r.underlying.UnregisterAll()
 }
 
+// Close stops and unregisters the metrics under the registry's prefix.  A
+// registry from NewPrefixedRegistry owns its underlying registry and closes
+// that too.
+func (r *PrefixedRegistry) Close() error {
+	if r.owned {
+		return r.underlying.Close()
+	}
+	baseRegistry, prefix := findPrefix(r, "")
+	var names []string
+	baseRegistry.EachUnsorted(func(name string, i interface{}) {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	})
+	for _, name := range names {
+		baseRegistry.Unregister(name)
+	}
+	return nil
+}
+
+func (r *PrefixedRegistry) onClose(f func()) {
+	if c, ok := r.underlying.(interface {
+		onClose(func())
+	}); ok {
+		c.onClose(f)
+	}
+}
+
 func (r *PrefixedRegistry) GetCurrent() string {
 	return r.underlying.GetCurrent()
 }
@@ -311,6 +874,11 @@ func Each(f func(string, interface{})) {
 	DefaultRegistry.Each(f)
 }
 
+// Call the given function for each registered metric in no particular order.
+func EachUnsorted(f func(string, interface{})) {
+	DefaultRegistry.EachUnsorted(f)
+}
+
 // Get the metric by the given name or nil if none is registered.
 func Get(name string) interface{} {
 	return DefaultRegistry.Get(name)
@@ -336,6 +904,22 @@ func MustRegister(name string, i interface{}) {
 	}
 }
 
+// Alias makes oldName another name for the metric registered as newName.
+func Alias(oldName, newName string) error {
+	return DefaultRegistry.Alias(oldName, newName)
+}
+
+// Close stops and unregisters all metrics and collectors of the default
+// registry.
+func Close() error {
+	return DefaultRegistry.Close()
+}
+
+// Reset zeroes the counter or histogram with the given name.
+func Reset(name string) error {
+	return DefaultRegistry.Reset(name)
+}
+
 // Run all registered healthchecks.
 func RunHealthchecks() {
 	DefaultRegistry.RunHealthchecks()
@@ -350,6 +934,10 @@ func Update(name string, val int64) {
 	DefaultRegistry.Update(name, val)
 }
 
+func UpdateBatch(batch map[string]int64) {
+	DefaultRegistry.UpdateBatch(batch)
+}
+
 func GetCurrent() string {
 	return DefaultRegistry.GetCurrent()
 }
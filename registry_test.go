@@ -1,7 +1,10 @@
 package metrics
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 )
 
 func BenchmarkRegistry(b *testing.B) {
@@ -225,6 +228,60 @@ func TestPrefixedChildRegistryGet(t *testing.T) {
 	}
 }
 
+func TestPrefixedRegistryGetCached(t *testing.T) {
+	r := NewRegistry()
+	pr := NewPrefixedChildRegistry(r, "prefix.")
+	c := NewCounter()
+	pr.Register("foo", c)
+	if m := pr.Get("foo"); c != m {
+		t.Fatalf("pr.Get(\"foo\"): %v", m)
+	}
+	if n := testing.AllocsPerRun(100, func() { GetOrRegisterCounter("foo", pr).Inc(1) }); 0 != n {
+		t.Errorf("cached lookup allocated %v times", n)
+	}
+
+	r.Unregister("prefix.foo")
+	if m := pr.Get("foo"); nil != m {
+		t.Errorf("unregistered metric: %v", m)
+	}
+	c2 := NewCounter()
+	r.Register("prefix.foo", c2)
+	if m := pr.Get("foo"); c2 != m {
+		t.Errorf("re-registered metric: %v", m)
+	}
+
+	c3 := NewCounter()
+	pr.Register("bar", c3)
+	pr.Unregister("foo")
+	if err := pr.Alias("foo", "bar"); nil != err {
+		t.Fatal(err)
+	}
+	if m := pr.Get("foo"); c3 != m {
+		t.Errorf("aliased metric: %v", m)
+	}
+}
+
+func TestPrefixedRegistryGetOrRegisterCached(t *testing.T) {
+	defer ResetMetricPolicies()
+	r := NewRegistry()
+	pr := NewPrefixedChildRegistry(r, "prefix.").(*PrefixedRegistry)
+	c := GetOrRegisterCounter("foo", pr)
+	if _, m := pr.lookup("foo"); c != m {
+		t.Fatalf("cached metric: %v", m)
+	}
+
+	// Stubs of disabled metrics aren't cached.
+	DisableMetrics("prefix.noisy")
+	GetOrRegisterCounter("noisy", pr)
+	if _, m := pr.lookup("noisy"); nil != m {
+		t.Errorf("cached stub: %v", m)
+	}
+	ResetMetricPolicies()
+	if _, ok := GetOrRegisterCounter("noisy", pr).(*StandardCounter); !ok {
+		t.Error("re-enabled metric still a stub")
+	}
+}
+
 func TestChildPrefixedRegistryRegister(t *testing.T) {
 	r := NewPrefixedChildRegistry(DefaultRegistry, "prefix.")
 	err := r.Register("foo", NewCounter())
@@ -287,3 +344,339 @@ func TestWalkRegistries(t *testing.T) {
 
 
 }
+
+func TestRegistryUpdateGaugeFloat64(t *testing.T) {
+	r := NewRegistry()
+	g := NewRegisteredGaugeFloat64("foo", r)
+	r.Update("foo", 47)
+	if v := g.Value(); v != 47.0 {
+		t.Fatal(v)
+	}
+}
+
+func BenchmarkRegistryGetOrRegisterExisting(b *testing.B) {
+	r := NewRegistry()
+	GetOrRegisterCounter("foo", r)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		GetOrRegisterCounter("foo", r)
+	}
+}
+
+func BenchmarkRegistryGetOrRegisterExistingParallel(b *testing.B) {
+	r := NewRegistry()
+	GetOrRegisterTimer("foo", r)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			GetOrRegisterTimer("foo", r)
+		}
+	})
+}
+
+func TestRegistryGetOrRegisterCustomConstructor(t *testing.T) {
+	r := NewRegistry()
+
+	// Constructors of types the registry does not special-case still work.
+	m := r.GetOrRegister("foo", func() *StandardCounter { return &StandardCounter{} })
+	if _, ok := m.(*StandardCounter); !ok {
+		t.Fatal(m)
+	}
+	if c := GetOrRegisterCounter("foo", r); c != m {
+		t.Fatal(c)
+	}
+	if m := r.GetOrRegister("bar", func() interface{} { return NewMeter() }); m == nil {
+		t.Fatal(m)
+	} else if _, ok := r.Get("bar").(Meter); !ok {
+		t.Fatal(r.Get("bar"))
+	}
+}
+
+func TestRegistryUpdateBatch(t *testing.T) {
+	r := NewRegistry()
+	c := NewRegisteredCounter("foo", r)
+	g := NewRegisteredGauge("baz", r)
+	r.UpdateBatch(map[string]int64{"foo": 2, "bar": 3, "baz": 4})
+	r.UpdateBatch(map[string]int64{"foo": 1, "bar": 1})
+	if n := c.Count(); n != 3 {
+		t.Fatal(n)
+	}
+	if n := r.Get("bar").(Counter).Count(); n != 4 {
+		t.Fatal(n)
+	}
+	if v := g.Value(); v != 4 {
+		t.Fatal(v)
+	}
+}
+
+func BenchmarkRegistryUpdate(b *testing.B) {
+	r := NewRegistry()
+	names := make([]string, 100)
+	for i := range names {
+		names[i] = fmt.Sprintf("counter%d", i)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, name := range names {
+			r.Update(name, 1)
+		}
+	}
+}
+
+func BenchmarkRegistryUpdateBatch(b *testing.B) {
+	r := NewRegistry()
+	batch := make(map[string]int64, 100)
+	for i := 0; i < 100; i++ {
+		batch[fmt.Sprintf("counter%d", i)] = 1
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.UpdateBatch(batch)
+	}
+}
+
+func TestRegistryEachUnsorted(t *testing.T) {
+	r := NewRegistry()
+	r.Register("foo", NewCounter())
+	r.Register("bar", NewGauge())
+	seen := make(map[string]bool)
+	r.EachUnsorted(func(name string, iface interface{}) {
+		seen[name] = true
+		r.Get(name) // f may use the registry
+	})
+	if len(seen) != 2 || !seen["foo"] || !seen["bar"] {
+		t.Fatal(seen)
+	}
+
+	pr := NewPrefixedChildRegistry(r, "prefix.")
+	pr.Register("baz", NewCounter())
+	var names []string
+	pr.EachUnsorted(func(name string, iface interface{}) {
+		names = append(names, name)
+	})
+	if len(names) != 1 || names[0] != "prefix.baz" {
+		t.Fatal(names)
+	}
+}
+
+func BenchmarkRegistryEach10k(b *testing.B) {
+	benchmarkRegistryEach(b, func(r Registry, f func(string, interface{})) { r.Each(f) })
+}
+
+func BenchmarkRegistryEachUnsorted10k(b *testing.B) {
+	benchmarkRegistryEach(b, func(r Registry, f func(string, interface{})) { r.EachUnsorted(f) })
+}
+
+func benchmarkRegistryEach(b *testing.B, each func(Registry, func(string, interface{}))) {
+	r := NewRegistry()
+	for i := 0; i < 10000; i++ {
+		r.Register(fmt.Sprintf("counter%d", i), NewCounter())
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		each(r, func(string, interface{}) {})
+	}
+}
+
+func TestRegistryReset(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("counter", r).Inc(2)
+	GetOrRegisterInstantCounter("instant", r).Inc(2)
+	NewRegisteredMeter("meter", r)
+	pr := NewPrefixedChildRegistry(r, "prefix.")
+	NewRegisteredCounter("counter", pr).Inc(1)
+
+	for _, name := range []string{"counter", "instant"} {
+		if err := r.Reset(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pr.Reset("counter"); err != nil {
+		t.Fatal(err)
+	}
+	if n := r.Get("counter").(Counter).Count() + r.Get("instant").(Instant).Count() + r.Get("prefix.counter").(Counter).Count(); n != 0 {
+		t.Fatal(n)
+	}
+	if err := r.Reset("missing"); err != MissingMetric("missing") {
+		t.Fatal(err)
+	}
+	if err := r.Reset("meter"); err == nil {
+		t.Fatal("meter reset")
+	}
+}
+
+func TestRegistryAlias(t *testing.T) {
+	r := NewRegistry()
+	c := NewRegisteredCounter("requests.total", r)
+	if err := r.Alias("requests", "requests.total"); err != nil {
+		t.Fatal(err)
+	}
+	GetOrRegisterCounter("requests", r).Inc(2)
+	r.Update("requests", 1)
+	if n := c.Count(); n != 3 {
+		t.Fatal(n)
+	}
+
+	var names []string
+	r.Each(func(name string, i interface{}) {
+		if i != c {
+			t.Fatal(name, i)
+		}
+		names = append(names, name)
+	})
+	if len(names) != 2 || names[0] != "requests" || names[1] != "requests.total" {
+		t.Fatal(names)
+	}
+	n := 0
+	r.EachUnsorted(func(string, interface{}) { n++ })
+	if n != 2 {
+		t.Fatal(n)
+	}
+
+	if err := r.Alias("requests.total", "other"); err != DuplicateMetric("requests.total") {
+		t.Fatal(err)
+	}
+	if err := r.Register("requests", NewCounter()); err != DuplicateMetric("requests.total") {
+		t.Fatal(err)
+	}
+	r.Unregister("requests")
+	if r.Get("requests") != nil || r.Get("requests.total") != c {
+		t.Fatal("alias outlived Unregister")
+	}
+}
+
+func TestRegistryAliasBeforeRegister(t *testing.T) {
+	r := NewRegistry()
+	pr := NewPrefixedChildRegistry(r, "app.")
+	if err := pr.Alias("hits", "requests"); err != nil {
+		t.Fatal(err)
+	}
+	GetOrRegisterCounter("hits", pr).Inc(1)
+	if c, ok := r.Get("app.requests").(Counter); !ok || c.Count() != 1 {
+		t.Fatal(r.Get("app.requests"))
+	}
+	if err := r.Alias("app.old", "app.hits"); err != nil {
+		t.Fatal(err)
+	}
+	if r.Get("app.old") != r.Get("app.requests") {
+		t.Fatal("alias of an alias not resolved")
+	}
+}
+
+func TestRegistryClose(t *testing.T) {
+	r := NewRegistry()
+	m := NewRegisteredMeter("meter", r)
+	tm := NewRegisteredTimer("timer", r)
+	s := NewScheduler(r)
+	s.Add("noop", CollectorFunc(func(Registry) {}), time.Hour)
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m.Mark(1)
+	tm.Update(1)
+	if m.Count() != 0 || tm.Count() != 0 {
+		t.Fatal("metrics not stopped")
+	}
+	if r.Get("meter") != nil || r.Get("timer") != nil {
+		t.Fatal("metrics not unregistered")
+	}
+	s.mutex.Lock()
+	started := s.started
+	s.mutex.Unlock()
+	if started {
+		t.Fatal("scheduler not stopped")
+	}
+}
+
+func TestPrefixedRegistryClose(t *testing.T) {
+	r := NewRegistry()
+	other := NewRegisteredMeter("other", r)
+	child := NewPrefixedChildRegistry(r, "child.")
+	m := NewRegisteredMeter("meter", child)
+	if err := child.Close(); err != nil {
+		t.Fatal(err)
+	}
+	m.Mark(1)
+	other.Mark(1)
+	if m.Count() != 0 || r.Get("child.meter") != nil {
+		t.Fatal("child metric not closed")
+	}
+	if other.Count() != 1 || r.Get("other") == nil {
+		t.Fatal("parent metric closed")
+	}
+}
+
+func TestRegistryCloseTwice(t *testing.T) {
+	r := NewRegistry().(*StandardRegistry)
+	closed := 0
+	r.onClose(func() { closed++ })
+	r.Close()
+	r.Close()
+	if closed != 1 {
+		t.Fatalf("closer called %d times", closed)
+	}
+}
+
+func TestRegistryUnregisterStops(t *testing.T) {
+	r := NewRegistry()
+	m := NewRegisteredMeter("meter", r)
+	r.Unregister("meter")
+	m.Mark(1)
+	if m.Count() != 0 {
+		t.Fatal("unregistered meter not stopped")
+	}
+}
+
+func TestRegistryGetCurrentTags(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("plain", r).Inc(1)
+	NewRegisteredCounter(TaggedMetricName("errors", NewTagBoard("lobby", "login")), r).Inc(2)
+	NewRegisteredCounter(TaggedMetricName("errors", NewTagBoard("game", "poker", "", "fold")), r).Inc(3)
+	NewRegisteredCounter(TaggedMetricName("users", NewTagBoard("game")), r).Inc(4)
+
+	want := "<--------Metrics--------->\n" +
+		"Metrics: users{ns=game}: 4\n" +
+		"Metrics: errors{ns=game,grp=poker}: 3\n" +
+		"Metrics: errors{ns=lobby,grp=login}: 2\n" +
+		"Metrics: plain: 1\n"
+	if s := r.GetCurrent(); s != want {
+		t.Errorf("GetCurrent:\n%s", s)
+	}
+
+	defer func(b bool) { CurrentGroupByNamespace = b }(CurrentGroupByNamespace)
+	CurrentGroupByNamespace = true
+	want = "<--------Metrics--------->\n" +
+		"Metrics: plain: 1\n" +
+		"[game]\n" +
+		"Metrics: users: 4\n" +
+		"Metrics: errors{grp=poker}: 3\n" +
+		"[lobby]\n" +
+		"Metrics: errors{grp=login}: 2\n"
+	if s := r.GetCurrent(); s != want {
+		t.Errorf("grouped GetCurrent:\n%s", s)
+	}
+}
+
+func TestRegistryRunHealthchecks(t *testing.T) {
+	r := NewRegistry()
+	r.Register("db", NewHealthcheck(func(h Healthcheck) {
+		// Checks may use the registry they are registered in.
+		GetOrRegisterCounter("db.checks", r).Inc(1)
+		h.Healthy()
+	}))
+	r.Register("queue", NewHealthcheck(func(h Healthcheck) { h.Unhealthy(errors.New("backlog")) }))
+	r.RunHealthchecks()
+
+	if v := r.Get("db.healthy").(Gauge).Value(); 1 != v {
+		t.Errorf("db.healthy: %d", v)
+	}
+	if v := r.Get("queue.healthy").(Gauge).Value(); 0 != v {
+		t.Errorf("queue.healthy: %d", v)
+	}
+	if c := r.Get("db.checks").(Counter).Count(); 1 != c {
+		t.Errorf("db.checks: %d", c)
+	}
+}
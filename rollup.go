@@ -0,0 +1,213 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// NewRollupChildRegistry is like NewPrefixedChildRegistry, but the counters,
+// meters, histograms and timers it returns also update the metric of the
+// same unprefixed name in parent, registering it if need be, so parent holds
+// both each module's metrics and their whole-process totals.  Gauges and
+// metrics registered directly with Register aren't rolled up, nor are
+// metrics whose name parent has registered as another kind.
+func NewRollupChildRegistry(parent Registry, prefix string) Registry {
+	return &PrefixedRegistry{
+		underlying: parent,
+		prefix:     prefix,
+		rollup:     &rollupCache{},
+	}
+}
+
+// rollupCache holds the rolled-up metrics a PrefixedRegistry has returned
+// by name, so that getting a metric doesn't allocate each time.
+type rollupCache struct {
+	entries sync.Map // name -> rollupEntry
+}
+
+type rollupEntry struct {
+	child  interface{}
+	rollup interface{}
+}
+
+// get returns the rolled-up form of the child metric m named name.
+func (c *rollupCache) get(parent Registry, name string, m interface{}) interface{} {
+	if e, ok := c.entries.Load(name); ok && e.(rollupEntry).child == m {
+		return e.(rollupEntry).rollup
+	}
+	f := newLike(parent, m)
+	if nil == f {
+		return m
+	}
+	rolled := rollupMetric(m, parent.GetOrRegister(name, f))
+	c.entries.Store(name, rollupEntry{m, rolled})
+	return rolled
+}
+
+// newLike returns a constructor for a standard metric of the same kind as
+// m, or nil for kinds that aren't rolled up.
+func newLike(r Registry, m interface{}) interface{} {
+	switch m.(type) {
+	case Counter:
+		return NewCounter
+	case CounterFloat64:
+		return NewCounterFloat64
+	case Instant:
+		return NewInstantCounter
+	case Meter:
+		return NewMeter
+	case Timer:
+		c := registryTimerConfig(r)
+		return func() Timer { return NewTimerWithConfig(c) }
+	case Histogram:
+		return func() Histogram { return NewHistogram(NewExpDecaySample(1028, 0.015)) }
+	}
+	return nil
+}
+
+// rollupMetric returns m updating parent too, or m itself if parent isn't
+// of the same kind.
+func rollupMetric(m, parent interface{}) interface{} {
+	switch m := m.(type) {
+	case Counter:
+		if p, ok := parent.(Counter); ok {
+			return &rollupCounter{m, p}
+		}
+	case CounterFloat64:
+		if p, ok := parent.(CounterFloat64); ok {
+			return &rollupCounterFloat64{m, p}
+		}
+	case Instant:
+		if p, ok := parent.(Instant); ok {
+			return &rollupInstant{m, p}
+		}
+	case Meter:
+		if p, ok := parent.(Meter); ok {
+			return &rollupMeter{m, p}
+		}
+	case Timer:
+		if p, ok := parent.(Timer); ok {
+			return &rollupTimer{m, p}
+		}
+	case Histogram:
+		if p, ok := parent.(Histogram); ok {
+			return &rollupHistogram{m, p}
+		}
+	}
+	return m
+}
+
+// rollupCounter is a Counter whose changes also apply to its parent.
+type rollupCounter struct {
+	Counter
+	parent Counter
+}
+
+func (c *rollupCounter) Dec(i int64) {
+	c.Counter.Dec(i)
+	c.parent.Dec(i)
+}
+
+func (c *rollupCounter) Inc(i int64) {
+	c.Counter.Inc(i)
+	c.parent.Inc(i)
+}
+
+func (c *rollupCounter) Update(i int64) {
+	c.Inc(i)
+}
+
+// rollupCounterFloat64 is a CounterFloat64 whose changes also apply to its
+// parent.
+type rollupCounterFloat64 struct {
+	CounterFloat64
+	parent CounterFloat64
+}
+
+func (c *rollupCounterFloat64) Dec(f float64) {
+	c.CounterFloat64.Dec(f)
+	c.parent.Dec(f)
+}
+
+func (c *rollupCounterFloat64) Inc(f float64) {
+	c.CounterFloat64.Inc(f)
+	c.parent.Inc(f)
+}
+
+// rollupInstant is an Instant whose changes also apply to its parent.
+type rollupInstant struct {
+	Instant
+	parent Instant
+}
+
+func (c *rollupInstant) Dec(i int64) {
+	c.Instant.Dec(i)
+	c.parent.Dec(i)
+}
+
+func (c *rollupInstant) Inc(i int64) {
+	c.Instant.Inc(i)
+	c.parent.Inc(i)
+}
+
+func (c *rollupInstant) Update(i int64) {
+	c.Inc(i)
+}
+
+// rollupMeter is a Meter whose events are also marked on its parent.
+type rollupMeter struct {
+	Meter
+	parent Meter
+}
+
+func (m *rollupMeter) Mark(n int64) {
+	m.Meter.Mark(n)
+	m.parent.Mark(n)
+}
+
+func (m *rollupMeter) Update(n int64) {
+	m.Mark(n)
+}
+
+// rollupHistogram is a Histogram whose values are also recorded by its
+// parent.
+type rollupHistogram struct {
+	Histogram
+	parent Histogram
+}
+
+func (h *rollupHistogram) Update(v int64) {
+	h.Histogram.Update(v)
+	h.parent.Update(v)
+}
+
+func (h *rollupHistogram) UpdateAll(values []int64) {
+	h.Histogram.UpdateAll(values)
+	h.parent.UpdateAll(values)
+}
+
+// rollupTimer is a Timer whose durations are also recorded by its parent.
+type rollupTimer struct {
+	Timer
+	parent Timer
+}
+
+func (t *rollupTimer) Time(f func()) {
+	ts := DefaultClock.Now()
+	f()
+	t.UpdateSince(ts)
+}
+
+func (t *rollupTimer) Update(d int64) {
+	t.Timer.Update(d)
+	t.parent.Update(d)
+}
+
+func (t *rollupTimer) UpdateTime(d time.Duration) {
+	t.Timer.UpdateTime(d)
+	t.parent.UpdateTime(d)
+}
+
+func (t *rollupTimer) UpdateSince(ts time.Time) {
+	t.UpdateTime(DefaultClock.Now().Sub(ts))
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRollupChildRegistry(t *testing.T) {
+	r := NewRegistry()
+	auth := NewRollupChildRegistry(r, "auth.")
+	lobby := NewRollupChildRegistry(r, "lobby.")
+
+	GetOrRegisterCounter("requests", auth).Inc(2)
+	GetOrRegisterCounter("requests", lobby).Inc(3)
+	GetOrRegisterCounter("requests", lobby).Dec(1)
+	GetOrRegisterMeter("logins", auth).Mark(4)
+	GetOrRegisterTimer("latency", auth).UpdateTime(time.Millisecond)
+	GetOrRegisterTimer("latency", lobby).UpdateTime(3 * time.Millisecond)
+	GetOrRegisterCounterFloat64("credits", lobby).Inc(0.5)
+	GetOrRegisterInstantCounter("errors", auth).Inc(1)
+
+	for name, want := range map[string]int64{
+		"auth.requests":  2,
+		"lobby.requests": 2,
+		"requests":       4,
+	} {
+		if c := r.Get(name).(Counter).Count(); c != want {
+			t.Errorf("%s: %v != %v", name, want, c)
+		}
+	}
+	if c := r.Get("logins").(Meter).Count(); 4 != c {
+		t.Errorf("logins: 4 != %v", c)
+	}
+	if tm := r.Get("latency").(Timer); 2 != tm.Count() || int64(3*time.Millisecond) != tm.Max() {
+		t.Errorf("latency: count %v, max %v", tm.Count(), tm.Max())
+	}
+	if c := r.Get("credits").(CounterFloat64).Count(); 0.5 != c {
+		t.Errorf("credits: 0.5 != %v", c)
+	}
+	if c := r.Get("errors").(Instant).Count(); 1 != c {
+		t.Errorf("errors: 1 != %v", c)
+	}
+	if GetOrRegisterCounter("requests", auth) != auth.Get("requests") {
+		t.Error("rolled-up counter not cached")
+	}
+}
+
+func TestRollupChildRegistryUnrolled(t *testing.T) {
+	r := NewRegistry()
+	child := NewRollupChildRegistry(r, "child.")
+	GetOrRegisterGauge("queued", child).Update(5)
+	if nil != r.Get("queued") {
+		t.Error("gauge rolled up")
+	}
+
+	NewRegisteredGauge("hits", r)
+	GetOrRegisterCounter("hits", child).Inc(1)
+	if c := r.Get("child.hits").(Counter).Count(); 1 != c {
+		t.Errorf("child.hits: 1 != %v", c)
+	}
+
+	child.Update("updates", 2)
+	if c := r.Get("child.updates").(Counter).Count(); 2 != c {
+		t.Errorf("child.updates: 2 != %v", c)
+	}
+	if c := r.Get("updates").(Counter).Count(); 2 != c {
+		t.Errorf("updates: 2 != %v", c)
+	}
+}
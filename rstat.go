@@ -0,0 +1,41 @@
+package metrics
+
+// Error increments the RSTAT_ERROR Instant counter tagged with the given tags
+// in DefaultRegistry, e.g. Error("lobby", "matchmaking").
+func Error(tags ...string) {
+	if UseNilMetrics {
+		return
+	}
+	GetOrRegisterInstantCounter(taggedName(RSTAT_ERROR, tags), nil).Inc(1)
+}
+
+// Warn increments the RSTAT_WARN Instant counter tagged with the given tags in
+// DefaultRegistry.
+func Warn(tags ...string) {
+	if UseNilMetrics {
+		return
+	}
+	GetOrRegisterInstantCounter(taggedName(RSTAT_WARN, tags), nil).Inc(1)
+}
+
+// Panic increments the RSTAT_PANIC Instant counter tagged with the given tags
+// in DefaultRegistry.  It doesn't panic.
+func Panic(tags ...string) {
+	if UseNilMetrics {
+		return
+	}
+	GetOrRegisterInstantCounter(taggedName(RSTAT_PANIC, tags), nil).Inc(1)
+}
+
+// RecoverAndCount recovers from a panic in progress and counts it with Panic.
+// It must be deferred directly so that recover works:
+//
+//	defer metrics.RecoverAndCount("lobby", "matchmaking")
+//
+// The panic is swallowed; goroutines that must not die quietly should log it
+// before calling Panic themselves.
+func RecoverAndCount(tags ...string) {
+	if recover() != nil {
+		Panic(tags...)
+	}
+}
@@ -0,0 +1,40 @@
+package metrics
+
+import "testing"
+
+func TestRstatHelpers(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+
+	Error()
+	Error("lobby", "matchmaking")
+	Error("lobby", "matchmaking")
+	Warn("lobby")
+
+	for name, want := range map[string]int64{
+		RSTAT_ERROR:                          1,
+		"lobby|matchmakingTAG" + RSTAT_ERROR: 2,
+		"lobbyTAG" + RSTAT_WARN:              1,
+	} {
+		if c := Get(name).(Instant).Count(); c != want {
+			t.Fatalf("%s: got %d, want %d", name, c, want)
+		}
+	}
+}
+
+func TestRecoverAndCount(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+
+	func() {
+		defer RecoverAndCount("lobby")
+		panic("boom")
+	}()
+	func() {
+		defer RecoverAndCount("lobby")
+	}()
+
+	if c := Get("lobbyTAG" + RSTAT_PANIC).(Instant).Count(); c != 1 {
+		t.Fatal(c)
+	}
+}
@@ -0,0 +1,146 @@
+//go:build go1.16
+// +build go1.16
+
+package metrics
+
+import (
+	"math"
+	rtmetrics "runtime/metrics"
+	"strings"
+	"time"
+)
+
+// runtimeMetricsSampleBudget bounds how many values a single runtime/metrics
+// histogram feeds into its Histogram per capture.  Larger deltas are scaled
+// down proportionally across buckets so percentiles are preserved.
+const runtimeMetricsSampleBudget = 1028
+
+var (
+	runtimeMetricsSamples []rtmetrics.Sample
+	runtimeMetricsGauges  map[string]interface{}
+	runtimeMetricsCounts  map[string][]uint64
+	readRuntimeMetrics    Timer
+)
+
+// Capture new values for the Go runtime statistics exported by the
+// runtime/metrics package.  This is designed to be called as a goroutine.
+func CaptureRuntimeMetrics(r Registry, d time.Duration) {
+	for _ = range time.Tick(d) {
+		CaptureRuntimeMetricsOnce(r)
+	}
+}
+
+// Capture new values for the Go runtime statistics exported by the
+// runtime/metrics package.  Unlike runtime.ReadMemStats, rtmetrics.Read does
+// not stop the world.  Giving a registry which has not been given to
+// RegisterRuntimeMetrics is a no-op.
+func CaptureRuntimeMetricsOnce(r Registry) {
+	if readRuntimeMetrics == nil {
+		return
+	}
+	t := time.Now()
+	rtmetrics.Read(runtimeMetricsSamples)
+	readRuntimeMetrics.UpdateSince(t)
+
+	for _, sample := range runtimeMetricsSamples {
+		switch metric := runtimeMetricsGauges[sample.Name].(type) {
+		case Gauge:
+			metric.Update(int64(sample.Value.Uint64()))
+		case GaugeFloat64:
+			metric.Update(sample.Value.Float64())
+		case Histogram:
+			updateRuntimeMetricsHistogram(sample.Name, metric, sample.Value.Float64Histogram())
+		}
+	}
+}
+
+// Register metrics for every statistic the runtime/metrics package supports,
+// such as scheduler latencies, the GC CPU limiter and memory classes.  A
+// runtime/metrics name like /sched/latencies:seconds is registered as
+// runtime.sched.latencies.seconds.  Scalar values become Gauges or
+// GaugeFloat64s and distributions become Histograms, with durations recorded
+// in nanoseconds.
+func RegisterRuntimeMetrics(r Registry) {
+	descs := rtmetrics.All()
+	runtimeMetricsSamples = make([]rtmetrics.Sample, 0, len(descs))
+	runtimeMetricsGauges = make(map[string]interface{}, len(descs))
+	runtimeMetricsCounts = make(map[string][]uint64)
+	for _, desc := range descs {
+		var m interface{}
+		switch desc.Kind {
+		case rtmetrics.KindUint64:
+			m = NewGauge()
+		case rtmetrics.KindFloat64:
+			m = NewGaugeFloat64()
+		case rtmetrics.KindFloat64Histogram:
+			m = NewHistogram(NewExpDecaySample(1028, 0.015))
+		default:
+			continue
+		}
+		runtimeMetricsSamples = append(runtimeMetricsSamples, rtmetrics.Sample{Name: desc.Name})
+		runtimeMetricsGauges[desc.Name] = m
+		r.Register(RuntimeMetricName(desc.Name), m)
+	}
+	readRuntimeMetrics = NewTimer()
+	r.Register("runtime.ReadRuntimeMetrics", readRuntimeMetrics)
+}
+
+// RuntimeMetricName maps a runtime/metrics name such as /gc/heap/allocs:bytes
+// to the registry name runtime.gc.heap.allocs.bytes.
+func RuntimeMetricName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	name = strings.Replace(name, "/", ".", -1)
+	name = strings.Replace(name, ":", ".", -1)
+	return "runtime." + name
+}
+
+// updateRuntimeMetricsHistogram feeds the observations added to a cumulative
+// runtime/metrics histogram since the previous capture into h, using each
+// bucket's midpoint as the observed value.
+func updateRuntimeMetricsHistogram(name string, h Histogram, fh *rtmetrics.Float64Histogram) {
+	prev := runtimeMetricsCounts[name]
+	if len(prev) != len(fh.Counts) {
+		prev = make([]uint64, len(fh.Counts))
+	}
+	deltas := make([]uint64, len(fh.Counts))
+	var total uint64
+	for i, count := range fh.Counts {
+		if count > prev[i] {
+			deltas[i] = count - prev[i]
+			total += deltas[i]
+		}
+	}
+	runtimeMetricsCounts[name] = append(prev[:0], fh.Counts...)
+	if total == 0 {
+		return
+	}
+
+	scale := 1.0
+	if total > runtimeMetricsSampleBudget {
+		scale = float64(runtimeMetricsSampleBudget) / float64(total)
+	}
+	unit := 1.0
+	if strings.HasSuffix(name, ":seconds") {
+		unit = float64(time.Second)
+	}
+	for i, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		v := int64(bucketMidpoint(fh.Buckets[i], fh.Buckets[i+1]) * unit)
+		n := int(math.Ceil(float64(delta) * scale))
+		for j := 0; j < n; j++ {
+			h.Update(v)
+		}
+	}
+}
+
+func bucketMidpoint(lo, hi float64) float64 {
+	switch {
+	case math.IsInf(lo, -1):
+		return hi
+	case math.IsInf(hi, 1):
+		return lo
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,45 @@
+//go:build go1.16
+// +build go1.16
+
+package metrics
+
+import (
+	"runtime"
+	"testing"
+)
+
+func BenchmarkRuntimeMetrics(b *testing.B) {
+	r := NewRegistry()
+	RegisterRuntimeMetrics(r)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		CaptureRuntimeMetricsOnce(r)
+	}
+}
+
+func TestRuntimeMetricName(t *testing.T) {
+	if name := RuntimeMetricName("/sched/latencies:seconds"); name != "runtime.sched.latencies.seconds" {
+		t.Fatal(name)
+	}
+}
+
+func TestRuntimeMetrics(t *testing.T) {
+	r := NewRegistry()
+	RegisterRuntimeMetrics(r)
+	runtime.GC()
+	CaptureRuntimeMetricsOnce(r)
+
+	g, ok := r.Get("runtime.gc.cycles.total.gc-cycles").(Gauge)
+	if !ok {
+		t.Fatal(r.Get("runtime.gc.cycles.total.gc-cycles"))
+	}
+	if v := g.Value(); v < 1 {
+		t.Fatal(v)
+	}
+	if _, ok := r.Get("runtime.sched.latencies.seconds").(Histogram); !ok {
+		t.Fatal(r.Get("runtime.sched.latencies.seconds"))
+	}
+	if c := r.Get("runtime.ReadRuntimeMetrics").(Timer).Count(); c != 1 {
+		t.Fatal(c)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !go1.16
+// +build !go1.16
+
+package metrics
+
+import "time"
+
+// CaptureRuntimeMetrics is a no-op before Go 1.16.
+func CaptureRuntimeMetrics(r Registry, d time.Duration) {}
+
+// CaptureRuntimeMetricsOnce is a no-op before Go 1.16.
+func CaptureRuntimeMetricsOnce(r Registry) {}
+
+// RegisterRuntimeMetrics is a no-op before Go 1.16.
+func RegisterRuntimeMetrics(r Registry) {}
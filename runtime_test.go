@@ -86,3 +86,14 @@ func testRuntimeMemStatsBlocking(ch chan int) {
 		}
 	}
 }
+
+func TestRuntimeMemStatsGCCPUFraction(t *testing.T) {
+	r := NewRegistry()
+	RegisterRuntimeMemStats(r)
+	if _, ok := r.Get("runtime.MemStats.GCCPUFraction").(GaugeFloat64); !ok {
+		t.Fatal(r.Get("runtime.MemStats.GCCPUFraction"))
+	}
+	if _, ok := r.Get("runtime.MemStats.PauseNs").(Histogram); !ok {
+		t.Fatal(r.Get("runtime.MemStats.PauseNs"))
+	}
+}
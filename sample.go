@@ -3,8 +3,10 @@ package metrics
 import (
 	"math"
 	"math/rand"
+	"runtime"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -23,6 +25,7 @@ type Sample interface {
 	StdDev() float64
 	Sum() int64
 	Update(int64)
+	UpdateAll([]int64)
 	Values() []int64
 	Variance() float64
 }
@@ -38,22 +41,33 @@ type ExpDecaySample struct {
 	mutex         sync.Mutex
 	reservoirSize int
 	t0, t1        time.Time
+	threshold     time.Duration
 	values        *expDecaySampleHeap
+	clock         Clock
 }
 
 // NewExpDecaySample constructs a new exponentially-decaying sample with the
-// given reservoir size and alpha.
+// given reservoir size and alpha that rescales every MeterRescaleThreshold.
 func NewExpDecaySample(reservoirSize int, alpha float64) Sample {
+	return NewExpDecaySampleWithThreshold(reservoirSize, alpha, MeterRescaleThreshold)
+}
+
+// NewExpDecaySampleWithThreshold constructs a new exponentially-decaying
+// sample with the given reservoir size and alpha that rescales its
+// priorities every threshold.
+func NewExpDecaySampleWithThreshold(reservoirSize int, alpha float64, threshold time.Duration) Sample {
 	if UseNilMetrics {
 		return NilSample{}
 	}
 	s := &ExpDecaySample{
 		alpha:         alpha,
 		reservoirSize: reservoirSize,
-		t0:            time.Now(),
+		t0:            DefaultClock.Now(),
+		threshold:     threshold,
 		values:        newExpDecaySampleHeap(reservoirSize),
+		clock:         DefaultClock,
 	}
-	s.t1 = s.t0.Add(MeterRescaleThreshold)
+	s.t1 = s.t0.Add(threshold)
 	return s
 }
 
@@ -62,8 +76,8 @@ func (s *ExpDecaySample) Clear() {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 	s.count = 0
-	s.t0 = time.Now()
-	s.t1 = s.t0.Add(MeterRescaleThreshold)
+	s.t0 = s.clock.Now()
+	s.t1 = s.t0.Add(s.threshold)
 	s.values.Clear()
 }
 
@@ -137,7 +151,12 @@ func (s *ExpDecaySample) Sum() int64 {
 
 // Update samples a new value.
 func (s *ExpDecaySample) Update(v int64) {
-	s.update(time.Now(), v)
+	s.update(s.clock.Now(), v)
+}
+
+// UpdateAll samples each of values, taking the sample's lock once.
+func (s *ExpDecaySample) UpdateAll(values []int64) {
+	s.updateAll(s.clock.Now(), values)
 }
 
 // Values returns a copy of the values in the sample.
@@ -162,6 +181,21 @@ func (s *ExpDecaySample) Variance() float64 {
 func (s *ExpDecaySample) update(t time.Time, v int64) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.insert(t, v)
+}
+
+// updateAll samples values at a particular timestamp under one lock.
+func (s *ExpDecaySample) updateAll(t time.Time, values []int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, v := range values {
+		s.insert(t, v)
+	}
+}
+
+// insert samples a new value at a particular timestamp.  The caller holds
+// the sample's lock.
+func (s *ExpDecaySample) insert(t time.Time, v int64) {
 	s.count++
 	if s.values.Size() == s.reservoirSize {
 		s.values.Pop()
@@ -175,7 +209,7 @@ func (s *ExpDecaySample) update(t time.Time, v int64) {
 		t0 := s.t0
 		s.values.Clear()
 		s.t0 = t
-		s.t1 = s.t0.Add(MeterRescaleThreshold)
+		s.t1 = s.t0.Add(s.threshold)
 		for _, v := range values {
 			v.k = v.k * math.Exp(-s.alpha*s.t0.Sub(t0).Seconds())
 			s.values.Push(v)
@@ -183,6 +217,221 @@ func (s *ExpDecaySample) update(t time.Time, v int64) {
 	}
 }
 
+// ShardedExpDecaySample is an ExpDecaySample split across several
+// independently locked reservoirs so that concurrent updates rarely contend
+// on the same mutex.  Each update lands in the next shard in turn; reads
+// merge the shards, rescaling their priorities to a common landmark, and keep
+// the reservoirSize samples with the highest priority, which is the same set
+// a single ExpDecaySample would have kept.
+//
+// Updates are cheaper under contention while reads are more expensive, so it
+// is best suited to timers on hot paths that are read once per reporting
+// interval.
+type ShardedExpDecaySample struct {
+	alpha         float64
+	reservoirSize int
+	next          uint32
+	shards        []expDecayShard
+	clock         Clock
+}
+
+// expDecayShard pads each shard onto its own cache lines.
+type expDecayShard struct {
+	ExpDecaySample
+	_ [64]byte
+}
+
+// maxExpDecayShards bounds the number of shards, each of which may grow to a
+// full reservoir.
+const maxExpDecayShards = 16
+
+// NewShardedExpDecaySample constructs a new sharded exponentially-decaying
+// sample with the given reservoir size and alpha and one shard per CPU, up to
+// sixteen.
+func NewShardedExpDecaySample(reservoirSize int, alpha float64) Sample {
+	if UseNilMetrics {
+		return NilSample{}
+	}
+	n := runtime.GOMAXPROCS(0)
+	if n > maxExpDecayShards {
+		n = maxExpDecayShards
+	}
+	return newShardedExpDecaySample(reservoirSize, alpha, n)
+}
+
+func newShardedExpDecaySample(reservoirSize int, alpha float64, n int) *ShardedExpDecaySample {
+	s := &ShardedExpDecaySample{
+		alpha:         alpha,
+		reservoirSize: reservoirSize,
+		shards:        make([]expDecayShard, n),
+		clock:         DefaultClock,
+	}
+	t0 := s.clock.Now()
+	for i := range s.shards {
+		shard := &s.shards[i].ExpDecaySample
+		shard.alpha = alpha
+		shard.reservoirSize = reservoirSize
+		shard.t0 = t0
+		shard.threshold = MeterRescaleThreshold
+		shard.t1 = t0.Add(shard.threshold)
+		shard.values = newExpDecaySampleHeap(0)
+		shard.clock = s.clock
+	}
+	return s
+}
+
+// Clear clears all samples.
+func (s *ShardedExpDecaySample) Clear() {
+	for i := range s.shards {
+		s.shards[i].Clear()
+	}
+}
+
+// Count returns the number of samples recorded, which may exceed the
+// reservoir size.
+func (s *ShardedExpDecaySample) Count() int64 {
+	var count int64
+	for i := range s.shards {
+		count += s.shards[i].Count()
+	}
+	return count
+}
+
+// Max returns the maximum value in the sample, which may not be the maximum
+// value ever to be part of the sample.
+func (s *ShardedExpDecaySample) Max() int64 {
+	return SampleMax(s.Values())
+}
+
+// Mean returns the mean of the values in the sample.
+func (s *ShardedExpDecaySample) Mean() float64 {
+	return SampleMean(s.Values())
+}
+
+// Min returns the minimum value in the sample, which may not be the minimum
+// value ever to be part of the sample.
+func (s *ShardedExpDecaySample) Min() int64 {
+	return SampleMin(s.Values())
+}
+
+// Percentile returns an arbitrary percentile of values in the sample.
+func (s *ShardedExpDecaySample) Percentile(p float64) float64 {
+	return SamplePercentile(s.Values(), p)
+}
+
+// Percentiles returns a slice of arbitrary percentiles of values in the
+// sample.
+func (s *ShardedExpDecaySample) Percentiles(ps []float64) []float64 {
+	return SamplePercentiles(s.Values(), ps)
+}
+
+// Size returns the size of the sample, which is at most the reservoir size.
+func (s *ShardedExpDecaySample) Size() int {
+	var size int
+	for i := range s.shards {
+		size += s.shards[i].Size()
+	}
+	if size > s.reservoirSize {
+		size = s.reservoirSize
+	}
+	return size
+}
+
+// Snapshot returns a read-only copy of the sample.
+func (s *ShardedExpDecaySample) Snapshot() Sample {
+	count, values := s.merge()
+	return &SampleSnapshot{
+		count:  count,
+		values: values,
+	}
+}
+
+// StdDev returns the standard deviation of the values in the sample.
+func (s *ShardedExpDecaySample) StdDev() float64 {
+	return SampleStdDev(s.Values())
+}
+
+// Sum returns the sum of the values in the sample.
+func (s *ShardedExpDecaySample) Sum() int64 {
+	return SampleSum(s.Values())
+}
+
+// Update samples a new value.
+func (s *ShardedExpDecaySample) Update(v int64) {
+	s.update(s.clock.Now(), v)
+}
+
+// UpdateAll samples each of values into a single shard, taking its lock
+// once.
+func (s *ShardedExpDecaySample) UpdateAll(values []int64) {
+	i := atomic.AddUint32(&s.next, 1) % uint32(len(s.shards))
+	s.shards[i].updateAll(s.clock.Now(), values)
+}
+
+// Values returns a copy of the values in the sample.
+func (s *ShardedExpDecaySample) Values() []int64 {
+	_, values := s.merge()
+	return values
+}
+
+// Variance returns the variance of the values in the sample.
+func (s *ShardedExpDecaySample) Variance() float64 {
+	return SampleVariance(s.Values())
+}
+
+// update samples a new value at a particular timestamp.  This is a method all
+// its own to facilitate testing.
+func (s *ShardedExpDecaySample) update(t time.Time, v int64) {
+	i := atomic.AddUint32(&s.next, 1) % uint32(len(s.shards))
+	s.shards[i].update(t, v)
+}
+
+// merge returns the total count and the values of the reservoirSize samples
+// with the highest priority across all shards.  Shards rescale
+// independently, so priorities are first brought to the latest landmark.
+func (s *ShardedExpDecaySample) merge() (int64, []int64) {
+	type shardValues struct {
+		t0     time.Time
+		values []expDecaySample
+	}
+	var (
+		count  int64
+		latest time.Time
+	)
+	shards := make([]shardValues, len(s.shards))
+	for i := range s.shards {
+		shard := &s.shards[i].ExpDecaySample
+		shard.mutex.Lock()
+		count += shard.count
+		shards[i].t0 = shard.t0
+		shards[i].values = append([]expDecaySample(nil), shard.values.Values()...)
+		shard.mutex.Unlock()
+		if shards[i].t0.After(latest) {
+			latest = shards[i].t0
+		}
+	}
+
+	h := newExpDecaySampleHeap(s.reservoirSize)
+	for _, shard := range shards {
+		scale := math.Exp(-s.alpha * latest.Sub(shard.t0).Seconds())
+		for _, v := range shard.values {
+			v.k *= scale
+			if h.Size() < s.reservoirSize {
+				h.Push(v)
+			} else if s.reservoirSize > 0 && h.s[0].k < v.k {
+				h.Pop()
+				h.Push(v)
+			}
+		}
+	}
+	vals := h.Values()
+	values := make([]int64, len(vals))
+	for i, v := range vals {
+		values[i] = v.v
+	}
+	return count, values
+}
+
 // NilSample is a no-op Sample.
 type NilSample struct{}
 
@@ -224,6 +473,9 @@ func (NilSample) Sum() int64 { return 0 }
 // Update is a no-op.
 func (NilSample) Update(v int64) {}
 
+// UpdateAll is a no-op.
+func (NilSample) UpdateAll([]int64) {}
+
 // Values is a no-op.
 func (NilSample) Values() []int64 { return []int64{} }
 
@@ -274,10 +526,15 @@ func SamplePercentile(values int64Slice, p float64) float64 {
 // SamplePercentiles returns a slice of arbitrary percentiles of the slice of
 // int64.
 func SamplePercentiles(values int64Slice, ps []float64) []float64 {
+	sort.Sort(values)
+	return sortedPercentiles(values, ps)
+}
+
+// sortedPercentiles is SamplePercentiles of values already sorted.
+func sortedPercentiles(values []int64, ps []float64) []float64 {
 	scores := make([]float64, len(ps))
 	size := len(values)
 	if size > 0 {
-		sort.Sort(values)
 		for i, p := range ps {
 			pos := p * float64(size+1)
 			if pos < 1.0 {
@@ -298,6 +555,7 @@ func SamplePercentiles(values int64Slice, ps []float64) []float64 {
 type SampleSnapshot struct {
 	count  int64
 	values []int64
+	sorted bool // values are sorted, by sort
 }
 
 // Clear panics.
@@ -320,15 +578,26 @@ func (s *SampleSnapshot) Min() int64 { return SampleMin(s.values) }
 // Percentile returns an arbitrary percentile of values at the time the
 // snapshot was taken.
 func (s *SampleSnapshot) Percentile(p float64) float64 {
-	return SamplePercentile(s.values, p)
+	return s.Percentiles([]float64{p})[0]
 }
 
 // Percentiles returns a slice of arbitrary percentiles of values at the time
 // the snapshot was taken.
 func (s *SampleSnapshot) Percentiles(ps []float64) []float64 {
+	if s.sorted {
+		return sortedPercentiles(s.values, ps)
+	}
 	return SamplePercentiles(s.values, ps)
 }
 
+// sort sorts the values once, so that Percentiles doesn't have to every time
+// and can be called concurrently.  It must be called before the snapshot is
+// shared.
+func (s *SampleSnapshot) sort() {
+	sort.Sort(int64Slice(s.values))
+	s.sorted = true
+}
+
 // Size returns the size of the sample at the time the snapshot was taken.
 func (s *SampleSnapshot) Size() int { return len(s.values) }
 
@@ -347,6 +616,11 @@ func (*SampleSnapshot) Update(int64) {
 	panic("Update called on a SampleSnapshot")
 }
 
+// UpdateAll panics.
+func (*SampleSnapshot) UpdateAll([]int64) {
+	panic("UpdateAll called on a SampleSnapshot")
+}
+
 // Values returns a copy of the values in the sample.
 func (s *SampleSnapshot) Values() []int64 {
 	values := make([]int64, len(s.values))
@@ -357,6 +631,44 @@ func (s *SampleSnapshot) Values() []int64 {
 // Variance returns the variance of values at the time the snapshot was taken.
 func (s *SampleSnapshot) Variance() float64 { return SampleVariance(s.values) }
 
+// Merge returns a snapshot pooling the values of s and other, as if both had
+// been recorded into one sample.  A reservoir keeps a value for every
+// count/size values it saw, so the values of whichever sample kept more of
+// its inputs are thinned to match the other before pooling.  The result is
+// exact when neither sample has dropped values, and approximate otherwise.
+func (s *SampleSnapshot) Merge(other Sample) *SampleSnapshot {
+	a, ca := s.values, s.count
+	b, cb := other.Values(), other.Count()
+	if len(a) > 0 && len(b) > 0 {
+		wa := float64(ca) / float64(len(a))
+		wb := float64(cb) / float64(len(b))
+		if wa < wb {
+			a = thinValues(a, wa/wb)
+		} else if wb < wa {
+			b = thinValues(b, wb/wa)
+		}
+	}
+	values := make([]int64, 0, len(a)+len(b))
+	values = append(append(values, a...), b...)
+	return &SampleSnapshot{count: ca + cb, values: values}
+}
+
+// thinValues returns an evenly spaced fraction f of values, at least one.
+func thinValues(values []int64, f float64) []int64 {
+	n := int(math.Round(float64(len(values)) * f))
+	if n < 1 {
+		n = 1
+	}
+	if n >= len(values) {
+		return values
+	}
+	thinned := make([]int64, n)
+	for i := range thinned {
+		thinned[i] = values[i*len(values)/n]
+	}
+	return thinned
+}
+
 // SampleStdDev returns the standard deviation of the slice of int64.
 func SampleStdDev(values []int64) float64 {
 	return math.Sqrt(SampleVariance(values))
@@ -498,6 +810,20 @@ func (s *UniformSample) Sum() int64 {
 func (s *UniformSample) Update(v int64) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
+	s.insert(v)
+}
+
+// UpdateAll samples each of values, taking the sample's lock once.
+func (s *UniformSample) UpdateAll(values []int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, v := range values {
+		s.insert(v)
+	}
+}
+
+// insert samples a new value.  The caller holds the sample's lock.
+func (s *UniformSample) insert(v int64) {
 	s.count++
 	if len(s.values) < s.reservoirSize {
 		s.values = append(s.values, v)
@@ -547,8 +873,7 @@ func (h *expDecaySampleHeap) Clear() {
 
 func (h *expDecaySampleHeap) Push(s expDecaySample) {
 	n := len(h.s)
-	h.s = h.s[0 : n+1]
-	h.s[n] = s
+	h.s = append(h.s, s)
 	h.up(n)
 }
 
@@ -3,6 +3,7 @@ package metrics
 import (
 	"math/rand"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 )
@@ -66,6 +67,29 @@ func BenchmarkExpDecaySample1028(b *testing.B) {
 	benchmarkSample(b, NewExpDecaySample(1028, 0.015))
 }
 
+func BenchmarkExpDecaySample1028Parallel(b *testing.B) {
+	benchmarkSampleParallel(b, NewExpDecaySample(1028, 0.015))
+}
+
+func BenchmarkShardedExpDecaySample1028(b *testing.B) {
+	benchmarkSample(b, NewShardedExpDecaySample(1028, 0.015))
+}
+
+func BenchmarkShardedExpDecaySample1028Parallel(b *testing.B) {
+	benchmarkSampleParallel(b, NewShardedExpDecaySample(1028, 0.015))
+}
+
+func BenchmarkShardedExpDecaySample1028Snapshot(b *testing.B) {
+	s := NewShardedExpDecaySample(1028, 0.015)
+	for i := 0; i < 100000; i++ {
+		s.Update(int64(i))
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Snapshot()
+	}
+}
+
 func BenchmarkUniformSample257(b *testing.B) {
 	benchmarkSample(b, NewUniformSample(257))
 }
@@ -202,6 +226,70 @@ func TestExpDecaySampleStatistics(t *testing.T) {
 	testExpDecaySampleStatistics(t, s)
 }
 
+func TestShardedExpDecaySampleStatistics(t *testing.T) {
+	now := time.Now()
+	rand.Seed(1)
+	s := NewShardedExpDecaySample(100, 0.99)
+	for i := 1; i <= 10000; i++ {
+		s.(*ShardedExpDecaySample).update(now.Add(time.Duration(i)), int64(i))
+	}
+	testExpDecaySampleStatistics(t, s)
+	testExpDecaySampleStatistics(t, s.Snapshot())
+	if size := s.Size(); 100 != size {
+		t.Errorf("s.Size(): 100 != %v\n", size)
+	}
+}
+
+func TestShardedExpDecaySampleRescale(t *testing.T) {
+	s := newShardedExpDecaySample(2, 0.001, 2)
+	now := time.Now()
+	shard := &s.shards[0].ExpDecaySample
+	shard.update(now, 1)
+	shard.update(now.Add(2*time.Hour), 2)
+	s.shards[1].update(now.Add(time.Minute), 3)
+
+	// The first shard has rescaled past the second; its recent value must
+	// still outrank the older values in both shards.
+	values := s.Values()
+	if len(values) != 2 {
+		t.Fatal(values)
+	}
+	if values[0] != 2 && values[1] != 2 {
+		t.Errorf("expected the most recent value in the sample, got %v", values)
+	}
+	if count := s.Count(); count != 3 {
+		t.Errorf("s.Count(): 3 != %v\n", count)
+	}
+}
+
+func TestShardedExpDecaySampleConcurrent(t *testing.T) {
+	s := NewShardedExpDecaySample(1028, 0.015)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				s.Update(int64(j))
+				if j%100 == 0 {
+					s.Snapshot()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if count := s.Count(); count != 8000 {
+		t.Errorf("s.Count(): 8000 != %v\n", count)
+	}
+	if size := s.Size(); size != 1028 {
+		t.Errorf("s.Size(): 1028 != %v\n", size)
+	}
+	s.Clear()
+	if count := s.Count(); count != 0 {
+		t.Errorf("s.Count(): 0 != %v\n", count)
+	}
+}
+
 func TestUniformSample(t *testing.T) {
 	rand.Seed(1)
 	s := NewUniformSample(100)
@@ -275,6 +363,14 @@ func benchmarkSample(b *testing.B, s Sample) {
 	b.Logf("GC cost: %d ns/op", int(memStats.PauseTotalNs-pauseTotalNs)/b.N)
 }
 
+func benchmarkSampleParallel(b *testing.B, s Sample) {
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			s.Update(1)
+		}
+	})
+}
+
 func testExpDecaySampleStatistics(t *testing.T, s Sample) {
 	if count := s.Count(); 10000 != count {
 		t.Errorf("s.Count(): 10000 != %v\n", count)
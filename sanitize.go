@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// A NameSanitizer rewrites a metric name into one a backend accepts.
+type NameSanitizer func(name string) string
+
+// SanitizeGraphite replaces every character but letters, digits, '.', '-'
+// and '_' with '_', which also suits OpenTSDB.  Tagged names such as
+// lobby|loginTAGerrors become lobby_loginTAGerrors.
+func SanitizeGraphite(name string) string {
+	return replaceInvalid(name, func(_ int, r rune) bool {
+		return isAlnum(r) || r == '.' || r == '-' || r == '_'
+	})
+}
+
+// SanitizePrometheus makes name match [a-zA-Z_:][a-zA-Z0-9_:]*, replacing
+// invalid characters with '_'.
+func SanitizePrometheus(name string) string {
+	return replaceInvalid(name, func(i int, r rune) bool {
+		return isAlnum(r) && (i > 0 || r < '0' || r > '9') || r == '_' || r == ':'
+	})
+}
+
+// SanitizeInflux replaces the characters InfluxDB's line protocol would need
+// escaped in a measurement name, whitespace, ',', '=' and '"', with '_'.
+func SanitizeInflux(name string) string {
+	return replaceInvalid(name, func(_ int, r rune) bool {
+		return r > ' ' && r != ',' && r != '=' && r != '"' && r != 0x7f
+	})
+}
+
+func isAlnum(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+// replaceInvalid replaces the runes of name for which valid returns false,
+// given their byte offset, with '_'.
+func replaceInvalid(name string, valid func(i int, r rune) bool) string {
+	if name == "" {
+		return "_"
+	}
+	for i, r := range name {
+		if !valid(i, r) {
+			var b strings.Builder
+			b.Grow(len(name))
+			b.WriteString(name[:i])
+			for j, r := range name[i:] {
+				if valid(i+j, r) {
+					b.WriteRune(r)
+				} else {
+					b.WriteByte('_')
+				}
+			}
+			return b.String()
+		}
+	}
+	return name
+}
+
+// SanitizedRegistry is a read-only view of a Registry whose metric names are
+// rewritten by a NameSanitizer whenever the view is read.  When several names
+// sanitize to the same one, the first in name order is kept and the others
+// are dropped and reported to OnCollision, or logged once per sanitized name
+// if OnCollision is nil.  Writes go straight to the underlying registry.
+type SanitizedRegistry struct {
+	Registry
+	sanitize NameSanitizer
+
+	// OnCollision is called on every read of the view for each sanitized
+	// name more than one metric maps to, with the names of the metrics
+	// that were dropped.
+	OnCollision func(sanitized string, dropped []string)
+
+	mutex    sync.Mutex
+	reported map[string]bool
+}
+
+// sanitizedMetric is a metric of a SanitizedRegistry and its original name.
+type sanitizedMetric struct {
+	name   string
+	metric interface{}
+}
+
+// NewSanitizedRegistry returns a view of r with names rewritten by s.
+func NewSanitizedRegistry(r Registry, s NameSanitizer) *SanitizedRegistry {
+	return &SanitizedRegistry{Registry: r, sanitize: s, reported: make(map[string]bool)}
+}
+
+// Each calls f for each metric under its sanitized name, in sanitized name
+// order.
+func (r *SanitizedRegistry) Each(f func(string, interface{})) {
+	metrics := r.sanitized(r.Registry)
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f(name, metrics[name].metric)
+	}
+}
+
+// EachUnsorted calls f for each metric under its sanitized name in no
+// particular order.
+func (r *SanitizedRegistry) EachUnsorted(f func(string, interface{})) {
+	for name, m := range r.sanitized(r.Registry) {
+		f(name, m.metric)
+	}
+}
+
+// Get returns the metric with the given sanitized name or nil.
+func (r *SanitizedRegistry) Get(name string) interface{} {
+	return r.sanitized(r.Registry)[name].metric
+}
+
+// GetCurrent renders the metrics like StandardRegistry.GetCurrent.
+func (r *SanitizedRegistry) GetCurrent() string {
+	return renderCurrent(r.Each)
+}
+
+// MarshalJSON renders the metrics like StandardRegistry.MarshalJSON.
+func (r *SanitizedRegistry) MarshalJSON() ([]byte, error) {
+	return marshalRegistry(r)
+}
+
+// eachSanitized calls f for each metric of reg, a view of the underlying
+// registry, with its sanitized and original names, in no particular order.
+// Exporters look units up by the original name.
+func (r *SanitizedRegistry) eachSanitized(reg Registry, f func(name, original string, i interface{})) {
+	for name, m := range r.sanitized(reg) {
+		f(name, m.name, m.metric)
+	}
+}
+
+// sanitized maps the sanitized names of the metrics of reg to them and
+// reports collisions.
+func (r *SanitizedRegistry) sanitized(reg Registry) map[string]sanitizedMetric {
+	out := make(map[string]sanitizedMetric)
+	var dropped map[string][]string
+	reg.EachUnsorted(func(name string, i interface{}) {
+		s := r.sanitize(name)
+		if kept, ok := out[s]; ok {
+			if dropped == nil {
+				dropped = make(map[string][]string)
+			}
+			// Keep the first in name order, whatever order they come in.
+			if name < kept.name {
+				out[s] = sanitizedMetric{name, i}
+				name = kept.name
+			}
+			dropped[s] = append(dropped[s], name)
+			return
+		}
+		out[s] = sanitizedMetric{name, i}
+	})
+	for s, names := range dropped {
+		sort.Strings(names)
+		r.collision(s, names)
+	}
+	return out
+}
+
+func (r *SanitizedRegistry) collision(sanitized string, dropped []string) {
+	if r.OnCollision != nil {
+		r.OnCollision(sanitized, dropped)
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if !r.reported[sanitized] {
+		r.reported[sanitized] = true
+		log.Printf("metrics: %v sanitize to %s, which is taken; dropping them", dropped, sanitized)
+	}
+}
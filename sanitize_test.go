@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSanitizers(t *testing.T) {
+	for _, c := range []struct {
+		s          NameSanitizer
+		name, want string
+	}{
+		{SanitizeGraphite, "lobby|loginTAGerrors", "lobby_loginTAGerrors"},
+		{SanitizeGraphite, "db query.p99", "db_query.p99"},
+		{SanitizeGraphite, "ok.name-1_2", "ok.name-1_2"},
+		{SanitizePrometheus, "http.requests total", "http_requests_total"},
+		{SanitizePrometheus, "5xx:errors", "_xx:errors"},
+		{SanitizePrometheus, "", "_"},
+		{SanitizeInflux, "cpu load,host=a", "cpu_load_host_a"},
+		{SanitizeInflux, "lobby|loginTAGerrors", "lobby|loginTAGerrors"},
+	} {
+		if got := c.s(c.name); c.want != got {
+			t.Errorf("%q: %q != %q", c.name, c.want, got)
+		}
+	}
+}
+
+func TestSanitizedRegistry(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("a b", r).Inc(1)
+	NewRegisteredCounter("a|b", r).Inc(2)
+	NewRegisteredCounter("c", r).Inc(3)
+
+	s := NewSanitizedRegistry(r, SanitizeGraphite)
+	var collisions [][]string
+	s.OnCollision = func(sanitized string, dropped []string) {
+		collisions = append(collisions, append([]string{sanitized}, dropped...))
+	}
+	counts := make(map[string]int64)
+	s.Each(func(name string, i interface{}) {
+		counts[name] = i.(Counter).Count()
+	})
+	if !reflect.DeepEqual(map[string]int64{"a_b": 1, "c": 3}, counts) {
+		t.Errorf("counts: %v", counts)
+	}
+	if !reflect.DeepEqual([][]string{{"a_b", "a|b"}}, collisions) {
+		t.Errorf("collisions: %v", collisions)
+	}
+	if c, ok := s.Get("a_b").(Counter); !ok || 1 != c.Count() {
+		t.Errorf("s.Get(\"a_b\"): %v", s.Get("a_b"))
+	}
+}
@@ -0,0 +1,131 @@
+package metrics
+
+import (
+	"context"
+	"time"
+)
+
+// A Scope records metrics in a registry under tags shared by all of them,
+// such as the game and endpoint of a request, so handler code names each
+// metric once instead of repeating its tags:
+//
+//	s := metrics.NewScope(nil, "lobby", "login")
+//	s.Counter("requests").Inc(1)
+//	defer s.Timer("latency").UpdateSince(time.Now())
+//
+// Scopes are small values that are cheap to create per request: the tags
+// are rendered once, by NewScope or With, and each metric looked up costs a
+// single concatenation of its name to them.  While UseNilMetrics is set,
+// they return Nil metrics without building any names.
+type Scope struct {
+	r      Registry
+	tags   TagBoard
+	prefix string // tags.String() + TAG_METRIC_DELIMITER, or "" for no tags
+}
+
+// NewScope returns a Scope recording in r, or DefaultRegistry if r is nil,
+// under tags, which are taken in TagBoard order like NewTagBoard takes them.
+func NewScope(r Registry, tags ...string) Scope {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return Scope{r: r}.With(tags...)
+}
+
+// With returns a Scope recording in the same registry under s's tags
+// followed by tags, which fill s's empty tags in TagBoard order.  Tags beyond
+// the fifth are dropped.
+func (s Scope) With(tags ...string) Scope {
+	all := []string{s.tags.Ns, s.tags.Grp, s.tags.Tgt, s.tags.Act, s.tags.Sub}
+	n := 0
+	for n < len(all) && all[n] != "" {
+		n++
+	}
+	copy(all[n:], tags)
+	s.tags = NewTagBoard(all...)
+	s.prefix = ""
+	if s.tags.Ns != "" {
+		s.prefix = s.tags.String() + TAG_METRIC_DELIMITER
+	}
+	return s
+}
+
+// Tags returns the scope's tags.
+func (s Scope) Tags() TagBoard { return s.tags }
+
+// Name returns the tagged name the metric called name is recorded under.
+func (s Scope) Name(name string) string { return s.prefix + name }
+
+// Counter returns the scope's Counter called name, constructing and
+// registering it if needed.
+func (s Scope) Counter(name string) Counter {
+	if UseNilMetrics {
+		return NilCounter{}
+	}
+	return GetOrRegisterCounter(s.Name(name), s.registry())
+}
+
+// GaugeFloat64 returns the scope's GaugeFloat64 called name, constructing
+// and registering it if needed.
+func (s Scope) GaugeFloat64(name string) GaugeFloat64 {
+	if UseNilMetrics {
+		return NilGaugeFloat64{}
+	}
+	return GetOrRegisterGaugeFloat64(s.Name(name), s.registry())
+}
+
+// Meter returns the scope's Meter called name, constructing and registering
+// it if needed.
+func (s Scope) Meter(name string) Meter {
+	if UseNilMetrics {
+		return NilMeter{}
+	}
+	return GetOrRegisterMeter(s.Name(name), s.registry())
+}
+
+// Timer returns the scope's Timer called name, constructing and registering
+// it if needed.
+func (s Scope) Timer(name string) Timer {
+	if UseNilMetrics {
+		return NilTimer{}
+	}
+	return GetOrRegisterTimer(s.Name(name), s.registry())
+}
+
+// Measure starts measuring an operation and returns a function that records
+// the time elapsed since on the scope's Timer called name:
+//
+//	defer s.Measure("db.query")()
+func (s Scope) Measure(name string) func() {
+	if UseNilMetrics {
+		return nopMeasure
+	}
+	t, start := s.Timer(name), time.Now()
+	return func() { t.UpdateSince(start) }
+}
+
+// registry returns the scope's registry, which is DefaultRegistry for the
+// zero Scope.
+func (s Scope) registry() Registry {
+	if nil == s.r {
+		return DefaultRegistry
+	}
+	return s.r
+}
+
+type scopeKey struct{}
+
+// ContextWithScope returns a copy of ctx carrying s, for the code handling a
+// request to record its metrics under the request's tags.
+func ContextWithScope(ctx context.Context, s Scope) context.Context {
+	return context.WithValue(ctx, scopeKey{}, s)
+}
+
+// ScopeFromContext returns the Scope ctx carries, or an untagged Scope of
+// DefaultRegistry if it carries none.
+func ScopeFromContext(ctx context.Context) Scope {
+	if s, ok := ctx.Value(scopeKey{}).(Scope); ok {
+		return s
+	}
+	return NewScope(nil)
+}
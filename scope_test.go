@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	r := NewRegistry()
+	s := NewScope(r, "lobby")
+	login := s.With("login")
+	login.Counter("requests").Inc(2)
+	login.With("google", "eu", "extra", "dropped").Timer("latency").Update(5)
+	s.Meter("hits").Mark(1)
+	NewScope(r).GaugeFloat64("load").Update(0.5)
+	login.Measure("db")()
+
+	for name, want := range map[string]int64{
+		"lobby|loginTAGrequests":                2,
+		"lobby|login|google|eu|extraTAGlatency": 1,
+		"lobbyTAGhits":                          1,
+		"lobby|loginTAGdb":                      1,
+	} {
+		var c int64
+		switch m := r.Get(name).(type) {
+		case Counter:
+			c = m.Count()
+		case Timer:
+			c = m.Count()
+		case Meter:
+			c = m.Count()
+		default:
+			t.Fatalf("%s: %T", name, m)
+		}
+		if c != want {
+			t.Errorf("%s: %d, want %d", name, c, want)
+		}
+	}
+	if v := r.Get("load").(GaugeFloat64).Value(); 0.5 != v {
+		t.Error(v)
+	}
+	if tb := login.Tags(); (TagBoard{Ns: "lobby", Grp: "login"}) != tb {
+		t.Error(tb)
+	}
+
+	ctx := ContextWithScope(context.Background(), login)
+	if ScopeFromContext(ctx).Name("x") != "lobby|loginTAGx" {
+		t.Error(ScopeFromContext(ctx).Name("x"))
+	}
+	if ScopeFromContext(context.Background()).Name("x") != "x" {
+		t.Error("untagged scope")
+	}
+
+	if n := testing.AllocsPerRun(100, func() { login.Counter("requests").Inc(1) }); n > 1 {
+		t.Errorf("%v allocations per lookup", n)
+	}
+}
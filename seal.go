@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPayloadOpen is returned by PayloadCipher.Open for lines that weren't
+// sealed with its key or were altered.
+var ErrPayloadOpen = errors.New("metrics: payload doesn't authenticate")
+
+// A PayloadCipher encrypts and authenticates the lines of line-based
+// protocols such as optron's with AES-GCM under a pre-shared key, for
+// deployments where TLS can't be terminated at the collector.  A sealed line
+// is the base64 encoding of a random nonce followed by the ciphertext, so it
+// stays a single line.  PayloadCiphers are safe for concurrent use.
+type PayloadCipher struct {
+	aead cipher.AEAD
+}
+
+// NewPayloadCipher returns a PayloadCipher using key, which must be 16, 24
+// or 32 bytes long for AES-128, AES-192 or AES-256.
+func NewPayloadCipher(key []byte) (*PayloadCipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: payload key: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &PayloadCipher{aead: aead}, nil
+}
+
+// ParsePayloadKey decodes a key kept in configuration as standard base64,
+// such as the output of `openssl rand -base64 32`, for NewPayloadCipher.
+func ParsePayloadKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("metrics: payload key: %v", err)
+	}
+	return key, nil
+}
+
+// Seal returns line encrypted and encoded as a single line, without a line
+// terminator.
+func (c *PayloadCipher) Seal(line []byte) []byte {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(line)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("metrics: payload nonce: %v", err))
+	}
+	sealed := c.aead.Seal(nonce, nonce, line, nil)
+	out := make([]byte, base64.StdEncoding.EncodedLen(len(sealed)))
+	base64.StdEncoding.Encode(out, sealed)
+	return out
+}
+
+// Open returns the line sealed in line, or ErrPayloadOpen if it wasn't
+// sealed with c's key or was altered.
+func (c *PayloadCipher) Open(line []byte) ([]byte, error) {
+	sealed := make([]byte, base64.StdEncoding.DecodedLen(len(line)))
+	n, err := base64.StdEncoding.Decode(sealed, line)
+	if err != nil || n < c.aead.NonceSize() {
+		return nil, ErrPayloadOpen
+	}
+	sealed = sealed[:n]
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plain, err := c.aead.Open(ciphertext[:0], nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrPayloadOpen
+	}
+	return plain, nil
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPayloadCipher(t *testing.T) {
+	key, err := ParsePayloadKey("MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n")
+	if nil != err {
+		t.Fatal(err)
+	}
+	c, err := NewPayloadCipher(key)
+	if nil != err {
+		t.Fatal(err)
+	}
+	line := []byte(`{"requests":3}`)
+	sealed := c.Seal(line)
+	if bytes.Contains(sealed, []byte("requests")) || bytes.ContainsAny(sealed, "\r\n") {
+		t.Errorf("sealed: %q", sealed)
+	}
+	if again := c.Seal(line); bytes.Equal(sealed, again) {
+		t.Error("nonce reused")
+	}
+	if opened, err := c.Open(sealed); nil != err || !bytes.Equal(line, opened) {
+		t.Errorf("opened: %q, %v", opened, err)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)/2] ^= 'A' ^ 'B'
+	if _, err := c.Open(tampered); ErrPayloadOpen != err {
+		t.Errorf("tampered: %v", err)
+	}
+	other, _ := NewPayloadCipher(make([]byte, 16))
+	if _, err := other.Open(sealed); ErrPayloadOpen != err {
+		t.Errorf("other key: %v", err)
+	}
+	if _, err := c.Open(line); ErrPayloadOpen != err {
+		t.Errorf("plain line: %v", err)
+	}
+	if _, err := NewPayloadCipher(make([]byte, 10)); nil == err {
+		t.Error("short key accepted")
+	}
+}
+
+func TestOptronServerCipher(t *testing.T) {
+	c, _ := NewPayloadCipher(make([]byte, 32))
+	r := NewRegistry()
+	s := &OptronServer{Registry: r, Cipher: c}
+	if err := s.Ingest(append(c.Seal([]byte(`{"hostName":"a","id":"svc","requests":3}`)), "\r\n"...)); nil != err {
+		t.Fatal(err)
+	}
+	if v := r.Get("requests").(GaugeFloat64).Value(); 3 != v {
+		t.Errorf("requests: 3 != %v", v)
+	}
+	if err := s.Ingest([]byte(`{"hostName":"a","id":"svc","requests":4}`)); ErrPayloadOpen != err {
+		t.Errorf("plain line: %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// registryStats are the counts a StandardRegistry keeps about itself.
+type registryStats struct {
+	registrations   uint64
+	unregistrations uint64
+	lockWait        int64 // total nanoseconds spent waiting for the write lock
+	lockWaitMax     int64 // longest wait since SelfMetrics last collected
+}
+
+// lock takes the registry's write lock, counting the time spent waiting for
+// it.  Readers aren't counted, so lookups stay as cheap as they were.
+func (r *StandardRegistry) lock() {
+	start := time.Now()
+	r.mutex.Lock()
+	wait := int64(time.Since(start))
+	atomic.AddInt64(&r.stats.lockWait, wait)
+	for {
+		max := atomic.LoadInt64(&r.stats.lockWaitMax)
+		if wait <= max || atomic.CompareAndSwapInt64(&r.stats.lockWaitMax, max, wait) {
+			return
+		}
+	}
+}
+
+var (
+	exportTimersMutex sync.Mutex
+	exportTimers      = make(map[string]Timer)
+)
+
+// timeExport runs export, a flush of the exporter called exporter, and
+// records its duration for SelfMetrics.
+func timeExport(exporter string, export func()) {
+	exportTimersMutex.Lock()
+	t, ok := exportTimers[exporter]
+	if !ok {
+		t = NewTimer()
+		exportTimers[exporter] = t
+	}
+	exportTimersMutex.Unlock()
+	t.Time(export)
+}
+
+// SelfMetrics returns a Collector recording metrics about the registry it
+// collects and the exporters of this package, so the health of the metrics
+// system is itself observable:
+//
+//	registry.metrics.<Type>        gauges of the registered metrics by type
+//	registry.registrations         gauge of the metrics registered and
+//	registry.unregistrations       unregistered since the last collection
+//	registry.lock.Wait             gauges of the nanoseconds spent waiting
+//	registry.lock.MaxWait          for the write lock since the last
+//	                               collection, in total and at most
+//	export.<exporter>.Duration     timers of the flushes of the Graphite,
+//	                               OpenTSDB, log and JSON exporters
+//	counter.misuse                 CounterMisuse, the process-wide count of
+//	                               updates that would leave counters negative
+//
+// The registration and lock metrics are kept by StandardRegistry and those
+// of registries wrapping one, such as PrefixedRegistry, and are missing for
+// other registries.  Add one SelfMetrics collector per registry.
+func SelfMetrics() Collector {
+	var last registryStats
+	return CollectorFunc(func(r Registry) {
+		// Read the stats first, so the metrics registered below count
+		// towards the next collection.
+		base, isStandard := standardBase(r)
+		var stats registryStats
+		if isStandard {
+			stats = registryStats{
+				registrations:   atomic.LoadUint64(&base.stats.registrations),
+				unregistrations: atomic.LoadUint64(&base.stats.unregistrations),
+				lockWait:        atomic.LoadInt64(&base.stats.lockWait),
+				lockWaitMax:     atomic.SwapInt64(&base.stats.lockWaitMax, 0),
+			}
+		}
+
+		counts := make(map[string]int64)
+		r.EachUnsorted(func(_ string, i interface{}) {
+			counts[selfMetricType(i)]++
+		})
+		for _, typ := range selfMetricTypes {
+			GetOrRegisterGauge("registry.metrics."+typ, r).Update(counts[typ])
+		}
+
+		if isStandard {
+			GetOrRegisterGauge("registry.registrations", r).Update(int64(stats.registrations - last.registrations))
+			GetOrRegisterGauge("registry.unregistrations", r).Update(int64(stats.unregistrations - last.unregistrations))
+			GetOrRegisterGauge("registry.lock.Wait", r).Update(stats.lockWait - last.lockWait)
+			GetOrRegisterGauge("registry.lock.MaxWait", r).Update(stats.lockWaitMax)
+			last = stats
+		}
+
+		exportTimersMutex.Lock()
+		timers := make(map[string]Timer, len(exportTimers))
+		for exporter, t := range exportTimers {
+			timers[exporter] = t
+		}
+		exportTimersMutex.Unlock()
+		for exporter, t := range timers {
+			r.GetOrRegister("export."+exporter+".Duration", t)
+		}
+		r.GetOrRegister("counter.misuse", CounterMisuse)
+	})
+}
+
+// CaptureSelfMetrics adds SelfMetrics to DefaultScheduler, to run every
+// interval on DefaultRegistry.
+func CaptureSelfMetrics(interval time.Duration) {
+	AddCollector("self", SelfMetrics(), interval)
+}
+
+var selfMetricTypes = []string{
+	"Counter", "CounterFloat64", "Gauge", "GaugeFloat64", "Healthcheck",
+	"Histogram", "Instant", "Meter", "Timer",
+}
+
+// selfMetricType returns the name of the type of metric i is.
+func selfMetricType(i interface{}) string {
+	return string(MetricKind(i))
+}
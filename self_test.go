@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSelfMetrics(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("requests", r)
+	NewRegisteredCounter("errors", r)
+	NewRegisteredTimer("latency", r)
+	r.Unregister("errors")
+	timeExport("test", func() { time.Sleep(time.Millisecond) })
+
+	c := SelfMetrics()
+	c.Collect(r)
+	gauge := func(name string) int64 {
+		g, ok := r.Get(name).(Gauge)
+		if !ok {
+			t.Fatalf("%s isn't registered", name)
+		}
+		return g.Value()
+	}
+	if n := gauge("registry.metrics.Counter"); 1 != n {
+		t.Errorf("counters: 1 != %v", n)
+	}
+	if n := gauge("registry.metrics.Timer"); 1 != n {
+		t.Errorf("timers: 1 != %v", n)
+	}
+	if n := gauge("registry.registrations"); 3 != n {
+		t.Errorf("registrations: 3 != %v", n)
+	}
+	if n := gauge("registry.unregistrations"); 1 != n {
+		t.Errorf("unregistrations: 1 != %v", n)
+	}
+	if n := gauge("registry.lock.Wait"); n < 0 {
+		t.Errorf("lock wait: %v", n)
+	}
+	if tm, ok := r.Get("export.test.Duration").(Timer); !ok || tm.Count() < 1 || tm.Max() < int64(time.Millisecond) {
+		t.Errorf("export timer: %v", r.Get("export.test.Duration"))
+	}
+
+	// Later collections report what changed since the one before, the
+	// first of which registered the self-metrics themselves.
+	c.Collect(r)
+	NewRegisteredCounter("more", r)
+	c.Collect(r)
+	if n := gauge("registry.registrations"); 1 != n {
+		t.Errorf("registrations since: 1 != %v", n)
+	}
+	if n := gauge("registry.metrics.Gauge"); int64(len(selfMetricTypes)+4) != n {
+		t.Errorf("gauges: %v", n)
+	}
+}
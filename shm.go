@@ -0,0 +1,251 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// A SharedRegion holds counters and gauges in a file mapped into the memory
+// of every process that opens it, so forked workers on one host can update
+// metrics that a single exporter process reads and flushes, instead of each
+// worker keeping its own connection to the collector.  Workers get their
+// metrics from the region:
+//
+//	region, err := metrics.OpenSharedRegion("/dev/shm/game.metrics", 4096)
+//	requests, err := region.Counter("requests")
+//	requests.Inc(1)
+//
+// and the exporter registers them as they appear:
+//
+//	metrics.AddCollector("shm", region, 10*time.Second)
+//
+// Updates are atomic operations on the mapping, as cheap as those of a
+// StandardCounter.  The region holds a fixed number of metrics, chosen when
+// it is created, whose names are at most SharedNameLen bytes; metrics can't
+// be removed.  It is experimental and only supported on Linux and Darwin.
+type SharedRegion struct {
+	data  []byte
+	slots int
+	close func() error
+}
+
+// SharedNameLen is the longest metric name a SharedRegion holds.
+const SharedNameLen = sharedSlotSize - sharedNameOffset
+
+// ErrSharedRegionFull is returned for new metrics once every slot of a
+// SharedRegion is taken.
+var ErrSharedRegionFull = errors.New("metrics: shared region is full")
+
+// The layout of a region: a header, then slots of sharedSlotSize bytes,
+// each an int64 value, a uint32 state and a uint32 kind, then the name,
+// NUL-padded.  Slots are found by hashing their name and probing linearly,
+// so processes racing to add the same name claim the same slot.  A slot
+// being claimed has a state of sharedClaiming tagged with the claimant's
+// PID, so a claim left by a process that died can be taken over.
+const (
+	sharedMagic       = "GOMSHM01"
+	sharedHeaderSize  = 64
+	sharedSlotSize    = 128
+	sharedStateOffset = 8
+	sharedKindOffset  = 12
+	sharedNameOffset  = 16
+
+	sharedFree     = 0
+	sharedClaiming = 1
+	sharedReady    = 2
+
+	sharedKindCounter = 1
+	sharedKindGauge   = 2
+)
+
+// sharedClaim is the state of the slots this process claims.
+var sharedClaim = sharedClaiming | uint32(os.Getpid())<<2
+
+// sharedClaimTimeout is how long a slot may stay claimed before another
+// process takes the claim over, its claimant having presumably died between
+// claiming and naming the slot.
+var sharedClaimTimeout = time.Second
+
+// sharedRegionSize returns the size of a region of the given number of
+// slots.
+func sharedRegionSize(slots int) int {
+	return sharedHeaderSize + slots*sharedSlotSize
+}
+
+// initSharedRegion writes the header of a new region to data.
+func initSharedRegion(data []byte, slots int) {
+	copy(data, sharedMagic)
+	binary.LittleEndian.PutUint64(data[8:], uint64(slots))
+}
+
+// newSharedRegion returns the region mapped at data, checking its header.
+func newSharedRegion(data []byte, close func() error) (*SharedRegion, error) {
+	if len(data) < sharedHeaderSize || string(data[:8]) != sharedMagic {
+		return nil, errors.New("metrics: not a shared region")
+	}
+	slots := int(binary.LittleEndian.Uint64(data[8:]))
+	if slots < 1 || len(data) < sharedRegionSize(slots) {
+		return nil, fmt.Errorf("metrics: shared region of %d slots is truncated", slots)
+	}
+	return &SharedRegion{data: data, slots: slots, close: close}, nil
+}
+
+// Counter returns the Counter called name in the region, adding it if no
+// process has yet.
+func (s *SharedRegion) Counter(name string) (Counter, error) {
+	slot, err := s.slot(name, sharedKindCounter)
+	if err != nil {
+		return nil, err
+	}
+	return (*sharedCounter)(slot), nil
+}
+
+// Gauge returns the Gauge called name in the region, adding it if no
+// process has yet.
+func (s *SharedRegion) Gauge(name string) (Gauge, error) {
+	slot, err := s.slot(name, sharedKindGauge)
+	if err != nil {
+		return nil, err
+	}
+	return (*sharedGauge)(slot), nil
+}
+
+// Collect registers in r the metrics of the region that aren't yet, so a
+// SharedRegion can be scheduled as a Collector in the exporting process.
+func (s *SharedRegion) Collect(r Registry) {
+	for i := 0; i < s.slots; i++ {
+		off := sharedHeaderSize + i*sharedSlotSize
+		if atomic.LoadUint32(s.uint32At(off+sharedStateOffset)) != sharedReady {
+			continue
+		}
+		name := s.name(off)
+		if nil != r.Get(name) {
+			continue
+		}
+		switch *s.uint32At(off + sharedKindOffset) {
+		case sharedKindCounter:
+			r.Register(name, (*sharedCounter)(s.int64At(off)))
+		case sharedKindGauge:
+			r.Register(name, (*sharedGauge)(s.int64At(off)))
+		}
+	}
+}
+
+// Close unmaps the region.  Its metrics must not be used afterwards.
+func (s *SharedRegion) Close() error {
+	return s.close()
+}
+
+// slot returns the value of the slot of name, claiming a free one for it if
+// it has none, or an error if it is of another kind.
+func (s *SharedRegion) slot(name string, kind uint32) (*int64, error) {
+	if "" == name || len(name) > SharedNameLen || bytes.IndexByte([]byte(name), 0) >= 0 {
+		return nil, fmt.Errorf("metrics: shared metric name %q is empty, too long or holds NUL", name)
+	}
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	start := int(h.Sum32() % uint32(s.slots))
+	for n := 0; n < s.slots; n++ {
+		off := sharedHeaderSize + (start+n)%s.slots*sharedSlotSize
+		for state := uint32(sharedFree); sharedReady != state; state = s.waitClaimed(off) {
+			if s.claim(off, state, name, kind) {
+				return s.int64At(off), nil
+			}
+		}
+		if s.name(off) != name {
+			continue
+		}
+		if *s.uint32At(off + sharedKindOffset) != kind {
+			return nil, DuplicateMetric(name)
+		}
+		return s.int64At(off), nil
+	}
+	return nil, ErrSharedRegionFull
+}
+
+// claim names the slot at off for a metric if its state is still old, free
+// or a stale claim, reporting whether it did.
+func (s *SharedRegion) claim(off int, old uint32, name string, kind uint32) bool {
+	state := s.uint32At(off + sharedStateOffset)
+	if !atomic.CompareAndSwapUint32(state, old, sharedClaim) {
+		return false
+	}
+	field := s.data[off+sharedNameOffset : off+sharedSlotSize]
+	for i := copy(field, name); i < len(field); i++ {
+		field[i] = 0 // a stale claim may have left part of a name
+	}
+	*s.uint32At(off + sharedKindOffset) = kind
+	// The claim fails if it was taken over meanwhile.
+	return atomic.CompareAndSwapUint32(state, sharedClaim, sharedReady)
+}
+
+// waitClaimed waits for the slot at off to be named, returning its state:
+// sharedReady, or the claim still held after sharedClaimTimeout.
+func (s *SharedRegion) waitClaimed(off int) uint32 {
+	state := s.uint32At(off + sharedStateOffset)
+	deadline := time.Now().Add(sharedClaimTimeout)
+	for {
+		v := atomic.LoadUint32(state)
+		if sharedReady == v || time.Now().After(deadline) {
+			return v
+		}
+		runtime.Gosched()
+	}
+}
+
+func (s *SharedRegion) name(off int) string {
+	name := s.data[off+sharedNameOffset : off+sharedSlotSize]
+	if i := bytes.IndexByte(name, 0); i >= 0 {
+		name = name[:i]
+	}
+	return string(name)
+}
+
+func (s *SharedRegion) int64At(off int) *int64 {
+	return (*int64)(unsafe.Pointer(&s.data[off]))
+}
+
+func (s *SharedRegion) uint32At(off int) *uint32 {
+	return (*uint32)(unsafe.Pointer(&s.data[off]))
+}
+
+// sharedCounter is a Counter whose count is in a SharedRegion.
+type sharedCounter int64
+
+// Clear sets the counter to zero.
+func (c *sharedCounter) Clear() { atomic.StoreInt64((*int64)(c), 0) }
+
+// Count returns the current count.
+func (c *sharedCounter) Count() int64 { return atomic.LoadInt64((*int64)(c)) }
+
+// Dec decrements the counter by the given amount.
+func (c *sharedCounter) Dec(i int64) { atomic.AddInt64((*int64)(c), -i) }
+
+// Inc increments the counter by the given amount.
+func (c *sharedCounter) Inc(i int64) { atomic.AddInt64((*int64)(c), i) }
+
+// Update increments the counter like Inc.
+func (c *sharedCounter) Update(i int64) { c.Inc(i) }
+
+// Snapshot returns a read-only copy of the counter.
+func (c *sharedCounter) Snapshot() Counter { return CounterSnapshot(c.Count()) }
+
+// sharedGauge is a Gauge whose value is in a SharedRegion.
+type sharedGauge int64
+
+// Snapshot returns a read-only copy of the gauge.
+func (g *sharedGauge) Snapshot() Gauge { return GaugeSnapshot(g.Value()) }
+
+// Update updates the gauge's value.
+func (g *sharedGauge) Update(v int64) { atomic.StoreInt64((*int64)(g), v) }
+
+// Value returns the gauge's current value.
+func (g *sharedGauge) Value() int64 { return atomic.LoadInt64((*int64)(g)) }
@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package metrics
+
+import "errors"
+
+// OpenSharedRegion returns an error; shared regions are only supported on
+// Linux and Darwin.
+func OpenSharedRegion(path string, slots int) (*SharedRegion, error) {
+	return nil, errors.New("metrics: shared regions not supported on this platform")
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSharedRegion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics")
+	worker, err := OpenSharedRegion(path, 4)
+	if err != nil {
+		t.Skip(err)
+	}
+	defer worker.Close()
+	exporter, err := OpenSharedRegion(path, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close()
+
+	c, err := worker.Counter("requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Inc(3)
+	g, _ := worker.Gauge("players")
+	g.Update(42)
+	if _, err := exporter.Gauge("requests"); nil == err {
+		t.Error("counter reused as a gauge")
+	}
+	same, _ := exporter.Counter("requests")
+	same.Inc(2)
+
+	r := NewRegistry()
+	exporter.Collect(r)
+	if n := r.Get("requests").(Counter).Count(); 5 != n {
+		t.Errorf("requests: 5 != %v", n)
+	}
+	if v := r.Get("players").(Gauge).Value(); 42 != v {
+		t.Errorf("players: 42 != %v", v)
+	}
+
+	worker.Counter("a")
+	worker.Counter("b")
+	if _, err := worker.Counter("c"); ErrSharedRegionFull != err {
+		t.Errorf("full region: %v", err)
+	}
+
+	if _, err := OpenSharedRegion(filepath.Join(t.TempDir(), "empty"), 0); nil == err {
+		t.Error("region of no slots opened")
+	}
+}
+
+func TestSharedRegionStaleClaim(t *testing.T) {
+	defer func(d time.Duration) { sharedClaimTimeout = d }(sharedClaimTimeout)
+	sharedClaimTimeout = 10 * time.Millisecond
+	data := make([]byte, sharedRegionSize(1))
+	initSharedRegion(data, 1)
+	s, err := newSharedRegion(data, func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A process died after claiming the only slot and naming it in part.
+	off := sharedHeaderSize
+	copy(data[off+sharedNameOffset:], "requests.partial")
+	*s.uint32At(off + sharedStateOffset) = sharedClaiming | 1<<2
+
+	c, err := s.Counter("players")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Inc(1)
+	if same, err := s.Counter("players"); err != nil || 1 != same.Count() {
+		t.Errorf("players: %v, %v", same, err)
+	}
+	r := NewRegistry()
+	s.Collect(r)
+	if nil == r.Get("players") {
+		t.Error("taken over slot not collected")
+	}
+}
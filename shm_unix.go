@@ -0,0 +1,56 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package metrics
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// OpenSharedRegion maps the SharedRegion in the file at path, creating it
+// with room for slots metrics if it doesn't exist or is empty.  The size of
+// an existing region is kept.  Put the file on a memory-backed filesystem,
+// such as /dev/shm on Linux, to keep updates off the disk.  Slots must be at
+// least 1.
+func OpenSharedRegion(path string, slots int) (*SharedRegion, error) {
+	if slots < 1 {
+		return nil, fmt.Errorf("metrics: shared region of %d slots", slots)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// Serialize creating the region with the other processes opening it.
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		return nil, err
+	}
+	defer syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size, created := int(fi.Size()), false
+	if 0 == size {
+		size, created = sharedRegionSize(slots), true
+		if err := f.Truncate(int64(size)); err != nil {
+			return nil, err
+		}
+	}
+	data, err := syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		initSharedRegion(data, slots)
+	}
+	s, err := newSharedRegion(data, func() error { return syscall.Munmap(data) })
+	if err != nil {
+		syscall.Munmap(data)
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,218 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSLOWindows are the windows a LatencySLO reports burn rates over if
+// its SLOConfig lists none: the short and long windows of the usual
+// multi-window burn rate alerts.
+var DefaultSLOWindows = []time.Duration{5 * time.Minute, 30 * time.Minute, time.Hour, 6 * time.Hour}
+
+// SLOConfig configures a LatencySLO.
+type SLOConfig struct {
+	// Threshold is the latency at or under which an operation is good.
+	Threshold time.Duration
+
+	// Target is the ratio of operations that should be good, such as 0.999.
+	Target float64
+
+	// Windows are the windows to report burn rates over, DefaultSLOWindows
+	// if empty.
+	Windows []time.Duration
+}
+
+// A LatencySLO classifies the durations of an operation as good or bad
+// against a latency threshold and reports how fast the operation is burning
+// its error budget, so burn rate alerts can be driven from the registry.
+// The burn rate over a window is the ratio of bad operations in the window
+// divided by the ratio the target allows: 1 spends the budget exactly over
+// the SLO period and 14.4 over an hour spends 2% of a 30 day budget.
+type LatencySLO struct {
+	threshold time.Duration
+	budget    float64 // the ratio of bad operations allowed
+	windows   []time.Duration
+	clock     Clock
+	good, bad Counter
+
+	mutex   sync.Mutex
+	width   time.Duration // of a bucket
+	buckets []sloBucket
+}
+
+// sloBucket counts the operations of one bucket-wide slice of time.
+type sloBucket struct {
+	index     int64 // of the slice of time counted, since the Unix epoch
+	good, bad int64
+}
+
+// NewLatencySLO constructs a LatencySLO configured by c.
+func NewLatencySLO(c SLOConfig) *LatencySLO {
+	windows := append([]time.Duration(nil), c.Windows...)
+	if 0 == len(windows) {
+		windows = append(windows, DefaultSLOWindows...)
+	}
+	sort.Slice(windows, func(i, j int) bool { return windows[i] < windows[j] })
+	// Buckets a tenth of the shortest window wide bound the error of the
+	// windows to a tenth of it.
+	width := windows[0] / 10
+	if width <= 0 {
+		width = 1
+	}
+	return &LatencySLO{
+		threshold: c.Threshold,
+		budget:    1 - c.Target,
+		windows:   windows,
+		clock:     DefaultClock,
+		good:      NewCounter(),
+		bad:       NewCounter(),
+		width:     width,
+		buckets:   make([]sloBucket, int(windows[len(windows)-1]/width)+1),
+	}
+}
+
+// NewRegisteredLatencySLO constructs a LatencySLO configured by c and
+// registers Counters of its good and bad operations named name.good and
+// name.bad and a GaugeFloat64 of the burn rate over each window, such as
+// name.burn_rate.5m.
+func NewRegisteredLatencySLO(name string, r Registry, c SLOConfig) *LatencySLO {
+	s := NewLatencySLO(c)
+	if nil == r {
+		r = DefaultRegistry
+	}
+	r.Register(name+".good", s.good)
+	r.Register(name+".bad", s.bad)
+	for _, window := range s.windows {
+		window := window
+		r.Register(name+".burn_rate."+windowLabel(window), NewFunctionalGaugeFloat64(func() float64 {
+			return s.BurnRate(window)
+		}))
+	}
+	return s
+}
+
+// Update records an operation that took d.
+func (s *LatencySLO) Update(d time.Duration) {
+	good := d <= s.threshold
+	if good {
+		s.good.Inc(1)
+	} else {
+		s.bad.Inc(1)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	b := s.bucket(s.clock.Now())
+	if good {
+		b.good++
+	} else {
+		b.bad++
+	}
+}
+
+// UpdateSince records an operation that started at ts.
+func (s *LatencySLO) UpdateSince(ts time.Time) {
+	s.Update(s.clock.Now().Sub(ts))
+}
+
+// Good returns the number of operations that took at most the threshold.
+func (s *LatencySLO) Good() int64 { return s.good.Count() }
+
+// Bad returns the number of operations that took longer than the threshold.
+func (s *LatencySLO) Bad() int64 { return s.bad.Count() }
+
+// BurnRate returns the burn rate over the last window, 0 if no operations
+// were recorded in it.  Windows longer than the longest configured are cut
+// short to it.
+func (s *LatencySLO) BurnRate(window time.Duration) float64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	index := s.clock.Now().UnixNano() / int64(s.width)
+	n := int64(window / s.width)
+	if n < 1 {
+		n = 1
+	}
+	if n > int64(len(s.buckets)) {
+		n = int64(len(s.buckets))
+	}
+	var good, bad int64
+	for i := index - n + 1; i <= index; i++ {
+		if b := &s.buckets[ringIndex(i, len(s.buckets))]; b.index == i {
+			good += b.good
+			bad += b.bad
+		}
+	}
+	if 0 == good+bad || s.budget <= 0 {
+		return 0
+	}
+	return float64(bad) / float64(good+bad) / s.budget
+}
+
+// Timer returns a Timer recording into t whose durations are also recorded
+// by s.
+func (s *LatencySLO) Timer(t Timer) Timer {
+	return &sloTimer{Timer: t, slo: s}
+}
+
+// bucket returns the bucket counting now, emptying it if it last counted an
+// earlier slice of time.
+// assumes lock is taken
+func (s *LatencySLO) bucket(now time.Time) *sloBucket {
+	index := now.UnixNano() / int64(s.width)
+	b := &s.buckets[ringIndex(index, len(s.buckets))]
+	if b.index != index {
+		*b = sloBucket{index: index}
+	}
+	return b
+}
+
+// ringIndex returns the index in a ring of n buckets of the bucket numbered i.
+func ringIndex(i int64, n int) int {
+	m := int(i % int64(n))
+	if m < 0 {
+		m += n
+	}
+	return m
+}
+
+// windowLabel formats d in the largest whole unit of hours, minutes or
+// seconds, such as 5m for five minutes.
+func windowLabel(d time.Duration) string {
+	switch {
+	case 0 == d%time.Hour:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case 0 == d%time.Minute:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	case 0 == d%time.Second:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+	return d.String()
+}
+
+// sloTimer is a Timer whose durations are also recorded by a LatencySLO.
+type sloTimer struct {
+	Timer
+	slo *LatencySLO
+}
+
+func (t *sloTimer) Time(f func()) {
+	ts := DefaultClock.Now()
+	f()
+	t.UpdateSince(ts)
+}
+
+func (t *sloTimer) Update(d int64) {
+	t.Timer.Update(d)
+	t.slo.Update(time.Duration(d))
+}
+
+func (t *sloTimer) UpdateTime(d time.Duration) {
+	t.Timer.UpdateTime(d)
+	t.slo.Update(d)
+}
+
+func (t *sloTimer) UpdateSince(ts time.Time) {
+	t.UpdateTime(DefaultClock.Now().Sub(ts))
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencySLO(t *testing.T) {
+	defer func(c Clock) { DefaultClock = c }(DefaultClock)
+	clock := NewMockClock(time.Unix(0, 0))
+	DefaultClock = clock
+
+	r := NewRegistry()
+	s := NewRegisteredLatencySLO("checkout", r, SLOConfig{
+		Threshold: 100 * time.Millisecond,
+		Target:    0.9,
+		Windows:   []time.Duration{time.Hour, 5 * time.Minute},
+	})
+	timer := s.Timer(NewTimer())
+	for i := 0; i < 8; i++ {
+		timer.UpdateTime(50 * time.Millisecond)
+	}
+	timer.UpdateTime(100 * time.Millisecond)
+	timer.UpdateTime(time.Second)
+	if 9 != s.Good() || 1 != s.Bad() || 10 != timer.Count() {
+		t.Errorf("good %v, bad %v, timed %v", s.Good(), s.Bad(), timer.Count())
+	}
+	if rate := s.BurnRate(5 * time.Minute); rate < 0.999 || rate > 1.001 {
+		t.Errorf("burn rate: 1 != %v", rate)
+	}
+
+	clock.Add(10 * time.Minute)
+	s.Update(time.Second)
+	if rate := r.Get("checkout.burn_rate.5m").(GaugeFloat64).Value(); rate < 9.999 || rate > 10.001 {
+		t.Errorf("5m burn rate: 10 != %v", rate)
+	}
+	if rate := r.Get("checkout.burn_rate.1h").(GaugeFloat64).Value(); rate < 1.817 || rate > 1.819 {
+		t.Errorf("1h burn rate: 1.818 != %v", rate)
+	}
+	if bad := r.Get("checkout.bad").(Counter).Count(); 2 != bad {
+		t.Errorf("checkout.bad: 2 != %v", bad)
+	}
+
+	clock.Add(2 * time.Hour)
+	if rate := s.BurnRate(time.Hour); 0 != rate {
+		t.Errorf("burn rate of an empty window: 0 != %v", rate)
+	}
+}
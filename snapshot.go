@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RegistrySnapshot holds the values of every metric in a registry at one
+// point in time, by metric name and then by the field names MarshalJSON
+// uses: count, value, min, max, mean, median, 99%, 1m.rate and so on.
+// Healthchecks are left out.
+type RegistrySnapshot map[string]map[string]float64
+
+// SnapshotRegistry captures the values of every metric in r.
+func SnapshotRegistry(r Registry) RegistrySnapshot {
+	s := make(RegistrySnapshot)
+	r.EachUnsorted(func(name string, i interface{}) {
+		if _, ok := i.(Healthcheck); ok {
+			return
+		}
+		fields := make(map[string]float64)
+		for field, v := range jsonValues(i, DefaultJSONPercentiles) {
+			switch v := v.(type) {
+			case int64:
+				fields[field] = float64(v)
+			case float64:
+				fields[field] = v
+			}
+		}
+		s[name] = fields
+	})
+	return s
+}
+
+// A MetricDelta describes how one metric differs between two snapshots.
+// Before and After hold the fields that differ, or every field of a metric
+// that was added or removed.
+type MetricDelta struct {
+	Name          string
+	Added         bool
+	Removed       bool
+	Before, After map[string]float64
+}
+
+// Change returns how much the given field changed: the increment of a
+// counter's count, say, or the movement of a gauge's value.
+func (d MetricDelta) Change(field string) float64 {
+	return d.After[field] - d.Before[field]
+}
+
+func (d MetricDelta) String() string {
+	switch {
+	case d.Added:
+		return d.Name + ": added"
+	case d.Removed:
+		return d.Name + ": removed"
+	}
+	fields := make([]string, 0, len(d.After))
+	for field := range d.After {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	changes := make([]string, len(fields))
+	for i, field := range fields {
+		changes[i] = fmt.Sprintf("%s %v -> %v", field, d.Before[field], d.After[field])
+	}
+	return d.Name + ": " + strings.Join(changes, ", ")
+}
+
+// Diff returns a MetricDelta, in name order, for every metric that differs
+// between a and b, including those only in one of them.
+func Diff(a, b RegistrySnapshot) []MetricDelta {
+	names := make([]string, 0, len(a)+len(b))
+	for name := range a {
+		names = append(names, name)
+	}
+	for name := range b {
+		if _, ok := a[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var deltas []MetricDelta
+	for _, name := range names {
+		before, inA := a[name]
+		after, inB := b[name]
+		switch {
+		case !inA:
+			deltas = append(deltas, MetricDelta{Name: name, Added: true, Before: map[string]float64{}, After: after})
+		case !inB:
+			deltas = append(deltas, MetricDelta{Name: name, Removed: true, Before: before, After: map[string]float64{}})
+		default:
+			d := MetricDelta{Name: name, Before: make(map[string]float64), After: make(map[string]float64)}
+			for field, v := range after {
+				if before[field] != v {
+					d.Before[field] = before[field]
+					d.After[field] = v
+				}
+			}
+			if len(d.After) > 0 {
+				deltas = append(deltas, d)
+			}
+		}
+	}
+	return deltas
+}
+
+// Changes runs f and returns how the metrics of r changed meanwhile, for
+// seeing what a single request recorded.  Concurrent updates by others show
+// up too.
+func Changes(r Registry, f func()) []MetricDelta {
+	before := SnapshotRegistry(r)
+	f()
+	return Diff(before, SnapshotRegistry(r))
+}
@@ -0,0 +1,57 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	r := NewRegistry()
+	c := NewRegisteredCounter("requests", r)
+	g := NewRegisteredGauge("queue", r)
+	h := NewRegisteredHistogram("latency", r, NewUniformSample(100))
+	NewRegisteredCounter("idle", r)
+	NewRegisteredCounter("retired", r)
+	c.Inc(1)
+	g.Update(10)
+	r.Register("healthy", NewHealthcheck(func(Healthcheck) {}))
+
+	deltas := Changes(r, func() {
+		c.Inc(3)
+		g.Update(7)
+		h.Update(100)
+		r.Unregister("retired")
+		NewRegisteredMeter("hits", r)
+	})
+	var names []string
+	for _, d := range deltas {
+		names = append(names, d.String())
+	}
+	want := []string{
+		"hits: added",
+		"latency: 75% 0 -> 100, 95% 0 -> 100, 99% 0 -> 100, 99.9% 0 -> 100, count 0 -> 1, max 0 -> 100, mean 0 -> 100, median 0 -> 100, min 0 -> 100",
+		"queue: value 10 -> 7",
+		"requests: count 1 -> 4",
+		"retired: removed",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("deltas: %v", names)
+	}
+	for i := range want {
+		if want[i] != names[i] {
+			t.Errorf("%q != %q", want[i], names[i])
+		}
+	}
+	if c := deltas[3].Change("count"); 3 != c {
+		t.Errorf("requests count change: 3 != %v", c)
+	}
+	if c := deltas[2].Change("value"); -3 != c {
+		t.Errorf("queue value change: -3 != %v", c)
+	}
+	if !reflect.DeepEqual(map[string]float64{"count": 0}, deltas[4].Before) {
+		t.Errorf("retired: %v", deltas[4].Before)
+	}
+	if p := deltas[1].After["99%"]; 100 != p {
+		t.Errorf("latency 99%%: 100 != %v", p)
+	}
+}
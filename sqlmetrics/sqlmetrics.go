@@ -0,0 +1,133 @@
+// Package sqlmetrics records database/sql queries and connection pool
+// statistics into a go-metrics registry.
+//
+// Queries update metrics tagged ns=sql, grp=<database name>, tgt=<label>,
+// where the label is the statement's operation (select, insert, ...) unless
+// given explicitly with Time:
+//
+//	latency   a Timer of query durations
+//	errors    a Counter of failed queries
+//
+// Collect publishes sql.DBStats as gauges tagged ns=sql, grp=<database name>.
+package sqlmetrics
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/moonfrog/go-metrics"
+)
+
+const namespace = "sql"
+
+// DB wraps a *sql.DB, timing every Exec and Query made through it.  Methods
+// not redefined here, such as Begin and Close, go straight to the *sql.DB.
+type DB struct {
+	*sql.DB
+	name     string
+	registry metrics.Registry
+}
+
+// Wrap instruments db, recording its metrics into r under the given name.
+func Wrap(db *sql.DB, name string, r metrics.Registry) *DB {
+	if nil == r {
+		r = metrics.DefaultRegistry
+	}
+	return &DB{DB: db, name: name, registry: r}
+}
+
+// Exec executes a query without returning any rows.
+func (db *DB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a query without returning any rows.
+func (db *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := db.DB.ExecContext(ctx, query, args...)
+	db.record(Operation(query), start, err)
+	return res, err
+}
+
+// Query executes a query that returns rows.
+func (db *DB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query that returns rows.  Only the time until the
+// first row is available is recorded.
+func (db *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := db.DB.QueryContext(ctx, query, args...)
+	db.record(Operation(query), start, err)
+	return rows, err
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+func (db *DB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one
+// row.  sql.ErrNoRows is not counted as an error.
+func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := db.DB.QueryRowContext(ctx, query, args...)
+	db.record(Operation(query), start, row.Err())
+	return row
+}
+
+// Time runs f and records it under the given label, for transactions and
+// other work that isn't a single statement.
+func (db *DB) Time(label string, f func() error) error {
+	start := time.Now()
+	err := f()
+	db.record(label, start, err)
+	return err
+}
+
+func (db *DB) record(label string, start time.Time, err error) {
+	tb := metrics.NewTagBoard(namespace, db.name, label)
+	metrics.GetOrRegisterTimer(metrics.TaggedMetricName("latency", tb), db.registry).UpdateSince(start)
+	if err != nil && err != sql.ErrNoRows {
+		metrics.GetOrRegisterCounter(metrics.TaggedMetricName("errors", tb), db.registry).Inc(1)
+	}
+}
+
+// Collect publishes the connection pool statistics of db into r.  DB is a
+// metrics.Collector, so it can be added to a metrics.Scheduler.
+func (db *DB) Collect(r metrics.Registry) {
+	stats := db.Stats()
+	tb := metrics.NewTagBoard(namespace, db.name)
+	gauge := func(name string, v int64) {
+		metrics.GetOrRegisterGauge(metrics.TaggedMetricName(name, tb), r).Update(v)
+	}
+	gauge("MaxOpenConnections", int64(stats.MaxOpenConnections))
+	gauge("OpenConnections", int64(stats.OpenConnections))
+	gauge("InUse", int64(stats.InUse))
+	gauge("Idle", int64(stats.Idle))
+	gauge("WaitCount", stats.WaitCount)
+	gauge("WaitDuration", int64(stats.WaitDuration))
+	gauge("MaxIdleClosed", stats.MaxIdleClosed)
+	gauge("MaxLifetimeClosed", stats.MaxLifetimeClosed)
+}
+
+// CaptureStats publishes the connection pool statistics of db every d.  This
+// is designed to be called as a goroutine.
+func (db *DB) CaptureStats(d time.Duration) {
+	for _ = range time.Tick(d) {
+		db.Collect(db.registry)
+	}
+}
+
+// Operation returns the lower-cased first keyword of a statement, e.g.
+// "select" for "SELECT * FROM players", used to label its metrics.
+func Operation(query string) string {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "unknown"
+	}
+	return strings.ToLower(fields[0])
+}
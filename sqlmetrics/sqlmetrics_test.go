@@ -0,0 +1,146 @@
+package sqlmetrics
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/moonfrog/go-metrics"
+)
+
+// stubDriver answers every statement with one row holding 1, and fails
+// those mentioning "missing".
+type stubDriver struct{}
+
+func (stubDriver) Open(string) (driver.Conn, error) { return stubConn{}, nil }
+
+type stubConn struct{}
+
+func (stubConn) Prepare(query string) (driver.Stmt, error) { return stubStmt(query), nil }
+func (stubConn) Close() error                              { return nil }
+func (stubConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type stubStmt string
+
+func (s stubStmt) err() error {
+	if strings.Contains(string(s), "missing") {
+		return errors.New("no such table")
+	}
+	return nil
+}
+
+func (stubStmt) Close() error  { return nil }
+func (stubStmt) NumInput() int { return -1 }
+
+func (s stubStmt) Exec([]driver.Value) (driver.Result, error) {
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s stubStmt) Query([]driver.Value) (driver.Rows, error) {
+	if err := s.err(); err != nil {
+		return nil, err
+	}
+	return &stubRows{}, nil
+}
+
+type stubRows struct{ done bool }
+
+func (*stubRows) Columns() []string { return []string{"n"} }
+func (*stubRows) Close() error      { return nil }
+
+func (r *stubRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func init() {
+	sql.Register("sqlmetrics-stub", stubDriver{})
+}
+
+func TestDB(t *testing.T) {
+	sqlDB, err := sql.Open("sqlmetrics-stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	r := metrics.NewRegistry()
+	db := Wrap(sqlDB, "players", r)
+
+	db.Exec("INSERT INTO players VALUES (1)")
+	db.Exec("insert into missing values (1)")
+	rows, err := db.Query("SELECT n FROM players")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	var n int
+	if err := db.QueryRow("select n from players").Scan(&n); err != nil || 1 != n {
+		t.Fatal(n, err)
+	}
+	db.QueryRow("select n from missing")
+	db.Time("transfer", func() error { return errors.New("aborted") })
+
+	for _, c := range []struct {
+		label         string
+		calls, errors int64
+	}{
+		{"insert", 2, 1},
+		{"select", 3, 1},
+		{"transfer", 1, 1},
+	} {
+		tb := metrics.NewTagBoard(namespace, "players", c.label)
+		if m, ok := r.Get(metrics.TaggedMetricName("latency", tb)).(metrics.Timer); !ok || c.calls != m.Count() {
+			t.Errorf("%s latency: %v, want %d", c.label, m, c.calls)
+		}
+		if m, ok := r.Get(metrics.TaggedMetricName("errors", tb)).(metrics.Counter); !ok || c.errors != m.Count() {
+			t.Errorf("%s errors: %v, want %d", c.label, m, c.errors)
+		}
+	}
+}
+
+func TestDBNoRows(t *testing.T) {
+	r := metrics.NewRegistry()
+	db := &DB{name: "players", registry: r}
+	db.Time("lookup", func() error { return sql.ErrNoRows })
+	tb := metrics.NewTagBoard(namespace, "players", "lookup")
+	if nil != r.Get(metrics.TaggedMetricName("errors", tb)) {
+		t.Error("sql.ErrNoRows counted as an error")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	sqlDB, err := sql.Open("sqlmetrics-stub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlDB.Close()
+	sqlDB.SetMaxOpenConns(4)
+	r := metrics.NewRegistry()
+	Wrap(sqlDB, "players", nil).Collect(r)
+	name := metrics.TaggedMetricName("MaxOpenConnections", metrics.NewTagBoard(namespace, "players"))
+	if m, ok := r.Get(name).(metrics.Gauge); !ok || 4 != m.Value() {
+		t.Errorf("MaxOpenConnections: %v", m)
+	}
+}
+
+func TestOperation(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT * FROM players":  "select",
+		"\n\tUpdate players SET": "update",
+		"":                       "unknown",
+	} {
+		if got := Operation(query); want != got {
+			t.Errorf("%q: %s, want %s", query, got, want)
+		}
+	}
+}
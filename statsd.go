@@ -0,0 +1,204 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxStatsDPacket is the size of the buffer a StatsDServer reads each
+// datagram into.  Longer datagrams are truncated.
+var MaxStatsDPacket = 64 << 10
+
+// StatsDServer reads statsd datagrams from a UDP socket and records them in a
+// registry: counters ("c") are added to a Counter, scaled up by their sample
+// rate, gauges ("g") set a GaugeFloat64 or, when signed, adjust it, timings
+// ("ms") update a Timer, histograms ("h") a Histogram and meters ("m") a
+// Meter.  A datagram may carry several lines.  Sets are not supported.
+type StatsDServer struct {
+	// Registry receives the metrics.  It defaults to DefaultRegistry.
+	Registry Registry
+
+	// Logger, when set, is told about malformed lines and read errors.
+	Logger Logger
+
+	mutex  sync.Mutex
+	conn   net.PacketConn
+	closed bool
+	done   chan struct{}
+}
+
+// ListenStatsD listens on the UDP address addr and serves statsd datagrams
+// in the background, recording them in r, or DefaultRegistry if r is nil.
+func ListenStatsD(addr string, r Registry) (*StatsDServer, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &StatsDServer{Registry: r, conn: conn}
+	go s.Serve(conn)
+	return s, nil
+}
+
+// Serve reads datagrams from conn until it fails or the server is closed.
+// It returns nil once the server has been closed.
+func (s *StatsDServer) Serve(conn net.PacketConn) error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		conn.Close()
+		return nil
+	}
+	s.conn = conn
+	done := make(chan struct{})
+	s.done = done
+	s.mutex.Unlock()
+	defer close(done)
+
+	buf := make([]byte, MaxStatsDPacket)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			s.mutex.Lock()
+			closed := s.closed
+			s.mutex.Unlock()
+			if closed || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		if err := s.Ingest(buf[:n]); err != nil {
+			s.logf("statsd: %v: %v", addr, err)
+		}
+	}
+}
+
+// Addr returns the address the server is listening on, or nil before Serve.
+func (s *StatsDServer) Addr() net.Addr {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if nil == s.conn {
+		return nil
+	}
+	return s.conn.LocalAddr()
+}
+
+// Close closes the socket and waits for Serve to return.  Metrics already
+// recorded stay registered.
+func (s *StatsDServer) Close() error {
+	s.mutex.Lock()
+	if s.closed {
+		s.mutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if nil != s.conn {
+		err = s.conn.Close()
+	}
+	done := s.done
+	s.mutex.Unlock()
+	if nil != done {
+		<-done
+	}
+	return err
+}
+
+// Ingest records every line of a statsd datagram, as though it had been read
+// from the socket.  It returns the errors of the lines it could not record,
+// joined, having recorded the rest.
+func (s *StatsDServer) Ingest(packet []byte) error {
+	var errs []error
+	for _, line := range bytes.Split(packet, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		if err := s.record(string(line)); err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", line, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// record parses one "name:value|type[|@rate]" line and applies it.
+func (s *StatsDServer) record(line string) error {
+	i := strings.LastIndex(line, ":")
+	if i <= 0 {
+		return errors.New("missing name")
+	}
+	name, fields := line[:i], strings.Split(line[i+1:], "|")
+	if len(fields) < 2 {
+		return errors.New("missing type")
+	}
+	v, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("bad value %q", fields[0])
+	}
+	rate := 1.0
+	for _, f := range fields[2:] {
+		if strings.HasPrefix(f, "@") {
+			rate, err = strconv.ParseFloat(f[1:], 64)
+			if err != nil || rate <= 0 || rate > 1 {
+				return fmt.Errorf("bad sample rate %q", f)
+			}
+		}
+	}
+
+	r := s.Registry
+	if nil == r {
+		r = DefaultRegistry
+	}
+	var ok bool
+	switch fields[1] {
+	case "c":
+		var c Counter
+		if c, ok = r.GetOrRegister(name, NewCounter).(Counter); ok {
+			c.Inc(int64(math.Round(v / rate)))
+		}
+	case "g":
+		var g GaugeFloat64
+		if g, ok = r.GetOrRegister(name, NewGaugeFloat64).(GaugeFloat64); ok {
+			if sign := fields[0][0]; sign == '+' || sign == '-' {
+				v += g.Value()
+			}
+			g.Update(v)
+		}
+	case "ms":
+		var t Timer
+		c := registryTimerConfig(r)
+		if t, ok = r.GetOrRegister(name, func() Timer { return NewTimerWithConfig(c) }).(Timer); ok {
+			t.UpdateTime(time.Duration(v * float64(time.Millisecond)))
+		}
+	case "h":
+		var h Histogram
+		if h, ok = r.GetOrRegister(name, func() Histogram {
+			return NewHistogram(NewExpDecaySample(1028, 0.015))
+		}).(Histogram); ok {
+			h.Update(int64(math.Round(v)))
+		}
+	case "m":
+		var m Meter
+		if m, ok = r.GetOrRegister(name, NewMeter).(Meter); ok {
+			m.Mark(int64(math.Round(v)))
+		}
+	default:
+		return fmt.Errorf("unsupported type %q", fields[1])
+	}
+	if !ok {
+		return fmt.Errorf("%s is registered as another type", name)
+	}
+	return nil
+}
+
+func (s *StatsDServer) logf(format string, v ...interface{}) {
+	if nil != s.Logger {
+		s.Logger.Printf(format, v...)
+	}
+}
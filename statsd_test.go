@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsDServerIngest(t *testing.T) {
+	r := NewRegistry()
+	s := &StatsDServer{Registry: r}
+	err := s.Ingest([]byte("hits:2|c\nhits:1|c|@0.5\nload:1.5|g\nload:+2|g\nload:-0.5|g\n" +
+		"query:250|ms\nsize:40|h\nevents:3|m\n"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if c := r.Get("hits").(Counter).Count(); 4 != c {
+		t.Errorf("hits: 4 != %v", c)
+	}
+	if v := r.Get("load").(GaugeFloat64).Value(); 3 != v {
+		t.Errorf("load: 3 != %v", v)
+	}
+	if tm := r.Get("query").(Timer); 1 != tm.Count() || int64(250*time.Millisecond) != tm.Max() {
+		t.Errorf("query: count %v, max %v", tm.Count(), tm.Max())
+	}
+	if h := r.Get("size").(Histogram); 40 != h.Max() {
+		t.Errorf("size: 40 != %v", h.Max())
+	}
+	if m := r.Get("events").(Meter); 3 != m.Count() {
+		t.Errorf("events: 3 != %v", m.Count())
+	}
+}
+
+func TestStatsDServerIngestErrors(t *testing.T) {
+	r := NewRegistry()
+	s := &StatsDServer{Registry: r}
+	err := s.Ingest([]byte("users:7|s\nhits|c\nhits:x|c\nhits:1|c|@2\nok:1|c\n"))
+	if nil == err {
+		t.Fatal("no error")
+	}
+	r.GetOrRegister("temp", NewGaugeFloat64)
+	if err := s.Ingest([]byte("temp:1|c")); nil == err {
+		t.Error("type clash not reported")
+	}
+	if c, ok := r.Get("ok").(Counter); !ok || 1 != c.Count() {
+		t.Error("valid line after bad ones was not recorded")
+	}
+	if nil != r.Get("users") {
+		t.Error("set was registered")
+	}
+}
+
+func TestListenStatsD(t *testing.T) {
+	r := NewRegistry()
+	s, err := ListenStatsD("127.0.0.1:0", r)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	conn, err := net.Dial("udp", s.Addr().String())
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		// Datagrams may be dropped, so keep sending until one arrives.
+		conn.Write([]byte("jobs:1|c"))
+		if c, ok := r.Get("jobs").(Counter); ok && c.Count() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no datagram recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := s.Close(); nil != err {
+		t.Error(err)
+	}
+}
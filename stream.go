@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// MinStreamInterval is the shortest interval a client of a handler from
+// NewStreamHandler may ask for.
+var MinStreamInterval = time.Second
+
+// NewStreamHandler returns an http.Handler that streams the metrics of r, or
+// DefaultRegistry if r is nil, as Server-Sent Events: a "metrics" event
+// every interval whose data is a JSON object of the same shape the admin
+// handler serves.  Clients select metrics with the admin handler's ?name=,
+// ?prefix=, ?tag= and ?percentiles= parameters and may choose their own
+// ?interval=, no shorter than MinStreamInterval.  The stream ends when the
+// client disconnects.  With ?history=<duration>, the stream starts with a
+// "history" event holding what the admin handler answers to the same query,
+// if a HistoryRecorder of r is running.
+func NewStreamHandler(r Registry, interval time.Duration) http.Handler {
+	if nil == r {
+		r = DefaultRegistry
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+		q, err := parseAdminQuery(req)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		d := interval
+		if s := req.URL.Query().Get("interval"); s != "" {
+			if d, err = time.ParseDuration(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if d < MinStreamInterval {
+			d = MinStreamInterval
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		if hr := historyOf(r); q.history > 0 && nil != hr {
+			b, err := json.Marshal(q.collectHistory(hr))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: history\ndata: %s\n\n", b); err != nil {
+				return
+			}
+		}
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			b, err := json.Marshal(q.collect(r))
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: metrics\ndata: %s\n\n", b); err != nil {
+				return
+			}
+			flusher.Flush()
+			select {
+			case <-ticker.C:
+			case <-req.Context().Done():
+				return
+			}
+		}
+	})
+}
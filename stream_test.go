@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamHandler(t *testing.T) {
+	defer func(d time.Duration) { MinStreamInterval = d }(MinStreamInterval)
+	MinStreamInterval = time.Millisecond
+
+	r := NewRegistry()
+	c := NewRegisteredCounter("requests", r)
+	NewRegisteredCounter("other", r)
+	server := httptest.NewServer(NewStreamHandler(r, time.Hour))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req := httptest.NewRequest("GET", server.URL+"/?prefix=req&interval=10ms", nil).WithContext(ctx)
+	req.RequestURI = ""
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatal(ct)
+	}
+
+	// The first event is sent straight away; later ones pick up the update.
+	scanner := bufio.NewScanner(resp.Body)
+	var counts []float64
+	for len(counts) < 100 && scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var data map[string]map[string]float64
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 1 {
+			t.Fatal(data)
+		}
+		counts = append(counts, data["requests"]["count"])
+		if len(counts) == 1 {
+			c.Inc(1)
+		} else if counts[len(counts)-1] == 1 {
+			break
+		}
+	}
+	if len(counts) < 2 || counts[0] != 0 || counts[len(counts)-1] != 1 {
+		t.Fatal(counts, scanner.Err())
+	}
+}
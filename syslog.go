@@ -11,11 +11,18 @@ import (
 // Output each metric in the given registry to syslog periodically using
 // the given syslogger.
 func Syslog(r Registry, d time.Duration, w *syslog.Writer) {
-	for _ = range time.Tick(d) {
-		r.Each(func(name string, i interface{}) {
+	SyslogLimit(r, d, w, nil)
+}
+
+// SyslogLimit is like Syslog but sends at most limit.Max metrics per tick.
+func SyslogLimit(r Registry, d time.Duration, w *syslog.Writer, limit *LogLimit) {
+	RunPeriodic(d, func() {
+		limit.Each(r, func(name string, i interface{}) {
 			switch metric := i.(type) {
 			case Counter:
 				w.Info(fmt.Sprintf("counter %s: count: %d", name, metric.Count()))
+			case CounterFloat64:
+				w.Info(fmt.Sprintf("counter %s: count: %f", name, metric.Count()))
 			case Gauge:
 				w.Info(fmt.Sprintf("gauge %s: value: %d", name, metric.Value()))
 			case GaugeFloat64:
@@ -74,5 +81,5 @@ func Syslog(r Registry, d time.Duration, w *syslog.Writer) {
 				))
 			}
 		})
-	}
+	})
 }
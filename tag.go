@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"errors"
+	"fmt"
 	"strings"
 )
 
@@ -53,6 +55,10 @@ func NewTagBoard(tags ...string) TagBoard {
 	return tb
 }
 
+// tagKeys are the keys tagMap gives the tags of a tagged metric, in TagBoard
+// order.
+var tagKeys = [...]string{"ns", "grp", "tgt", "act", "sub"}
+
 func tagMap(tbString string) map[string]string {
 	tags := strings.Split(tbString, TAG_DELIMITER)
 	res := make(map[string]string)
@@ -77,11 +83,57 @@ func TaggedMetricName(name string, tb TagBoard) string {
 	return tb.String() + TAG_METRIC_DELIMITER + name
 }
 
+// taggedName returns name tagged with the given tags, or name itself when
+// there are none.
+func taggedName(name string, tags []string) string {
+	if len(tags) == 0 || tags[0] == "" {
+		return name
+	}
+	return TaggedMetricName(name, NewTagBoard(tags...))
+}
+
 func IsTagged(name string) bool {
 	return strings.Contains(name, TAG_METRIC_DELIMITER)
 }
 
+// ParseTaggedMetric splits a tagged metric name into its name and its tags,
+// keyed ns, grp, tgt, act and sub.  The name is split at the first
+// TAG_METRIC_DELIMITER, so it may contain more.  An untagged name is returned
+// as is, with no tags.  See ParseTagged for a strict parser.
 func ParseTaggedMetric(name string) (string, map[string]string) {
-	fields := strings.Split(name, TAG_METRIC_DELIMITER)
-	return fields[1], tagMap(fields[0])
+	i := strings.Index(name, TAG_METRIC_DELIMITER)
+	if i < 0 {
+		return name, map[string]string{}
+	}
+	return name[i+len(TAG_METRIC_DELIMITER):], tagMap(name[:i])
+}
+
+// ErrNotTagged is returned by ParseTagged for names without tags.
+var ErrNotTagged = errors.New("metrics: name is not tagged")
+
+// ParseTagged splits a tagged metric name into its name and tags, like
+// ParseTaggedMetric, but strictly: it returns ErrNotTagged for names without
+// TAG_METRIC_DELIMITER and an error unless the tags are a non-empty
+// namespace followed by at most four more non-empty tags, and the name is
+// non-empty.  Whenever it succeeds, TaggedMetricName rebuilds name from its
+// results, so exporters can rely on it for any input.
+func ParseTagged(name string) (string, TagBoard, error) {
+	i := strings.Index(name, TAG_METRIC_DELIMITER)
+	if i < 0 {
+		return "", TagBoard{}, ErrNotTagged
+	}
+	base := name[i+len(TAG_METRIC_DELIMITER):]
+	if base == "" {
+		return "", TagBoard{}, fmt.Errorf("metrics: tagged name %q has no name", name)
+	}
+	tags := strings.Split(name[:i], TAG_DELIMITER)
+	if len(tags) > len(tagKeys) {
+		return "", TagBoard{}, fmt.Errorf("metrics: tagged name %q has more than %d tags", name, len(tagKeys))
+	}
+	for _, tag := range tags {
+		if tag == "" {
+			return "", TagBoard{}, fmt.Errorf("metrics: tagged name %q has an empty tag", name)
+		}
+	}
+	return base, NewTagBoard(tags...), nil
 }
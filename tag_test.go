@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseTaggedMetric(t *testing.T) {
+	name, tags := ParseTaggedMetric("lobby|loginTAGerrors")
+	if "errors" != name || "lobby" != tags["ns"] || "login" != tags["grp"] {
+		t.Errorf("%q %v", name, tags)
+	}
+	if name, tags := ParseTaggedMetric("plain"); "plain" != name || 0 != len(tags) {
+		t.Errorf("untagged: %q %v", name, tags)
+	}
+	if name, _ := ParseTaggedMetric("lobbyTAGerrorsTAGx"); "errorsTAGx" != name {
+		t.Errorf("repeated delimiter: %q", name)
+	}
+}
+
+func TestParseTagged(t *testing.T) {
+	name, tb, err := ParseTagged("lobby|login|a|b|cTAGerrors")
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "errors" != name || (TagBoard{"lobby", "login", "a", "b", "c"}) != tb {
+		t.Errorf("%q %+v", name, tb)
+	}
+	if _, _, err := ParseTagged("plain"); ErrNotTagged != err {
+		t.Errorf("untagged: %v", err)
+	}
+	for _, bad := range []string{"TAGerrors", "lobbyTAG", "lobby||loginTAGx", "a|b|c|d|e|fTAGx", "|aTAGx"} {
+		if _, _, err := ParseTagged(bad); nil == err {
+			t.Errorf("%q parsed", bad)
+		}
+	}
+}
+
+func FuzzParseTagged(f *testing.F) {
+	for _, s := range []string{"", "TAG", "plain", "lobby|loginTAGerrors", "aTAGbTAGc", "|TAG|", "a|b|c|d|e|fTAGx"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		ParseTaggedMetric(s)
+		name, tb, err := ParseTagged(s)
+		if nil != err {
+			if IsTagged(s) == (ErrNotTagged == err) {
+				t.Fatalf("%q: IsTagged %v, err %v", s, IsTagged(s), err)
+			}
+			return
+		}
+		if rebuilt := TaggedMetricName(name, tb); rebuilt != s {
+			t.Fatalf("%q rebuilt as %q", s, rebuilt)
+		}
+		if strings.Contains(tb.String(), TAG_METRIC_DELIMITER) {
+			t.Fatalf("%q: tags %q contain the delimiter", s, tb.String())
+		}
+	})
+}
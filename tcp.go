@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProcNetTCP lists the files TCP connection states are read from.
+var ProcNetTCP = []string{"/proc/net/tcp", "/proc/net/tcp6"}
+
+// tcpStates names the socket states of /proc/net/tcp, indexed by their code.
+var tcpStates = [...]string{
+	1:  "Established",
+	2:  "SynSent",
+	3:  "SynRecv",
+	4:  "FinWait1",
+	5:  "FinWait2",
+	6:  "TimeWait",
+	7:  "Close",
+	8:  "CloseWait",
+	9:  "LastAck",
+	10: "Listen",
+	11: "Closing",
+}
+
+var tcpMetrics struct {
+	States     [len(tcpStates)]Gauge
+	ReadErrors Counter
+}
+
+// Capture new values for the TCP connection state gauges.  This is designed
+// to be called as a goroutine.
+func CaptureTCPStats(r Registry, d time.Duration) {
+	for _ = range time.Tick(d) {
+		CaptureTCPStatsOnce(r)
+	}
+}
+
+// Capture the number of IPv4 and IPv6 TCP sockets in each state from
+// /proc/net/tcp and /proc/net/tcp6.  The counts cover every socket of the
+// network namespace, not only those of this process.  Failed reads increment
+// tcp.ReadErrors and leave the previous values in place.  Giving a registry
+// which has not been given to RegisterTCPStats will panic.
+func CaptureTCPStatsOnce(r Registry) {
+	var counts [len(tcpStates)]int64
+	for _, path := range ProcNetTCP {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue // IPv6 disabled
+		}
+		if err != nil {
+			tcpMetrics.ReadErrors.Inc(1)
+			return
+		}
+		err = countTCPStates(f, &counts)
+		f.Close()
+		if err != nil {
+			tcpMetrics.ReadErrors.Inc(1)
+			return
+		}
+	}
+
+	for state, g := range tcpMetrics.States {
+		if g != nil {
+			g.Update(counts[state])
+		}
+	}
+}
+
+// Register gauges counting TCP sockets per state, named after the state, i.e.
+// tcp.Established, tcp.TimeWait and tcp.CloseWait.
+func RegisterTCPStats(r Registry) {
+	for state, name := range tcpStates {
+		if name == "" {
+			continue
+		}
+		tcpMetrics.States[state] = NewGauge()
+		r.Register("tcp."+name, tcpMetrics.States[state])
+	}
+	tcpMetrics.ReadErrors = NewCounter()
+	r.Register("tcp.ReadErrors", tcpMetrics.ReadErrors)
+}
+
+func countTCPStates(r io.Reader, counts *[len(tcpStates)]int64) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		// sl local_address rem_address st ...
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 {
+			continue
+		}
+		state, err := strconv.ParseUint(fields[3], 16, 8)
+		if err != nil {
+			return err
+		}
+		if int(state) < len(counts) {
+			counts[state]++
+		}
+	}
+	return scanner.Err()
+}
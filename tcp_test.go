@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testProcNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 12345 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:D4C2 01 00000000:00000000 00:00000000 00000000  1000        0 12346 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:1F90 0100007F:D4C4 06 00000000:00000000 03:00000D5A 00000000     0        0 0 3 0000000000000000
+   3: 0100007F:1F90 0100007F:D4C6 06 00000000:00000000 03:00000D5A 00000000     0        0 0 3 0000000000000000
+   4: 0100007F:1F90 0100007F:D4C8 08 00000000:00000000 00:00000000 00000000  1000        0 12347 1 0000000000000000 20 4 30 10 -1
+`
+
+func TestTCPStats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tcp")
+	if err := ioutil.WriteFile(path, []byte(testProcNetTCP), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(files []string) { ProcNetTCP = files }(ProcNetTCP)
+	ProcNetTCP = []string{path, filepath.Join(dir, "tcp6")}
+
+	r := NewRegistry()
+	RegisterTCPStats(r)
+	CaptureTCPStatsOnce(r)
+
+	for name, want := range map[string]int64{
+		"tcp.Listen":      1,
+		"tcp.Established": 1,
+		"tcp.TimeWait":    2,
+		"tcp.CloseWait":   1,
+		"tcp.SynSent":     0,
+		"tcp.ReadErrors":  0,
+	} {
+		var v int64
+		switch m := r.Get(name).(type) {
+		case Gauge:
+			v = m.Value()
+		case Counter:
+			v = m.Count()
+		}
+		if v != want {
+			t.Fatalf("%s: got %d, want %d", name, v, want)
+		}
+	}
+}
@@ -19,6 +19,7 @@ type Timer interface {
 	RateMean() float64
 	Snapshot() Timer
 	StdDev() float64
+	Stop()
 	Sum() int64
 	Time(func())
 	Update(int64)
@@ -33,9 +34,42 @@ func GetOrRegisterTimer(name string, r Registry) Timer {
 	if nil == r {
 		r = DefaultRegistry
 	}
-	return r.GetOrRegister(name, NewTimer).(Timer)
+	if m, ok := r.Get(name).(Timer); ok {
+		return m
+	}
+	c := registryTimerConfig(r)
+	return r.GetOrRegister(name, func() Timer { return NewTimerWithConfig(c) }).(Timer)
+}
+
+// TimeFunc records the duration of the execution of f on the Timer named name
+// in DefaultRegistry, constructing and registering it if needed.
+func TimeFunc(name string, f func()) {
+	GetOrRegisterTimer(name, nil).Time(f)
+}
+
+// MeasureSince records the time elapsed since start on the Timer named name in
+// DefaultRegistry, constructing and registering it if needed.
+func MeasureSince(name string, start time.Time) {
+	GetOrRegisterTimer(name, nil).UpdateSince(start)
+}
+
+// Measure starts measuring an operation and returns a function that records
+// the time elapsed since on the Timer named name, tagged with the given tags,
+// in DefaultRegistry:
+//
+//	defer metrics.Measure("db.query", "lobby")()
+func Measure(name string, tags ...string) func() {
+	if UseNilMetrics {
+		return nopMeasure
+	}
+	name, start := taggedName(name, tags), time.Now()
+	return func() {
+		GetOrRegisterTimer(name, nil).UpdateSince(start)
+	}
 }
 
+func nopMeasure() {}
+
 // NewCustomTimer constructs a new StandardTimer from a Histogram and a Meter.
 func NewCustomTimer(h Histogram, m Meter) Timer {
 	if UseNilMetrics {
@@ -44,15 +78,17 @@ func NewCustomTimer(h Histogram, m Meter) Timer {
 	return &StandardTimer{
 		histogram: h,
 		meter:     m,
+		clock:     DefaultClock,
 	}
 }
 
-// NewRegisteredTimer constructs and registers a new StandardTimer.
+// NewRegisteredTimer constructs and registers a new StandardTimer configured
+// with the registry's TimerConfig.
 func NewRegisteredTimer(name string, r Registry) Timer {
-	c := NewTimer()
 	if nil == r {
 		r = DefaultRegistry
 	}
+	c := NewTimerWithConfig(registryTimerConfig(r))
 	r.Register(name, c)
 	return c
 }
@@ -60,13 +96,55 @@ func NewRegisteredTimer(name string, r Registry) Timer {
 // NewTimer constructs a new StandardTimer using an exponentially-decaying
 // sample with the same reservoir size and alpha as UNIX load averages.
 func NewTimer() Timer {
+	return NewTimerWithConfig(TimerConfig{})
+}
+
+// TimerConfig sizes the sample of a StandardTimer, so low-volume timers can
+// use small reservoirs and high-volume ones large.  Zero fields take the
+// package defaults.
+type TimerConfig struct {
+	Window           int           // reservoir size, TimerWindow if zero
+	RescaleThreshold time.Duration // MeterRescaleThreshold if zero
+
+	// Outliers, when positive, makes the timer keep that many of the
+	// largest observations of every OutlierInterval, DefaultOutlierInterval
+	// if zero, for Outliers to return.
+	Outliers        int
+	OutlierInterval time.Duration
+}
+
+// NewTimerWithConfig constructs a new StandardTimer like NewTimer but with
+// the sample described by c.
+func NewTimerWithConfig(c TimerConfig) Timer {
 	if UseNilMetrics {
 		return NilTimer{}
 	}
-	return &StandardTimer{
-		histogram: NewHistogram(NewExpDecaySample(TimerWindow, 0.015)),
+	if c.Window <= 0 {
+		c.Window = TimerWindow
+	}
+	if c.RescaleThreshold <= 0 {
+		c.RescaleThreshold = MeterRescaleThreshold
+	}
+	t := &StandardTimer{
+		histogram: NewHistogram(NewExpDecaySampleWithThreshold(c.Window, 0.015, c.RescaleThreshold)),
 		meter:     NewMeter(),
+		clock:     DefaultClock,
 	}
+	if c.Outliers > 0 {
+		t.outliers = newOutlierSet(c.Outliers, c.OutlierInterval)
+	}
+	return t
+}
+
+// registryTimerConfig returns the TimerConfig r gives the timers it
+// constructs, or the zero TimerConfig if it has none.
+func registryTimerConfig(r Registry) TimerConfig {
+	if r, ok := r.(interface {
+		DefaultTimerConfig() TimerConfig
+	}); ok {
+		return r.DefaultTimerConfig()
+	}
+	return TimerConfig{}
 }
 
 // NilTimer is a no-op Timer.
@@ -113,6 +191,9 @@ func (NilTimer) Snapshot() Timer { return NilTimer{} }
 // StdDev is a no-op.
 func (NilTimer) StdDev() float64 { return 0.0 }
 
+// Stop is a no-op.
+func (NilTimer) Stop() {}
+
 // Sum is a no-op.
 func (NilTimer) Sum() int64 { return 0 }
 
@@ -137,6 +218,14 @@ type StandardTimer struct {
 	histogram Histogram
 	meter     Meter
 	mutex     sync.Mutex
+	clock     Clock
+	stopped   bool
+	outliers  *outlierSet // nil unless configured
+
+	// cached, set by PrecomputePercentiles, is returned by Snapshot until
+	// cachedUntil.
+	cached      *TimerSnapshot
+	cachedUntil time.Time
 }
 
 // Count returns the number of events recorded.
@@ -190,14 +279,47 @@ func (t *StandardTimer) RateMean() float64 {
 	return t.meter.RateMean()
 }
 
-// Snapshot returns a read-only copy of the timer.
+// Snapshot returns a read-only copy of the timer, or the one cached by
+// PrecomputePercentiles while it is fresh.
 func (t *StandardTimer) Snapshot() Timer {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
-	return &TimerSnapshot{
+	if nil != t.cached && t.clock.Now().Before(t.cachedUntil) {
+		return t.cached
+	}
+	return t.snapshot()
+}
+
+func (t *StandardTimer) snapshot() *TimerSnapshot {
+	s := &TimerSnapshot{
 		histogram: t.histogram.Snapshot().(*HistogramSnapshot),
 		meter:     t.meter.Snapshot().(*MeterSnapshot),
 	}
+	if nil != t.outliers {
+		s.outliers = t.outliers.values(t.clock.Now())
+	}
+	return s
+}
+
+// precompute caches a snapshot, its sample sorted, for Snapshot to return for
+// ttl.  The sort runs without the timer's lock held.
+func (t *StandardTimer) precompute(ttl time.Duration) {
+	t.mutex.Lock()
+	s := t.snapshot()
+	t.mutex.Unlock()
+	s.histogram.sample.sort()
+	t.mutex.Lock()
+	t.cached, t.cachedUntil = s, t.clock.Now().Add(ttl)
+	t.mutex.Unlock()
+}
+
+// Stop stops the timer: later events are ignored and its rates stay as
+// they were when it stopped.
+func (t *StandardTimer) Stop() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.stopped = true
+	t.meter.Stop()
 }
 
 // StdDev returns the standard deviation of the values in the sample.
@@ -212,9 +334,9 @@ func (t *StandardTimer) Sum() int64 {
 
 // Record the duration of the execution of the given function.
 func (t *StandardTimer) Time(f func()) {
-	ts := time.Now()
+	ts := t.clock.Now()
 	f()
-	t.UpdateTime(time.Since(ts))
+	t.UpdateTime(t.clock.Now().Sub(ts))
 }
 
 // Record the duration of an event.
@@ -225,16 +347,64 @@ func (t *StandardTimer) UpdateTime(d time.Duration) {
 func (t *StandardTimer) Update(val int64) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
+	if t.stopped {
+		return
+	}
 	t.histogram.Update(val)
 	t.meter.Mark(1)
+	if nil != t.outliers {
+		t.outliers.add(Exemplar{Value: val, Time: t.clock.Now()})
+	}
+}
+
+// UpdateWithExemplar records the duration of an event along with an exemplar
+// of it, if the timer's histogram keeps exemplars.
+func (t *StandardTimer) UpdateWithExemplar(val int64, labels map[string]string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.stopped {
+		return
+	}
+	UpdateWithExemplar(t.histogram, val, labels)
+	t.meter.Mark(1)
+	if nil != t.outliers {
+		t.outliers.add(Exemplar{Value: val, Labels: labels, Time: t.clock.Now()})
+	}
+}
+
+// Outliers returns the largest observations of the last complete outlier
+// interval, largest first, or nil if the timer keeps no outliers.
+func (t *StandardTimer) Outliers() []Exemplar {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if nil == t.outliers {
+		return nil
+	}
+	return t.outliers.values(t.clock.Now())
+}
+
+// Exemplars returns the latest exemplars of the timer's histogram, oldest
+// first.
+func (t *StandardTimer) Exemplars() []Exemplar {
+	if e, ok := t.histogram.(ExemplarRecorder); ok {
+		return e.Exemplars()
+	}
+	return nil
 }
 
 // Record the duration of an event that started at a time and ends now.
 func (t *StandardTimer) UpdateSince(ts time.Time) {
 	t.mutex.Lock()
 	defer t.mutex.Unlock()
-	t.histogram.Update(int64(time.Since(ts)))
+	if t.stopped {
+		return
+	}
+	now := t.clock.Now()
+	t.histogram.Update(int64(now.Sub(ts)))
 	t.meter.Mark(1)
+	if nil != t.outliers {
+		t.outliers.add(Exemplar{Value: int64(now.Sub(ts)), Time: now})
+	}
 }
 
 // Variance returns the variance of the values in the sample.
@@ -246,6 +416,40 @@ func (t *StandardTimer) Variance() float64 {
 type TimerSnapshot struct {
 	histogram *HistogramSnapshot
 	meter     *MeterSnapshot
+	outliers  []Exemplar
+}
+
+// Merge returns a snapshot combining t with a snapshot of other: the samples
+// are merged like HistogramSnapshot.Merge and the counts and rates summed.
+// Timers that don't snapshot to a TimerSnapshot, such as NilTimer, add
+// nothing.
+func (t *TimerSnapshot) Merge(other Timer) *TimerSnapshot {
+	o, ok := other.Snapshot().(*TimerSnapshot)
+	if !ok {
+		return t
+	}
+	meter := *t.meter
+	addMeterSnapshot(&meter, o.meter)
+	n := len(t.outliers)
+	if len(o.outliers) > n {
+		n = len(o.outliers)
+	}
+	return &TimerSnapshot{
+		histogram: t.histogram.Merge(o.histogram),
+		meter:     &meter,
+		outliers:  largestExemplars(t.outliers, o.outliers, n),
+	}
+}
+
+// Exemplars returns the exemplars at the time the snapshot was taken.
+func (t *TimerSnapshot) Exemplars() []Exemplar { return t.histogram.Exemplars() }
+
+// Outliers returns the outliers at the time the snapshot was taken.
+func (t *TimerSnapshot) Outliers() []Exemplar { return t.outliers }
+
+// UpdateWithExemplar panics.
+func (*TimerSnapshot) UpdateWithExemplar(int64, map[string]string) {
+	panic("UpdateWithExemplar called on a TimerSnapshot")
 }
 
 // Count returns the number of events recorded at the time the snapshot was
@@ -273,6 +477,18 @@ func (t *TimerSnapshot) Percentiles(ps []float64) []float64 {
 	return t.histogram.Percentiles(ps)
 }
 
+// P50 returns the median of sampled values at the time the snapshot was
+// taken, sharing one sorted pass with P95 and P99.
+func (t *TimerSnapshot) P50() float64 { return t.histogram.P50() }
+
+// P95 returns the 95th percentile of sampled values at the time the
+// snapshot was taken.
+func (t *TimerSnapshot) P95() float64 { return t.histogram.P95() }
+
+// P99 returns the 99th percentile of sampled values at the time the
+// snapshot was taken.
+func (t *TimerSnapshot) P99() float64 { return t.histogram.P99() }
+
 // Rate1 returns the one-minute moving average rate of events per second at the
 // time the snapshot was taken.
 func (t *TimerSnapshot) Rate1() float64 { return t.meter.Rate1() }
@@ -296,6 +512,9 @@ func (t *TimerSnapshot) Snapshot() Timer { return t }
 // was taken.
 func (t *TimerSnapshot) StdDev() float64 { return t.histogram.StdDev() }
 
+// Stop is a no-op.
+func (t *TimerSnapshot) Stop() {}
+
 // Sum returns the sum at the time the snapshot was taken.
 func (t *TimerSnapshot) Sum() int64 { return t.histogram.Sum() }
 
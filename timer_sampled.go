@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// SampledTimer returns a Timer that records only the fraction rate of the
+// updates to t, spread evenly, for code paths hot enough that updating the
+// reservoir every time is measurable.  Count and the rates are corrected to
+// include the updates that weren't recorded; the percentiles, mean and
+// extremes come from the recorded ones.  It panics unless 0 < rate <= 1 and
+// returns t itself if rate is 1 or t is a NilTimer.
+func SampledTimer(t Timer, rate float64) Timer {
+	if rate <= 0 || rate > 1 {
+		panic("metrics: SampledTimer rate must be in (0, 1]")
+	}
+	if _, ok := t.(NilTimer); ok || rate == 1 {
+		return t
+	}
+	return &fractionTimer{Timer: t, rate: rate}
+}
+
+// fractionTimer records a fraction of the updates of a Timer.
+type fractionTimer struct {
+	Timer
+	rate  float64
+	count int64
+}
+
+// record counts an update and reports whether to record it.
+func (t *fractionTimer) record() bool {
+	n := atomic.AddInt64(&t.count, 1)
+	return int64(float64(n)*t.rate) != int64(float64(n-1)*t.rate)
+}
+
+// Count returns the number of updates, recorded or not.
+func (t *fractionTimer) Count() int64 {
+	return atomic.LoadInt64(&t.count)
+}
+
+// Rate1 returns the one-minute rate of updates, recorded or not.
+func (t *fractionTimer) Rate1() float64 { return t.Timer.Rate1() / t.rate }
+
+// Rate5 returns the five-minute rate of updates, recorded or not.
+func (t *fractionTimer) Rate5() float64 { return t.Timer.Rate5() / t.rate }
+
+// Rate15 returns the fifteen-minute rate of updates, recorded or not.
+func (t *fractionTimer) Rate15() float64 { return t.Timer.Rate15() / t.rate }
+
+// RateMean returns the mean rate of updates, recorded or not.
+func (t *fractionTimer) RateMean() float64 { return t.Timer.RateMean() / t.rate }
+
+// Snapshot returns a read-only copy of the timer with its count and rates
+// corrected.
+func (t *fractionTimer) Snapshot() Timer {
+	count := t.Count()
+	snapshot := t.Timer.Snapshot()
+	s, ok := snapshot.(*TimerSnapshot)
+	if !ok {
+		return snapshot
+	}
+	return &TimerSnapshot{
+		histogram: &HistogramSnapshot{
+			sample:    &SampleSnapshot{count: count, values: s.histogram.sample.values},
+			exemplars: s.histogram.exemplars,
+		},
+		outliers: s.outliers,
+		meter: &MeterSnapshot{
+			count:    count,
+			rate1:    s.meter.rate1 / t.rate,
+			rate5:    s.meter.rate5 / t.rate,
+			rate15:   s.meter.rate15 / t.rate,
+			rateMean: s.meter.rateMean / t.rate,
+		},
+	}
+}
+
+// Time runs f and records its duration if this update is sampled.
+func (t *fractionTimer) Time(f func()) {
+	if t.record() {
+		t.Timer.Time(f)
+		return
+	}
+	f()
+}
+
+// Update records d if this update is sampled.
+func (t *fractionTimer) Update(d int64) {
+	if t.record() {
+		t.Timer.Update(d)
+	}
+}
+
+// UpdateTime records d if this update is sampled.
+func (t *fractionTimer) UpdateTime(d time.Duration) {
+	if t.record() {
+		t.Timer.UpdateTime(d)
+	}
+}
+
+// UpdateSince records the time since ts if this update is sampled.
+func (t *fractionTimer) UpdateSince(ts time.Time) {
+	if t.record() {
+		t.Timer.UpdateSince(ts)
+	}
+}
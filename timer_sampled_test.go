@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func BenchmarkSampledTimer(b *testing.B) {
+	tm := SampledTimer(NewTimer(), 0.01)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tm.Update(1)
+	}
+}
+
+func TestSampledTimer(t *testing.T) {
+	underlying := NewTimer()
+	tm := SampledTimer(underlying, 0.25)
+	for i := 1; i <= 100; i++ {
+		tm.UpdateTime(time.Duration(i))
+	}
+	tm.Time(func() {})
+	if count := underlying.Count(); 25 != count {
+		t.Errorf("underlying.Count(): 25 != %v", count)
+	}
+	if count := tm.Count(); 101 != count {
+		t.Errorf("tm.Count(): 101 != %v", count)
+	}
+	if max := tm.Max(); 100 != max {
+		t.Errorf("tm.Max(): 100 != %v", max)
+	}
+	if r, u := tm.RateMean(), underlying.RateMean(); r < 3.9*u || r > 4.1*u {
+		t.Errorf("tm.RateMean(): %v, underlying %v", r, u)
+	}
+
+	s := tm.Snapshot()
+	tm.Update(1)
+	if count := s.Count(); 101 != count {
+		t.Errorf("s.Count(): 101 != %v", count)
+	}
+	if max := s.Max(); 100 != max {
+		t.Errorf("s.Max(): 100 != %v", max)
+	}
+}
+
+func TestSampledTimerRate(t *testing.T) {
+	tm := NewTimer()
+	if SampledTimer(tm, 1) != tm {
+		t.Error("rate 1 wrapped the timer")
+	}
+	for _, rate := range []float64{0, -1, 1.5} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("rate %v didn't panic", rate)
+				}
+			}()
+			SampledTimer(tm, rate)
+		}()
+	}
+}
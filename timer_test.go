@@ -23,6 +23,36 @@ func TestGetOrRegisterTimer(t *testing.T) {
 	}
 }
 
+func TestTimerConfig(t *testing.T) {
+	tm := NewTimerWithConfig(TimerConfig{Window: 10, RescaleThreshold: time.Minute}).(*StandardTimer)
+	s := tm.histogram.Sample().(*ExpDecaySample)
+	if 10 != s.reservoirSize || time.Minute != s.threshold {
+		t.Errorf("sample: %v, %v", s.reservoirSize, s.threshold)
+	}
+	for i := 0; i < 100; i++ {
+		tm.Update(int64(i))
+	}
+	if size := s.Size(); 10 != size {
+		t.Errorf("s.Size(): 10 != %v", size)
+	}
+
+	s = NewTimer().(*StandardTimer).histogram.Sample().(*ExpDecaySample)
+	if TimerWindow != s.reservoirSize || MeterRescaleThreshold != s.threshold {
+		t.Errorf("default sample: %v, %v", s.reservoirSize, s.threshold)
+	}
+}
+
+func TestRegistryTimerConfig(t *testing.T) {
+	r := NewPrefixedRegistry("db.")
+	r.(*PrefixedRegistry).SetDefaultTimerConfig(TimerConfig{Window: 50})
+	for _, tm := range []Timer{GetOrRegisterTimer("query", r), NewRegisteredTimer("commit", r)} {
+		s := tm.(*StandardTimer).histogram.Sample().(*ExpDecaySample)
+		if 50 != s.reservoirSize || MeterRescaleThreshold != s.threshold {
+			t.Errorf("sample: %v, %v", s.reservoirSize, s.threshold)
+		}
+	}
+}
+
 func TestTimerExtremes(t *testing.T) {
 	tm := NewTimer()
 	tm.Update(math.MaxInt64)
@@ -87,3 +117,69 @@ func ExampleGetOrRegisterTimer() {
 	t.Update(47)
 	fmt.Println(t.Max()) // Output: 47
 }
+
+func TestTimeFuncAndMeasureSince(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+
+	TimeFunc("foo", func() { time.Sleep(time.Millisecond) })
+	MeasureSince("foo", time.Now().Add(-time.Second))
+
+	tm := Get("foo").(Timer)
+	if count := tm.Count(); count != 2 {
+		t.Fatal(count)
+	}
+	if max := tm.Max(); max < int64(time.Second) {
+		t.Fatal(max)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	defer func(r Registry) { DefaultRegistry = r }(DefaultRegistry)
+	DefaultRegistry = NewRegistry()
+
+	func() {
+		defer Measure("db.query", "lobby")()
+		time.Sleep(time.Millisecond)
+	}()
+	Measure("db.query")()
+
+	tm := Get("lobbyTAGdb.query").(Timer)
+	if count := tm.Count(); count != 1 {
+		t.Fatal(count)
+	}
+	if max := tm.Max(); max < int64(time.Millisecond) {
+		t.Fatal(max)
+	}
+	if count := Get("db.query").(Timer).Count(); count != 1 {
+		t.Fatal(count)
+	}
+}
+
+func TestTimerSnapshotMerge(t *testing.T) {
+	a, b := NewTimer(), NewTimer()
+	a.Update(10)
+	b.Update(20)
+	b.Update(30)
+	m := a.Snapshot().(*TimerSnapshot).Merge(b).Merge(NilTimer{})
+	if count := m.Count(); 3 != count {
+		t.Errorf("m.Count(): 3 != %v", count)
+	}
+	if min, max := m.Min(), m.Max(); 10 != min || 30 != max {
+		t.Errorf("m.Min(), m.Max(): %v, %v", min, max)
+	}
+}
+
+func TestTimerStop(t *testing.T) {
+	tm := NewTimer()
+	tm.Update(1)
+	tm.Stop()
+	tm.Update(2)
+	tm.Time(func() {})
+	if count := tm.Count(); 1 != count {
+		t.Errorf("tm.Count(): 1 != %v", count)
+	}
+	if count := tm.Snapshot().(*TimerSnapshot).meter.Count(); 1 != count {
+		t.Errorf("meter count: 1 != %v", count)
+	}
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// A Unit is what the values of a metric measure.  Exporters scale values by
+// unit, so a histogram of latencies is exported like a timer once it is
+// given UnitNanoseconds.
+type Unit string
+
+// Units known to the exporters in this package.
+const (
+	UnitCount       Unit = ""
+	UnitNanoseconds Unit = "ns"
+	UnitBytes       Unit = "bytes"
+)
+
+var (
+	unitMutex sync.RWMutex
+	units     = make(map[string]Unit)
+)
+
+// SetUnit records the unit of the values of the metric named name, as seen by
+// exporters, tags included.
+func SetUnit(name string, u Unit) {
+	unitMutex.Lock()
+	defer unitMutex.Unlock()
+	if u == UnitCount {
+		delete(units, name)
+	} else {
+		units[name] = u
+	}
+}
+
+// MetricUnit returns the unit of the named metric: the one given to SetUnit,
+// or else UnitNanoseconds for timers and UnitCount for everything else.
+func MetricUnit(name string, metric interface{}) Unit {
+	unitMutex.RLock()
+	u, ok := units[name]
+	unitMutex.RUnlock()
+	if ok {
+		return u
+	}
+	if _, ok := metric.(Timer); ok {
+		return UnitNanoseconds
+	}
+	return UnitCount
+}
+
+// A UnitScale maps units to what an exporter divides their values by.  Units
+// it doesn't list are exported unscaled.
+type UnitScale map[Unit]float64
+
+// Scales for exporting durations in seconds or milliseconds.
+var (
+	SecondsScale      = DurationScale(time.Second)
+	MillisecondsScale = DurationScale(time.Millisecond)
+)
+
+// DurationScale returns the UnitScale exporting durations in units of d.
+func DurationScale(d time.Duration) UnitScale {
+	return UnitScale{UnitNanoseconds: float64(d)}
+}
+
+// Divisor returns what the values of the named metric are divided by when
+// exported in s.  Counts and rates are never scaled.
+func (s UnitScale) Divisor(name string, metric interface{}) float64 {
+	if d, ok := s[MetricUnit(name, metric)]; ok && d > 0 {
+		return d
+	}
+	return 1
+}
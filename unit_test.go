@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnitScaleDivisor(t *testing.T) {
+	defer SetUnit("latency", UnitCount)
+	h := NewHistogram(NewUniformSample(10))
+	if d := SecondsScale.Divisor("latency", h); 1 != d {
+		t.Errorf("unitless histogram: %v", d)
+	}
+	if d := SecondsScale.Divisor("login", NewTimer()); float64(time.Second) != d {
+		t.Errorf("timer: %v", d)
+	}
+	SetUnit("latency", UnitNanoseconds)
+	if d := MillisecondsScale.Divisor("latency", h); float64(time.Millisecond) != d {
+		t.Errorf("histogram in ns: %v", d)
+	}
+	if d := DurationScale(0).Divisor("login", NewTimer()); 1 != d {
+		t.Errorf("zero DurationScale: %v", d)
+	}
+}
+
+func TestCurrentLineUnits(t *testing.T) {
+	defer SetUnit("latency", UnitCount)
+	h := NewHistogram(NewUniformSample(10))
+	h.Update(int64(1500 * time.Millisecond))
+	if line := currentLine("latency", "latency", h); !strings.Contains(line, "min: 1500000000,") {
+		t.Error(line)
+	}
+	SetUnit("latency", UnitNanoseconds)
+	if line := currentLine("latency", "latency", h); !strings.Contains(line, "min: 1.500000,") {
+		t.Error(line)
+	}
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// Watch calls f with the previous and new value every time the counter,
+// Instant counter or gauge registered in DefaultRegistry under name changes,
+// until the returned cancel function is called.  It returns a MissingMetric
+// if there is no such metric and an error for metrics that can't be watched.
+//
+// f runs synchronously on the goroutine making the update, so it must be
+// quick; concurrent updates may call it concurrently.  It may look up and
+// register metrics, except when called for a counter merged into by
+// Register under DuplicateMerge, which updates the counter with the
+// registry locked.  Unwatched metrics pay only a nil check per update.
+func Watch(name string, f func(old, new int64)) (cancel func(), err error) {
+	return WatchRegistry(DefaultRegistry, name, f)
+}
+
+// WatchRegistry is like Watch for the metric registered in r.
+func WatchRegistry(r Registry, name string, f func(old, new int64)) (cancel func(), err error) {
+	m := r.Get(name)
+	if m == nil {
+		return nil, MissingMetric(name)
+	}
+	w, ok := m.(interface {
+		watch(func(int64, int64)) func()
+	})
+	if !ok {
+		return nil, fmt.Errorf("metric %s of type %T can't be watched", name, m)
+	}
+	return w.watch(f), nil
+}
+
+// watchMutex serializes changes to every watcherSet; updates read the sets
+// without locking.
+var watchMutex sync.Mutex
+
+// watcherSet holds the listeners of one metric as an immutable slice that
+// is replaced whenever a listener is added or removed.
+type watcherSet struct {
+	list atomic.Value // []*watcher
+}
+
+type watcher struct {
+	f func(old, new int64)
+}
+
+func (s *watcherSet) add(f func(old, new int64)) func() {
+	w := &watcher{f}
+	watchMutex.Lock()
+	defer watchMutex.Unlock()
+	list, _ := s.list.Load().([]*watcher)
+	s.list.Store(append(list[:len(list):len(list)], w))
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			watchMutex.Lock()
+			defer watchMutex.Unlock()
+			list, _ := s.list.Load().([]*watcher)
+			kept := make([]*watcher, 0, len(list))
+			for _, other := range list {
+				if other != w {
+					kept = append(kept, other)
+				}
+			}
+			s.list.Store(kept)
+		})
+	}
+}
+
+func (s *watcherSet) notify(old, new int64) {
+	list, _ := s.list.Load().([]*watcher)
+	for _, w := range list {
+		w.f(old, new)
+	}
+}
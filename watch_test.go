@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	r := NewRegistry()
+	c := NewRegisteredCounter("counter", r)
+	g := NewRegisteredGauge("gauge", r)
+	i := GetOrRegisterInstantCounter("instant", r)
+
+	var seen [][3]int64
+	for n, name := range []string{"counter", "gauge", "instant"} {
+		n := int64(n)
+		if _, err := WatchRegistry(r, name, func(old, new int64) {
+			seen = append(seen, [3]int64{n, old, new})
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c.Inc(3)
+	c.Dec(1)
+	g.Update(5)
+	g.Update(2)
+	i.Inc(4)
+	i.Clear()
+	want := [][3]int64{{0, 0, 3}, {0, 3, 2}, {1, 0, 5}, {1, 5, 2}, {2, 0, 4}, {2, 4, 0}}
+	if len(seen) != len(want) {
+		t.Fatal(seen)
+	}
+	for j := range want {
+		if seen[j] != want[j] {
+			t.Fatal(seen)
+		}
+	}
+}
+
+func TestWatchCancel(t *testing.T) {
+	r := NewRegistry()
+	g := NewRegisteredGauge("gauge", r)
+	var a, b int
+	cancelA, _ := WatchRegistry(r, "gauge", func(old, new int64) { a++ })
+	WatchRegistry(r, "gauge", func(old, new int64) { b++ })
+	g.Update(1)
+	cancelA()
+	cancelA()
+	g.Update(2)
+	if a != 1 || b != 2 {
+		t.Fatal(a, b)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredMeter("meter", r)
+	if _, err := WatchRegistry(r, "missing", func(old, new int64) {}); err != MissingMetric("missing") {
+		t.Fatal(err)
+	}
+	if _, err := WatchRegistry(r, "meter", func(old, new int64) {}); err == nil {
+		t.Fatal("watched a meter")
+	}
+}
+
+func TestWatchUpdateBatchRegisters(t *testing.T) {
+	r := NewRegistry()
+	NewRegisteredCounter("counter", r)
+	WatchRegistry(r, "counter", func(old, new int64) {
+		GetOrRegisterCounter("counter.watched", r).Inc(new - old)
+	})
+	done := make(chan struct{})
+	go func() {
+		r.UpdateBatch(map[string]int64{"counter": 2, "other": 1})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("UpdateBatch deadlocked")
+	}
+	if n := r.Get("counter.watched").(Counter).Count(); 2 != n {
+		t.Errorf("counter.watched: %d, want 2", n)
+	}
+}
@@ -10,76 +10,105 @@ import (
 // Write sorts writes each metric in the given registry periodically to the
 // given io.Writer.
 func Write(r Registry, d time.Duration, w io.Writer) {
-	for _ = range time.Tick(d) {
-		WriteOnce(r, w)
-	}
+	WriteLimit(r, d, w, nil)
+}
+
+// WriteLimit is like Write but writes at most limit.Max metrics per tick.
+func WriteLimit(r Registry, d time.Duration, w io.Writer, limit *LogLimit) {
+	RunPeriodic(d, func() {
+		WriteOnceLimit(r, w, limit)
+	})
 }
 
 // WriteOnce sorts and writes metrics in the given registry to the given
 // io.Writer.
 func WriteOnce(r Registry, w io.Writer) {
+	WriteOnceLimit(r, w, nil)
+}
+
+// WriteOnceLimit sorts and writes the metrics of the given registry selected
+// by limit to the given io.Writer.  A nil limit writes every metric.
+func WriteOnceLimit(r Registry, w io.Writer, limit *LogLimit) {
 	var namedMetrics namedMetricSlice
-	r.Each(func(name string, i interface{}) {
+	limit.Each(r, func(name string, i interface{}) {
 		namedMetrics = append(namedMetrics, namedMetric{name, i})
 	})
 
 	sort.Sort(namedMetrics)
-	for _, namedMetric := range namedMetrics {
-		switch metric := namedMetric.m.(type) {
-		case Counter:
-			fmt.Fprintf(w, "counter %s\n", namedMetric.name)
-			fmt.Fprintf(w, "  count:       %9d\n", metric.Count())
-		case Gauge:
-			fmt.Fprintf(w, "gauge %s\n", namedMetric.name)
-			fmt.Fprintf(w, "  value:       %9d\n", metric.Value())
-		case GaugeFloat64:
-			fmt.Fprintf(w, "gauge %s\n", namedMetric.name)
-			fmt.Fprintf(w, "  value:       %f\n", metric.Value())
-		case Healthcheck:
-			metric.Check()
-			fmt.Fprintf(w, "healthcheck %s\n", namedMetric.name)
-			fmt.Fprintf(w, "  error:       %v\n", metric.Error())
-		case Histogram:
-			h := metric.Snapshot()
-			ps := h.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
-			fmt.Fprintf(w, "histogram %s\n", namedMetric.name)
-			fmt.Fprintf(w, "  count:       %9d\n", h.Count())
-			fmt.Fprintf(w, "  min:         %9d\n", h.Min())
-			fmt.Fprintf(w, "  max:         %9d\n", h.Max())
-			fmt.Fprintf(w, "  mean:        %12.2f\n", h.Mean())
-			fmt.Fprintf(w, "  stddev:      %12.2f\n", h.StdDev())
-			fmt.Fprintf(w, "  median:      %12.2f\n", ps[0])
-			fmt.Fprintf(w, "  75%%:         %12.2f\n", ps[1])
-			fmt.Fprintf(w, "  95%%:         %12.2f\n", ps[2])
-			fmt.Fprintf(w, "  99%%:         %12.2f\n", ps[3])
-			fmt.Fprintf(w, "  99.9%%:       %12.2f\n", ps[4])
-		case Meter:
-			m := metric.Snapshot()
-			fmt.Fprintf(w, "meter %s\n", namedMetric.name)
-			fmt.Fprintf(w, "  count:       %9d\n", m.Count())
-			fmt.Fprintf(w, "  1-min rate:  %12.2f\n", m.Rate1())
-			fmt.Fprintf(w, "  5-min rate:  %12.2f\n", m.Rate5())
-			fmt.Fprintf(w, "  15-min rate: %12.2f\n", m.Rate15())
-			fmt.Fprintf(w, "  mean rate:   %12.2f\n", m.RateMean())
-		case Timer:
-			t := metric.Snapshot()
-			ps := t.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
-			fmt.Fprintf(w, "timer %s\n", namedMetric.name)
-			fmt.Fprintf(w, "  count:       %9d\n", t.Count())
-			fmt.Fprintf(w, "  min:         %9d\n", t.Min())
-			fmt.Fprintf(w, "  max:         %9d\n", t.Max())
-			fmt.Fprintf(w, "  mean:        %12.2f\n", t.Mean())
-			fmt.Fprintf(w, "  stddev:      %12.2f\n", t.StdDev())
-			fmt.Fprintf(w, "  median:      %12.2f\n", ps[0])
-			fmt.Fprintf(w, "  75%%:         %12.2f\n", ps[1])
-			fmt.Fprintf(w, "  95%%:         %12.2f\n", ps[2])
-			fmt.Fprintf(w, "  99%%:         %12.2f\n", ps[3])
-			fmt.Fprintf(w, "  99.9%%:       %12.2f\n", ps[4])
-			fmt.Fprintf(w, "  1-min rate:  %12.2f\n", t.Rate1())
-			fmt.Fprintf(w, "  5-min rate:  %12.2f\n", t.Rate5())
-			fmt.Fprintf(w, "  15-min rate: %12.2f\n", t.Rate15())
-			fmt.Fprintf(w, "  mean rate:   %12.2f\n", t.RateMean())
+	if TextBreakdown {
+		for _, item := range breakdownItems(namedMetrics.each) {
+			if item.header != "" {
+				fmt.Fprintf(w, "%s\n", item.header)
+			}
+			writeMetric(w, item.display, item.m)
 		}
+		return
+	}
+	for _, namedMetric := range namedMetrics {
+		writeMetric(w, namedMetric.name, namedMetric.m)
+	}
+}
+
+// writeMetric writes one metric the way WriteOnce does, under the name
+// display.
+func writeMetric(w io.Writer, display string, m interface{}) {
+	switch metric := m.(type) {
+	case Counter:
+		fmt.Fprintf(w, "counter %s\n", display)
+		fmt.Fprintf(w, "  count:       %9d\n", metric.Count())
+	case CounterFloat64:
+		fmt.Fprintf(w, "counter %s\n", display)
+		fmt.Fprintf(w, "  count:       %f\n", metric.Count())
+	case Gauge:
+		fmt.Fprintf(w, "gauge %s\n", display)
+		fmt.Fprintf(w, "  value:       %9d\n", metric.Value())
+	case GaugeFloat64:
+		fmt.Fprintf(w, "gauge %s\n", display)
+		fmt.Fprintf(w, "  value:       %f\n", metric.Value())
+	case Healthcheck:
+		metric.Check()
+		fmt.Fprintf(w, "healthcheck %s\n", display)
+		fmt.Fprintf(w, "  error:       %v\n", metric.Error())
+	case Histogram:
+		h := metric.Snapshot()
+		ps := h.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+		fmt.Fprintf(w, "histogram %s\n", display)
+		fmt.Fprintf(w, "  count:       %9d\n", h.Count())
+		fmt.Fprintf(w, "  min:         %9d\n", h.Min())
+		fmt.Fprintf(w, "  max:         %9d\n", h.Max())
+		fmt.Fprintf(w, "  mean:        %12.2f\n", h.Mean())
+		fmt.Fprintf(w, "  stddev:      %12.2f\n", h.StdDev())
+		fmt.Fprintf(w, "  median:      %12.2f\n", ps[0])
+		fmt.Fprintf(w, "  75%%:         %12.2f\n", ps[1])
+		fmt.Fprintf(w, "  95%%:         %12.2f\n", ps[2])
+		fmt.Fprintf(w, "  99%%:         %12.2f\n", ps[3])
+		fmt.Fprintf(w, "  99.9%%:       %12.2f\n", ps[4])
+	case Meter:
+		m := metric.Snapshot()
+		fmt.Fprintf(w, "meter %s\n", display)
+		fmt.Fprintf(w, "  count:       %9d\n", m.Count())
+		fmt.Fprintf(w, "  1-min rate:  %12.2f\n", m.Rate1())
+		fmt.Fprintf(w, "  5-min rate:  %12.2f\n", m.Rate5())
+		fmt.Fprintf(w, "  15-min rate: %12.2f\n", m.Rate15())
+		fmt.Fprintf(w, "  mean rate:   %12.2f\n", m.RateMean())
+	case Timer:
+		t := metric.Snapshot()
+		ps := t.Percentiles([]float64{0.5, 0.75, 0.95, 0.99, 0.999})
+		fmt.Fprintf(w, "timer %s\n", display)
+		fmt.Fprintf(w, "  count:       %9d\n", t.Count())
+		fmt.Fprintf(w, "  min:         %9d\n", t.Min())
+		fmt.Fprintf(w, "  max:         %9d\n", t.Max())
+		fmt.Fprintf(w, "  mean:        %12.2f\n", t.Mean())
+		fmt.Fprintf(w, "  stddev:      %12.2f\n", t.StdDev())
+		fmt.Fprintf(w, "  median:      %12.2f\n", ps[0])
+		fmt.Fprintf(w, "  75%%:         %12.2f\n", ps[1])
+		fmt.Fprintf(w, "  95%%:         %12.2f\n", ps[2])
+		fmt.Fprintf(w, "  99%%:         %12.2f\n", ps[3])
+		fmt.Fprintf(w, "  99.9%%:       %12.2f\n", ps[4])
+		fmt.Fprintf(w, "  1-min rate:  %12.2f\n", t.Rate1())
+		fmt.Fprintf(w, "  5-min rate:  %12.2f\n", t.Rate5())
+		fmt.Fprintf(w, "  15-min rate: %12.2f\n", t.Rate15())
+		fmt.Fprintf(w, "  mean rate:   %12.2f\n", t.RateMean())
 	}
 }
 
@@ -98,3 +127,10 @@ func (nms namedMetricSlice) Swap(i, j int) { nms[i], nms[j] = nms[j], nms[i] }
 func (nms namedMetricSlice) Less(i, j int) bool {
 	return nms[i].name < nms[j].name
 }
+
+// each calls f for each metric in order.
+func (nms namedMetricSlice) each(f func(string, interface{})) {
+	for _, nm := range nms {
+		f(nm.name, nm.m)
+	}
+}
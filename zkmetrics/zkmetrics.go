@@ -0,0 +1,60 @@
+// Package zkmetrics applies metric policies kept in zookeeper to go-metrics
+// registries while they run, so noisy metrics can be muted in production
+// without a redeploy.
+//
+// The node WatchDisabled watches holds the prefixes to disable as JSON:
+//
+//	{"Disabled": ["debug.", "lobby|matchTAG"]}
+package zkmetrics
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/moonfrog/go-metrics"
+	"github.com/moonfrog/nucleus/zootils"
+)
+
+// DisabledConfig is the content of a node listing disabled metric prefixes.
+type DisabledConfig struct {
+	Disabled []string
+}
+
+// WatchDisabled loads the prefixes listed at configUri, disables them with
+// metrics.SetDisabledMetrics on r, or metrics.DefaultRegistry if r is nil,
+// and applies the list again whenever the node changes.  Reload failures are
+// reported to l, if it isn't nil, and leave the last list in force.
+func WatchDisabled(r metrics.Registry, configUri string, l metrics.Logger) error {
+	w := &watcher{registry: r, configUri: configUri, l: l}
+	config := &DisabledConfig{}
+	if err := zootils.GetInstance().LoadConfig(config, configUri, w.changed); err != nil {
+		return fmt.Errorf("zkmetrics: load: %v", err)
+	}
+	w.apply(config)
+	return nil
+}
+
+type watcher struct {
+	registry  metrics.Registry
+	configUri string
+	l         metrics.Logger
+	mutex     sync.Mutex // serializes reloads
+}
+
+// changed reloads the node, without watching it again, when it changes.
+func (w *watcher) changed(string) {
+	config := &DisabledConfig{}
+	if err := zootils.GetInstance().LoadConfig(config, w.configUri, func(string) {}); err != nil {
+		if nil != w.l {
+			w.l.Printf("zkmetrics: reload %s: %v", w.configUri, err)
+		}
+		return
+	}
+	w.apply(config)
+}
+
+func (w *watcher) apply(config *DisabledConfig) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	metrics.SetDisabledMetrics(w.registry, config.Disabled)
+}
@@ -0,0 +1,46 @@
+package zkmetrics
+
+import (
+	"testing"
+
+	"github.com/moonfrog/go-metrics"
+)
+
+func disabled(r metrics.Registry, name string) bool {
+	_, ok := metrics.GetOrRegisterCounter(name, r).(metrics.NilCounter)
+	return ok
+}
+
+func TestWatcherApply(t *testing.T) {
+	defer metrics.ResetMetricPolicies()
+	r := metrics.NewRegistry()
+	w := &watcher{registry: r}
+	metrics.GetOrRegisterCounter("debug.requests", r).Inc(1)
+	metrics.GetOrRegisterCounter("requests", r).Inc(1)
+
+	w.apply(&DisabledConfig{Disabled: []string{"debug.", "lobby|matchTAG"}})
+	if nil != r.Get("debug.requests") || nil == r.Get("requests") {
+		t.Error("disabled metrics weren't unregistered alone")
+	}
+	if !disabled(r, "debug.requests") || !disabled(r, metrics.TaggedMetricName("errors", metrics.NewTagBoard("lobby", "match"))) {
+		t.Error("listed prefixes aren't disabled")
+	}
+	if disabled(r, "lobby.requests") {
+		t.Error("unlisted prefix disabled")
+	}
+
+	// A changed list re-enables the prefixes it no longer holds.
+	w.apply(&DisabledConfig{Disabled: []string{"lobby|matchTAG"}})
+	if disabled(r, "debug.requests") {
+		t.Error("debug. is still disabled")
+	}
+	if !disabled(r, metrics.TaggedMetricName("errors", metrics.NewTagBoard("lobby", "match"))) {
+		t.Error("lobby|matchTAG was re-enabled")
+	}
+
+	// An empty list clears them all.
+	w.apply(&DisabledConfig{})
+	if disabled(r, metrics.TaggedMetricName("errors", metrics.NewTagBoard("lobby", "match"))) {
+		t.Error("lobby|matchTAG is still disabled")
+	}
+}